@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcmd
+
+import (
+	"github.com/spf13/cobra"
+	kubectlnfd "sigs.k8s.io/node-feature-discovery/pkg/kubectl-nfd"
+)
+
+var (
+	// Namespace of nfd-master, where feature baselines are stored
+	baselineNamespace string
+)
+
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Manage the feature baselines of nodes",
+	Long:  `Manage the per-node feature baselines that nfd-master compares the features of nodes against`,
+}
+
+var baselineAcceptCmd = &cobra.Command{
+	Use:   "accept",
+	Short: "Accept the current features of a node as its baseline",
+	Long: `Request nfd-master to record the current features of a node as the feature
+baseline of the node, e.g. after an intended hardware change. This clears the
+deviations reported for the node.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnErrors(cmd, kubectlnfd.BaselineAccept(node, baselineNamespace, kubeconfig))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(baselineCmd)
+	baselineCmd.AddCommand(baselineAcceptCmd)
+
+	baselineAcceptCmd.Flags().StringVarP(&node, "node", "n", "", "Node whose features to accept")
+	baselineAcceptCmd.Flags().StringVarP(&baselineNamespace, "namespace", "s", "", "Namespace of nfd-master where the baselines are stored (default: search all namespaces)")
+	baselineAcceptCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "kubeconfig file to use")
+	err := baselineAcceptCmd.MarkFlagRequired("node")
+	if err != nil {
+		panic(err)
+	}
+}
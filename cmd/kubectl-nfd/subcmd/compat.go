@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcmd
+
+import (
+	"github.com/spf13/cobra"
+	kubectlnfd "sigs.k8s.io/node-feature-discovery/pkg/kubectl-nfd"
+)
+
+var (
+	// Source of the image compatibility spec
+	compatSource kubectlnfd.CompatSource
+)
+
+var compatCmd = &cobra.Command{
+	Use:   "compat",
+	Short: "Check the compatibility of container images with nodes",
+	Long:  `Check the hardware and kernel requirements of container images, described in image compatibility specs, against the features of nodes`,
+}
+
+var compatValidateNodeCmd = &cobra.Command{
+	Use:   "validate-node",
+	Short: "Validate an image compatibility spec against the features of nodes",
+	Long: `Evaluate the requirements of an image compatibility spec against the features
+of a node and report pass/fail per requirement. The spec is read from a file or
+from an artifact in an OCI image layout directory. The features are read from a
+NodeFeature file or from the cluster. If neither a node nor a NodeFeature file
+is given, the compatible nodes of the cluster are listed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnErrors(cmd, kubectlnfd.ValidateImageCompatibility(compatSource, node, nodefeature, kubeconfig))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(compatCmd)
+	compatCmd.AddCommand(compatValidateNodeCmd)
+
+	compatValidateNodeCmd.Flags().StringVar(&compatSource.SpecFile, "spec", "", "Path to the image compatibility spec file")
+	compatValidateNodeCmd.Flags().StringVar(&compatSource.OCILayout, "oci-layout", "", "Path to an OCI image layout directory containing the image compatibility artifact")
+	compatValidateNodeCmd.Flags().StringVar(&compatSource.Tag, "tag", "", "Tag of the image (or artifact) in the OCI image layout")
+	compatValidateNodeCmd.Flags().StringVarP(&node, "node", "n", "", "Node to validate (default: all nodes of the cluster)")
+	compatValidateNodeCmd.Flags().StringVarP(&nodefeature, "nodefeature-file", "f", "", "Path to a NodeFeature file to validate against")
+	compatValidateNodeCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "kubeconfig file to use")
+	compatValidateNodeCmd.MarkFlagsMutuallyExclusive("spec", "oci-layout")
+	compatValidateNodeCmd.MarkFlagsMutuallyExclusive("node", "nodefeature-file")
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcmd
+
+import (
+	"github.com/spf13/cobra"
+	kubectlnfd "sigs.k8s.io/node-feature-discovery/pkg/kubectl-nfd"
+)
+
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Show an inventory of node features in one or more clusters",
+	Long: `Show the number of nodes having each element of the selected feature sets.
+Multiple clusters are shown side by side when multiple kubeconfig contexts are specified.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runInventory(cmd, false)
+	},
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare node features between clusters",
+	Long:  `Show the elements of the selected feature sets that are not present on the same share of nodes in all of the specified clusters`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runInventory(cmd, true)
+	},
+}
+
+func runInventory(cmd *cobra.Command, diffOnly bool) {
+	exitOnErrors(cmd, kubectlnfd.Inventory(kubeconfig, contexts, allContexts, featureSets, diffOnly))
+}
+
+func init() {
+	for _, c := range []*cobra.Command{inventoryCmd, diffCmd} {
+		RootCmd.AddCommand(c)
+
+		c.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "kubeconfig file to use")
+		c.Flags().StringSliceVar(&contexts, "contexts", nil, "Comma separated list of kubeconfig contexts (clusters) to inspect")
+		c.Flags().BoolVar(&allContexts, "all-contexts", false, "Inspect all contexts (clusters) found in the kubeconfig")
+		c.Flags().StringSliceVarP(&featureSets, "feature", "F", nil, "Comma separated list of feature sets to inspect, e.g. cpu.model or 'pci.*'")
+		err := c.MarkFlagRequired("feature")
+		if err != nil {
+			panic(err)
+		}
+	}
+}
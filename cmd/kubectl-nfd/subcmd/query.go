@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcmd
+
+import (
+	"github.com/spf13/cobra"
+	kubectlnfd "sigs.k8s.io/node-feature-discovery/pkg/kubectl-nfd"
+)
+
+// Feature expressions to query
+var queryExprs []string
+
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "List the nodes whose features match a query in one or more clusters",
+	Long: `List the nodes whose features match all of the given expressions. An expression
+has the form <domain>.<feature>.<element>, optionally followed by =<values> or
+!=<values> where values is a comma-separated list, e.g.
+  kernel.loadedmodule.kvm
+  cpu.model.vendor_id=Intel,AMD
+  pci.device.vendor!=10de
+Multiple clusters are shown line by line when multiple kubeconfig contexts are specified.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnErrors(cmd, kubectlnfd.Query(kubeconfig, contexts, allContexts, queryExprs))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(queryCmd)
+
+	queryCmd.Flags().StringArrayVarP(&queryExprs, "expr", "e", nil, "Feature expression to match, can be specified multiple times")
+	queryCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "kubeconfig file to use")
+	queryCmd.Flags().StringSliceVar(&contexts, "contexts", nil, "Comma separated list of kubeconfig contexts (clusters) to query")
+	queryCmd.Flags().BoolVar(&allContexts, "all-contexts", false, "Query all contexts (clusters) found in the kubeconfig")
+	err := queryCmd.MarkFlagRequired("expr")
+	if err != nil {
+		panic(err)
+	}
+}
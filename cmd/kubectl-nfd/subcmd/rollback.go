@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	kubectlnfd "sigs.k8s.io/node-feature-discovery/pkg/kubectl-nfd"
+)
+
+var (
+	// Namespace of nfd-master, where NodeFeatureRule revisions are stored
+	revisionNamespace string
+	// Revision to roll back to
+	toRevision int64
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history NAME",
+	Short: "Show the revision history of a NodeFeatureRule",
+	Long:  `Show the revisions of a NodeFeatureRule recorded by nfd-master, with the time each revision was applied and the number of nodes it matched`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnErrors(cmd, kubectlnfd.History(args[0], revisionNamespace, kubeconfig))
+	},
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback NAME",
+	Short: "Roll back a NodeFeatureRule to a previous revision",
+	Long: `Restore the spec of a NodeFeatureRule from a revision recorded by nfd-master.
+By default the revision preceding the latest one is restored.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnErrors(cmd, kubectlnfd.Rollback(args[0], revisionNamespace, kubeconfig, toRevision))
+	},
+}
+
+func exitOnErrors(cmd *cobra.Command, errs []error) {
+	if len(errs) > 0 {
+		for _, e := range errs {
+			cmd.PrintErrln(e)
+		}
+		// Return non-zero exit code to indicate failure
+		os.Exit(1)
+	}
+}
+
+func init() {
+	for _, c := range []*cobra.Command{historyCmd, rollbackCmd} {
+		RootCmd.AddCommand(c)
+
+		c.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "kubeconfig file to use")
+		c.Flags().StringVarP(&revisionNamespace, "namespace", "s", "", "Namespace of nfd-master where the revisions are stored (default: search all namespaces)")
+	}
+	rollbackCmd.Flags().Int64Var(&toRevision, "to-revision", 0, "Revision to roll back to (default: the previous revision)")
+}
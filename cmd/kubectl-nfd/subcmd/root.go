@@ -32,6 +32,12 @@ var (
 	node string
 	// kubeconfig file to use
 	kubeconfig string
+	// kubeconfig contexts (clusters) to operate on
+	contexts []string
+	// Operate on all contexts found in the kubeconfig
+	allContexts bool
+	// Feature sets to inspect
+	featureSets []string
 )
 
 // RootCmd represents the base command when called without any subcommands
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcmd
+
+import (
+	"github.com/spf13/cobra"
+	kubectlnfd "sigs.k8s.io/node-feature-discovery/pkg/kubectl-nfd"
+)
+
+var (
+	// Path to the workload file to simulate
+	workloadFile string
+	// Paths to NodeFeatureRule files with proposed rule changes
+	proposedRules []string
+	// Path to the nfd-master config file
+	masterConfig string
+	// Namespace of nfd-master
+	masterNamespace string
+)
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Simulate on which nodes a workload could be scheduled",
+	Long: `Compute the NFD labels and taints of each node of the cluster, combined with
+the existing non-NFD labels and taints, and list the nodes where the workload is
+eligible for scheduling, with the reasons for ineligible nodes. Optionally, the
+effect of new or changed NodeFeatureRules and NamespacedNodeFeatureRules is
+shown side by side.
+
+The rules are processed like nfd-master does, with the nfd-master configuration
+given with --master-config (the defaults if not specified). NFD taints are only
+created if enabled in the configuration.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnErrors(cmd, kubectlnfd.Simulate(workloadFile, proposedRules, masterConfig, masterNamespace, kubeconfig))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(simulateCmd)
+
+	simulateCmd.Flags().StringVarP(&workloadFile, "workload-file", "f", "", "Path to the file with the workload(s) to simulate")
+	simulateCmd.Flags().StringSliceVar(&proposedRules, "rules", nil, "Path to a NodeFeatureRule or NamespacedNodeFeatureRule file with proposed rule changes, can be specified multiple times")
+	simulateCmd.Flags().StringVar(&masterConfig, "master-config", "", "Path to the nfd-master config file (default: nfd-master defaults)")
+	simulateCmd.Flags().StringVarP(&masterNamespace, "namespace", "s", "node-feature-discovery", "Namespace of nfd-master")
+	simulateCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "kubeconfig file to use")
+	err := simulateCmd.MarkFlagRequired("workload-file")
+	if err != nil {
+		panic(err)
+	}
+}
@@ -27,19 +27,25 @@ import (
 var testCmd = &cobra.Command{
 	Use:   "test",
 	Short: "Test a NodeFeatureRule file against a Node",
-	Long:  `Test a NodeFeatureRule file against a Node to ensure it is valid before applying it to a cluster`,
+	Long: `Test a NodeFeatureRule file against a Node to ensure it is valid before applying it to a cluster.
+If no node is specified the rule is evaluated against all nodes and a summary of the output is printed.
+Multiple clusters can be tested at once by specifying multiple kubeconfig contexts.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("Evaluating NodeFeatureRule against Node %s\n", node)
-		err := kubectlnfd.Test(nodefeaturerule, node, kubeconfig)
+		target := "all Nodes"
+		if node != "" {
+			target = "Node " + node
+		}
+		fmt.Printf("Evaluating NodeFeatureRule against %s\n", target)
+		err := kubectlnfd.Test(nodefeaturerule, node, kubeconfig, contexts, allContexts)
 		if len(err) > 0 {
-			fmt.Printf("NodeFeatureRule is not valid for Node %s\n", node)
+			fmt.Printf("NodeFeatureRule is not valid for %s\n", target)
 			for _, e := range err {
 				cmd.PrintErrln(e)
 			}
 			// Return non-zero exit code to indicate failure
 			os.Exit(1)
 		}
-		fmt.Printf("NodeFeatureRule is valid for Node %s\n", node)
+		fmt.Printf("NodeFeatureRule is valid for %s\n", target)
 	},
 }
 
@@ -49,6 +55,8 @@ func init() {
 	testCmd.Flags().StringVarP(&nodefeaturerule, "nodefeaturerule-file", "f", "", "Path to the NodeFeatureRule file to validate")
 	testCmd.Flags().StringVarP(&node, "nodename", "n", "", "Node to validate against")
 	testCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "kubeconfig file to use")
+	testCmd.Flags().StringSliceVar(&contexts, "contexts", nil, "Comma separated list of kubeconfig contexts (clusters) to test against")
+	testCmd.Flags().BoolVar(&allContexts, "all-contexts", false, "Test against all contexts (clusters) found in the kubeconfig")
 	err := testCmd.MarkFlagRequired("nodefeaturerule-file")
 	if err != nil {
 		panic(err)
@@ -0,0 +1,54 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcmd
+
+import (
+	"github.com/spf13/cobra"
+	kubectlnfd "sigs.k8s.io/node-feature-discovery/pkg/kubectl-nfd"
+)
+
+var (
+	// Path to the file where the timeline of changes is recorded
+	timelineFile string
+	// Name of the nfd-master instance
+	masterInstance string
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch the features and NFD-managed properties of a node",
+	Long: `Watch the NodeFeature objects and the Node object of a node and print the
+changes of features and NFD-managed labels, annotations, extended resources and
+taints as they happen. Optionally, the changes are recorded to a timeline file
+as JSON lines.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnErrors(cmd, kubectlnfd.Watch(node, masterInstance, kubeconfig, timelineFile))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringVarP(&node, "node", "n", "", "Node to watch")
+	watchCmd.Flags().StringVar(&timelineFile, "timeline", "", "Path to the file where changes are appended as JSON lines")
+	watchCmd.Flags().StringVar(&masterInstance, "instance", "", "Name of the nfd-master instance (the -instance flag of nfd-master) managing the node")
+	watchCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "kubeconfig file to use")
+	err := watchCmd.MarkFlagRequired("node")
+	if err != nil {
+		panic(err)
+	}
+}
@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"os"
 	"time"
+	// Embed time zone data for evaluating NodeFeatureRule schedules
+	_ "time/tzdata"
 
 	"k8s.io/klog/v2"
 	klogutils "sigs.k8s.io/node-feature-discovery/pkg/utils/klog"
@@ -153,6 +155,17 @@ func initFlags(flagset *flag.FlagSet) (*master.Args, *master.ConfigOverrideArgs)
 			"in the same format as in the config file (i.e. json or yaml). These options")
 	flagset.BoolVar(&args.EnableLeaderElection, "enable-leader-election", false,
 		"Enables a leader election. Enable this when running more than one replica on nfd master.")
+	flagset.BoolVar(&args.EnableApiServer, "enable-apiserver", false,
+		"Serve the NFD API group from an aggregated API server run by nfd-master, "+
+			"instead of storing the objects in the etcd of the Kubernetes API server.")
+	flagset.IntVar(&args.ApiServerPort, "apiserver-port", 8443,
+		"Port on which to serve the aggregated NFD API. It has effect only when -enable-apiserver has been set.")
+	flagset.StringVar(&args.ApiServerStorageDir, "apiserver-storage-dir", "",
+		"Directory where the aggregated API server persists the objects. Objects are only kept in memory if empty.")
+	flagset.StringVar(&args.ApiServerCertFile, "apiserver-cert-file", "",
+		"Serving certificate of the aggregated API server. A self-signed certificate is generated if not specified.")
+	flagset.StringVar(&args.ApiServerKeyFile, "apiserver-key-file", "",
+		"Private key matching -apiserver-cert-file.")
 
 	args.Klog = klogutils.InitKlogFlags(flagset)
 
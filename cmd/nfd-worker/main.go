@@ -127,6 +127,9 @@ func initFlags(flagset *flag.FlagSet) (*worker.Args, *worker.ConfigOverrideArgs)
 	flagset.BoolVar(&args.EnableNodeFeatureApi, "enable-nodefeature-api", true,
 		"Enable the NodeFeature CRD API for communicating with nfd-master. This will automatically disable the gRPC communication."+
 			" DEPRECATED: will be removed in a future release along with the deprecated gRPC API.")
+	flagset.StringVar(&args.Instance, "instance", "",
+		"Name of this worker instance, used for running multiple independent worker instances on the same node. "+
+			"The instance name is appended to the name of the NodeFeature object created by the worker.")
 	flagset.StringVar(&args.Kubeconfig, "kubeconfig", "",
 		"Kubeconfig to use")
 	flagset.BoolVar(&args.Oneshot, "oneshot", false,
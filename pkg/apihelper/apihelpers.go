@@ -19,6 +19,8 @@ package apihelper
 //go:generate mockery --name=APIHelpers --inpackage
 
 import (
+	"context"
+
 	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
 	corev1 "k8s.io/api/core/v1"
 	k8sclient "k8s.io/client-go/kubernetes"
@@ -30,19 +32,19 @@ type APIHelpers interface {
 	GetClient() (*k8sclient.Clientset, error)
 
 	// GetNode returns the Kubernetes node on which this container is running.
-	GetNode(*k8sclient.Clientset, string) (*corev1.Node, error)
+	GetNode(context.Context, *k8sclient.Clientset, string) (*corev1.Node, error)
 
 	// GetNodes returns all the nodes in the cluster
-	GetNodes(*k8sclient.Clientset) (*corev1.NodeList, error)
+	GetNodes(context.Context, *k8sclient.Clientset) (*corev1.NodeList, error)
 
 	// UpdateNode updates the node via the API server using a client.
-	UpdateNode(*k8sclient.Clientset, *corev1.Node) error
+	UpdateNode(context.Context, *k8sclient.Clientset, *corev1.Node) error
 
 	// PatchNode updates the node object via the API server using a client.
-	PatchNode(*k8sclient.Clientset, string, []JsonPatch) error
+	PatchNode(context.Context, *k8sclient.Clientset, string, []JsonPatch) error
 
 	// PatchNodeStatus updates the node status via the API server using a client.
-	PatchNodeStatus(*k8sclient.Clientset, string, []JsonPatch) error
+	PatchNodeStatus(context.Context, *k8sclient.Clientset, string, []JsonPatch) error
 
 	// GetTopologyClient returns a topologyclientset
 	GetTopologyClient() (*topologyclientset.Clientset, error)
@@ -52,9 +52,9 @@ func (h K8sHelpers) GetTopologyClient() (*topologyclientset.Clientset, error) {
 }
 
 // GetNode retrieves one node object.
-func (h K8sHelpers) GetNode(cli *k8sclient.Clientset, nodeName string) (*corev1.Node, error) {
+func (h K8sHelpers) GetNode(ctx context.Context, cli *k8sclient.Clientset, nodeName string) (*corev1.Node, error) {
 	// Get the node object using node name
-	node, err := cli.CoreV1().Nodes().Get(context.TODO(), nodeName, meta_v1.GetOptions{})
+	node, err := cli.CoreV1().Nodes().Get(ctx, nodeName, meta_v1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -63,14 +63,14 @@ func (h K8sHelpers) GetNode(cli *k8sclient.Clientset, nodeName string) (*corev1.
 }
 
 // GetNodes retrieves all the node objects.
-func (h K8sHelpers) GetNodes(cli *k8sclient.Clientset) (*corev1.NodeList, error) {
-	return cli.CoreV1().Nodes().List(context.TODO(), meta_v1.ListOptions{})
+func (h K8sHelpers) GetNodes(ctx context.Context, cli *k8sclient.Clientset) (*corev1.NodeList, error) {
+	return cli.CoreV1().Nodes().List(ctx, meta_v1.ListOptions{})
 }
 
 // UpdateNode sends updated node object to the apiserver
-func (h K8sHelpers) UpdateNode(c *k8sclient.Clientset, n *corev1.Node) error {
+func (h K8sHelpers) UpdateNode(ctx context.Context, c *k8sclient.Clientset, n *corev1.Node) error {
 	// Send the updated node to the apiserver.
-	_, err := c.CoreV1().Nodes().Update(context.TODO(), n, meta_v1.UpdateOptions{})
+	_, err := c.CoreV1().Nodes().Update(ctx, n, meta_v1.UpdateOptions{})
 	if err != nil {
 		return err
 	}
@@ -78,22 +78,22 @@ func (h K8sHelpers) UpdateNode(c *k8sclient.Clientset, n *corev1.Node) error {
 	return nil
 }
 
-func (h K8sHelpers) PatchNode(c *k8sclient.Clientset, nodeName string, patches []JsonPatch) error {
+func (h K8sHelpers) PatchNode(ctx context.Context, c *k8sclient.Clientset, nodeName string, patches []JsonPatch) error {
 	if len(patches) > 0 {
 		data, err := json.Marshal(patches)
 		if err == nil {
-			_, err = c.CoreV1().Nodes().Patch(context.TODO(), nodeName, types.JSONPatchType, data, meta_v1.PatchOptions{})
+			_, err = c.CoreV1().Nodes().Patch(ctx, nodeName, types.JSONPatchType, data, meta_v1.PatchOptions{})
 		}
 		return err
 	}
 	return nil
 }
 
-func (h K8sHelpers) PatchNodeStatus(c *k8sclient.Clientset, nodeName string, patches []JsonPatch) error {
+func (h K8sHelpers) PatchNodeStatus(ctx context.Context, c *k8sclient.Clientset, nodeName string, patches []JsonPatch) error {
 	if len(patches) > 0 {
 		data, err := json.Marshal(patches)
 		if err == nil {
-			_, err = c.CoreV1().Nodes().Patch(context.TODO(), nodeName, types.JSONPatchType, data, meta_v1.PatchOptions{}, "status")
+			_, err = c.CoreV1().Nodes().Patch(ctx, nodeName, types.JSONPatchType, data, meta_v1.PatchOptions{}, "status")
 		}
 		return err
 	}
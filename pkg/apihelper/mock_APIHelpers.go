@@ -3,6 +3,8 @@
 package apihelper
 
 import (
+	context "context"
+
 	mock "github.com/stretchr/testify/mock"
 	kubernetes "k8s.io/client-go/kubernetes"
 
@@ -42,25 +44,25 @@ func (_m *MockAPIHelpers) GetClient() (*kubernetes.Clientset, error) {
 	return r0, r1
 }
 
-// GetNode provides a mock function with given fields: _a0, _a1
-func (_m *MockAPIHelpers) GetNode(_a0 *kubernetes.Clientset, _a1 string) (*v1.Node, error) {
-	ret := _m.Called(_a0, _a1)
+// GetNode provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockAPIHelpers) GetNode(_a0 context.Context, _a1 *kubernetes.Clientset, _a2 string) (*v1.Node, error) {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 *v1.Node
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*kubernetes.Clientset, string) (*v1.Node, error)); ok {
-		return rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, *kubernetes.Clientset, string) (*v1.Node, error)); ok {
+		return rf(_a0, _a1, _a2)
 	}
-	if rf, ok := ret.Get(0).(func(*kubernetes.Clientset, string) *v1.Node); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, *kubernetes.Clientset, string) *v1.Node); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*v1.Node)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(*kubernetes.Clientset, string) error); ok {
-		r1 = rf(_a0, _a1)
+	if rf, ok := ret.Get(1).(func(context.Context, *kubernetes.Clientset, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -68,25 +70,25 @@ func (_m *MockAPIHelpers) GetNode(_a0 *kubernetes.Clientset, _a1 string) (*v1.No
 	return r0, r1
 }
 
-// GetNodes provides a mock function with given fields: _a0
-func (_m *MockAPIHelpers) GetNodes(_a0 *kubernetes.Clientset) (*v1.NodeList, error) {
-	ret := _m.Called(_a0)
+// GetNodes provides a mock function with given fields: _a0, _a1
+func (_m *MockAPIHelpers) GetNodes(_a0 context.Context, _a1 *kubernetes.Clientset) (*v1.NodeList, error) {
+	ret := _m.Called(_a0, _a1)
 
 	var r0 *v1.NodeList
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*kubernetes.Clientset) (*v1.NodeList, error)); ok {
-		return rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *kubernetes.Clientset) (*v1.NodeList, error)); ok {
+		return rf(_a0, _a1)
 	}
-	if rf, ok := ret.Get(0).(func(*kubernetes.Clientset) *v1.NodeList); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *kubernetes.Clientset) *v1.NodeList); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*v1.NodeList)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(*kubernetes.Clientset) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, *kubernetes.Clientset) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -146,13 +148,13 @@ func (_m *MockAPIHelpers) GetTopologyClient() (*versioned.Clientset, error) {
 	return r0, r1
 }
 
-// PatchNode provides a mock function with given fields: _a0, _a1, _a2
-func (_m *MockAPIHelpers) PatchNode(_a0 *kubernetes.Clientset, _a1 string, _a2 []JsonPatch) error {
-	ret := _m.Called(_a0, _a1, _a2)
+// PatchNode provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *MockAPIHelpers) PatchNode(_a0 context.Context, _a1 *kubernetes.Clientset, _a2 string, _a3 []JsonPatch) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*kubernetes.Clientset, string, []JsonPatch) error); ok {
-		r0 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(0).(func(context.Context, *kubernetes.Clientset, string, []JsonPatch) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -160,13 +162,13 @@ func (_m *MockAPIHelpers) PatchNode(_a0 *kubernetes.Clientset, _a1 string, _a2 [
 	return r0
 }
 
-// PatchNodeStatus provides a mock function with given fields: _a0, _a1, _a2
-func (_m *MockAPIHelpers) PatchNodeStatus(_a0 *kubernetes.Clientset, _a1 string, _a2 []JsonPatch) error {
-	ret := _m.Called(_a0, _a1, _a2)
+// PatchNodeStatus provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *MockAPIHelpers) PatchNodeStatus(_a0 context.Context, _a1 *kubernetes.Clientset, _a2 string, _a3 []JsonPatch) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*kubernetes.Clientset, string, []JsonPatch) error); ok {
-		r0 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(0).(func(context.Context, *kubernetes.Clientset, string, []JsonPatch) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -174,13 +176,13 @@ func (_m *MockAPIHelpers) PatchNodeStatus(_a0 *kubernetes.Clientset, _a1 string,
 	return r0
 }
 
-// UpdateNode provides a mock function with given fields: _a0, _a1
-func (_m *MockAPIHelpers) UpdateNode(_a0 *kubernetes.Clientset, _a1 *v1.Node) error {
-	ret := _m.Called(_a0, _a1)
+// UpdateNode provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockAPIHelpers) UpdateNode(_a0 context.Context, _a1 *kubernetes.Clientset, _a2 *v1.Node) error {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*kubernetes.Clientset, *v1.Node) error); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, *kubernetes.Clientset, *v1.Node) error); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 contains the specification format of the hardware and
+// kernel requirements of container images, i.e. image compatibility specs.
+package v1alpha1
+
+import (
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+const (
+	// Version is the version of the spec format.
+	Version = "v1alpha1"
+
+	// ArtifactType is the artifact type of OCI artifacts carrying an image
+	// compatibility spec.
+	ArtifactType = "application/vnd.nfd.image-compatibility.v1alpha1"
+
+	// SpecMediaType is the media type of the layer of an OCI artifact that
+	// contains the image compatibility spec.
+	SpecMediaType = "application/vnd.nfd.image-compatibility.spec.v1alpha1+yaml"
+)
+
+// Spec describes the requirements that a node must satisfy for running a
+// container image.
+type Spec struct {
+	// Version of the spec format.
+	Version string `json:"version"`
+	// Compatibilities is a list of alternative sets of requirements, e.g. one
+	// per hardware platform that the image was built for. A node is
+	// compatible with the image if it satisfies all the requirements of any
+	// of the sets.
+	Compatibilities []Compatibility `json:"compatibilities"`
+}
+
+// Compatibility is a set of requirements, all of which must be satisfied.
+type Compatibility struct {
+	// Description of the set of requirements.
+	// +optional
+	Description string `json:"description,omitempty"`
+	// Tag of the set of requirements, e.g. the name of the image variant.
+	// +optional
+	Tag string `json:"tag,omitempty"`
+	// Rules are the requirements, expressed as feature matchers.
+	Rules []GroupRule `json:"rules"`
+}
+
+// GroupRule is one requirement, expressed as feature matchers that are
+// evaluated with the same semantics as the rules of NodeFeatureRule objects.
+type GroupRule struct {
+	// Name of the requirement.
+	Name string `json:"name"`
+	// Description of the requirement.
+	// +optional
+	Description string `json:"description,omitempty"`
+	// MatchFeatures specifies a set of matcher terms all of which must match.
+	// +optional
+	MatchFeatures nfdv1alpha1.FeatureMatcher `json:"matchFeatures,omitempty"`
+	// MatchAny specifies a list of matchers one of which must match.
+	// +optional
+	MatchAny []nfdv1alpha1.MatchAnyElem `json:"matchAny,omitempty"`
+}
@@ -69,6 +69,50 @@ const (
 	// label for filtering features designated for a certain node.
 	NodeFeatureObjNodeNameLabel = "nfd.node.kubernetes.io/node-name"
 
+	// NodeFeatureObjClusterNameLabel is the label that specifies the (edge)
+	// cluster that a NodeFeature object in a hub cluster was mirrored from.
+	NodeFeatureObjClusterNameLabel = "nfd.node.kubernetes.io/cluster-name"
+
+	// NodeFeatureObjHubOutputLabel is the label that marks NodeFeature objects
+	// carrying the labels that a hub cluster created for an edge node.
+	NodeFeatureObjHubOutputLabel = "nfd.node.kubernetes.io/hub-output"
+
+	// NodeFeatureObjWorkerInstanceLabel is the label that specifies the
+	// nfd-worker instance that created a NodeFeature object, when multiple
+	// worker instances are run on the same node.
+	NodeFeatureObjWorkerInstanceLabel = "nfd.node.kubernetes.io/worker-instance"
+
+	// NodeFeatureRuleNameLabel is the label that specifies the
+	// NodeFeatureRule that a revision (ControllerRevision object) recorded by
+	// nfd-master belongs to.
+	NodeFeatureRuleNameLabel = "nfd.node.kubernetes.io/nodefeaturerule-name"
+
+	// NodeFeatureRuleNameAnnotation is the annotation that holds the full
+	// name of the NodeFeatureRule that a revision belongs to. The value of
+	// NodeFeatureRuleNameLabel is truncated for long names.
+	NodeFeatureRuleNameAnnotation = AnnotationNs + "/nodefeaturerule-name"
+
+	// RuleRevisionAppliedAtAnnotation is the annotation that holds the time
+	// when a NodeFeatureRule revision was recorded.
+	RuleRevisionAppliedAtAnnotation = AnnotationNs + "/applied-at"
+
+	// RuleRevisionAffectedNodesAnnotation is the annotation that holds the
+	// number of nodes matched by a NodeFeatureRule revision when it was
+	// applied.
+	RuleRevisionAffectedNodesAnnotation = AnnotationNs + "/affected-nodes"
+
+	// FeatureBaselineLabel is the label that marks the ConfigMap objects
+	// holding the feature baselines of nodes recorded by nfd-master.
+	FeatureBaselineLabel = "nfd.node.kubernetes.io/feature-baseline"
+
+	// BaselineAcceptAnnotation is the annotation that requests nfd-master to
+	// record the current features of a node as its feature baseline.
+	BaselineAcceptAnnotation = AnnotationNs + "/baseline-accept"
+
+	// BaselineAcceptedAtAnnotation is the annotation that holds the time when
+	// the feature baseline of a node was recorded.
+	BaselineAcceptedAtAnnotation = AnnotationNs + "/baseline-accepted-at"
+
 	// FeatureAnnotationNs is the (default) namespace for feature annotations.
 	FeatureAnnotationNs = "feature.node.kubernetes.io"
 
@@ -80,6 +80,15 @@ func (f *Features) InsertAttributeFeatures(domain, feature string, values map[st
 	maps.Copy(f.Attributes[key].Elements, values)
 }
 
+// SetWorkerLabels makes the labels published by nfd-worker available to rules
+// as the worker.labels feature, replacing any previous elements of it.
+func (f *Features) SetWorkerLabels(labels map[string]string) {
+	if f.Attributes == nil {
+		f.Attributes = make(map[string]AttributeFeatureSet)
+	}
+	f.Attributes[WorkerLabelsDomain+"."+WorkerLabelsFeature] = NewAttributeFeatures(maps.Clone(labels))
+}
+
 // Exists returns a non-empty string if a feature exists. The return value is
 // the type of the feautre, i.e. "flag", "attribute" or "instance".
 func (f *Features) Exists(name string) string {
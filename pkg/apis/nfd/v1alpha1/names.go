@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	featureBaselineNamePrefix = "nfd-baseline-"
+	// nameHashLen is the length of the hash suffix of truncated names
+	nameHashLen = 10
+)
+
+// FeatureBaselineName returns the name of the ConfigMap holding the feature
+// baseline of a node. Names that would exceed the maximum object name length
+// are truncated and suffixed with a hash of the node name to keep them
+// unique.
+func FeatureBaselineName(nodeName string) string {
+	return truncateName(featureBaselineNamePrefix+nodeName, nodeName, validation.DNS1123SubdomainMaxLength)
+}
+
+// NodeFeatureRuleNameLabelValue returns the value of the
+// NodeFeatureRuleNameLabel for a NodeFeatureRule. Names that would exceed the
+// maximum label value length are truncated and suffixed with a hash of the
+// name. The full name is stored in the NodeFeatureRuleNameAnnotation.
+func NodeFeatureRuleNameLabelValue(nfrName string) string {
+	return truncateName(nfrName, nfrName, validation.LabelValueMaxLength)
+}
+
+// InstanceAnnotation returns the name of an NFD annotation of a named
+// nfd-master instance, i.e. the name prefixed with the instance name. The name
+// is returned as is if the instance name is empty.
+func InstanceAnnotation(instance, name string) string {
+	if instance == "" {
+		return name
+	}
+	return instance + "." + name
+}
+
+// ParseNsNames converts the value of an NFD tracking annotation, i.e. a
+// comma-separated list of names, into a slice of fully namespaced names. Names
+// without a namespace get the given default namespace.
+func ParseNsNames(val, defaultNs string) []string {
+	if val == "" {
+		return nil
+	}
+	names := strings.Split(val, ",")
+	for i, name := range names {
+		if !strings.Contains(name, "/") {
+			names[i] = defaultNs + "/" + name
+		}
+	}
+	return names
+}
+
+// truncateName truncates a name to maxLen characters, replacing the end with
+// a hash of the given key if the name is too long.
+func truncateName(name, key string, maxLen int) string {
+	if len(name) <= maxLen {
+		return name
+	}
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])[:nameHashLen]
+	prefix := strings.TrimRight(name[:maxLen-len(hash)-1], "-.")
+	return prefix + "-" + hash
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestFeatureBaselineName(t *testing.T) {
+	assert.Equal(t, "nfd-baseline-node-1", FeatureBaselineName("node-1"))
+
+	long1 := strings.Repeat("a", 250)
+	long2 := long1[:249] + "b"
+	n1 := FeatureBaselineName(long1)
+	n2 := FeatureBaselineName(long2)
+	assert.Len(t, n1, validation.DNS1123SubdomainMaxLength)
+	assert.Empty(t, validation.IsDNS1123Subdomain(n1))
+	assert.True(t, strings.HasPrefix(n1, "nfd-baseline-aaa"))
+	assert.NotEqual(t, n1, n2)
+	assert.Equal(t, n1, FeatureBaselineName(long1))
+}
+
+func TestNodeFeatureRuleNameLabelValue(t *testing.T) {
+	assert.Equal(t, "my-rule", NodeFeatureRuleNameLabelValue("my-rule"))
+
+	long := strings.Repeat("a", 100)
+	v := NodeFeatureRuleNameLabelValue(long)
+	assert.Len(t, v, validation.LabelValueMaxLength)
+	assert.Empty(t, validation.IsValidLabelValue(v))
+	assert.NotEqual(t, v, NodeFeatureRuleNameLabelValue(long+"b"))
+}
+
+func TestInstanceAnnotation(t *testing.T) {
+	assert.Equal(t, FeatureLabelsAnnotation, InstanceAnnotation("", FeatureLabelsAnnotation))
+	assert.Equal(t, "blue."+FeatureLabelsAnnotation, InstanceAnnotation("blue", FeatureLabelsAnnotation))
+}
+
+func TestParseNsNames(t *testing.T) {
+	assert.Nil(t, ParseNsNames("", FeatureLabelNs))
+	assert.Equal(t, []string{FeatureLabelNs + "/foo", "example.com/bar"}, ParseNsNames("foo,example.com/bar", FeatureLabelNs))
+}
@@ -31,6 +31,17 @@ import (
 	"sigs.k8s.io/node-feature-discovery/pkg/utils"
 )
 
+// FeatureNotAvailableError is returned when a rule references a feature that
+// is not present in the features the rule is executed against.
+// +k8s:deepcopy-gen=false
+type FeatureNotAvailableError struct {
+	Feature string
+}
+
+func (e *FeatureNotAvailableError) Error() string {
+	return fmt.Sprintf("feature %q not available", e.Feature)
+}
+
 // RuleOutput contains the output out rule execution.
 // +k8s:deepcopy-gen=false
 type RuleOutput struct {
@@ -39,6 +50,8 @@ type RuleOutput struct {
 	Annotations       map[string]string
 	Vars              map[string]string
 	Taints            []corev1.Taint
+	// Matched is true if the rule matched the input features.
+	Matched bool
 }
 
 // Execute the rule against a set of input features.
@@ -103,6 +116,7 @@ func Execute(r *nfdv1alpha1.Rule, features *nfdv1alpha1.Features) (RuleOutput, e
 		Annotations:       maps.Clone(r.Annotations),
 		ExtendedResources: maps.Clone(r.ExtendedResources),
 		Taints:            slices.Clone(r.Taints),
+		Matched:           true,
 	}
 	klog.V(2).InfoS("rule matched", "ruleName", r.Name, "ruleOutput", utils.DelayedDumper(ret))
 	return ret, nil
@@ -210,7 +224,7 @@ func evaluateFeatureMatcher(m *nfdv1alpha1.FeatureMatcher, features *nfdv1alpha1
 
 			}
 		} else {
-			return false, nil, fmt.Errorf("feature %q not available", featureName)
+			return false, nil, &FeatureNotAvailableError{Feature: featureName}
 		}
 		matches[dom][nam] = append(matches[dom][nam], matchedElems...)
 
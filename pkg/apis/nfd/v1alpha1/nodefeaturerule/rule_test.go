@@ -63,9 +63,12 @@ func TestRule(t *testing.T) {
 	assert.Nilf(t, err, "unexpected error: %v", err)
 	assert.Equal(t, r1.Labels, m.Labels, "empty matcher should have matched empty features")
 	assert.Empty(t, r1.Vars, "vars should be empty")
+	assert.True(t, m.Matched, "rule should have matched")
 
 	_, err = Execute(r2, f)
 	assert.Error(t, err, "matching against a missing feature type should have returned an error")
+	var notAvailable *FeatureNotAvailableError
+	assert.ErrorAs(t, err, &notAvailable)
 
 	// Test empty feature sets
 	f.Flags["domain-1.kf-1"] = nfdv1alpha1.NewFlagFeatures()
@@ -79,6 +82,7 @@ func TestRule(t *testing.T) {
 	m, err = Execute(r2, f)
 	assert.Nilf(t, err, "unexpected error: %v", err)
 	assert.Nil(t, m.Labels, "unexpected match")
+	assert.False(t, m.Matched, "unexpected match")
 
 	// Test non-empty feature sets
 	f.Flags["domain-1.kf-1"].Elements["key-x"] = nfdv1alpha1.Nil{}
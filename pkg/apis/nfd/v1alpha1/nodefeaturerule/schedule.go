@@ -0,0 +1,245 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodefeaturerule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// Schedule is a parsed rule schedule, ready for evaluation.
+// +k8s:deepcopy-gen=false
+type Schedule struct {
+	windows  []scheduleWindow
+	location *time.Location
+}
+
+// ScheduleStatus is the state of a rule schedule at a certain point in time.
+// +k8s:deepcopy-gen=false
+type ScheduleStatus struct {
+	// Active is true if any of the schedule windows is open.
+	Active bool
+	// NextBoundary is the time of the next opening or closing of any of the
+	// schedule windows, i.e. the next point in time when Active may change.
+	// Zero if there are no upcoming boundaries.
+	NextBoundary time.Time
+}
+
+type scheduleWindow struct {
+	start    *cronExpr
+	duration time.Duration
+}
+
+// ParseSchedule parses and validates a rule schedule.
+func ParseSchedule(s *nfdv1alpha1.RuleSchedule) (*Schedule, error) {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeZone %q: %w", s.TimeZone, err)
+	}
+	if len(s.Windows) == 0 {
+		return nil, fmt.Errorf("schedule must have at least one window")
+	}
+
+	out := &Schedule{location: loc, windows: make([]scheduleWindow, len(s.Windows))}
+	for i, w := range s.Windows {
+		c, err := parseCronExpr(w.Start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start %q of window #%d: %w", w.Start, i, err)
+		}
+		if w.Duration.Duration <= 0 {
+			return nil, fmt.Errorf("invalid duration %q of window #%d: must be positive", w.Duration.Duration, i)
+		}
+		out.windows[i] = scheduleWindow{start: c, duration: w.Duration.Duration}
+	}
+	return out, nil
+}
+
+// EvaluateSchedule returns the state of a rule schedule at the given time.
+func EvaluateSchedule(s *nfdv1alpha1.RuleSchedule, now time.Time) (ScheduleStatus, error) {
+	sched, err := ParseSchedule(s)
+	if err != nil {
+		return ScheduleStatus{}, err
+	}
+	return sched.Status(now), nil
+}
+
+// Status returns the state of the schedule at the given time.
+func (s *Schedule) Status(now time.Time) ScheduleStatus {
+	now = now.In(s.location)
+	status := ScheduleStatus{}
+
+	updateBoundary := func(t time.Time) {
+		if !t.IsZero() && (status.NextBoundary.IsZero() || t.Before(status.NextBoundary)) {
+			status.NextBoundary = t
+		}
+	}
+
+	for _, w := range s.windows {
+		// The earliest window opening that is still open or upcoming
+		first := w.start.next(now.Add(-w.duration))
+		if first.IsZero() {
+			continue
+		}
+		if first.After(now) {
+			updateBoundary(first)
+		} else {
+			status.Active = true
+			updateBoundary(first.Add(w.duration))
+			updateBoundary(w.start.next(now))
+		}
+	}
+	return status
+}
+
+// cronExpr is a parsed five-field cron expression. Each field is stored as a
+// bitmask of the allowed values.
+type cronExpr struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar are set if the day-of-month or day-of-week field
+	// was "*", following the standard cron semantics of matching either of
+	// them if both are restricted.
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day-of-month", 1, 31},
+	{"month", 1, 12},
+	{"day-of-week", 0, 7},
+}
+
+func parseCronExpr(spec string) (*cronExpr, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("expected %d fields, got %d", len(cronFields), len(fields))
+	}
+
+	masks := make([]uint64, len(fields))
+	for i, f := range fields {
+		m, err := parseCronField(f, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		masks[i] = m
+	}
+
+	c := &cronExpr{
+		minute:  masks[0],
+		hour:    masks[1],
+		dom:     masks[2],
+		month:   masks[3],
+		dow:     masks[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}
+	// Sunday may be specified as 0 or 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+// parseCronField parses one comma-separated cron field, each element of which
+// may be "*", a number or a range, optionally with a step (e.g. "*/15" or
+// "1-5/2").
+func parseCronField(s string, f cronField) (uint64, error) {
+	var mask uint64
+	for _, elem := range strings.Split(s, ",") {
+		rangeStr, stepStr, hasStep := strings.Cut(elem, "/")
+
+		start, end := f.min, f.max
+		if rangeStr != "*" {
+			lo, hi, isRange := strings.Cut(rangeStr, "-")
+			var err error
+			if start, err = strconv.Atoi(lo); err != nil {
+				return 0, fmt.Errorf("invalid %s %q", f.name, elem)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(hi); err != nil {
+					return 0, fmt.Errorf("invalid %s %q", f.name, elem)
+				}
+			} else if hasStep {
+				// "N/step" means from N to max
+				end = f.max
+			}
+		}
+		if start < f.min || end > f.max || start > end {
+			return 0, fmt.Errorf("%s %q out of range [%d-%d]", f.name, elem, f.min, f.max)
+		}
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s %q", f.name, elem)
+			}
+		}
+		for i := start; i <= end; i += step {
+			mask |= 1 << uint(i)
+		}
+	}
+	return mask, nil
+}
+
+func (c *cronExpr) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// next returns the earliest time strictly after t that matches the cron
+// expression, or zero time if there is no match within the next five years.
+func (c *cronExpr) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	yearLimit := t.Year() + 5
+
+	for t.Year() <= yearLimit {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
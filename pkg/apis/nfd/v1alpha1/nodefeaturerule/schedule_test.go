@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodefeaturerule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestCronExpr(t *testing.T) {
+	tcs := []struct {
+		spec string
+		from string
+		next string
+	}{
+		{spec: "* * * * *", from: "2024-01-01T10:00:30Z", next: "2024-01-01T10:01:00Z"},
+		{spec: "0 22 * * *", from: "2024-01-01T10:00:00Z", next: "2024-01-01T22:00:00Z"},
+		{spec: "0 22 * * *", from: "2024-01-01T22:00:00Z", next: "2024-01-02T22:00:00Z"},
+		{spec: "*/15 * * * *", from: "2024-01-01T10:16:00Z", next: "2024-01-01T10:30:00Z"},
+		{spec: "30 2 * * 0", from: "2024-01-01T00:00:00Z", next: "2024-01-07T02:30:00Z"},
+		{spec: "30 2 * * 7", from: "2024-01-01T00:00:00Z", next: "2024-01-07T02:30:00Z"},
+		{spec: "0 0 1 */3 *", from: "2024-02-15T00:00:00Z", next: "2024-04-01T00:00:00Z"},
+		{spec: "0 0 29 2 *", from: "2024-03-01T00:00:00Z", next: "2028-02-29T00:00:00Z"},
+		// Either day-of-month or day-of-week matches if both are restricted
+		{spec: "0 0 15 * 1", from: "2024-01-02T00:00:00Z", next: "2024-01-08T00:00:00Z"},
+		{spec: "0 8-17/4 * * 1-5", from: "2024-01-05T17:00:00Z", next: "2024-01-08T08:00:00Z"},
+	}
+	for _, tc := range tcs {
+		c, err := parseCronExpr(tc.spec)
+		assert.NoError(t, err, tc.spec)
+		from, _ := time.Parse(time.RFC3339, tc.from)
+		next, _ := time.Parse(time.RFC3339, tc.next)
+		assert.Equal(t, next, c.next(from), tc.spec)
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := parseCronExpr(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	mustParse := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+
+	// Nightly window from 22:00 to 06:00 in Helsinki time (UTC+2 in winter)
+	s := &nfdv1alpha1.RuleSchedule{
+		TimeZone: "Europe/Helsinki",
+		Windows: []nfdv1alpha1.ScheduleWindow{
+			{Start: "0 22 * * *", Duration: metav1.Duration{Duration: 8 * time.Hour}},
+		},
+	}
+
+	status, err := EvaluateSchedule(s, mustParse("2024-01-10T12:00:00Z"))
+	assert.NoError(t, err)
+	assert.False(t, status.Active)
+	assert.Equal(t, mustParse("2024-01-10T20:00:00Z"), status.NextBoundary.UTC())
+
+	// Window opens exactly at the boundary
+	status, err = EvaluateSchedule(s, mustParse("2024-01-10T20:00:00Z"))
+	assert.NoError(t, err)
+	assert.True(t, status.Active)
+	assert.Equal(t, mustParse("2024-01-11T04:00:00Z"), status.NextBoundary.UTC())
+
+	// Window closes exactly at the boundary
+	status, err = EvaluateSchedule(s, mustParse("2024-01-11T04:00:00Z"))
+	assert.NoError(t, err)
+	assert.False(t, status.Active)
+	assert.Equal(t, mustParse("2024-01-11T20:00:00Z"), status.NextBoundary.UTC())
+
+	// Multiple windows, the earliest boundary is reported
+	s.Windows = append(s.Windows, nfdv1alpha1.ScheduleWindow{Start: "0 12 * * *", Duration: metav1.Duration{Duration: time.Hour}})
+	status, err = EvaluateSchedule(s, mustParse("2024-01-10T04:30:00Z"))
+	assert.NoError(t, err)
+	assert.False(t, status.Active)
+	assert.Equal(t, mustParse("2024-01-10T10:00:00Z"), status.NextBoundary.UTC())
+
+	// Invalid schedules
+	_, err = EvaluateSchedule(&nfdv1alpha1.RuleSchedule{}, time.Now())
+	assert.Error(t, err)
+	_, err = EvaluateSchedule(&nfdv1alpha1.RuleSchedule{TimeZone: "Foo/Bar", Windows: s.Windows}, time.Now())
+	assert.Error(t, err)
+	_, err = EvaluateSchedule(&nfdv1alpha1.RuleSchedule{Windows: []nfdv1alpha1.ScheduleWindow{{Start: "* * * * *"}}}, time.Now())
+	assert.Error(t, err)
+}
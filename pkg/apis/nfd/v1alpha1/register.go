@@ -41,7 +41,11 @@ func Resource(resource string) schema.GroupResource {
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&NodeFeature{},
+		&NodeFeatureList{},
 		&NodeFeatureRule{},
+		&NodeFeatureRuleList{},
+		&NamespacedNodeFeatureRule{},
+		&NamespacedNodeFeatureRuleList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
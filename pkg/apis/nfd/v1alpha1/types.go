@@ -125,6 +125,33 @@ type NodeFeatureRule struct {
 	Spec NodeFeatureRuleSpec `json:"spec"`
 }
 
+// NamespacedNodeFeatureRuleList contains a list of NamespacedNodeFeatureRule
+// objects.
+// +kubebuilder:object:root=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type NamespacedNodeFeatureRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []NamespacedNodeFeatureRule `json:"items"`
+}
+
+// NamespacedNodeFeatureRule is a namespaced variant of NodeFeatureRule,
+// intended for application teams (tenants) to define node labels for their
+// own workloads. The outputs of the rules are restricted by the nfd-master
+// configuration to the label and annotation namespaces allowed for the
+// namespace of the object.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced,shortName=nnfr
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+type NamespacedNodeFeatureRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NodeFeatureRuleSpec `json:"spec"`
+}
+
 // NodeFeatureRuleSpec describes a NodeFeatureRule.
 type NodeFeatureRuleSpec struct {
 	// Rules is a list of node customization rules.
@@ -178,6 +205,35 @@ type Rule struct {
 	// MatchAny specifies a list of matchers one of which must match.
 	// +optional
 	MatchAny []MatchAnyElem `json:"matchAny"`
+
+	// Schedule restricts the rule to be active only during specific time
+	// windows. Outside of the windows the rule does not match. If not
+	// specified the rule is always active.
+	// +optional
+	Schedule *RuleSchedule `json:"schedule,omitempty"`
+}
+
+// RuleSchedule specifies the time windows during which a rule is active.
+type RuleSchedule struct {
+	// Windows is the list of time windows. The rule is active if any of the
+	// windows is active.
+	Windows []ScheduleWindow `json:"windows"`
+
+	// TimeZone is the name of the IANA time zone in which the windows are
+	// evaluated, e.g. "Europe/Helsinki". Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// ScheduleWindow specifies one recurring time window.
+type ScheduleWindow struct {
+	// Start is a cron expression (in the standard five field format of
+	// "minute hour day-of-month month day-of-week") specifying when the window
+	// opens.
+	Start string `json:"start"`
+
+	// Duration is the length of the window, e.g. "2h30m".
+	Duration metav1.Duration `json:"duration"`
 }
 
 // MatchAnyElem specifies one sub-matcher of MatchAny.
@@ -282,6 +338,12 @@ const (
 	// RuleBackrefFeature is the special feature name for backreferencing
 	// output of preceding rules.
 	RuleBackrefFeature = "matched"
+	// WorkerLabelsDomain is the special feature domain for matching the
+	// labels published by nfd-worker.
+	WorkerLabelsDomain = "worker"
+	// WorkerLabelsFeature is the special feature name for matching the
+	// labels published by nfd-worker.
+	WorkerLabelsFeature = "labels"
 )
 
 // MatchAllNames is a special key in MatchExpressionSet to use field names
@@ -26,6 +26,7 @@ import (
 	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 
 	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
 )
 
 var (
@@ -39,6 +40,25 @@ var (
 	ErrEmptyTaintEffect = fmt.Errorf("empty taint effect")
 )
 
+// Rule validates the matching, templating and scheduling of a rule and
+// returns a slice of errors if the rule cannot be processed. The outputs of
+// the rule (labels, annotations etc.) are not validated as they are subject
+// to the configuration of nfd-master.
+func Rule(rule *nfdv1alpha1.Rule) []error {
+	var validationErr []error
+
+	if rule.Name == "" {
+		validationErr = append(validationErr, fmt.Errorf("rule name cannot be empty"))
+	}
+	validationErr = append(validationErr, Template(rule.LabelsTemplate)...)
+	validationErr = append(validationErr, Template(rule.VarsTemplate)...)
+	validationErr = append(validationErr, MatchFeatures(rule.MatchFeatures)...)
+	validationErr = append(validationErr, MatchAny(rule.MatchAny)...)
+	validationErr = append(validationErr, Schedule(rule.Schedule)...)
+
+	return validationErr
+}
+
 // MatchAny validates a slice of MatchAnyElem and returns a slice of errors if
 // any of the MatchAnyElem are invalid.
 func MatchAny(matchAny []nfdv1alpha1.MatchAnyElem) []error {
@@ -79,6 +99,18 @@ func Template(labelsTemplate string) []error {
 	return validationErr
 }
 
+// Schedule validates a rule schedule and returns a slice of errors if the
+// schedule is invalid. A nil schedule is valid.
+func Schedule(schedule *nfdv1alpha1.RuleSchedule) []error {
+	if schedule == nil {
+		return nil
+	}
+	if _, err := nodefeaturerule.ParseSchedule(schedule); err != nil {
+		return []error{fmt.Errorf("invalid schedule: %w", err)}
+	}
+	return nil
+}
+
 // Labels validates a map of labels and returns a slice of errors if any of the
 // labels are invalid.
 func Labels(labels map[string]string) []error {
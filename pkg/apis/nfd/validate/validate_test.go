@@ -3,8 +3,12 @@ package validate
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 )
 
 func TestAnnotation(t *testing.T) {
@@ -234,3 +238,85 @@ func TestExtendedResource(t *testing.T) {
 		})
 	}
 }
+
+func TestSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *nfdv1alpha1.RuleSchedule
+		fail     bool
+	}{
+		{
+			name: "No schedule",
+		},
+		{
+			name: "Valid schedule",
+			schedule: &nfdv1alpha1.RuleSchedule{
+				TimeZone: "UTC",
+				Windows:  []nfdv1alpha1.ScheduleWindow{{Start: "0 22 * * 1-5", Duration: metav1.Duration{Duration: time.Hour}}},
+			},
+		},
+		{
+			name: "Invalid cron expression",
+			schedule: &nfdv1alpha1.RuleSchedule{
+				Windows: []nfdv1alpha1.ScheduleWindow{{Start: "0 25 * * *", Duration: metav1.Duration{Duration: time.Hour}}},
+			},
+			fail: true,
+		},
+		{
+			name:     "No windows",
+			schedule: &nfdv1alpha1.RuleSchedule{},
+			fail:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Schedule(tt.schedule)
+			if tt.fail != (len(errs) > 0) {
+				t.Errorf("Schedule() = %v, expected failure: %v", errs, tt.fail)
+			}
+		})
+	}
+}
+
+func TestRule(t *testing.T) {
+	tests := []struct {
+		name string
+		rule nfdv1alpha1.Rule
+		fail bool
+	}{
+		{
+			name: "Valid rule",
+			rule: nfdv1alpha1.Rule{
+				Name:           "rule",
+				Labels:         map[string]string{"unprefixed": "true"},
+				LabelsTemplate: "{{ range .cpu.model }}{{ .Name }}=true{{ end }}",
+				MatchFeatures:  nfdv1alpha1.FeatureMatcher{{Feature: "cpu.model"}},
+			},
+		},
+		{
+			name: "Empty name",
+			rule: nfdv1alpha1.Rule{},
+			fail: true,
+		},
+		{
+			name: "Invalid template",
+			rule: nfdv1alpha1.Rule{Name: "rule", VarsTemplate: "{{ .foo "},
+			fail: true,
+		},
+		{
+			name: "Invalid feature name",
+			rule: nfdv1alpha1.Rule{Name: "rule", MatchAny: []nfdv1alpha1.MatchAnyElem{{MatchFeatures: nfdv1alpha1.FeatureMatcher{{Feature: "cpu"}}}}},
+			fail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Rule(&tt.rule)
+			if tt.fail != (len(errs) > 0) {
+				t.Errorf("Rule() = %v, expected failure: %v", errs, tt.fail)
+			}
+		})
+	}
+}
@@ -18,6 +18,8 @@ limitations under the License.
 
 package v1alpha1
 
+type NamespacedNodeFeatureRuleExpansion interface{}
+
 type NodeFeatureExpansion interface{}
 
 type NodeFeatureRuleExpansion interface{}
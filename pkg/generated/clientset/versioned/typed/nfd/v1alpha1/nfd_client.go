@@ -28,6 +28,7 @@ import (
 
 type NfdV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	NamespacedNodeFeatureRulesGetter
 	NodeFeaturesGetter
 	NodeFeatureRulesGetter
 }
@@ -37,6 +38,10 @@ type NfdV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *NfdV1alpha1Client) NamespacedNodeFeatureRules(namespace string) NamespacedNodeFeatureRuleInterface {
+	return newNamespacedNodeFeatureRules(c, namespace)
+}
+
 func (c *NfdV1alpha1Client) NodeFeatures(namespace string) NodeFeatureInterface {
 	return newNodeFeatures(c, namespace)
 }
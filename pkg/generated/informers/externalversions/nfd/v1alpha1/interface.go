@@ -24,6 +24,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// NamespacedNodeFeatureRules returns a NamespacedNodeFeatureRuleInformer.
+	NamespacedNodeFeatureRules() NamespacedNodeFeatureRuleInformer
 	// NodeFeatures returns a NodeFeatureInformer.
 	NodeFeatures() NodeFeatureInformer
 	// NodeFeatureRules returns a NodeFeatureRuleInformer.
@@ -41,6 +43,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// NamespacedNodeFeatureRules returns a NamespacedNodeFeatureRuleInformer.
+func (v *version) NamespacedNodeFeatureRules() NamespacedNodeFeatureRuleInformer {
+	return &namespacedNodeFeatureRuleInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // NodeFeatures returns a NodeFeatureInformer.
 func (v *version) NodeFeatures() NodeFeatureInformer {
 	return &nodeFeatureInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
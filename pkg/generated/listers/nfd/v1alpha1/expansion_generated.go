@@ -18,6 +18,14 @@ limitations under the License.
 
 package v1alpha1
 
+// NamespacedNodeFeatureRuleListerExpansion allows custom methods to be added to
+// NamespacedNodeFeatureRuleLister.
+type NamespacedNodeFeatureRuleListerExpansion interface{}
+
+// NamespacedNodeFeatureRuleNamespaceListerExpansion allows custom methods to be added to
+// NamespacedNodeFeatureRuleNamespaceLister.
+type NamespacedNodeFeatureRuleNamespaceListerExpansion interface{}
+
 // NodeFeatureListerExpansion allows custom methods to be added to
 // NodeFeatureLister.
 type NodeFeatureListerExpansion interface{}
@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// BaselineAccept requests nfd-master to record the current features of a node
+// as the feature baseline of the node.
+func BaselineAccept(nodeName, namespace, kubeconfig string) []error {
+	cli, _, err := getRollbackClients(kubeconfig)
+	if err != nil {
+		return []error{err}
+	}
+
+	if err := acceptBaseline(cli, namespace, nodeName, time.Now()); err != nil {
+		return []error{err}
+	}
+	fmt.Printf("Requested nfd-master to accept the current features of node %q as the baseline\n", nodeName)
+	return nil
+}
+
+// acceptBaseline marks the feature baseline of a node to be re-recorded by
+// nfd-master. The baseline object is created if the node does not have a
+// baseline yet, in which case the namespace of nfd-master must be specified.
+// An empty namespace searches all namespaces.
+func acceptBaseline(cli kubernetes.Interface, namespace, nodeName string, now time.Time) error {
+	sel := metav1.LabelSelector{MatchLabels: map[string]string{
+		nfdv1alpha1.FeatureBaselineLabel:        "true",
+		nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName,
+	}}
+	list, err := cli.CoreV1().ConfigMaps(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: metav1.FormatLabelSelector(&sel)})
+	if err != nil {
+		return fmt.Errorf("failed to list feature baselines of node %q: %w", nodeName, err)
+	}
+	if len(list.Items) > 1 {
+		return fmt.Errorf("feature baselines of node %q found in multiple namespaces (%s, %s), specify the namespace of nfd-master", nodeName, list.Items[0].Namespace, list.Items[1].Namespace)
+	}
+
+	acceptedAt := now.UTC().Format(time.RFC3339)
+	if len(list.Items) == 0 {
+		if namespace == "" {
+			return fmt.Errorf("no feature baseline of node %q found, specify the namespace of nfd-master to create one", nodeName)
+		}
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        nfdv1alpha1.FeatureBaselineName(nodeName),
+				Namespace:   namespace,
+				Labels:      sel.MatchLabels,
+				Annotations: map[string]string{nfdv1alpha1.BaselineAcceptAnnotation: acceptedAt},
+			},
+		}
+		if _, err := cli.CoreV1().ConfigMaps(namespace).Create(context.TODO(), cm, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create feature baseline of node %q: %w", nodeName, err)
+		}
+		return nil
+	}
+
+	cm := list.Items[0].DeepCopy()
+	if cm.Annotations == nil {
+		cm.Annotations = make(map[string]string)
+	}
+	cm.Annotations[nfdv1alpha1.BaselineAcceptAnnotation] = acceptedAt
+	if _, err := cli.CoreV1().ConfigMaps(cm.Namespace).Update(context.TODO(), cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update feature baseline of node %q: %w", nodeName, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestAcceptBaseline(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	existing := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nfd-baseline-node-1",
+			Namespace: "nfd",
+			Labels: map[string]string{
+				nfdv1alpha1.FeatureBaselineLabel:        "true",
+				nfdv1alpha1.NodeFeatureObjNodeNameLabel: "node-1",
+			},
+		},
+		Data: map[string]string{"baseline": "{}"},
+	}
+	cli := k8sfake.NewSimpleClientset(existing)
+
+	// Existing baseline is found in any namespace
+	assert.NoError(t, acceptBaseline(cli, "", "node-1", now))
+	cm, err := cli.CoreV1().ConfigMaps("nfd").Get(context.TODO(), "nfd-baseline-node-1", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01-02T03:04:05Z", cm.Annotations[nfdv1alpha1.BaselineAcceptAnnotation])
+	assert.Equal(t, "{}", cm.Data["baseline"])
+
+	// Missing baseline requires the namespace
+	assert.Error(t, acceptBaseline(cli, "", "node-2", now))
+	assert.NoError(t, acceptBaseline(cli, "nfd", "node-2", now))
+	cm, err = cli.CoreV1().ConfigMaps("nfd").Get(context.TODO(), "nfd-baseline-node-2", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "node-2", cm.Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel])
+	assert.Contains(t, cm.Annotations, nfdv1alpha1.BaselineAcceptAnnotation)
+}
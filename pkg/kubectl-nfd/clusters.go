@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned"
+)
+
+// Cluster is one cluster to operate on, identified by its kubeconfig context.
+type Cluster struct {
+	// Context is the name of the kubeconfig context. Empty for the current
+	// context.
+	Context string
+	Config  *restclient.Config
+}
+
+// Name returns a human readable name of the cluster.
+func (c Cluster) Name() string {
+	if c.Context == "" {
+		return "current-context"
+	}
+	return c.Context
+}
+
+// GetClusters returns client configurations for a set of kubeconfig contexts.
+// If no contexts are specified the current context is used. If allContexts is
+// true all contexts found in the kubeconfig are used.
+func GetClusters(kubeconfig string, contexts []string, allContexts bool) ([]Cluster, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		loadingRules.ExplicitPath = kubeconfig
+	}
+
+	if allContexts {
+		rawConfig, err := loadingRules.Load()
+		if err != nil {
+			return nil, fmt.Errorf("error loading kubeconfig: %w", err)
+		}
+		contexts = make([]string, 0, len(rawConfig.Contexts))
+		for name := range rawConfig.Contexts {
+			contexts = append(contexts, name)
+		}
+		sort.Strings(contexts)
+		if len(contexts) == 0 {
+			return nil, fmt.Errorf("no contexts found in kubeconfig")
+		}
+	}
+
+	if len(contexts) == 0 {
+		contexts = []string{""}
+	}
+
+	clusters := make([]Cluster, 0, len(contexts))
+	for _, ctx := range contexts {
+		overrides := &clientcmd.ConfigOverrides{CurrentContext: ctx}
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("error building kubeconfig for context %q: %w", ctx, err)
+		}
+		clusters = append(clusters, Cluster{Context: ctx, Config: config})
+	}
+	return clusters, nil
+}
+
+// getNodeFeatures fetches all NodeFeature objects from a cluster and returns
+// the merged feature data of each node, indexed by node name.
+func getNodeFeatures(config *restclient.Config) (map[string]*nfdv1alpha1.NodeFeatureSpec, error) {
+	nfdClient, err := nfdclientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	objs, err := nfdClient.NfdV1alpha1().NodeFeatures("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NodeFeature objects: %w", err)
+	}
+
+	// Sort objects so that merging is deterministic
+	items := objs.Items
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Name != items[j].Name {
+			return items[i].Name < items[j].Name
+		}
+		return items[i].Namespace < items[j].Namespace
+	})
+
+	specs := make(map[string]*nfdv1alpha1.NodeFeatureSpec)
+	for _, o := range items {
+		nodeName, ok := o.Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel]
+		if !ok || nodeName == "" {
+			continue
+		}
+		if _, ok := specs[nodeName]; !ok {
+			specs[nodeName] = nfdv1alpha1.NewNodeFeatureSpec()
+		}
+		s := o.Spec.DeepCopy()
+		s.MergeInto(specs[nodeName])
+	}
+	return specs, nil
+}
+
+// sortedNodeNames returns the node names of a feature map in sorted order.
+func sortedNodeNames(specs map[string]*nfdv1alpha1.NodeFeatureSpec) []string {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
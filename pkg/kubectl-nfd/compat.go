@@ -0,0 +1,322 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"sigs.k8s.io/yaml"
+
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/imagecompatibility/v1alpha1"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+// CompatSource specifies where to read an image compatibility spec from.
+type CompatSource struct {
+	// SpecFile is the path to a spec file
+	SpecFile string
+	// OCILayout is the path to an OCI image layout directory
+	OCILayout string
+	// Tag selects the image or artifact in the OCI image layout
+	Tag string
+}
+
+// requirementResult is the result of evaluating one requirement of an image
+// compatibility spec.
+type requirementResult struct {
+	Name        string
+	Description string
+	Matched     bool
+	// Details explain why the requirement was not satisfied
+	Details []string
+}
+
+// compatibilityResult is the result of evaluating one set of requirements of
+// an image compatibility spec.
+type compatibilityResult struct {
+	Description  string
+	Tag          string
+	Requirements []requirementResult
+}
+
+// compatible returns true if all requirements of the set are satisfied.
+func (r *compatibilityResult) compatible() bool {
+	for _, req := range r.Requirements {
+		if !req.Matched {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateImageCompatibility evaluates an image compatibility spec against the
+// features of a node, read from a NodeFeature file or from the cluster, and
+// prints the result per requirement. If no node or file is given, the spec is
+// evaluated against all nodes of the cluster and the compatible nodes are
+// listed.
+func ValidateImageCompatibility(src CompatSource, nodeName, nodeFeatureFile, kubeconfig string) []error {
+	spec, err := readCompatSpec(src)
+	if err != nil {
+		return []error{err}
+	}
+
+	if nodeFeatureFile != "" {
+		data, err := os.ReadFile(nodeFeatureFile)
+		if err != nil {
+			return []error{fmt.Errorf("error reading NodeFeature file: %w", err)}
+		}
+		nf := nfdv1alpha1.NodeFeature{}
+		if err := yaml.Unmarshal(data, &nf); err != nil {
+			return []error{fmt.Errorf("error parsing NodeFeature: %w", err)}
+		}
+		return validateNodeCompatibility(os.Stdout, nf.Name, spec, &nf.Spec.Features)
+	}
+
+	clusters, err := GetClusters(kubeconfig, nil, false)
+	if err != nil {
+		return []error{err}
+	}
+	specs, err := getNodeFeatures(clusters[0].Config)
+	if err != nil {
+		return []error{err}
+	}
+
+	if nodeName != "" {
+		nodeSpec, ok := specs[nodeName]
+		if !ok {
+			return []error{fmt.Errorf("no NodeFeature objects found for node %q", nodeName)}
+		}
+		return validateNodeCompatibility(os.Stdout, nodeName, spec, &nodeSpec.Features)
+	}
+
+	var errs []error
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tCOMPATIBLE\tMATCHING SETS")
+	compatibleNodes := 0
+	for _, name := range sortedNodeNames(specs) {
+		results, err := evaluateCompatSpec(spec, &specs[name].Features)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %q: %w", name, err))
+			continue
+		}
+		var matching []string
+		for i := range results {
+			if results[i].compatible() {
+				matching = append(matching, compatibilityName(i, &results[i]))
+			}
+		}
+		compatible := "no"
+		if len(matching) > 0 {
+			compatible = "yes"
+			compatibleNodes++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, compatible, strings.Join(matching, ","))
+	}
+	w.Flush()
+	fmt.Printf("\nCompatible nodes: %d of %d\n", compatibleNodes, len(specs))
+
+	if compatibleNodes == 0 && len(errs) == 0 {
+		errs = append(errs, fmt.Errorf("no node is compatible with the image"))
+	}
+	return errs
+}
+
+// validateNodeCompatibility evaluates the spec against the features of one
+// node and prints the results. Returns an error if the node is not
+// compatible.
+func validateNodeCompatibility(out io.Writer, nodeName string, spec *compatv1alpha1.Spec, features *nfdv1alpha1.Features) []error {
+	results, err := evaluateCompatSpec(spec, features)
+	if err != nil {
+		return []error{err}
+	}
+	if printCompatResults(out, nodeName, results) {
+		return nil
+	}
+	return []error{fmt.Errorf("node %q is not compatible with the image", nodeName)}
+}
+
+// readCompatSpec reads an image compatibility spec from a file or an OCI image
+// layout.
+func readCompatSpec(src CompatSource) (*compatv1alpha1.Spec, error) {
+	switch {
+	case src.SpecFile != "" && src.OCILayout != "":
+		return nil, fmt.Errorf("only one of spec file and OCI image layout may be specified")
+	case src.SpecFile != "":
+		data, err := os.ReadFile(src.SpecFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image compatibility spec: %w", err)
+		}
+		return parseCompatSpec(data)
+	case src.OCILayout != "":
+		return readOCILayoutCompatSpec(src.OCILayout, src.Tag)
+	}
+	return nil, fmt.Errorf("either spec file or OCI image layout must be specified")
+}
+
+// parseCompatSpec parses and validates an image compatibility spec.
+func parseCompatSpec(data []byte) (*compatv1alpha1.Spec, error) {
+	spec := &compatv1alpha1.Spec{}
+	if err := yaml.UnmarshalStrict(data, spec); err != nil {
+		return nil, fmt.Errorf("failed to parse image compatibility spec: %w", err)
+	}
+	if spec.Version != compatv1alpha1.Version {
+		return nil, fmt.Errorf("unsupported image compatibility spec version %q, expected %q", spec.Version, compatv1alpha1.Version)
+	}
+	if len(spec.Compatibilities) == 0 {
+		return nil, fmt.Errorf("image compatibility spec has no compatibilities")
+	}
+	for i, c := range spec.Compatibilities {
+		if len(c.Rules) == 0 {
+			return nil, fmt.Errorf("compatibility #%d has no rules", i+1)
+		}
+		for _, r := range c.Rules {
+			if strings.TrimSpace(r.Name) == "" {
+				return nil, fmt.Errorf("compatibility #%d: rule name must not be empty", i+1)
+			}
+			if len(r.MatchFeatures) == 0 && len(r.MatchAny) == 0 {
+				return nil, fmt.Errorf("compatibility #%d: rule %q has no matchFeatures or matchAny", i+1, r.Name)
+			}
+		}
+	}
+	return spec, nil
+}
+
+// evaluateCompatSpec evaluates all requirements of an image compatibility spec
+// against a set of node features.
+func evaluateCompatSpec(spec *compatv1alpha1.Spec, features *nfdv1alpha1.Features) ([]compatibilityResult, error) {
+	results := make([]compatibilityResult, len(spec.Compatibilities))
+	for i, c := range spec.Compatibilities {
+		results[i] = compatibilityResult{Description: c.Description, Tag: c.Tag}
+		for _, r := range c.Rules {
+			res, err := evaluateCompatRule(r, features)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate rule %q: %w", r.Name, err)
+			}
+			results[i].Requirements = append(results[i].Requirements, res)
+		}
+	}
+	return results, nil
+}
+
+// evaluateCompatRule evaluates one requirement with the rule engine. If the
+// requirement is not satisfied, the terms of matchFeatures are evaluated one
+// by one to find out which of them failed.
+func evaluateCompatRule(r compatv1alpha1.GroupRule, features *nfdv1alpha1.Features) (requirementResult, error) {
+	res := requirementResult{Name: r.Name, Description: r.Description}
+
+	matched, _, err := executeCompatRule(&nfdv1alpha1.Rule{Name: r.Name, MatchFeatures: r.MatchFeatures, MatchAny: r.MatchAny}, features)
+	if err != nil {
+		return res, err
+	}
+	if matched {
+		res.Matched = true
+		return res, nil
+	}
+
+	if len(r.MatchAny) > 0 {
+		ok, detail, err := executeCompatRule(&nfdv1alpha1.Rule{Name: r.Name, MatchAny: r.MatchAny}, features)
+		if err != nil {
+			return res, err
+		}
+		if !ok {
+			if detail == "" {
+				detail = "none of matchAny matched"
+			}
+			res.Details = append(res.Details, detail)
+		}
+	}
+	for _, term := range r.MatchFeatures {
+		ok, detail, err := executeCompatRule(&nfdv1alpha1.Rule{Name: r.Name, MatchFeatures: nfdv1alpha1.FeatureMatcher{term}}, features)
+		if err != nil {
+			return res, err
+		}
+		if !ok {
+			if detail == "" {
+				detail = term.Feature + " did not match"
+			}
+			res.Details = append(res.Details, detail)
+		}
+	}
+	return res, nil
+}
+
+// executeCompatRule executes a rule. Missing features make the rule fail
+// instead of being an error, with the reason returned as the detail.
+func executeCompatRule(r *nfdv1alpha1.Rule, features *nfdv1alpha1.Features) (bool, string, error) {
+	out, err := nodefeaturerule.Execute(r, features)
+	if err != nil {
+		var notAvailable *nodefeaturerule.FeatureNotAvailableError
+		if errors.As(err, &notAvailable) {
+			return false, err.Error(), nil
+		}
+		return false, "", err
+	}
+	return out.Matched, "", nil
+}
+
+// compatibilityName returns the tag of a set of requirements, or its index if
+// it has no tag.
+func compatibilityName(i int, r *compatibilityResult) string {
+	if r.Tag != "" {
+		return r.Tag
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// printCompatResults prints the results of evaluating an image compatibility
+// spec against one node. Returns true if the node is compatible.
+func printCompatResults(out io.Writer, nodeName string, results []compatibilityResult) bool {
+	compatible := false
+	for i := range results {
+		r := &results[i]
+		status := "incompatible"
+		if r.compatible() {
+			status = "compatible"
+			compatible = true
+		}
+		fmt.Fprintf(out, "Compatibility set %s", compatibilityName(i, r))
+		if r.Description != "" {
+			fmt.Fprintf(out, " (%s)", r.Description)
+		}
+		fmt.Fprintf(out, ": %s\n", status)
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  REQUIREMENT\tRESULT\tDETAILS")
+		for _, req := range r.Requirements {
+			result := "fail"
+			if req.Matched {
+				result = "pass"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", req.Name, result, strings.Join(req.Details, "; "))
+		}
+		w.Flush()
+		fmt.Fprintln(out)
+	}
+
+	if compatible {
+		fmt.Fprintf(out, "Node %q is compatible with the image\n", nodeName)
+	} else {
+		fmt.Fprintf(out, "Node %q is not compatible with the image\n", nodeName)
+	}
+	return compatible
+}
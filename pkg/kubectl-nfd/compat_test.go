@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/imagecompatibility/v1alpha1"
+)
+
+const testCompatSpec = `version: v1alpha1
+compatibilities:
+- description: generic build
+  tag: generic
+  rules:
+  - name: cpu-family
+    matchFeatures:
+    - feature: cpu.model
+      matchExpressions:
+        family: {op: In, value: ["6"]}
+  - name: kernel-modules
+    matchFeatures:
+    - feature: kernel.loadedmodule
+      matchExpressions:
+        kvm: {op: Exists}
+        vfio: {op: Exists}
+    - feature: pci.device
+      matchExpressions:
+        vendor: {op: In, value: ["8086"]}
+- tag: accelerated
+  rules:
+  - name: accelerator
+    matchAny:
+    - matchFeatures:
+      - feature: pci.device
+        matchExpressions:
+          vendor: {op: In, value: ["10de"]}
+`
+
+// writeTestBlob writes a blob into an OCI image layout and returns its
+// descriptor.
+func writeTestBlob(t *testing.T, dir, mediaType string, data []byte) ociDescriptor {
+	sum := sha256.Sum256(data)
+	encoded := hex.EncodeToString(sum[:])
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "blobs", "sha256"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "blobs", "sha256", encoded), data, 0644))
+	return ociDescriptor{MediaType: mediaType, Digest: "sha256:" + encoded, Size: int64(len(data))}
+}
+
+func writeTestManifest(t *testing.T, dir string, m ociManifest) ociDescriptor {
+	m.MediaType = "application/vnd.oci.image.manifest.v1+json"
+	data, err := json.Marshal(m)
+	assert.NoError(t, err)
+	d := writeTestBlob(t, dir, m.MediaType, data)
+	d.ArtifactType = m.ArtifactType
+	return d
+}
+
+func TestReadOCILayoutCompatSpec(t *testing.T) {
+	dir := t.TempDir()
+
+	// An image with a compatibility artifact attached to it
+	emptyConfig := writeTestBlob(t, dir, "application/vnd.oci.empty.v1+json", []byte("{}"))
+	image := writeTestManifest(t, dir, ociManifest{
+		Config: writeTestBlob(t, dir, "application/vnd.oci.image.config.v1+json", []byte(`{"architecture":"amd64"}`)),
+		Layers: []ociDescriptor{writeTestBlob(t, dir, "application/vnd.oci.image.layer.v1.tar", []byte("layer"))},
+	})
+	image.Annotations = map[string]string{ociRefNameAnnotation: "v1"}
+	artifact := writeTestManifest(t, dir, ociManifest{
+		ArtifactType: compatv1alpha1.ArtifactType,
+		Config:       emptyConfig,
+		Layers:       []ociDescriptor{writeTestBlob(t, dir, compatv1alpha1.SpecMediaType, []byte(testCompatSpec))},
+		Subject:      &image,
+	})
+	// Unrelated manifests whose blobs are not part of the layout
+	missing := ociDescriptor{MediaType: "application/vnd.oci.image.manifest.v1+json", Digest: "sha256:" + strings.Repeat("0", 64)}
+	otherArtifact := missing
+	otherArtifact.Digest = "sha256:" + strings.Repeat("1", 64)
+	otherArtifact.ArtifactType = "application/vnd.example.sbom"
+	index, err := json.Marshal(ociIndex{Manifests: []ociDescriptor{image, missing, artifact, otherArtifact}})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "index.json"), index, 0644))
+
+	spec, err := readOCILayoutCompatSpec(dir, "v1")
+	assert.NoError(t, err)
+	assert.Len(t, spec.Compatibilities, 2)
+
+	spec, err = readOCILayoutCompatSpec(dir, "")
+	assert.NoError(t, err)
+	assert.Len(t, spec.Compatibilities, 2)
+
+	_, err = readOCILayoutCompatSpec(dir, "v2")
+	assert.ErrorContains(t, err, "not found")
+
+	// Tagged manifest that cannot be read
+	missing.Annotations = map[string]string{ociRefNameAnnotation: "v3"}
+	index, err = json.Marshal(ociIndex{Manifests: []ociDescriptor{image, missing, artifact}})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "index.json"), index, 0644))
+	_, err = readOCILayoutCompatSpec(dir, "v3")
+	assert.ErrorContains(t, err, "failed to read blob")
+	_, err = readOCILayoutCompatSpec(dir, "v1")
+	assert.NoError(t, err)
+
+	// Corrupted blob
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "blobs", "sha256", artifact.Digest[len("sha256:"):]), []byte("{}"), 0644))
+	_, err = readOCILayoutCompatSpec(dir, "v1")
+	assert.ErrorContains(t, err, "digest mismatch")
+}
+
+func TestParseCompatSpec(t *testing.T) {
+	_, err := parseCompatSpec([]byte(testCompatSpec))
+	assert.NoError(t, err)
+
+	_, err = parseCompatSpec([]byte("version: v2\ncompatibilities: []"))
+	assert.ErrorContains(t, err, "unsupported")
+
+	_, err = parseCompatSpec([]byte("version: v1alpha1\ncompatibilities:\n- rules:\n  - name: empty"))
+	assert.ErrorContains(t, err, "no matchFeatures or matchAny")
+}
+
+func TestEvaluateCompatSpec(t *testing.T) {
+	spec, err := parseCompatSpec([]byte(testCompatSpec))
+	assert.NoError(t, err)
+
+	// Compatible
+	results, err := evaluateCompatSpec(spec, &newTestSpec("6", "kvm", "vfio").Features)
+	assert.NoError(t, err)
+	assert.True(t, results[0].compatible())
+	assert.False(t, results[1].compatible())
+	assert.Equal(t, []string{"none of matchAny matched"}, results[1].Requirements[0].Details)
+
+	var out bytes.Buffer
+	assert.True(t, printCompatResults(&out, "node-1", results))
+	assert.Contains(t, out.String(), `Node "node-1" is compatible with the image`)
+
+	// Failing requirements are reported per term
+	results, err = evaluateCompatSpec(spec, &newTestSpec("25", "kvm").Features)
+	assert.NoError(t, err)
+	assert.False(t, results[0].compatible())
+	assert.False(t, results[0].Requirements[0].Matched)
+	assert.False(t, results[0].Requirements[1].Matched)
+	assert.Equal(t, []string{"kernel.loadedmodule did not match"}, results[0].Requirements[1].Details)
+
+	out.Reset()
+	assert.False(t, printCompatResults(&out, "node-1", results))
+	assert.Contains(t, out.String(), "kernel-modules  fail    kernel.loadedmodule did not match")
+
+	// Missing features fail the requirement
+	features := newTestSpec("6", "kvm", "vfio").Features
+	delete(features.Instances, "pci.device")
+	results, err = evaluateCompatSpec(spec, &features)
+	assert.NoError(t, err)
+	assert.False(t, results[0].compatible())
+	assert.Equal(t, []string{`feature "pci.device" not available`}, results[0].Requirements[1].Details)
+}
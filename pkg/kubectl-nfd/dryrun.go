@@ -18,8 +18,10 @@ package kubectlnfd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"sigs.k8s.io/yaml"
 
@@ -60,23 +62,67 @@ func DryRun(nodefeaturerulepath, nodefeaturepath string) []error {
 	return errs
 }
 
-func processNodeFeatureRule(nodeFeatureRule nfdv1alpha1.NodeFeatureRule, nodeFeature nfdv1alpha1.NodeFeatureSpec) []error {
+// ruleOutput is the combined output of all rules of a NodeFeatureRule object
+// evaluated against the features of one node.
+type ruleOutput struct {
+	Labels            map[string]string
+	Annotations       map[string]string
+	ExtendedResources map[string]string
+	Taints            []corev1.Taint
+}
+
+// isEmpty returns true if the rule produced no output.
+func (o *ruleOutput) isEmpty() bool {
+	return len(o.Labels) == 0 && len(o.Annotations) == 0 && len(o.ExtendedResources) == 0 && len(o.Taints) == 0
+}
+
+// executeNodeFeatureRule evaluates all rules of a NodeFeatureRule object
+// against a set of node features. The progress of processing the rules is
+// printed to progress, unless it is nil.
+func executeNodeFeatureRule(nodeFeatureRule nfdv1alpha1.NodeFeatureRule, nodeFeature nfdv1alpha1.NodeFeatureSpec, progress io.Writer) (ruleOutput, []error) {
 	var errs []error
-	var taints []corev1.Taint
+	out := ruleOutput{
+		Labels:            make(map[string]string),
+		Annotations:       make(map[string]string),
+		ExtendedResources: make(map[string]string),
+	}
 
-	extendedResources := make(map[string]string)
-	labels := make(map[string]string)
-	annotations := make(map[string]string)
+	// Labels published by nfd-worker can be matched like in nfd-master
+	workerLabels := make(map[string]string, len(nodeFeature.Labels))
+	for k, v := range nodeFeature.Labels {
+		workerLabels[addDefaultNs(k)] = v
+	}
+	nodeFeature.Features.SetWorkerLabels(workerLabels)
 
 	for _, rule := range nodeFeatureRule.Spec.Rules {
-		fmt.Println("Processing rule: ", rule.Name)
+		if progress != nil {
+			fmt.Fprintln(progress, "Processing rule: ", rule.Name)
+		}
+		if rule.Schedule != nil {
+			status, err := nodefeaturerule.EvaluateSchedule(rule.Schedule, time.Now())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to process rule: %q - %w", rule.Name, err))
+				continue
+			}
+			if progress != nil {
+				state := "inactive"
+				if status.Active {
+					state = "active"
+				}
+				fmt.Fprintf(progress, "\tschedule: %s, next window boundary at %s\n", state, status.NextBoundary)
+			}
+			if !status.Active {
+				continue
+			}
+		}
+
 		ruleOut, err := nodefeaturerule.Execute(&rule, &nodeFeature.Features)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("failed to process rule: %q - %w", rule.Name, err))
 			continue
 		}
 		// taints
-		taints = append(taints, ruleOut.Taints...)
+		out.Taints = append(out.Taints, ruleOut.Taints...)
 		// labels
 		for k, v := range ruleOut.Labels {
 			// Dynamic Value
@@ -86,10 +132,10 @@ func processNodeFeatureRule(nodeFeatureRule nfdv1alpha1.NodeFeatureRule, nodeFea
 					errs = append(errs, fmt.Errorf("failed to get dynamic value for label %q: %w", k, err))
 					continue
 				}
-				labels[k] = dvalue
+				out.Labels[k] = dvalue
 				continue
 			}
-			labels[k] = v
+			out.Labels[k] = v
 		}
 		// extended resources
 		for k, v := range ruleOut.ExtendedResources {
@@ -100,16 +146,29 @@ func processNodeFeatureRule(nodeFeatureRule nfdv1alpha1.NodeFeatureRule, nodeFea
 					errs = append(errs, fmt.Errorf("failed to get dynamic value for extendedResource %q: %w", k, err))
 					continue
 				}
-				extendedResources[k] = dvalue
+				out.ExtendedResources[k] = dvalue
 				continue
 			}
-			extendedResources[k] = v
+			out.ExtendedResources[k] = v
 		}
 		// annotations
 		for k, v := range ruleOut.Annotations {
-			annotations[k] = v
+			out.Annotations[k] = v
 		}
+
+		// Feed back rule output to features map for subsequent rules to match
+		nodeFeature.Features.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, ruleOut.Labels)
+		nodeFeature.Features.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, ruleOut.Vars)
 	}
+	return out, errs
+}
+
+func processNodeFeatureRule(nodeFeatureRule nfdv1alpha1.NodeFeatureRule, nodeFeature nfdv1alpha1.NodeFeatureSpec) []error {
+	out, errs := executeNodeFeatureRule(nodeFeatureRule, nodeFeature, os.Stdout)
+	taints := out.Taints
+	labels := out.Labels
+	extendedResources := out.ExtendedResources
+	annotations := out.Annotations
 
 	if len(taints) > 0 {
 		taintValidation := validate.Taints(taints)
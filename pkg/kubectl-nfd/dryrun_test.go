@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestExecuteNodeFeatureRule(t *testing.T) {
+	// The second rule matches the output of the first one, like in nfd-master
+	nfr := nfdv1alpha1.NodeFeatureRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: nfdv1alpha1.NodeFeatureRuleSpec{Rules: []nfdv1alpha1.Rule{
+			{
+				Name:   "kvm",
+				Labels: map[string]string{"kvm": "true"},
+				Vars:   map[string]string{"virt": "true"},
+				MatchFeatures: nfdv1alpha1.FeatureMatcher{{
+					Feature:          "kernel.loadedmodule",
+					MatchExpressions: &nfdv1alpha1.MatchExpressionSet{"kvm": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchExists}},
+				}},
+			},
+			{
+				Name:   "backref",
+				Labels: map[string]string{"virt-host": "true"},
+				MatchFeatures: nfdv1alpha1.FeatureMatcher{{
+					Feature: nfdv1alpha1.RuleBackrefDomain + "." + nfdv1alpha1.RuleBackrefFeature,
+					MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+						"kvm":  &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchIsTrue},
+						"virt": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchIsTrue},
+					},
+				}},
+			},
+		}},
+	}
+	features := nfdv1alpha1.NewNodeFeatureSpec()
+	features.Features.Flags["kernel.loadedmodule"] = nfdv1alpha1.NewFlagFeatures("kvm")
+
+	out, errs := executeNodeFeatureRule(nfr, *features, nil)
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]string{"kvm": "true", "virt-host": "true"}, out.Labels)
+}
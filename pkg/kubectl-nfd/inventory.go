@@ -0,0 +1,240 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// Inventory prints the number of nodes having each element of the selected
+// feature sets, side by side for all selected clusters. If diffOnly is true
+// only elements that are not present on the same share of nodes in all
+// clusters are printed.
+func Inventory(kubeconfig string, contexts []string, allContexts bool, featureSets []string, diffOnly bool) []error {
+	var errs []error
+
+	for _, fs := range featureSets {
+		if _, err := path.Match(fs, ""); err != nil {
+			return []error{fmt.Errorf("invalid feature pattern %q: %w", fs, err)}
+		}
+	}
+
+	clusters, err := GetClusters(kubeconfig, contexts, allContexts)
+	if err != nil {
+		return []error{err}
+	}
+	if diffOnly && len(clusters) < 2 {
+		return []error{fmt.Errorf("at least two clusters are needed for a diff")}
+	}
+
+	names := make([]string, len(clusters))
+	for i, c := range clusters {
+		names[i] = c.Name()
+	}
+	inv := newInventory(names)
+	for i, c := range clusters {
+		specs, err := getNodeFeatures(c.Config)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %q: %w", c.Name(), err))
+			inv.failed[i] = true
+			continue
+		}
+		inv.add(i, specs, featureSets)
+	}
+
+	if diffOnly {
+		inv = inv.diff()
+	}
+	inv.print(os.Stdout)
+
+	return errs
+}
+
+// inventoryKey identifies one feature element (and its value).
+type inventoryKey struct {
+	feature string
+	element string
+}
+
+// inventory holds the per-cluster node counts of feature elements.
+type inventory struct {
+	clusters   []string
+	nodeCounts []int
+	counts     map[inventoryKey][]int
+	// failed marks the clusters whose features could not be listed
+	failed []bool
+}
+
+func newInventory(clusters []string) *inventory {
+	return &inventory{
+		clusters:   clusters,
+		nodeCounts: make([]int, len(clusters)),
+		counts:     make(map[inventoryKey][]int),
+		failed:     make([]bool, len(clusters)),
+	}
+}
+
+// add adds the features of all nodes of one cluster in the inventory.
+func (inv *inventory) add(cluster int, specs map[string]*nfdv1alpha1.NodeFeatureSpec, featureSets []string) {
+	inc := func(key inventoryKey) {
+		if _, ok := inv.counts[key]; !ok {
+			inv.counts[key] = make([]int, len(inv.clusters))
+		}
+		inv.counts[key][cluster]++
+	}
+
+	for _, spec := range specs {
+		inv.nodeCounts[cluster]++
+
+		for name, f := range spec.Features.Flags {
+			if matchesAny(name, featureSets) {
+				for e := range f.Elements {
+					inc(inventoryKey{feature: name, element: e})
+				}
+			}
+		}
+		for name, f := range spec.Features.Attributes {
+			if matchesAny(name, featureSets) {
+				for e, v := range f.Elements {
+					inc(inventoryKey{feature: name, element: e + "=" + v})
+				}
+			}
+		}
+		for name, f := range spec.Features.Instances {
+			if matchesAny(name, featureSets) {
+				// Count nodes, not instances
+				seen := make(map[string]struct{})
+				for _, i := range f.Elements {
+					s := instanceString(i)
+					if _, ok := seen[s]; !ok {
+						seen[s] = struct{}{}
+						inc(inventoryKey{feature: name, element: s})
+					}
+				}
+			}
+		}
+	}
+}
+
+// diff returns a new inventory that only contains the elements that are not
+// present on the same share of nodes in all clusters. The clusters whose
+// features could not be listed are not compared. The shares are compared
+// against the first non-empty cluster.
+func (inv *inventory) diff() *inventory {
+	out := newInventory(inv.clusters)
+	copy(out.nodeCounts, inv.nodeCounts)
+	copy(out.failed, inv.failed)
+
+	ref := -1
+	for i, n := range inv.nodeCounts {
+		if !inv.failed[i] && n > 0 {
+			ref = i
+			break
+		}
+	}
+	if ref < 0 {
+		return out
+	}
+
+	for key, counts := range inv.counts {
+		for i := range counts {
+			if inv.failed[i] || i == ref {
+				continue
+			}
+			if !sameShare(counts[i], inv.nodeCounts[i], counts[ref], inv.nodeCounts[ref]) {
+				out.counts[key] = counts
+				break
+			}
+		}
+	}
+	return out
+}
+
+// sameShare returns true if a of na nodes is the same share of nodes as b of
+// nb nodes. An empty cluster has none of the elements.
+func sameShare(a, na, b, nb int) bool {
+	if na == 0 || nb == 0 {
+		return a == 0 && b == 0
+	}
+	return a*nb == b*na
+}
+
+func (inv *inventory) print(w io.Writer) {
+	keys := make([]inventoryKey, 0, len(inv.counts))
+	for k := range inv.counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].feature != keys[j].feature {
+			return keys[i].feature < keys[j].feature
+		}
+		return keys[i].element < keys[j].element
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "FEATURE\tELEMENT\t%s\n", strings.Join(inv.clusters, "\t"))
+	fmt.Fprintf(tw, "(nodes)\t\t%s\n", inv.joinCounts(inv.nodeCounts, "error"))
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", k.feature, k.element, inv.joinCounts(inv.counts[k], "-"))
+	}
+	tw.Flush()
+}
+
+// joinCounts returns the per-cluster counts as a tab-separated string. The
+// counts of the clusters whose features could not be listed are replaced
+// with the given placeholder.
+func (inv *inventory) joinCounts(counts []int, failedPlaceholder string) string {
+	s := make([]string, len(counts))
+	for i, v := range counts {
+		if inv.failed[i] {
+			s[i] = failedPlaceholder
+		} else {
+			s[i] = strconv.Itoa(v)
+		}
+	}
+	return strings.Join(s, "\t")
+}
+
+// matchesAny returns true if the name matches any of the (glob) patterns.
+func matchesAny(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// instanceString returns a canonical string representation of an instance
+// feature, with attributes sorted by name.
+func instanceString(i nfdv1alpha1.InstanceFeature) string {
+	attrs := make([]string, 0, len(i.Attributes))
+	for k, v := range i.Attributes {
+		attrs = append(attrs, k+"="+v)
+	}
+	sort.Strings(attrs)
+	return strings.Join(attrs, ",")
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func newTestSpec(model string, modules ...string) *nfdv1alpha1.NodeFeatureSpec {
+	s := nfdv1alpha1.NewNodeFeatureSpec()
+	s.Features.Attributes["cpu.model"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"family": model})
+	s.Features.Flags["kernel.loadedmodule"] = nfdv1alpha1.NewFlagFeatures(modules...)
+	s.Features.Instances["pci.device"] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{
+		{Attributes: map[string]string{"vendor": "8086", "class": "0200"}},
+		{Attributes: map[string]string{"class": "0200", "vendor": "8086"}},
+	})
+	return s
+}
+
+func TestInventory(t *testing.T) {
+	inv := newInventory([]string{"a", "b"})
+	inv.add(0, map[string]*nfdv1alpha1.NodeFeatureSpec{
+		"node-1": newTestSpec("6", "kvm"),
+		"node-2": newTestSpec("6", "kvm", "vfio"),
+	}, []string{"cpu.*", "kernel.loadedmodule", "pci.device"})
+	inv.add(1, map[string]*nfdv1alpha1.NodeFeatureSpec{
+		"node-1": newTestSpec("25", "kvm"),
+	}, []string{"cpu.*", "kernel.loadedmodule", "pci.device"})
+
+	assert.Equal(t, []int{2, 1}, inv.nodeCounts)
+	assert.Equal(t, []int{2, 0}, inv.counts[inventoryKey{"cpu.model", "family=6"}])
+	assert.Equal(t, []int{0, 1}, inv.counts[inventoryKey{"cpu.model", "family=25"}])
+	assert.Equal(t, []int{2, 1}, inv.counts[inventoryKey{"kernel.loadedmodule", "kvm"}])
+	assert.Equal(t, []int{1, 0}, inv.counts[inventoryKey{"kernel.loadedmodule", "vfio"}])
+	// Identical instances are counted once per node
+	assert.Equal(t, []int{2, 1}, inv.counts[inventoryKey{"pci.device", "class=0200,vendor=8086"}])
+
+	// Only differing rows are retained
+	d := inv.diff()
+	assert.Len(t, d.counts, 3)
+	assert.NotContains(t, d.counts, inventoryKey{"kernel.loadedmodule", "kvm"})
+
+	// Empty clusters differ from non-empty ones, failed clusters are not
+	// compared
+	inv = newInventory([]string{"a", "b", "c"})
+	inv.add(1, map[string]*nfdv1alpha1.NodeFeatureSpec{"node-1": newTestSpec("6")}, []string{"cpu.model"})
+	inv.add(2, map[string]*nfdv1alpha1.NodeFeatureSpec{"node-1": newTestSpec("6")}, []string{"cpu.model"})
+	assert.Len(t, inv.diff().counts, 1)
+	inv.failed[0] = true
+	assert.Empty(t, inv.diff().counts)
+	var out strings.Builder
+	inv.print(&out)
+	assert.Contains(t, out.String(), "error")
+
+	// Unselected feature sets are ignored
+	inv = newInventory([]string{"a"})
+	inv.add(0, map[string]*nfdv1alpha1.NodeFeatureSpec{"node-1": newTestSpec("6")}, []string{"cpu.model"})
+	assert.Len(t, inv.counts, 1)
+}
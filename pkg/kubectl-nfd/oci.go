@@ -0,0 +1,178 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	compatv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/imagecompatibility/v1alpha1"
+)
+
+// ociRefNameAnnotation is the annotation of the OCI image index holding the
+// tag of a manifest.
+const ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+
+// ociDescriptor is an OCI content descriptor.
+type ociDescriptor struct {
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+// ociIndex is an OCI image index, e.g. the index.json of an OCI image layout.
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// ociManifest is an OCI image manifest. Only the fields needed for locating
+// image compatibility artifacts are included.
+type ociManifest struct {
+	MediaType    string          `json:"mediaType"`
+	ArtifactType string          `json:"artifactType,omitempty"`
+	Config       ociDescriptor   `json:"config"`
+	Layers       []ociDescriptor `json:"layers"`
+	Subject      *ociDescriptor  `json:"subject,omitempty"`
+}
+
+// isCompatArtifact returns true if the manifest is an image compatibility
+// artifact. Older tools without support for the artifactType field carry the
+// artifact type in the config media type.
+func (m *ociManifest) isCompatArtifact() bool {
+	return m.ArtifactType == compatv1alpha1.ArtifactType || m.Config.MediaType == compatv1alpha1.ArtifactType
+}
+
+var digestRegexp = regexp.MustCompile(`^([a-z0-9]+):([a-f0-9]+)$`)
+
+// readOCILayoutCompatSpec reads the image compatibility spec from an OCI image
+// layout directory. The spec is looked up from an artifact of type
+// compatv1alpha1.ArtifactType, either tagged with the given tag or
+// referring to (i.e. attached to) the image with the given tag. If tag is
+// empty, the layout must contain exactly one such artifact.
+func readOCILayoutCompatSpec(dir, tag string) (*compatv1alpha1.Spec, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCI image layout: %w", err)
+	}
+	index := ociIndex{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse index.json of OCI image layout %q: %w", dir, err)
+	}
+
+	// Manifests tagged with the given tag, or all manifests
+	targets := make(map[string]bool)
+	for _, d := range index.Manifests {
+		if tag == "" || d.Annotations[ociRefNameAnnotation] == tag {
+			targets[d.Digest] = true
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("tag %q not found in OCI image layout %q", tag, dir)
+	}
+
+	// The layout may contain other content that is incomplete or not readable
+	// by us, e.g. images of other platforms. Only a failure to read the tagged
+	// manifest is fatal, other failures are reported if no artifact is found.
+	var artifacts []*ociManifest
+	var readErrs []error
+	for _, d := range index.Manifests {
+		// Other artifacts can be skipped based on the descriptor
+		if d.ArtifactType != "" && d.ArtifactType != compatv1alpha1.ArtifactType {
+			continue
+		}
+		m, err := readOCIManifest(dir, d)
+		if err != nil {
+			if tag != "" && targets[d.Digest] {
+				return nil, err
+			}
+			readErrs = append(readErrs, err)
+			continue
+		}
+		if m == nil || !m.isCompatArtifact() {
+			continue
+		}
+		if targets[d.Digest] || (m.Subject != nil && targets[m.Subject.Digest]) {
+			artifacts = append(artifacts, m)
+		}
+	}
+	switch {
+	case len(artifacts) == 0:
+		err := fmt.Errorf("no image compatibility artifact found in OCI image layout %q", dir)
+		return nil, errors.Join(append([]error{err}, readErrs...)...)
+	case len(artifacts) > 1:
+		return nil, fmt.Errorf("multiple image compatibility artifacts found in OCI image layout %q, specify a tag", dir)
+	}
+
+	for _, l := range artifacts[0].Layers {
+		if l.MediaType != compatv1alpha1.SpecMediaType {
+			continue
+		}
+		data, err := readOCIBlob(dir, l.Digest)
+		if err != nil {
+			return nil, err
+		}
+		return parseCompatSpec(data)
+	}
+	return nil, fmt.Errorf("image compatibility artifact in OCI image layout %q has no layer of type %q", dir, compatv1alpha1.SpecMediaType)
+}
+
+// readOCIManifest reads the manifest that the descriptor points to. Returns nil
+// if the descriptor is not an image manifest, e.g. it is an image index.
+func readOCIManifest(dir string, d ociDescriptor) (*ociManifest, error) {
+	if d.MediaType != "" && d.MediaType != "application/vnd.oci.image.manifest.v1+json" {
+		return nil, nil
+	}
+	data, err := readOCIBlob(dir, d.Digest)
+	if err != nil {
+		return nil, err
+	}
+	m := &ociManifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", d.Digest, err)
+	}
+	return m, nil
+}
+
+// readOCIBlob reads a blob from an OCI image layout and verifies its digest.
+func readOCIBlob(dir, digest string) ([]byte, error) {
+	split := digestRegexp.FindStringSubmatch(digest)
+	if split == nil {
+		return nil, fmt.Errorf("invalid digest %q", digest)
+	}
+	alg, encoded := split[1], split[2]
+	if alg != "sha256" {
+		return nil, fmt.Errorf("unsupported digest algorithm %q", alg)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "blobs", alg, encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", digest, err)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != encoded {
+		return nil, fmt.Errorf("digest mismatch of blob %s", digest)
+	}
+	return data, nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+// Query prints the nodes whose features match all of the given expressions,
+// for each of the selected clusters. See parseQuery for the syntax of the
+// expressions.
+func Query(kubeconfig string, contexts []string, allContexts bool, exprs []string) []error {
+	var errs []error
+
+	rule, err := parseQuery(exprs)
+	if err != nil {
+		return []error{err}
+	}
+
+	clusters, err := GetClusters(kubeconfig, contexts, allContexts)
+	if err != nil {
+		return []error{err}
+	}
+
+	results := make([]queryResult, len(clusters))
+	for i, c := range clusters {
+		results[i].cluster = c.Name()
+		specs, err := getNodeFeatures(c.Config)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %q: %w", c.Name(), err))
+			results[i].failed = true
+			continue
+		}
+		for _, e := range results[i].evaluate(rule, specs) {
+			errs = append(errs, fmt.Errorf("cluster %q: %w", c.Name(), e))
+		}
+	}
+
+	printQueryResults(os.Stdout, results)
+
+	return errs
+}
+
+// queryResult holds the nodes of one cluster that matched a query.
+type queryResult struct {
+	cluster   string
+	nodeCount int
+	matched   []string
+	// failed is true if the features of the cluster could not be listed
+	failed bool
+}
+
+// evaluate evaluates the query rule against the features of all nodes of a
+// cluster.
+func (r *queryResult) evaluate(rule *nfdv1alpha1.Rule, specs map[string]*nfdv1alpha1.NodeFeatureSpec) []error {
+	var errs []error
+	r.nodeCount = len(specs)
+	for _, nodeName := range sortedNodeNames(specs) {
+		out, err := nodefeaturerule.Execute(rule, &specs[nodeName].Features)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %q: %w", nodeName, err))
+			continue
+		}
+		if out.Matched {
+			r.matched = append(r.matched, nodeName)
+		}
+	}
+	return errs
+}
+
+// parseQuery converts query expressions into a rule. An expression has the
+// form <domain>.<feature>.<element>, optionally followed by =<values> or
+// !=<values> where values is a comma-separated list. Without values the
+// element must exist. Expressions of the same instance feature must match the
+// same instance, e.g. the same PCI device.
+func parseQuery(exprs []string) (*nfdv1alpha1.Rule, error) {
+	if len(exprs) == 0 {
+		return nil, fmt.Errorf("no query expressions specified")
+	}
+
+	rule := &nfdv1alpha1.Rule{Name: "query"}
+	terms := make(map[string]int)
+	for _, e := range exprs {
+		name, values, op := e, "", nfdv1alpha1.MatchExists
+		if i := strings.Index(e, "!="); i >= 0 {
+			name, values, op = e[:i], e[i+2:], nfdv1alpha1.MatchNotIn
+		} else if i := strings.Index(e, "="); i >= 0 {
+			name, values, op = e[:i], e[i+1:], nfdv1alpha1.MatchIn
+		}
+
+		parts := strings.SplitN(name, ".", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid query expression %q: expected <domain>.<feature>.<element>", e)
+		}
+		feature, element := parts[0]+"."+parts[1], parts[2]
+
+		expr := &nfdv1alpha1.MatchExpression{Op: op}
+		if op != nfdv1alpha1.MatchExists {
+			if values == "" {
+				return nil, fmt.Errorf("invalid query expression %q: no values specified", e)
+			}
+			expr.Value = strings.Split(values, ",")
+		}
+
+		i, ok := terms[feature]
+		if !ok {
+			i = len(rule.MatchFeatures)
+			terms[feature] = i
+			rule.MatchFeatures = append(rule.MatchFeatures, nfdv1alpha1.FeatureMatcherTerm{
+				Feature:          feature,
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{},
+			})
+		}
+		set := *rule.MatchFeatures[i].MatchExpressions
+		if _, ok := set[element]; ok {
+			return nil, fmt.Errorf("invalid query expression %q: duplicate expression for %s.%s", e, feature, element)
+		}
+		set[element] = expr
+	}
+	return rule, nil
+}
+
+func printQueryResults(w io.Writer, results []queryResult) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLUSTER\tMATCHING\tNODES")
+	for _, r := range results {
+		if r.failed {
+			fmt.Fprintf(tw, "%s\terror\t\n", r.cluster)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d of %d\t%s\n", r.cluster, len(r.matched), r.nodeCount, strings.Join(r.matched, ","))
+	}
+	tw.Flush()
+}
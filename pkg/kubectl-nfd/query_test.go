@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestQuery(t *testing.T) {
+	for _, exprs := range [][]string{
+		nil,
+		{"cpu.model"},
+		{"cpu..family"},
+		{"cpu.model.family="},
+		{"cpu.model.family=6", "cpu.model.family!=25"},
+	} {
+		_, err := parseQuery(exprs)
+		assert.Error(t, err, "expressions %q", exprs)
+	}
+
+	specs := map[string]*nfdv1alpha1.NodeFeatureSpec{
+		"node-1": newTestSpec("6", "kvm"),
+		"node-2": newTestSpec("6", "kvm", "vfio"),
+		"node-3": newTestSpec("25", "kvm"),
+	}
+	specs["node-3"].Features.Instances["pci.device"] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{
+		{Attributes: map[string]string{"vendor": "8086", "class": "0300"}},
+		{Attributes: map[string]string{"vendor": "10de", "class": "0200"}},
+	})
+
+	query := func(exprs ...string) []string {
+		rule, err := parseQuery(exprs)
+		assert.NoError(t, err)
+		r := queryResult{}
+		assert.Empty(t, r.evaluate(rule, specs))
+		assert.Equal(t, 3, r.nodeCount)
+		return r.matched
+	}
+	assert.Equal(t, []string{"node-1", "node-2", "node-3"}, query("kernel.loadedmodule.kvm"))
+	assert.Equal(t, []string{"node-2"}, query("kernel.loadedmodule.vfio", "cpu.model.family=6,25"))
+	assert.Equal(t, []string{"node-3"}, query("cpu.model.family!=6"))
+	assert.Equal(t, []string{"node-1", "node-2", "node-3"}, query("pci.device.vendor=8086", "pci.device.class=0200,0300"))
+	// Expressions of an instance feature must match the same instance
+	assert.Equal(t, []string{"node-1", "node-2"}, query("pci.device.vendor=8086", "pci.device.class=0200"))
+	assert.Empty(t, query("cpu.model.family=6", "kernel.loadedmodule.nvidia"))
+
+	var out strings.Builder
+	printQueryResults(&out, []queryResult{
+		{cluster: "a", nodeCount: 3, matched: []string{"node-1", "node-2"}},
+		{cluster: "b", failed: true},
+	})
+	assert.Equal(t, "CLUSTER  MATCHING  NODES\na        2 of 3    node-1,node-2\nb        error     \n", out.String())
+}
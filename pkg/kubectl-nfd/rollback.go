@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned"
+)
+
+// History prints the revisions of a NodeFeatureRule recorded by nfd-master.
+func History(name, namespace, kubeconfig string) []error {
+	cli, _, err := getRollbackClients(kubeconfig)
+	if err != nil {
+		return []error{err}
+	}
+
+	revs, err := getRuleRevisions(cli, namespace, name)
+	if err != nil {
+		return []error{err}
+	}
+	printRuleRevisions(os.Stdout, revs)
+	return nil
+}
+
+// Rollback restores the spec of a NodeFeatureRule from a revision recorded by
+// nfd-master. If revision is zero, the revision preceding the latest one is
+// restored.
+func Rollback(name, namespace, kubeconfig string, revision int64) []error {
+	cli, nfdCli, err := getRollbackClients(kubeconfig)
+	if err != nil {
+		return []error{err}
+	}
+
+	rev, err := rollbackRule(cli, nfdCli, namespace, name, revision)
+	if err != nil {
+		return []error{err}
+	}
+	fmt.Printf("NodeFeatureRule %q rolled back to revision %d\n", name, rev.Revision)
+	return nil
+}
+
+func getRollbackClients(kubeconfig string) (kubernetes.Interface, nfdclientset.Interface, error) {
+	clusters, err := GetClusters(kubeconfig, nil, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	cli, err := kubernetes.NewForConfig(clusters[0].Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	nfdCli, err := nfdclientset.NewForConfig(clusters[0].Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cli, nfdCli, nil
+}
+
+// getRuleRevisions returns the recorded revisions of a NodeFeatureRule,
+// sorted by revision number. An empty namespace searches all namespaces.
+func getRuleRevisions(cli kubernetes.Interface, namespace, name string) ([]appsv1.ControllerRevision, error) {
+	sel := metav1.LabelSelector{MatchLabels: map[string]string{nfdv1alpha1.NodeFeatureRuleNameLabel: nfdv1alpha1.NodeFeatureRuleNameLabelValue(name)}}
+	list, err := cli.AppsV1().ControllerRevisions(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: metav1.FormatLabelSelector(&sel)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions of NodeFeatureRule %q: %w", name, err)
+	}
+	// The label value is truncated for long names, filter by the full name
+	revs := make([]appsv1.ControllerRevision, 0, len(list.Items))
+	for _, rev := range list.Items {
+		if n, ok := rev.Annotations[nfdv1alpha1.NodeFeatureRuleNameAnnotation]; !ok || n == name {
+			revs = append(revs, rev)
+		}
+	}
+	if len(revs) == 0 {
+		return nil, fmt.Errorf("no revisions of NodeFeatureRule %q found", name)
+	}
+	for _, rev := range revs[1:] {
+		if rev.Namespace != revs[0].Namespace {
+			return nil, fmt.Errorf("revisions of NodeFeatureRule %q found in multiple namespaces (%s, %s), specify the namespace of nfd-master", name, revs[0].Namespace, rev.Namespace)
+		}
+	}
+	sort.Slice(revs, func(i, j int) bool { return revs[i].Revision < revs[j].Revision })
+	return revs, nil
+}
+
+func printRuleRevisions(w io.Writer, revs []appsv1.ControllerRevision) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "REVISION\tAPPLIED AT\tAFFECTED NODES")
+	for _, rev := range revs {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", rev.Revision,
+			rev.Annotations[nfdv1alpha1.RuleRevisionAppliedAtAnnotation],
+			rev.Annotations[nfdv1alpha1.RuleRevisionAffectedNodesAnnotation])
+	}
+	tw.Flush()
+}
+
+// rollbackRule restores the spec of a NodeFeatureRule from a recorded
+// revision and returns the revision that was restored.
+func rollbackRule(cli kubernetes.Interface, nfdCli nfdclientset.Interface, namespace, name string, revision int64) (*appsv1.ControllerRevision, error) {
+	revs, err := getRuleRevisions(cli, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var rev *appsv1.ControllerRevision
+	if revision == 0 {
+		if len(revs) < 2 {
+			return nil, fmt.Errorf("no previous revision of NodeFeatureRule %q found", name)
+		}
+		rev = &revs[len(revs)-2]
+	} else {
+		for i := range revs {
+			if revs[i].Revision == revision {
+				rev = &revs[i]
+			}
+		}
+		if rev == nil {
+			return nil, fmt.Errorf("revision %d of NodeFeatureRule %q not found", revision, name)
+		}
+	}
+
+	spec := nfdv1alpha1.NodeFeatureRuleSpec{}
+	if err := json.Unmarshal(rev.Data.Raw, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse revision %d of NodeFeatureRule %q: %w", rev.Revision, name, err)
+	}
+
+	nfr, err := nfdCli.NfdV1alpha1().NodeFeatureRules().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get NodeFeatureRule %q: %w", name, err)
+	}
+
+	nfr.Spec = spec
+	if _, err := nfdCli.NfdV1alpha1().NodeFeatureRules().Update(context.TODO(), nfr, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to update NodeFeatureRule %q: %w", name, err)
+	}
+	return rev, nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func newTestRevision(name string, revision int64, label string) *appsv1.ControllerRevision {
+	data, _ := json.Marshal(nfdv1alpha1.NodeFeatureRuleSpec{
+		Rules: []nfdv1alpha1.Rule{{Name: "rule", Labels: map[string]string{label: "true"}}},
+	})
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", name, revision),
+			Namespace: "nfd",
+			Labels:    map[string]string{nfdv1alpha1.NodeFeatureRuleNameLabel: nfdv1alpha1.NodeFeatureRuleNameLabelValue(name)},
+			Annotations: map[string]string{
+				nfdv1alpha1.NodeFeatureRuleNameAnnotation:       name,
+				nfdv1alpha1.RuleRevisionAppliedAtAnnotation:     "2024-05-01T12:00:00Z",
+				nfdv1alpha1.RuleRevisionAffectedNodesAnnotation: fmt.Sprint(revision * 10),
+			},
+		},
+		Data:     runtime.RawExtension{Raw: data},
+		Revision: revision,
+	}
+}
+
+func TestRollback(t *testing.T) {
+	cli := k8sfake.NewSimpleClientset(
+		newTestRevision("my-rule", 2, "new"),
+		newTestRevision("my-rule", 1, "old"),
+		newTestRevision("other-rule", 1, "other"),
+	)
+	nfdCli := fake.NewSimpleClientset(&nfdv1alpha1.NodeFeatureRule{ObjectMeta: metav1.ObjectMeta{Name: "my-rule"}})
+
+	revs, err := getRuleRevisions(cli, "", "my-rule")
+	assert.NoError(t, err)
+	assert.Len(t, revs, 2)
+	assert.Equal(t, int64(1), revs[0].Revision)
+
+	buf := &bytes.Buffer{}
+	printRuleRevisions(buf, revs)
+	assert.Contains(t, buf.String(), "2         2024-05-01T12:00:00Z  20")
+
+	// Roll back to the previous revision
+	rev, err := rollbackRule(cli, nfdCli, "", "my-rule", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rev.Revision)
+	nfr, err := nfdCli.NfdV1alpha1().NodeFeatureRules().Get(context.TODO(), "my-rule", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"old": "true"}, nfr.Spec.Rules[0].Labels)
+
+	// Roll back to a specific revision
+	_, err = rollbackRule(cli, nfdCli, "nfd", "my-rule", 2)
+	assert.NoError(t, err)
+	nfr, err = nfdCli.NfdV1alpha1().NodeFeatureRules().Get(context.TODO(), "my-rule", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"new": "true"}, nfr.Spec.Rules[0].Labels)
+
+	// Errors
+	_, err = rollbackRule(cli, nfdCli, "", "other-rule", 1)
+	assert.Error(t, err)
+	_, err = rollbackRule(cli, nfdCli, "", "other-rule", 0)
+	assert.Error(t, err)
+	_, err = rollbackRule(cli, nfdCli, "", "my-rule", 3)
+	assert.Error(t, err)
+	_, err = rollbackRule(cli, nfdCli, "", "missing-rule", 0)
+	assert.Error(t, err)
+}
+
+func TestGetRuleRevisionsLongName(t *testing.T) {
+	name := strings.Repeat("long-rule-", 10)
+	cli := k8sfake.NewSimpleClientset(
+		newTestRevision(name, 1, "long"),
+		newTestRevision(name+"x", 1, "other"),
+	)
+
+	revs, err := getRuleRevisions(cli, "", name)
+	assert.NoError(t, err)
+	assert.Len(t, revs, 1)
+	assert.Equal(t, name, revs[0].Annotations[nfdv1alpha1.NodeFeatureRuleNameAnnotation])
+}
@@ -0,0 +1,432 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	taintutils "k8s.io/kubernetes/pkg/util/taints"
+	"sigs.k8s.io/yaml"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned"
+	nfdmaster "sigs.k8s.io/node-feature-discovery/pkg/nfd-master"
+)
+
+// workload is the pod template of a workload manifest.
+type workload struct {
+	name string
+	spec corev1.PodSpec
+}
+
+// ruleObjects are the NodeFeatureRule and NamespacedNodeFeatureRule objects
+// of a simulation scenario.
+type ruleObjects struct {
+	rules   []*nfdv1alpha1.NodeFeatureRule
+	nsRules []*nfdv1alpha1.NamespacedNodeFeatureRule
+}
+
+// Simulate evaluates on which nodes of the cluster the pods of a workload
+// could be scheduled, based on their node selector, required node affinity
+// and tolerations. The NFD-managed labels and taints of each node are
+// re-computed from the NodeFeature objects, NodeFeatureRules and
+// NamespacedNodeFeatureRules of the cluster, like nfd-master does with the
+// given configuration file and namespace. If rule files are given, the nodes
+// are evaluated also with the rules of the cluster replaced (or complemented)
+// by the rules from the files.
+func Simulate(workloadPath string, rulePaths []string, masterConfig, namespace, kubeconfig string) []error {
+	workloads, err := readWorkloads(workloadPath)
+	if err != nil {
+		return []error{err}
+	}
+
+	// nfd-master falls back to the defaults if its config file does not
+	// exist, which is not what the user wants here
+	if masterConfig != "" {
+		if _, err := os.Stat(masterConfig); err != nil {
+			return []error{fmt.Errorf("failed to read nfd-master config: %w", err)}
+		}
+	}
+
+	var proposed ruleObjects
+	for _, p := range rulePaths {
+		rules, err := readRuleObjects(p)
+		if err != nil {
+			return []error{err}
+		}
+		proposed.rules = append(proposed.rules, rules.rules...)
+		proposed.nsRules = append(proposed.nsRules, rules.nsRules...)
+	}
+
+	clusters, err := GetClusters(kubeconfig, nil, false)
+	if err != nil {
+		return []error{err}
+	}
+	config := clusters[0].Config
+	cli, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return []error{err}
+	}
+	nfdCli, err := nfdclientset.NewForConfig(config)
+	if err != nil {
+		return []error{err}
+	}
+
+	nodes, err := cli.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return []error{fmt.Errorf("failed to list nodes: %w", err)}
+	}
+	sort.Slice(nodes.Items, func(i, j int) bool { return nodes.Items[i].Name < nodes.Items[j].Name })
+	featureList, err := nfdCli.NfdV1alpha1().NodeFeatures("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return []error{fmt.Errorf("failed to list NodeFeature objects: %w", err)}
+	}
+	features := make([]*nfdv1alpha1.NodeFeature, len(featureList.Items))
+	for i := range featureList.Items {
+		features[i] = &featureList.Items[i]
+	}
+	current, err := listRuleObjects(nfdCli)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	scenarios := []ruleObjects{current}
+	if len(rulePaths) > 0 {
+		scenarios = append(scenarios, mergeRuleObjects(current, proposed))
+	}
+	simulated := make([][]*corev1.Node, len(scenarios))
+	for i, s := range scenarios {
+		evaluator, err := nfdmaster.NewRuleEvaluator(masterConfig, namespace, nfdmaster.RuleEvaluatorObjects{
+			NodeFeatures:               features,
+			NodeFeatureRules:           s.rules,
+			NamespacedNodeFeatureRules: s.nsRules,
+		})
+		if err != nil {
+			return []error{fmt.Errorf("invalid nfd-master config: %w", err)}
+		}
+		for j := range nodes.Items {
+			node := &nodes.Items[j]
+			labels, taints, err := evaluator.Evaluate(node.Name)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("node %q: %w", node.Name, err))
+			}
+			sim, err := simulateNode(node, labels, taints)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("node %q: %w", node.Name, err))
+			}
+			simulated[i] = append(simulated[i], sim)
+		}
+	}
+
+	for _, w := range workloads {
+		printSimulation(os.Stdout, w, simulated)
+	}
+	return errs
+}
+
+// listRuleObjects fetches the NodeFeatureRule and NamespacedNodeFeatureRule
+// objects of the cluster. A cluster without the NamespacedNodeFeatureRule CRD
+// is not an error.
+func listRuleObjects(nfdCli nfdclientset.Interface) (ruleObjects, error) {
+	var objs ruleObjects
+	rules, err := nfdCli.NfdV1alpha1().NodeFeatureRules().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return objs, fmt.Errorf("failed to list NodeFeatureRule objects: %w", err)
+	}
+	for i := range rules.Items {
+		objs.rules = append(objs.rules, &rules.Items[i])
+	}
+
+	nsRules, err := nfdCli.NfdV1alpha1().NamespacedNodeFeatureRules("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return objs, fmt.Errorf("failed to list NamespacedNodeFeatureRule objects: %w", err)
+	} else if err == nil {
+		for i := range nsRules.Items {
+			objs.nsRules = append(objs.nsRules, &nsRules.Items[i])
+		}
+	}
+	return objs, nil
+}
+
+// readWorkloads reads the pod templates of all workloads in a (multi-document)
+// manifest file.
+func readWorkloads(path string) ([]workload, error) {
+	docs, err := readYamlDocuments(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var workloads []workload
+	for _, doc := range docs {
+		obj, gvk, err := scheme.Codecs.UniversalDeserializer().Decode(doc, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse workload manifest %q: %w", path, err)
+		}
+		var spec corev1.PodSpec
+		switch o := obj.(type) {
+		case *corev1.Pod:
+			spec = o.Spec
+		case *corev1.PodTemplate:
+			spec = o.Template.Spec
+		case *appsv1.Deployment:
+			spec = o.Spec.Template.Spec
+		case *appsv1.StatefulSet:
+			spec = o.Spec.Template.Spec
+		case *appsv1.DaemonSet:
+			spec = o.Spec.Template.Spec
+		case *appsv1.ReplicaSet:
+			spec = o.Spec.Template.Spec
+		case *batchv1.Job:
+			spec = o.Spec.Template.Spec
+		case *batchv1.CronJob:
+			spec = o.Spec.JobTemplate.Spec.Template.Spec
+		default:
+			return nil, fmt.Errorf("unsupported workload kind %q in %q", gvk.Kind, path)
+		}
+		name := gvk.Kind
+		if m, ok := obj.(metav1.Object); ok {
+			name += "/" + m.GetName()
+		}
+		workloads = append(workloads, workload{name: name, spec: spec})
+	}
+	if len(workloads) == 0 {
+		return nil, fmt.Errorf("no workloads found in %q", path)
+	}
+	return workloads, nil
+}
+
+// readRuleObjects reads all NodeFeatureRules and NamespacedNodeFeatureRules
+// of a (multi-document) file. Documents without a kind are read as
+// NodeFeatureRules.
+func readRuleObjects(path string) (ruleObjects, error) {
+	var objs ruleObjects
+	docs, err := readYamlDocuments(path)
+	if err != nil {
+		return objs, err
+	}
+	for _, doc := range docs {
+		var typeMeta metav1.TypeMeta
+		if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+			return objs, fmt.Errorf("error parsing %q: %w", path, err)
+		}
+		switch typeMeta.Kind {
+		case "", "NodeFeatureRule":
+			rule := &nfdv1alpha1.NodeFeatureRule{}
+			if err := yaml.Unmarshal(doc, rule); err != nil {
+				return objs, fmt.Errorf("error parsing NodeFeatureRule in %q: %w", path, err)
+			}
+			objs.rules = append(objs.rules, rule)
+		case "NamespacedNodeFeatureRule":
+			rule := &nfdv1alpha1.NamespacedNodeFeatureRule{}
+			if err := yaml.Unmarshal(doc, rule); err != nil {
+				return objs, fmt.Errorf("error parsing NamespacedNodeFeatureRule in %q: %w", path, err)
+			}
+			if rule.Namespace == "" {
+				return objs, fmt.Errorf("NamespacedNodeFeatureRule %q in %q has no namespace", rule.Name, path)
+			}
+			objs.nsRules = append(objs.nsRules, rule)
+		default:
+			return objs, fmt.Errorf("unsupported kind %q in %q", typeMeta.Kind, path)
+		}
+	}
+	return objs, nil
+}
+
+func readYamlDocuments(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %q: %w", path, err)
+	}
+	var docs [][]byte
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading %q: %w", path, err)
+		}
+		if len(bytes.TrimSpace(doc)) > 0 {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// mergeRuleObjects replaces rules of the cluster with proposed rules of the
+// same name (and namespace) and adds the rest of the proposed rules.
+func mergeRuleObjects(current, proposed ruleObjects) ruleObjects {
+	rules := make(map[string]*nfdv1alpha1.NodeFeatureRule, len(current.rules)+len(proposed.rules))
+	nsRules := make(map[string]*nfdv1alpha1.NamespacedNodeFeatureRule, len(current.nsRules)+len(proposed.nsRules))
+	for _, objs := range []ruleObjects{current, proposed} {
+		for _, r := range objs.rules {
+			rules[r.Name] = r
+		}
+		for _, r := range objs.nsRules {
+			nsRules[r.Namespace+"/"+r.Name] = r
+		}
+	}
+
+	var out ruleObjects
+	for _, r := range rules {
+		out.rules = append(out.rules, r)
+	}
+	for _, r := range nsRules {
+		out.nsRules = append(out.nsRules, r)
+	}
+	return out
+}
+
+// simulateNode returns a copy of the node with the NFD-managed labels and
+// taints replaced by the given ones, as computed by nfd-master. Labels and
+// taints not managed by NFD are retained.
+func simulateNode(node *corev1.Node, labels map[string]string, taints []corev1.Taint) (*corev1.Node, error) {
+	var err error
+	n := node.DeepCopy()
+	if n.Labels == nil {
+		n.Labels = make(map[string]string)
+	}
+
+	// Drop NFD-managed labels and taints
+	if val := n.Annotations[nfdv1alpha1.FeatureLabelsAnnotation]; val != "" {
+		for _, name := range strings.Split(val, ",") {
+			delete(n.Labels, addDefaultNs(name))
+		}
+	}
+	if val := n.Annotations[nfdv1alpha1.NodeTaintsAnnotation]; val != "" {
+		var nfdTaints []corev1.Taint
+		nfdTaints, _, err = taintutils.ParseTaints(strings.Split(val, ","))
+		if err != nil {
+			err = fmt.Errorf("failed to parse taints annotation: %w", err)
+		}
+		for _, t := range nfdTaints {
+			n.Spec.Taints, _ = taintutils.DeleteTaint(n.Spec.Taints, &t)
+		}
+	}
+
+	maps.Copy(n.Labels, labels)
+	for _, t := range taints {
+		n, _, _ = taintutils.AddOrUpdateTaint(n, &t)
+	}
+	return n, err
+}
+
+func addDefaultNs(name string) string {
+	if strings.Contains(name, "/") {
+		return name
+	}
+	return nfdv1alpha1.FeatureLabelNs + "/" + name
+}
+
+// schedulingReasons returns the reasons why pods with the given spec could
+// not be scheduled on a node. An empty list means that the node is eligible.
+func schedulingReasons(spec *corev1.PodSpec, node *corev1.Node) []string {
+	var reasons []string
+
+	keys := make([]string, 0, len(spec.NodeSelector))
+	for k := range spec.NodeSelector {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		want := spec.NodeSelector[k]
+		if got, ok := node.Labels[k]; !ok {
+			reasons = append(reasons, fmt.Sprintf("nodeSelector %s=%s: label missing", k, want))
+		} else if got != want {
+			reasons = append(reasons, fmt.Sprintf("nodeSelector %s=%s: label value is %q", k, want, got))
+		}
+	}
+
+	if a := spec.Affinity; a != nil && a.NodeAffinity != nil && a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		terms := a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+		if ok, err := corev1helpers.MatchNodeSelectorTerms(node, terms); err != nil {
+			reasons = append(reasons, fmt.Sprintf("invalid required node affinity: %v", err))
+		} else if !ok {
+			reasons = append(reasons, "required node affinity not satisfied")
+		}
+	}
+
+	for i := range node.Spec.Taints {
+		t := &node.Spec.Taints[i]
+		if t.Effect != corev1.TaintEffectNoSchedule && t.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+		if !corev1helpers.TolerationsTolerateTaint(spec.Tolerations, t) {
+			reasons = append(reasons, fmt.Sprintf("untolerated taint %s", t.ToString()))
+		}
+	}
+	return reasons
+}
+
+// printSimulation prints the eligibility of each node for a workload. The
+// first scenario is the current state of the cluster and the optional second
+// one the state with the proposed NodeFeatureRules.
+func printSimulation(w io.Writer, wl workload, scenarios [][]*corev1.Node) {
+	fmt.Fprintf(w, "Workload %s\n", wl.name)
+
+	eligible := make([]int, len(scenarios))
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	if len(scenarios) > 1 {
+		fmt.Fprintln(tw, "NODE\tCURRENT\tPROPOSED\tREASONS")
+	} else {
+		fmt.Fprintln(tw, "NODE\tELIGIBLE\tREASONS")
+	}
+
+	for i, node := range scenarios[0] {
+		var reasons []string
+		cols := []string{node.Name}
+		for j, nodes := range scenarios {
+			// Reasons are shown for the last (i.e. proposed) scenario
+			reasons = schedulingReasons(&wl.spec, nodes[i])
+			if len(reasons) == 0 {
+				eligible[j]++
+				cols = append(cols, "eligible")
+			} else {
+				cols = append(cols, "ineligible")
+			}
+		}
+		cols = append(cols, strings.Join(reasons, "; "))
+		fmt.Fprintln(tw, strings.Join(cols, "\t"))
+	}
+	tw.Flush()
+
+	summary := fmt.Sprintf("Eligible nodes: %d of %d", eligible[0], len(scenarios[0]))
+	if len(scenarios) > 1 {
+		summary += fmt.Sprintf(" (proposed: %d of %d)", eligible[1], len(scenarios[1]))
+	}
+	fmt.Fprintln(w, summary)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdmaster "sigs.k8s.io/node-feature-discovery/pkg/nfd-master"
+)
+
+func newSimulationRule(name, module, label string, taint bool) *nfdv1alpha1.NodeFeatureRule {
+	rule := nfdv1alpha1.Rule{
+		Name:   name,
+		Labels: map[string]string{label: "true"},
+		MatchFeatures: nfdv1alpha1.FeatureMatcher{
+			{
+				Feature: "kernel.loadedmodule",
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+					module: &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchExists},
+				},
+			},
+		},
+	}
+	if taint {
+		rule.Taints = []corev1.Taint{{Key: nfdv1alpha1.TaintNs + "/" + label, Value: "true", Effect: corev1.TaintEffectNoSchedule}}
+	}
+	return &nfdv1alpha1.NodeFeatureRule{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: nfdv1alpha1.NodeFeatureRuleSpec{Rules: []nfdv1alpha1.Rule{rule}}}
+}
+
+func simulate(t *testing.T, node *corev1.Node, features []*nfdv1alpha1.NodeFeature, rules ruleObjects) *corev1.Node {
+	evaluator, err := nfdmaster.NewRuleEvaluator("testdata/nfd-master.conf", "node-feature-discovery", nfdmaster.RuleEvaluatorObjects{
+		NodeFeatures:               features,
+		NodeFeatureRules:           rules.rules,
+		NamespacedNodeFeatureRules: rules.nsRules,
+	})
+	assert.NoError(t, err)
+	labels, taints, err := evaluator.Evaluate(node.Name)
+	assert.NoError(t, err)
+	sim, err := simulateNode(node, labels, taints)
+	assert.NoError(t, err)
+	return sim
+}
+
+func TestSimulate(t *testing.T) {
+	workloads, err := readWorkloads("testdata/workload.yaml")
+	assert.NoError(t, err)
+	assert.Len(t, workloads, 2)
+	assert.Equal(t, "Deployment/kvm-app", workloads[0].name)
+	assert.Equal(t, "Pod/plain-pod", workloads[1].name)
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-1",
+			Labels: map[string]string{
+				"topology.kubernetes.io/zone":               "zone-a",
+				nfdv1alpha1.FeatureLabelNs + "/stale-label": "true",
+			},
+			Annotations: map[string]string{
+				nfdv1alpha1.FeatureLabelsAnnotation: "stale-label",
+				nfdv1alpha1.NodeTaintsAnnotation:    nfdv1alpha1.TaintNs + "/stale=true:NoSchedule",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{
+				{Key: nfdv1alpha1.TaintNs + "/stale", Value: "true", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+	}
+	nf := &nfdv1alpha1.NodeFeature{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "node-1",
+			Namespace: "node-feature-discovery",
+			Labels:    map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: "node-1"},
+		},
+		Spec: *nfdv1alpha1.NewNodeFeatureSpec(),
+	}
+	// Labels in the kubernetes.io namespace and in the namespaces denied in
+	// the nfd-master config are rejected
+	nf.Spec.Labels = map[string]string{
+		"worker-label":                   "1",
+		"node-role.kubernetes.io/worker": "true",
+		"denied.example.com/label":       "true",
+	}
+	nf.Spec.Features.Flags["kernel.loadedmodule"] = nfdv1alpha1.NewFlagFeatures("kvm")
+	features := []*nfdv1alpha1.NodeFeature{nf}
+
+	current := ruleObjects{rules: []*nfdv1alpha1.NodeFeatureRule{newSimulationRule("kvm", "kvm", "kvm", false)}}
+	sim := simulate(t, node, features, current)
+	// NFD-managed labels and taints are replaced, others are retained
+	assert.Equal(t, map[string]string{
+		"topology.kubernetes.io/zone":                "zone-a",
+		nfdv1alpha1.FeatureLabelNs + "/worker-label": "1",
+		nfdv1alpha1.FeatureLabelNs + "/kvm":          "true",
+	}, sim.Labels)
+	assert.Equal(t, []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}, sim.Spec.Taints)
+
+	assert.Equal(t, []string{"untolerated taint dedicated=gpu:NoSchedule"}, schedulingReasons(&workloads[0].spec, sim))
+
+	// Proposed rule change drops the kvm label, adds a namespaced label and
+	// a tolerated taint
+	proposed, err := readRuleObjects("testdata/rules.yaml")
+	assert.NoError(t, err)
+	assert.Len(t, proposed.rules, 1)
+	assert.Len(t, proposed.nsRules, 1)
+	proposed.rules = append(proposed.rules, newSimulationRule("special", "kvm", "special", true))
+	merged := mergeRuleObjects(current, proposed)
+	assert.Len(t, merged.rules, 2)
+	assert.Len(t, merged.nsRules, 1)
+	simProposed := simulate(t, node, features, merged)
+	assert.Equal(t, "true", simProposed.Labels["team-a.example.com/kvm"])
+	assert.Len(t, simProposed.Spec.Taints, 2)
+	assert.Equal(t, []string{
+		"nodeSelector " + nfdv1alpha1.FeatureLabelNs + "/kvm=true: label missing",
+		"untolerated taint dedicated=gpu:NoSchedule",
+	}, schedulingReasons(&workloads[0].spec, simProposed))
+
+	buf := &bytes.Buffer{}
+	printSimulation(buf, workloads[0], [][]*corev1.Node{{sim}, {simProposed}})
+	assert.Contains(t, buf.String(), "Workload Deployment/kvm-app\n")
+	assert.Contains(t, buf.String(), "Eligible nodes: 0 of 1 (proposed: 0 of 1)")
+
+	buf.Reset()
+	printSimulation(buf, workloads[1], [][]*corev1.Node{{simProposed}})
+	assert.Contains(t, buf.String(), "node-1  ineligible  untolerated taint dedicated=gpu:NoSchedule; untolerated taint "+nfdv1alpha1.TaintNs+"/special=true:NoSchedule")
+	assert.Contains(t, buf.String(), "Eligible nodes: 0 of 1\n")
+}
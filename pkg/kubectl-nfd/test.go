@@ -19,61 +19,134 @@ package kubectlnfd
 import (
 	"fmt"
 	"os"
-	"time"
-
-	k8sLabels "k8s.io/apimachinery/pkg/labels"
-	"k8s.io/client-go/tools/clientcmd"
+	"sort"
 
 	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
-	nfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned"
-	nfdinformers "sigs.k8s.io/node-feature-discovery/pkg/generated/informers/externalversions"
 
 	"sigs.k8s.io/yaml"
 )
 
-func Test(nodefeaturerulepath, nodeName, kubeconfig string) []error {
+// Test evaluates a NodeFeatureRule against the features of nodes in one or
+// more clusters. If nodeName is specified, the full rule output for that node
+// is printed, otherwise a fleet-wide summary of each cluster is printed.
+func Test(nodefeaturerulepath, nodeName, kubeconfig string, contexts []string, allContexts bool) []error {
 	var errs []error
-	var err error
 
 	nfr := nfdv1alpha1.NodeFeatureRule{}
 
-	if kubeconfig == "" {
-		kubeconfig = os.Getenv("KUBECONFIG")
-	}
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	nfrFile, err := os.ReadFile(nodefeaturerulepath)
 	if err != nil {
-		return []error{fmt.Errorf("error building kubeconfig: %w", err)}
+		return []error{fmt.Errorf("error reading NodeFeatureRule file: %w", err)}
 	}
 
-	nfdClient := nfdclientset.NewForConfigOrDie(config)
-	informerFactory := nfdinformers.NewSharedInformerFactory(nfdClient, 1*time.Second)
-	featureLister := informerFactory.Nfd().V1alpha1().NodeFeatures().Lister()
+	err = yaml.Unmarshal(nfrFile, &nfr)
+	if err != nil {
+		return []error{fmt.Errorf("error parsing NodeFeatureRule: %w", err)}
+	}
 
-	sel := k8sLabels.SelectorFromSet(k8sLabels.Set{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName})
-	objs, err := featureLister.List(sel)
+	clusters, err := GetClusters(kubeconfig, contexts, allContexts)
 	if err != nil {
-		return []error{fmt.Errorf("failed to get NodeFeature resources for node %q: %w", nodeName, err)}
+		return []error{err}
 	}
-	features := nfdv1alpha1.NewNodeFeatureSpec()
-	if len(objs) > 0 {
-		features = objs[0].Spec.DeepCopy()
-		for _, o := range objs[1:] {
-			s := o.Spec.DeepCopy()
-			s.MergeInto(features)
+
+	for _, c := range clusters {
+		if len(clusters) > 1 {
+			fmt.Printf("=== Cluster %s ===\n", c.Name())
+		}
+
+		specs, err := getNodeFeatures(c.Config)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %q: %w", c.Name(), err))
+			continue
+		}
+
+		if nodeName != "" {
+			features, ok := specs[nodeName]
+			if !ok {
+				features = nfdv1alpha1.NewNodeFeatureSpec()
+			}
+			for _, e := range processNodeFeatureRule(nfr, *features) {
+				errs = append(errs, fmt.Errorf("cluster %q: %w", c.Name(), e))
+			}
+			continue
+		}
+
+		summary, summaryErrs := summarizeNodeFeatureRule(nfr, specs)
+		summary.print()
+		for _, e := range summaryErrs {
+			errs = append(errs, fmt.Errorf("cluster %q: %w", c.Name(), e))
 		}
 	}
 
-	nfrFile, err := os.ReadFile(nodefeaturerulepath)
-	if err != nil {
-		return []error{fmt.Errorf("error reading NodeFeatureRule file: %w", err)}
+	return errs
+}
+
+// ruleSummary is the aggregated output of a NodeFeatureRule over all nodes of
+// a cluster. Maps are indexed by the printable form of the output item and
+// hold the number of nodes the item would be applied on.
+type ruleSummary struct {
+	nodeCount         int
+	matchedNodes      int
+	labels            map[string]int
+	annotations       map[string]int
+	extendedResources map[string]int
+	taints            map[string]int
+}
+
+func summarizeNodeFeatureRule(nfr nfdv1alpha1.NodeFeatureRule, specs map[string]*nfdv1alpha1.NodeFeatureSpec) (ruleSummary, []error) {
+	var errs []error
+	s := ruleSummary{
+		nodeCount:         len(specs),
+		labels:            make(map[string]int),
+		annotations:       make(map[string]int),
+		extendedResources: make(map[string]int),
+		taints:            make(map[string]int),
 	}
 
-	err = yaml.Unmarshal(nfrFile, &nfr)
-	if err != nil {
-		return []error{fmt.Errorf("error parsing NodeFeatureRule: %w", err)}
+	for _, nodeName := range sortedNodeNames(specs) {
+		out, nodeErrs := executeNodeFeatureRule(nfr, *specs[nodeName].DeepCopy(), nil)
+		for _, e := range nodeErrs {
+			errs = append(errs, fmt.Errorf("node %q: %w", nodeName, e))
+		}
+		if out.isEmpty() {
+			continue
+		}
+		s.matchedNodes++
+		for k, v := range out.Labels {
+			s.labels[k+"="+v]++
+		}
+		for k, v := range out.Annotations {
+			s.annotations[k+"="+v]++
+		}
+		for k, v := range out.ExtendedResources {
+			s.extendedResources[k+"="+v]++
+		}
+		for _, t := range out.Taints {
+			s.taints[t.ToString()]++
+		}
 	}
+	return s, errs
+}
 
-	errs = append(errs, processNodeFeatureRule(nfr, *features)...)
+func (s ruleSummary) print() {
+	fmt.Printf("NodeFeatureRule produces output on %d of %d nodes\n", s.matchedNodes, s.nodeCount)
 
-	return errs
+	printCounts := func(title string, counts map[string]int) {
+		if len(counts) == 0 {
+			return
+		}
+		fmt.Printf("***\t%s\t***\n", title)
+		keys := make([]string, 0, len(counts))
+		for k := range counts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("%s\t(nodes: %d)\n", k, counts[k])
+		}
+	}
+	printCounts("Taints", s.taints)
+	printCounts("Labels", s.labels)
+	printCounts("Extended Resources", s.extendedResources)
+	printCounts("Annotations", s.annotations)
 }
@@ -46,10 +46,8 @@ func ValidateNFR(filepath string) []error {
 
 	for _, rule := range nfr.Spec.Rules {
 		fmt.Println("Validating rule: ", rule.Name)
-		// Validate Rule Name
-		if rule.Name == "" {
-			validationErr = append(validationErr, fmt.Errorf("rule name cannot be empty"))
-		}
+		// Validate Rule Name, templates, matchFeatures, matchAny and schedule
+		validationErr = append(validationErr, validate.Rule(&rule)...)
 
 		// Validate Annotations
 		validationErr = append(validationErr, validate.Annotations(rule.Annotations)...)
@@ -76,18 +74,6 @@ func ValidateNFR(filepath string) []error {
 			}
 		}
 		validationErr = append(validationErr, validate.ExtendedResources(extendedResources)...)
-
-		// Validate LabelsTemplate
-		validationErr = append(validationErr, validate.Template(rule.LabelsTemplate)...)
-
-		// Validate VarsTemplate
-		validationErr = append(validationErr, validate.Template(rule.VarsTemplate)...)
-
-		// Validate matchFeatures
-		validationErr = append(validationErr, validate.MatchFeatures(rule.MatchFeatures)...)
-
-		// Validate matchAny
-		validationErr = append(validationErr, validate.MatchAny(rule.MatchAny)...)
 	}
 
 	return validationErr
@@ -0,0 +1,362 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned"
+	nfdinformers "sigs.k8s.io/node-feature-discovery/pkg/generated/informers/externalversions"
+)
+
+// Kinds of node properties tracked by Watch.
+const (
+	watchKindFeature          = "feature"
+	watchKindLabel            = "label"
+	watchKindAnnotation       = "annotation"
+	watchKindExtendedResource = "extendedresource"
+	watchKindTaint            = "taint"
+)
+
+// watchChange is a single change in the features or NFD-managed properties of
+// a node.
+type watchChange struct {
+	Time     time.Time `json:"time"`
+	Node     string    `json:"node"`
+	Kind     string    `json:"kind"`
+	Set      string    `json:"set,omitempty"`
+	Name     string    `json:"name"`
+	OldValue *string   `json:"oldValue,omitempty"`
+	NewValue *string   `json:"newValue,omitempty"`
+}
+
+// op returns the type of the change as a diff-style prefix.
+func (c watchChange) op() string {
+	switch {
+	case c.OldValue == nil:
+		return "+"
+	case c.NewValue == nil:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// watchSnapshot is the state of a node at one point in time. Each kind of
+// property is stored as a map of sets (feature names for features, a single
+// unnamed set for others) of name-value pairs.
+type watchSnapshot map[string]map[string]map[string]string
+
+// Watch streams the changes of the features and the NFD-managed labels,
+// annotations, extended resources and taints of a node until interrupted.
+// The changes are optionally appended to a timeline file as JSON lines.
+// instance is the name of the nfd-master instance (the -instance flag of
+// nfd-master) managing the node.
+func Watch(nodeName, instance, kubeconfig, timelinePath string) []error {
+	clusters, err := GetClusters(kubeconfig, nil, false)
+	if err != nil {
+		return []error{err}
+	}
+	cli, err := kubernetes.NewForConfig(clusters[0].Config)
+	if err != nil {
+		return []error{err}
+	}
+	nfdCli, err := nfdclientset.NewForConfig(clusters[0].Config)
+	if err != nil {
+		return []error{err}
+	}
+
+	var timeline io.Writer
+	if timelinePath != "" {
+		f, err := os.OpenFile(timelinePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return []error{fmt.Errorf("failed to open timeline file: %w", err)}
+		}
+		defer f.Close()
+		timeline = f
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := watchNode(ctx, cli, nfdCli, nodeName, instance, os.Stdout, timeline); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// watchNode prints the changes of a node until the context is cancelled.
+func watchNode(ctx context.Context, cli kubernetes.Interface, nfdCli nfdclientset.Interface, nodeName, instance string, out, timeline io.Writer) error {
+	changed := make(chan struct{}, 1)
+	notify := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { trigger(changed) },
+		UpdateFunc: func(interface{}, interface{}) { trigger(changed) },
+		DeleteFunc: func(interface{}) { trigger(changed) },
+	}
+
+	nodeInformerFactory := informers.NewSharedInformerFactoryWithOptions(cli, 0,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", nodeName).String()
+		}))
+	nodeInformer := nodeInformerFactory.Core().V1().Nodes()
+	if _, err := nodeInformer.Informer().AddEventHandler(notify); err != nil {
+		return err
+	}
+
+	selector := k8sLabels.SelectorFromSet(k8sLabels.Set{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName})
+	nfdInformerFactory := nfdinformers.NewSharedInformerFactoryWithOptions(nfdCli, 0,
+		nfdinformers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = selector.String()
+		}))
+	featureInformer := nfdInformerFactory.Nfd().V1alpha1().NodeFeatures()
+	if _, err := featureInformer.Informer().AddEventHandler(notify); err != nil {
+		return err
+	}
+
+	nodeInformerFactory.Start(ctx.Done())
+	nfdInformerFactory.Start(ctx.Done())
+	defer nodeInformerFactory.Shutdown()
+	defer nfdInformerFactory.Shutdown()
+	if !cache.WaitForCacheSync(ctx.Done(), nodeInformer.Informer().HasSynced, featureInformer.Informer().HasSynced) {
+		return fmt.Errorf("failed to sync informer caches")
+	}
+
+	snapshot := func() (watchSnapshot, error) {
+		node, err := nodeInformer.Lister().Get(nodeName)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		objs, err := featureInformer.Lister().List(k8sLabels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		return newWatchSnapshot(node, objs, instance), nil
+	}
+
+	prev, err := snapshot()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Watching node %q: %d feature sets, %d labels, %d annotations, %d extended resources, %d taints\n",
+		nodeName, len(prev[watchKindFeature]), len(prev[watchKindLabel][""]), len(prev[watchKindAnnotation][""]),
+		len(prev[watchKindExtendedResource][""]), len(prev[watchKindTaint][""]))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		}
+		cur, err := snapshot()
+		if err != nil {
+			return err
+		}
+		changes := diffWatchSnapshots(nodeName, time.Now().UTC(), prev, cur)
+		printWatchChanges(out, changes)
+		if timeline != nil {
+			if err := writeTimeline(timeline, changes); err != nil {
+				return err
+			}
+		}
+		prev = cur
+	}
+}
+
+// trigger does a non-blocking send on a channel used for coalescing events.
+func trigger(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// newWatchSnapshot creates a snapshot of the features and the properties of a
+// node managed by the given nfd-master instance. The node may be nil if it
+// does not exist.
+func newWatchSnapshot(node *corev1.Node, objs []*nfdv1alpha1.NodeFeature, instance string) watchSnapshot {
+	s := watchSnapshot{
+		watchKindFeature:          {},
+		watchKindLabel:            {"": {}},
+		watchKindAnnotation:       {"": {}},
+		watchKindExtendedResource: {"": {}},
+		watchKindTaint:            {"": {}},
+	}
+
+	// Features of all NodeFeature objects of the node, merged in name order
+	sort.Slice(objs, func(i, j int) bool { return objs[i].Namespace+"/"+objs[i].Name < objs[j].Namespace+"/"+objs[j].Name })
+	features := s[watchKindFeature]
+	set := func(name string) map[string]string {
+		if features[name] == nil {
+			features[name] = map[string]string{}
+		}
+		return features[name]
+	}
+	for _, obj := range objs {
+		for name, f := range obj.Spec.Features.Flags {
+			for e := range f.Elements {
+				set(name)[e] = ""
+			}
+		}
+		for name, f := range obj.Spec.Features.Attributes {
+			for e, v := range f.Elements {
+				set(name)[e] = v
+			}
+		}
+		for name, f := range obj.Spec.Features.Instances {
+			for _, i := range f.Elements {
+				set(name)[instanceString(i)] = ""
+			}
+		}
+	}
+
+	if node == nil {
+		return s
+	}
+
+	annotation := func(name string) string {
+		return node.Annotations[nfdv1alpha1.InstanceAnnotation(instance, name)]
+	}
+	for _, name := range nfdv1alpha1.ParseNsNames(annotation(nfdv1alpha1.FeatureLabelsAnnotation), nfdv1alpha1.FeatureLabelNs) {
+		if v, ok := node.Labels[name]; ok {
+			s[watchKindLabel][""][name] = v
+		}
+	}
+	for _, name := range nfdv1alpha1.ParseNsNames(annotation(nfdv1alpha1.FeatureAnnotationsTrackingAnnotation), nfdv1alpha1.FeatureAnnotationNs) {
+		if v, ok := node.Annotations[name]; ok {
+			s[watchKindAnnotation][""][name] = v
+		}
+	}
+	for _, name := range nfdv1alpha1.ParseNsNames(annotation(nfdv1alpha1.ExtendedResourceAnnotation), nfdv1alpha1.FeatureLabelNs) {
+		if v, ok := node.Status.Capacity[corev1.ResourceName(name)]; ok {
+			s[watchKindExtendedResource][""][name] = v.String()
+		}
+	}
+	if val := node.Annotations[nfdv1alpha1.NodeTaintsAnnotation]; val != "" {
+		managed := map[string]bool{}
+		for _, t := range strings.Split(val, ",") {
+			managed[t] = true
+		}
+		for _, t := range node.Spec.Taints {
+			if managed[t.ToString()] {
+				s[watchKindTaint][""][t.Key+":"+string(t.Effect)] = t.Value
+			}
+		}
+	}
+	return s
+}
+
+// diffWatchSnapshots returns the changes between two snapshots, sorted by
+// kind, set and name.
+func diffWatchSnapshots(nodeName string, t time.Time, prev, cur watchSnapshot) []watchChange {
+	var changes []watchChange
+	for _, kind := range []string{watchKindFeature, watchKindLabel, watchKindAnnotation, watchKindExtendedResource, watchKindTaint} {
+		sets := map[string]bool{}
+		for name := range prev[kind] {
+			sets[name] = true
+		}
+		for name := range cur[kind] {
+			sets[name] = true
+		}
+		for _, set := range sortedKeys(sets) {
+			oldVals, newVals := prev[kind][set], cur[kind][set]
+			names := map[string]bool{}
+			for name := range oldVals {
+				names[name] = true
+			}
+			for name := range newVals {
+				names[name] = true
+			}
+			for _, name := range sortedKeys(names) {
+				oldVal, hadOld := oldVals[name]
+				newVal, hasNew := newVals[name]
+				if hadOld && hasNew && oldVal == newVal {
+					continue
+				}
+				c := watchChange{Time: t, Node: nodeName, Kind: kind, Set: set, Name: name}
+				if hadOld {
+					c.OldValue = &oldVal
+				}
+				if hasNew {
+					c.NewValue = &newVal
+				}
+				changes = append(changes, c)
+			}
+		}
+	}
+	return changes
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// printWatchChanges prints changes in a diff-like format, one per line.
+func printWatchChanges(w io.Writer, changes []watchChange) {
+	for _, c := range changes {
+		name := c.Name
+		if c.Set != "" {
+			name = c.Set + " " + name
+		}
+		var val string
+		switch c.op() {
+		case "+":
+			val = *c.NewValue
+		case "-":
+			val = *c.OldValue
+		default:
+			val = *c.OldValue + " -> " + *c.NewValue
+		}
+		if val != "" {
+			name += ": " + val
+		}
+		fmt.Fprintf(w, "%s %s %s %s\n", c.Time.Format(time.RFC3339), c.op(), c.Kind, name)
+	}
+}
+
+// writeTimeline appends changes to a timeline as JSON lines.
+func writeTimeline(w io.Writer, changes []watchChange) error {
+	enc := json.NewEncoder(w)
+	for _, c := range changes {
+		if err := enc.Encode(c); err != nil {
+			return fmt.Errorf("failed to write timeline: %w", err)
+		}
+	}
+	return nil
+}
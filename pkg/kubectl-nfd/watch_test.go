@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func newWatchTestNode(labels map[string]string, taints ...corev1.Taint) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node-1",
+			Labels:      map[string]string{"kubernetes.io/hostname": "node-1"},
+			Annotations: map[string]string{},
+		},
+		Spec: corev1.NodeSpec{Taints: taints},
+		Status: corev1.NodeStatus{
+			Capacity: corev1.ResourceList{"feature.node.kubernetes.io/widgets": resource.MustParse("2")},
+		},
+	}
+	var names []string
+	for k, v := range labels {
+		node.Labels[k] = v
+		names = append(names, strings.TrimPrefix(k, nfdv1alpha1.FeatureLabelNs+"/"))
+	}
+	node.Annotations[nfdv1alpha1.FeatureLabelsAnnotation] = strings.Join(names, ",")
+	node.Annotations[nfdv1alpha1.ExtendedResourceAnnotation] = "widgets"
+	var taintStrs []string
+	for _, t := range taints {
+		taintStrs = append(taintStrs, t.ToString())
+	}
+	node.Annotations[nfdv1alpha1.NodeTaintsAnnotation] = strings.Join(taintStrs, ",")
+	return node
+}
+
+func TestWatchSnapshot(t *testing.T) {
+	nf := &nfdv1alpha1.NodeFeature{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Namespace: "nfd"},
+		Spec:       *newTestSpec("6", "kvm"),
+	}
+	taint := corev1.Taint{Key: "feature.node.kubernetes.io/special", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	node := newWatchTestNode(map[string]string{"feature.node.kubernetes.io/kvm": "true"}, taint)
+	node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{Key: "other", Effect: corev1.TaintEffectNoExecute})
+
+	s := newWatchSnapshot(node, []*nfdv1alpha1.NodeFeature{nf}, "")
+	assert.Equal(t, map[string]string{"family": "6"}, s[watchKindFeature]["cpu.model"])
+	assert.Equal(t, map[string]string{"kvm": ""}, s[watchKindFeature]["kernel.loadedmodule"])
+	assert.Equal(t, map[string]string{"class=0200,vendor=8086": ""}, s[watchKindFeature]["pci.device"])
+	// Only NFD-managed properties are included
+	assert.Equal(t, map[string]string{"feature.node.kubernetes.io/kvm": "true"}, s[watchKindLabel][""])
+	assert.Equal(t, map[string]string{"feature.node.kubernetes.io/widgets": "2"}, s[watchKindExtendedResource][""])
+	assert.Equal(t, map[string]string{"feature.node.kubernetes.io/special:NoSchedule": "true"}, s[watchKindTaint][""])
+
+	// Annotations of a named nfd-master instance
+	s = newWatchSnapshot(node, nil, "blue")
+	assert.Empty(t, s[watchKindLabel][""])
+	for _, a := range []string{nfdv1alpha1.FeatureLabelsAnnotation, nfdv1alpha1.ExtendedResourceAnnotation} {
+		node.Annotations[nfdv1alpha1.InstanceAnnotation("blue", a)] = node.Annotations[a]
+		delete(node.Annotations, a)
+	}
+	s = newWatchSnapshot(node, nil, "blue")
+	assert.Equal(t, map[string]string{"feature.node.kubernetes.io/kvm": "true"}, s[watchKindLabel][""])
+	assert.Equal(t, map[string]string{"feature.node.kubernetes.io/widgets": "2"}, s[watchKindExtendedResource][""])
+	assert.Empty(t, newWatchSnapshot(node, nil, "")[watchKindLabel][""])
+
+	// Missing node
+	s = newWatchSnapshot(nil, nil, "")
+	assert.Empty(t, s[watchKindFeature])
+	assert.Empty(t, s[watchKindLabel][""])
+}
+
+func TestWatchDiff(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	prevNf := &nfdv1alpha1.NodeFeature{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: *newTestSpec("6", "kvm")}
+	curNf := &nfdv1alpha1.NodeFeature{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: *newTestSpec("25", "kvm", "vfio")}
+	prev := newWatchSnapshot(newWatchTestNode(map[string]string{"feature.node.kubernetes.io/kvm": "true"}), []*nfdv1alpha1.NodeFeature{prevNf}, "")
+	cur := newWatchSnapshot(newWatchTestNode(map[string]string{"feature.node.kubernetes.io/vfio": "true"}), []*nfdv1alpha1.NodeFeature{curNf}, "")
+
+	changes := diffWatchSnapshots("node-1", ts, prev, cur)
+	assert.Len(t, changes, 4)
+	assert.Empty(t, diffWatchSnapshots("node-1", ts, cur, cur))
+
+	var out bytes.Buffer
+	printWatchChanges(&out, changes)
+	assert.Equal(t, `2024-01-02T03:04:05Z ~ feature cpu.model family: 6 -> 25
+2024-01-02T03:04:05Z + feature kernel.loadedmodule vfio
+2024-01-02T03:04:05Z - label feature.node.kubernetes.io/kvm: true
+2024-01-02T03:04:05Z + label feature.node.kubernetes.io/vfio: true
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, writeTimeline(&out, changes))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 4)
+	var c watchChange
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &c))
+	assert.Equal(t, "feature", c.Kind)
+	assert.Equal(t, "cpu.model", c.Set)
+	assert.Equal(t, "6", *c.OldValue)
+	assert.Equal(t, "25", *c.NewValue)
+}
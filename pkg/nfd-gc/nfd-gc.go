@@ -18,11 +18,13 @@ package nfdgarbagecollector
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
 
 	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -34,6 +36,7 @@ import (
 	"sigs.k8s.io/node-feature-discovery/pkg/apihelper"
 	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	nfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned"
+	"sigs.k8s.io/node-feature-discovery/pkg/nrtclient"
 	"sigs.k8s.io/node-feature-discovery/pkg/utils"
 	"sigs.k8s.io/node-feature-discovery/pkg/version"
 )
@@ -56,6 +59,15 @@ type nfdGarbageCollector struct {
 	nfdClient  nfdclientset.Interface
 	topoClient topologyclientset.Interface
 	factory    informers.SharedInformerFactory
+	// podFactory only watches the pods in the namespace of nfd-gc, where the
+	// nfd-worker pods are expected to run
+	podFactory informers.SharedInformerFactory
+	namespace  string
+
+	// nrtClient uses the NodeResourceTopology API version negotiated in the
+	// latest round of garbage collection, nil if the API is not served
+	nrtClientLock sync.Mutex
+	nrtClient     nrtclient.Interface
 }
 
 func New(args *Args) (NfdGarbageCollector, error) {
@@ -65,6 +77,7 @@ func New(args *Args) (NfdGarbageCollector, error) {
 	}
 
 	clientset := kubernetes.NewForConfigOrDie(kubeconfig)
+	namespace := utils.GetKubernetesNamespace()
 
 	return &nfdGarbageCollector{
 		args:       args,
@@ -72,13 +85,15 @@ func New(args *Args) (NfdGarbageCollector, error) {
 		topoClient: topologyclientset.NewForConfigOrDie(kubeconfig),
 		nfdClient:  nfdclientset.NewForConfigOrDie(kubeconfig),
 		factory:    informers.NewSharedInformerFactory(clientset, 5*time.Minute),
+		podFactory: informers.NewSharedInformerFactoryWithOptions(clientset, 5*time.Minute, informers.WithNamespace(namespace)),
+		namespace:  namespace,
 	}, nil
 }
 
 func (n *nfdGarbageCollector) deleteNodeFeature(namespace, name string) {
 	kind := "NodeFeature"
 	if err := n.nfdClient.NfdV1alpha1().NodeFeatures(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
-		if errors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			klog.V(2).InfoS("NodeFeature not found, omitting deletion", "nodefeature", klog.KRef(namespace, name))
 			return
 		} else {
@@ -91,10 +106,43 @@ func (n *nfdGarbageCollector) deleteNodeFeature(namespace, name string) {
 	objectsDeleted.WithLabelValues(kind).Inc()
 }
 
+// negotiateNRTClient re-negotiates the NodeResourceTopology API version so
+// that NodeResourceTopology CRD upgrades are picked up.
+func (n *nfdGarbageCollector) negotiateNRTClient() nrtclient.Interface {
+	cli, err := nrtclient.New(n.topoClient)
+	if errors.Is(err, nrtclient.ErrNotServed) {
+		klog.V(2).InfoS("NodeResourceTopology CRD does not exist")
+	} else if err != nil {
+		klog.ErrorS(err, "failed to negotiate NodeResourceTopology API version")
+	}
+
+	n.nrtClientLock.Lock()
+	defer n.nrtClientLock.Unlock()
+	if cli != nil && (n.nrtClient == nil || n.nrtClient.Version() != cli.Version()) {
+		klog.InfoS("using NodeResourceTopology API version", "version", cli.Version())
+	}
+	// Keep the previous client on transient discovery errors
+	if cli != nil || errors.Is(err, nrtclient.ErrNotServed) {
+		n.nrtClient = cli
+	}
+	return n.nrtClient
+}
+
+func (n *nfdGarbageCollector) getNRTClient() nrtclient.Interface {
+	n.nrtClientLock.Lock()
+	defer n.nrtClientLock.Unlock()
+	return n.nrtClient
+}
+
 func (n *nfdGarbageCollector) deleteNRT(nodeName string) {
 	kind := "NodeResourceTopology"
-	if err := n.topoClient.TopologyV1alpha2().NodeResourceTopologies().Delete(context.TODO(), nodeName, metav1.DeleteOptions{}); err != nil {
-		if errors.IsNotFound(err) {
+	cli := n.getNRTClient()
+	if cli == nil {
+		klog.V(2).InfoS("NodeResourceTopology API not available, omitting deletion", "nodeName", nodeName)
+		return
+	}
+	if err := cli.Delete(context.TODO(), nodeName, metav1.DeleteOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
 			klog.V(2).InfoS("NodeResourceTopology not found, omitting deletion", "nodeName", nodeName)
 			return
 		} else {
@@ -129,6 +177,9 @@ func (n *nfdGarbageCollector) deleteNodeHandler(object interface{}) {
 		klog.ErrorS(err, "failed to list NodeFeature objects")
 	} else {
 		for _, nf := range nfs.Items {
+			if isMirrored(&nf) {
+				continue
+			}
 			n.deleteNodeFeature(nf.Namespace, nf.Name)
 		}
 	}
@@ -149,25 +200,32 @@ func (n *nfdGarbageCollector) garbageCollect() {
 
 	// Handle NodeFeature objects
 	nfs, err := n.nfdClient.NfdV1alpha1().NodeFeatures("").List(context.TODO(), metav1.ListOptions{})
-	if errors.IsNotFound(err) {
+	if apierrors.IsNotFound(err) {
 		klog.V(2).InfoS("NodeFeature CRD does not exist")
 	} else if err != nil {
 		klog.ErrorS(err, "failed to list NodeFeature objects")
 	} else {
 		for _, nf := range nfs.Items {
+			if isMirrored(&nf) {
+				continue
+			}
 			nodeName, ok := nf.GetLabels()[nfdv1alpha1.NodeFeatureObjNodeNameLabel]
 			if !ok {
 				klog.InfoS("node name label missing from NodeFeature object", "nodefeature", klog.KObj(&nf))
 			}
-			if !nodeNames.Has(nodeName) {
+			if !nodeNames.Has(nodeName) || n.isOrphanedWorkerInstance(&nf) {
 				n.deleteNodeFeature(nf.Namespace, nf.Name)
 			}
 		}
 	}
 
 	// Handle NodeResourceTopology objects
-	nrts, err := n.topoClient.TopologyV1alpha2().NodeResourceTopologies().List(context.TODO(), metav1.ListOptions{})
-	if errors.IsNotFound(err) {
+	nrtCli := n.negotiateNRTClient()
+	if nrtCli == nil {
+		return
+	}
+	nrts, err := nrtCli.List(context.TODO(), metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
 		klog.V(2).InfoS("NodeResourceTopology CRD does not exist")
 	} else if err != nil {
 		klog.ErrorS(err, "failed to list NodeResourceTopology objects")
@@ -180,6 +238,63 @@ func (n *nfdGarbageCollector) garbageCollect() {
 	}
 }
 
+// isOrphanedWorkerInstance returns true if the NodeFeature object was created
+// by an nfd-worker instance whose pod does not exist anymore, e.g. because
+// the worker DaemonSet was deleted or no longer runs on the node. Only the
+// pods in the namespace of nfd-gc are watched, objects in other namespaces are
+// never considered orphaned.
+func (n *nfdGarbageCollector) isOrphanedWorkerInstance(nf *nfdv1alpha1.NodeFeature) bool {
+	if _, ok := nf.GetLabels()[nfdv1alpha1.NodeFeatureObjWorkerInstanceLabel]; !ok {
+		return false
+	}
+	if nf.Namespace != n.namespace {
+		klog.V(4).InfoS("not checking the nfd-worker instance of NodeFeature object outside the nfd-gc namespace", "nodefeature", klog.KObj(nf))
+		return false
+	}
+	for _, ref := range nf.GetOwnerReferences() {
+		if ref.APIVersion != "v1" || ref.Kind != "Pod" {
+			continue
+		}
+		pod, err := n.podFactory.Core().V1().Pods().Lister().Pods(nf.Namespace).Get(ref.Name)
+		if apierrors.IsNotFound(err) {
+			klog.InfoS("nfd-worker instance of NodeFeature object not found", "nodefeature", klog.KObj(nf), "pod", klog.KRef(nf.Namespace, ref.Name))
+			return true
+		} else if err != nil {
+			klog.ErrorS(err, "failed to get nfd-worker pod", "pod", klog.KRef(nf.Namespace, ref.Name))
+			return false
+		}
+		if pod.UID != ref.UID {
+			klog.InfoS("nfd-worker instance of NodeFeature object has been replaced", "nodefeature", klog.KObj(nf), "pod", klog.KObj(pod))
+			return true
+		}
+	}
+	return false
+}
+
+// podIdentity is an informer transform function that drops everything but the
+// identity of Pod objects to reduce the memory usage of the cache.
+func podIdentity(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return obj, nil
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+		},
+	}, nil
+}
+
+// isMirrored returns true if the NodeFeature object targets a node in another
+// (edge) cluster, i.e. it has been mirrored into a hub cluster.
+func isMirrored(nf *nfdv1alpha1.NodeFeature) bool {
+	_, ok := nf.GetLabels()[nfdv1alpha1.NodeFeatureObjClusterNameLabel]
+	return ok
+}
+
 // periodicGC runs garbage collector at every gcPeriod to make sure we haven't missed any node
 func (n *nfdGarbageCollector) periodicGC(gcPeriod time.Duration) {
 	// Do initial round of garbage collection at startup time
@@ -207,9 +322,17 @@ func (n *nfdGarbageCollector) startNodeInformer() error {
 		return err
 	}
 
+	// Pods are only needed for checking the owners of the NodeFeature
+	// objects of nfd-worker instances
+	if err := n.podFactory.Core().V1().Pods().Informer().SetTransform(podIdentity); err != nil {
+		return err
+	}
+
 	// start informers
 	n.factory.Start(n.stopChan)
+	n.podFactory.Start(n.stopChan)
 	n.factory.WaitForCacheSync(n.stopChan)
+	n.podFactory.WaitForCacheSync(n.stopChan)
 
 	return nil
 }
@@ -226,6 +349,10 @@ func (n *nfdGarbageCollector) Run() error {
 		defer m.Stop()
 	}
 
+	// Negotiate the NodeResourceTopology API version before reacting to
+	// node deletions
+	n.negotiateNRTClient()
+
 	if err := n.startNodeInformer(); err != nil {
 		return err
 	}
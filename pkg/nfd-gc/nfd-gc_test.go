@@ -21,16 +21,19 @@ import (
 	"testing"
 	"time"
 
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
 	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
 	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
 	faketopologyv1alpha2 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned/fake"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/informers"
 	k8sclientset "k8s.io/client-go/kubernetes"
 	fakek8sclientset "k8s.io/client-go/kubernetes/fake"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	fakenfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned/fake"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -89,15 +92,110 @@ func TestNRTGC(t *testing.T) {
 
 		So(waitForNRT(gc.topoClient, "node1", "node2"), ShouldBeTrue)
 	})
+	Convey("When only v1alpha1 of the NRT API is served", t, func() {
+		gc := newMockGCWithNRTObjs([]string{"node1"}, "v1alpha1",
+			&v1alpha1.NodeResourceTopology{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+			&v1alpha1.NodeResourceTopology{ObjectMeta: metav1.ObjectMeta{Name: "node2"}})
+
+		errChan := make(chan error, 1)
+		go func() { errChan <- gc.Run() }()
+
+		So(waitForNRTV1alpha1(gc.topoClient, "node1"), ShouldBeTrue)
+
+		gc.Stop()
+		So(<-errChan, ShouldBeNil)
+	})
+	Convey("When the NRT API is not served", t, func() {
+		gc := newMockGCWithNRTObjs([]string{"node1"}, "v1beta1", createFakeNRTs("node2")...)
+
+		gc.garbageCollect()
+		So(gc.getNRTClient(), ShouldBeNil)
+		So(waitForNRT(gc.topoClient, "node2"), ShouldBeTrue)
+	})
+}
+
+func TestNodeFeatureGC(t *testing.T) {
+	Convey("When there are NodeFeature objects of multiple worker instances", t, func() {
+		gc := newMockGC([]string{"node1"}, nil)
+		_, err := gc.k8sClient.CoreV1().Pods("nfd").Create(context.TODO(), &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-fast", Namespace: "nfd", UID: "uid-fast"},
+		}, metav1.CreateOptions{})
+		So(err, ShouldBeNil)
+
+		nfs := []*nfdv1alpha1.NodeFeature{
+			newTestNodeFeature("node1", "node1", "", "", ""),
+			newTestNodeFeature("node1-fast", "node1", "fast", "worker-fast", "uid-fast"),
+			newTestNodeFeature("node1-slow", "node1", "slow", "worker-slow", "uid-slow"),
+			newTestNodeFeature("node1-vendor", "node1", "vendor", "worker-fast", "uid-old"),
+			newTestNodeFeature("node1-noowner", "node1", "noowner", "", ""),
+			newTestNodeFeature("node2-fast", "node2", "fast", "worker-fast", "uid-fast"),
+		}
+		// Object of an instance in another namespace, whose pods are not watched
+		other := newTestNodeFeature("node1-other", "node1", "other", "worker-other", "uid-other")
+		other.Namespace = "other"
+		nfs = append(nfs, other)
+		for _, nf := range nfs {
+			_, err := gc.nfdClient.NfdV1alpha1().NodeFeatures(nf.Namespace).Create(context.TODO(), nf, metav1.CreateOptions{})
+			So(err, ShouldBeNil)
+		}
+
+		So(gc.startNodeInformer(), ShouldBeNil)
+		gc.garbageCollect()
+		gc.Stop()
+
+		Convey("objects of instances whose pod is gone should be removed", func() {
+			list, err := gc.nfdClient.NfdV1alpha1().NodeFeatures("nfd").List(context.TODO(), metav1.ListOptions{})
+			So(err, ShouldBeNil)
+			names := sets.NewString()
+			for _, nf := range list.Items {
+				names.Insert(nf.Name)
+			}
+			So(names.List(), ShouldResemble, []string{"node1", "node1-fast", "node1-noowner"})
+		})
+		Convey("objects outside the nfd-gc namespace should be kept", func() {
+			_, err := gc.nfdClient.NfdV1alpha1().NodeFeatures("other").Get(context.TODO(), "node1-other", metav1.GetOptions{})
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
+func newTestNodeFeature(name, nodeName, instance, podName, podUID string) *nfdv1alpha1.NodeFeature {
+	nf := &nfdv1alpha1.NodeFeature{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "nfd",
+			Labels:    map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName},
+		},
+	}
+	if instance != "" {
+		nf.Labels[nfdv1alpha1.NodeFeatureObjWorkerInstanceLabel] = instance
+	}
+	if podName != "" {
+		nf.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: podName, UID: types.UID(podUID)}}
+	}
+	return nf
 }
 
 func newMockGC(nodes, nrts []string) *mockGC {
+	return newMockGCWithNRTObjs(nodes, "v1alpha2", createFakeNRTs(nrts...)...)
+}
+
+func newMockGCWithNRTObjs(nodes []string, nrtVersion string, nrts ...runtime.Object) *mockGC {
 	k8sClient := fakek8sclientset.NewSimpleClientset(createFakeNodes(nodes...)...)
+	topoClient := faketopologyv1alpha2.NewSimpleClientset(nrts...)
+	topoClient.Fake.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "topology.node.k8s.io/" + nrtVersion,
+			APIResources: []metav1.APIResource{{Name: "noderesourcetopologies", Kind: "NodeResourceTopology"}},
+		},
+	}
 	return &mockGC{
 		nfdGarbageCollector: nfdGarbageCollector{
 			factory:    informers.NewSharedInformerFactory(k8sClient, 5*time.Minute),
+			podFactory: informers.NewSharedInformerFactoryWithOptions(k8sClient, 5*time.Minute, informers.WithNamespace("nfd")),
+			namespace:  "nfd",
 			nfdClient:  fakenfdclientset.NewSimpleClientset(),
-			topoClient: faketopologyv1alpha2.NewSimpleClientset(createFakeNRTs(nrts...)...),
+			topoClient: topoClient,
 			stopChan:   make(chan struct{}, 1),
 			args: &Args{
 				GCPeriod: 10 * time.Minute,
@@ -153,3 +251,22 @@ func waitForNRT(cli topologyclientset.Interface, names ...string) bool {
 	}
 	return false
 }
+
+func waitForNRTV1alpha1(cli topologyclientset.Interface, names ...string) bool {
+	nameSet := sets.NewString(names...)
+	for i := 0; i < 2; i++ {
+		nrts, err := cli.TopologyV1alpha1().NodeResourceTopologies().List(context.TODO(), metav1.ListOptions{})
+		So(err, ShouldBeNil)
+
+		nrtNames := sets.NewString()
+		for _, nrt := range nrts.Items {
+			nrtNames.Insert(nrt.Name)
+		}
+
+		if nrtNames.Equal(nameSet) {
+			return true
+		}
+		time.Sleep(1 * time.Second)
+	}
+	return false
+}
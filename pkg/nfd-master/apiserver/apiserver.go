@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package apiserver implements an aggregated API server serving the NFD API
+// group from a store owned by nfd-master, instead of storing the objects in
+// the etcd of the Kubernetes API server.
+package apiserver
+
+import (
+	"fmt"
+	"net"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apimachineryversion "k8s.io/apimachinery/pkg/version"
+	openapinamer "k8s.io/apiserver/pkg/endpoints/openapi"
+	"k8s.io/apiserver/pkg/registry/rest"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	genericoptions "k8s.io/apiserver/pkg/server/options"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/version"
+)
+
+// Config is the configuration of the API server.
+type Config struct {
+	// Port is the port to serve HTTPS on
+	Port int
+	// CertFile and KeyFile are the serving certificate of the server. A
+	// self-signed certificate is generated if not specified.
+	CertFile string
+	KeyFile  string
+	// StorageDir is the directory where the objects are persisted. Objects
+	// are only kept in memory if empty.
+	StorageDir string
+	// Kubeconfig is used for delegating authentication and authorization to
+	// the Kubernetes API server. The in-cluster config is used if empty.
+	Kubeconfig string
+
+	// disableAuth disables authentication and authorization, for tests
+	disableAuth bool
+}
+
+// Server is the NFD aggregated API server.
+type Server struct {
+	server *genericapiserver.GenericAPIServer
+}
+
+// New creates a new API server.
+func New(config Config) (*Server, error) {
+	servingOpts := genericoptions.NewSecureServingOptions()
+	servingOpts.BindPort = config.Port
+	servingOpts.ServerCert.CertKey = genericoptions.CertKey{CertFile: config.CertFile, KeyFile: config.KeyFile}
+	// Keep a generated certificate in memory
+	servingOpts.ServerCert.CertDirectory = ""
+	if err := servingOpts.MaybeDefaultWithSelfSignedCerts("localhost", nil, []net.IP{net.ParseIP("127.0.0.1")}); err != nil {
+		return nil, fmt.Errorf("failed to create self-signed certificate: %w", err)
+	}
+
+	serverConfig := genericapiserver.NewConfig(Codecs)
+	if err := servingOpts.WithLoopback().ApplyTo(&serverConfig.SecureServing, &serverConfig.LoopbackClientConfig); err != nil {
+		return nil, err
+	}
+	if !config.disableAuth {
+		authnOpts := genericoptions.NewDelegatingAuthenticationOptions()
+		authnOpts.RemoteKubeConfigFile = config.Kubeconfig
+		if err := authnOpts.ApplyTo(&serverConfig.Authentication, serverConfig.SecureServing, nil); err != nil {
+			return nil, fmt.Errorf("failed to configure authentication: %w", err)
+		}
+		authzOpts := genericoptions.NewDelegatingAuthorizationOptions()
+		authzOpts.RemoteKubeConfigFile = config.Kubeconfig
+		if err := authzOpts.ApplyTo(&serverConfig.Authorization); err != nil {
+			return nil, fmt.Errorf("failed to configure authorization: %w", err)
+		}
+	}
+	namer := openapinamer.NewDefinitionNamer(Scheme)
+	serverConfig.OpenAPIConfig = genericapiserver.DefaultOpenAPIConfig(getOpenAPIDefinitions, namer)
+	serverConfig.OpenAPIConfig.Info.Title = "NFD"
+	serverConfig.OpenAPIV3Config = genericapiserver.DefaultOpenAPIV3Config(getOpenAPIDefinitions, namer)
+	serverConfig.OpenAPIV3Config.Info.Title = "NFD"
+	serverConfig.Version = &apimachineryversion.Info{GitVersion: version.Get()}
+
+	server, err := serverConfig.Complete(nil).New("nfd-apiserver", genericapiserver.NewEmptyDelegate())
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := newMemStore(config.StorageDir, Codecs.LegacyCodec(nfdv1alpha1.SchemeGroupVersion))
+	if err != nil {
+		return nil, err
+	}
+	storage := make(map[string]rest.Storage, len(resources))
+	for _, res := range resources {
+		r, err := newREST(res, store)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create storage for %s: %w", res.plural, err)
+		}
+		storage[res.plural] = r
+	}
+	groupInfo := genericapiserver.NewDefaultAPIGroupInfo(nfdv1alpha1.SchemeGroupVersion.Group, Scheme, metav1.ParameterCodec, Codecs)
+	groupInfo.VersionedResourcesStorageMap[nfdv1alpha1.SchemeGroupVersion.Version] = storage
+	if err := server.InstallAPIGroup(&groupInfo); err != nil {
+		return nil, err
+	}
+
+	return &Server{server: server}, nil
+}
+
+// Run runs the API server until the stop channel is closed.
+func (s *Server) Run(stopCh <-chan struct{}) error {
+	klog.InfoS("starting the NFD API server")
+	return s.server.PrepareRun().Run(stopCh)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	restclient "k8s.io/client-go/rest"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned"
+)
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// startTestServer runs an API server without authentication and returns a
+// client for it.
+func startTestServer(t *testing.T, storageDir string) nfdclientset.Interface {
+	port := freePort(t)
+	s, err := New(Config{Port: port, StorageDir: storageDir, disableAuth: true})
+	require.NoError(t, err)
+
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	go func() { _ = s.Run(stopCh) }()
+
+	cli := nfdclientset.NewForConfigOrDie(&restclient.Config{
+		Host:            fmt.Sprintf("https://127.0.0.1:%d", port),
+		TLSClientConfig: restclient.TLSClientConfig{Insecure: true},
+	})
+	require.Eventually(t, func() bool {
+		_, err := cli.NfdV1alpha1().NodeFeatureRules().List(context.TODO(), metav1.ListOptions{})
+		return err == nil
+	}, 30*time.Second, 100*time.Millisecond)
+	return cli
+}
+
+func TestAPIServer(t *testing.T) {
+	storageDir := t.TempDir()
+	cli := startTestServer(t, storageDir)
+	ctx := context.TODO()
+	nfClient := cli.NfdV1alpha1().NodeFeatures("nfd")
+
+	w, err := nfClient.Watch(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	defer w.Stop()
+
+	// Create
+	nf := &nfdv1alpha1.NodeFeature{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-1",
+			Labels: map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: "node-1"},
+		},
+		Spec: nfdv1alpha1.NodeFeatureSpec{Features: *nfdv1alpha1.NewFeatures()},
+	}
+	nf.Spec.Labels = map[string]string{"feature.node.kubernetes.io/foo": "true"}
+	created, err := nfClient.Create(ctx, nf, metav1.CreateOptions{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ResourceVersion)
+	assert.NotEmpty(t, created.UID)
+	assert.Equal(t, int64(1), created.Generation)
+
+	_, err = nfClient.Create(ctx, nf, metav1.CreateOptions{})
+	assert.True(t, apierrors.IsAlreadyExists(err))
+
+	// Get and list
+	got, err := nfClient.Get(ctx, "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, created.Spec, got.Spec)
+
+	_, err = nfClient.Get(ctx, "node-2", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	list, err := cli.NfdV1alpha1().NodeFeatures("").List(ctx, metav1.ListOptions{LabelSelector: nfdv1alpha1.NodeFeatureObjNodeNameLabel + "=node-1"})
+	require.NoError(t, err)
+	assert.Len(t, list.Items, 1)
+	list, err = cli.NfdV1alpha1().NodeFeatures("other").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, list.Items)
+
+	// Update, only spec changes bump the generation
+	got.Spec.Labels["feature.node.kubernetes.io/bar"] = "true"
+	updated, err := nfClient.Update(ctx, got, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated.Generation)
+	assert.NotEqual(t, got.ResourceVersion, updated.ResourceVersion)
+
+	_, err = nfClient.Update(ctx, got, metav1.UpdateOptions{})
+	assert.True(t, apierrors.IsConflict(err), "stale update should conflict")
+
+	// Delete
+	require.NoError(t, nfClient.Delete(ctx, "node-1", metav1.DeleteOptions{}))
+	_, err = nfClient.Get(ctx, "node-1", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	for _, typ := range []watch.EventType{watch.Added, watch.Modified, watch.Deleted} {
+		select {
+		case e := <-w.ResultChan():
+			assert.Equal(t, typ, e.Type)
+			assert.Equal(t, "node-1", e.Object.(*nfdv1alpha1.NodeFeature).Name)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timeout waiting for %s event", typ)
+		}
+	}
+
+	// Cluster-scoped rules
+	nfr := &nfdv1alpha1.NodeFeatureRule{ObjectMeta: metav1.ObjectMeta{Name: "rule-1"}}
+	_, err = cli.NfdV1alpha1().NodeFeatureRules().Create(ctx, nfr, metav1.CreateOptions{})
+	require.NoError(t, err)
+	rules, err := cli.NfdV1alpha1().NodeFeatureRules().List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, rules.Items, 1)
+
+	// Invalid rules are rejected
+	invalid := &nfdv1alpha1.NodeFeatureRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "rule-2"},
+		Spec: nfdv1alpha1.NodeFeatureRuleSpec{Rules: []nfdv1alpha1.Rule{
+			{Name: "bad-template", LabelsTemplate: "{{ .foo "},
+		}},
+	}
+	_, err = cli.NfdV1alpha1().NodeFeatureRules().Create(ctx, invalid, metav1.CreateOptions{})
+	assert.True(t, apierrors.IsInvalid(err), "invalid rule should be rejected, got %v", err)
+
+	got1, err := cli.NfdV1alpha1().NodeFeatureRules().Get(ctx, "rule-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	got1.Spec.Rules = []nfdv1alpha1.Rule{{Name: ""}}
+	_, err = cli.NfdV1alpha1().NodeFeatureRules().Update(ctx, got1, metav1.UpdateOptions{})
+	assert.True(t, apierrors.IsInvalid(err), "invalid rule should be rejected, got %v", err)
+}
+
+func TestMemStorePersistence(t *testing.T) {
+	dir := t.TempDir()
+	codec := Codecs.LegacyCodec(nfdv1alpha1.SchemeGroupVersion)
+	ctx := context.TODO()
+
+	s, err := newMemStore(dir, codec)
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b"} {
+		nf := &nfdv1alpha1.NodeFeature{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "nfd"}}
+		require.NoError(t, s.Create(ctx, "/nodefeatures/nfd/"+name, nf, &nfdv1alpha1.NodeFeature{}, 0))
+	}
+	require.NoError(t, s.Delete(ctx, "/nodefeatures/nfd/b", &nfdv1alpha1.NodeFeature{}, nil, func(context.Context, runtime.Object) error { return nil }, nil))
+
+	// Objects and the resource version survive a restart
+	s, err = newMemStore(dir, codec)
+	require.NoError(t, err)
+	assert.Len(t, s.objects, 1)
+	assert.Contains(t, s.objects, "/nodefeatures/nfd/a")
+	assert.Equal(t, uint64(4), s.rv)
+}
+
+func TestMemStorePersistFailure(t *testing.T) {
+	dir := t.TempDir()
+	codec := Codecs.LegacyCodec(nfdv1alpha1.SchemeGroupVersion)
+	ctx := context.TODO()
+
+	s, err := newMemStore(dir, codec)
+	require.NoError(t, err)
+	nf := &nfdv1alpha1.NodeFeature{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "nfd"}}
+	require.NoError(t, s.Create(ctx, "/nodefeatures/nfd/a", nf, &nfdv1alpha1.NodeFeature{}, 0))
+
+	// Writes fail and leave the store unmodified if they cannot be persisted
+	rvFile := filepath.Join(dir, resourceVersionFile)
+	require.NoError(t, os.Remove(rvFile))
+	require.NoError(t, os.Mkdir(rvFile, 0700))
+	nf = &nfdv1alpha1.NodeFeature{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "nfd"}}
+	assert.Error(t, s.Create(ctx, "/nodefeatures/nfd/b", nf, &nfdv1alpha1.NodeFeature{}, 0))
+	assert.Error(t, s.Delete(ctx, "/nodefeatures/nfd/a", &nfdv1alpha1.NodeFeature{}, nil, func(context.Context, runtime.Object) error { return nil }, nil))
+	assert.Len(t, s.objects, 1)
+	assert.Contains(t, s.objects, "/nodefeatures/nfd/a")
+	assert.Equal(t, uint64(2), s.rv)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"reflect"
+	"strings"
+
+	apiextensionsopenapi "k8s.io/apiextensions-apiserver/pkg/generated/openapi"
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+const (
+	objectMetaType = "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"
+	listMetaType   = "k8s.io/apimachinery/pkg/apis/meta/v1.ListMeta"
+)
+
+// metaPackages are the packages of the meta types and their dependencies
+var metaPackages = []string{"k8s.io/apimachinery/pkg/apis/meta/v1", "k8s.io/apimachinery/pkg/runtime", "k8s.io/apimachinery/pkg/version"}
+
+// getOpenAPIDefinitions returns the OpenAPI definitions of the served types.
+// The generic API server needs them for server-side apply. The spec of the
+// objects is described as free-form, it is validated by nfd-master when the
+// objects are processed. The definitions of the meta types are borrowed from
+// apiextensions-apiserver.
+func getOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+	defs := make(map[string]common.OpenAPIDefinition)
+	for name, def := range apiextensionsopenapi.GetOpenAPIDefinitions(ref) {
+		for _, p := range metaPackages {
+			if strings.HasPrefix(name, p+".") {
+				defs[name] = def
+			}
+		}
+	}
+
+	for _, t := range []struct {
+		obj, list interface{}
+		desc      string
+	}{
+		{&nfdv1alpha1.NodeFeature{}, &nfdv1alpha1.NodeFeatureList{}, "NodeFeature resource holds the features discovered for one node in the cluster."},
+		{&nfdv1alpha1.NodeFeatureRule{}, &nfdv1alpha1.NodeFeatureRuleList{}, "NodeFeatureRule resource specifies a configuration for feature-based customization of node objects, such as node labeling."},
+		{&nfdv1alpha1.NamespacedNodeFeatureRule{}, &nfdv1alpha1.NamespacedNodeFeatureRuleList{}, "NamespacedNodeFeatureRule resource specifies a configuration for feature-based customization of node objects, restricted by the namespaced rules policy of nfd-master."},
+	} {
+		objName := typeName(t.obj)
+		defs[objName] = common.OpenAPIDefinition{
+			Schema: spec.Schema{
+				SchemaProps: spec.SchemaProps{
+					Description: t.desc,
+					Type:        []string{"object"},
+					Properties: map[string]spec.Schema{
+						"apiVersion": *spec.StringProperty(),
+						"kind":       *spec.StringProperty(),
+						"metadata":   {SchemaProps: spec.SchemaProps{Ref: ref(objectMetaType)}},
+						"spec": {
+							SchemaProps:      spec.SchemaProps{Type: []string{"object"}},
+							VendorExtensible: spec.VendorExtensible{Extensions: spec.Extensions{"x-kubernetes-preserve-unknown-fields": true}},
+						},
+					},
+					Required: []string{"spec"},
+				},
+			},
+			Dependencies: []string{objectMetaType},
+		}
+		defs[typeName(t.list)] = common.OpenAPIDefinition{
+			Schema: spec.Schema{
+				SchemaProps: spec.SchemaProps{
+					Type: []string{"object"},
+					Properties: map[string]spec.Schema{
+						"apiVersion": *spec.StringProperty(),
+						"kind":       *spec.StringProperty(),
+						"metadata":   {SchemaProps: spec.SchemaProps{Ref: ref(listMetaType)}},
+						"items": {
+							SchemaProps: spec.SchemaProps{
+								Type:  []string{"array"},
+								Items: &spec.SchemaOrArray{Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Ref: ref(objName)}}},
+							},
+						},
+					},
+					Required: []string{"items"},
+				},
+			},
+			Dependencies: []string{listMetaType, objName},
+		}
+	}
+	return defs
+}
+
+// typeName returns the fully qualified name of the type of an object, as used
+// in OpenAPI definitions.
+func typeName(obj interface{}) string {
+	t := reflect.TypeOf(obj).Elem()
+	return t.PkgPath() + "." + t.Name()
+}
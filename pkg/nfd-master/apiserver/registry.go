@@ -0,0 +1,255 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"fmt"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apimachineryvalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apiserver/pkg/registry/generic"
+	genericregistry "k8s.io/apiserver/pkg/registry/generic/registry"
+	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/apiserver/pkg/storage"
+	"k8s.io/apiserver/pkg/storage/names"
+	"k8s.io/apiserver/pkg/storage/storagebackend"
+	"k8s.io/apiserver/pkg/storage/storagebackend/factory"
+	"k8s.io/client-go/tools/cache"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/validate"
+)
+
+// resource describes a resource served by the API server.
+type resource struct {
+	plural     string
+	singular   string
+	shortNames []string
+	namespaced bool
+	newFunc    func() runtime.Object
+	newList    func() runtime.Object
+	// getSpec returns the spec of an object, used for tracking the
+	// generation
+	getSpec func(runtime.Object) interface{}
+	// validateSpec validates the spec of an object, nil if the spec is not
+	// validated
+	validateSpec func(runtime.Object) field.ErrorList
+}
+
+// resources are the resources served by the API server. The APIService
+// delegates the whole group version to the API server so all resources of
+// the group must be served.
+var resources = []resource{
+	{
+		plural:     "nodefeatures",
+		singular:   "nodefeature",
+		namespaced: true,
+		newFunc:    func() runtime.Object { return &nfdv1alpha1.NodeFeature{} },
+		newList:    func() runtime.Object { return &nfdv1alpha1.NodeFeatureList{} },
+		getSpec:    func(o runtime.Object) interface{} { return o.(*nfdv1alpha1.NodeFeature).Spec },
+	},
+	{
+		plural:     "nodefeaturerules",
+		singular:   "nodefeaturerule",
+		shortNames: []string{"nfr"},
+		newFunc:    func() runtime.Object { return &nfdv1alpha1.NodeFeatureRule{} },
+		newList:    func() runtime.Object { return &nfdv1alpha1.NodeFeatureRuleList{} },
+		getSpec:    func(o runtime.Object) interface{} { return o.(*nfdv1alpha1.NodeFeatureRule).Spec },
+		validateSpec: func(o runtime.Object) field.ErrorList {
+			return validateRules(o.(*nfdv1alpha1.NodeFeatureRule).Spec.Rules, field.NewPath("spec", "rules"))
+		},
+	},
+	{
+		plural:     "namespacednodefeaturerules",
+		singular:   "namespacednodefeaturerule",
+		shortNames: []string{"nnfr"},
+		namespaced: true,
+		newFunc:    func() runtime.Object { return &nfdv1alpha1.NamespacedNodeFeatureRule{} },
+		newList:    func() runtime.Object { return &nfdv1alpha1.NamespacedNodeFeatureRuleList{} },
+		getSpec:    func(o runtime.Object) interface{} { return o.(*nfdv1alpha1.NamespacedNodeFeatureRule).Spec },
+		validateSpec: func(o runtime.Object) field.ErrorList {
+			return validateRules(o.(*nfdv1alpha1.NamespacedNodeFeatureRule).Spec.Rules, field.NewPath("spec", "rules"))
+		},
+	},
+}
+
+// validateRules validates the rules of a NodeFeatureRule or
+// NamespacedNodeFeatureRule object.
+func validateRules(rules []nfdv1alpha1.Rule, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i := range rules {
+		for _, err := range validate.Rule(&rules[i]) {
+			errs = append(errs, field.Invalid(path.Index(i), rules[i].Name, err.Error()))
+		}
+	}
+	return errs
+}
+
+// strategy implements the create, update and delete strategies of a
+// resource. The object metadata and the rules of NodeFeatureRule and
+// NamespacedNodeFeatureRule objects are validated.
+type strategy struct {
+	runtime.ObjectTyper
+	names.NameGenerator
+	resource resource
+}
+
+func (s strategy) NamespaceScoped() bool {
+	return s.resource.namespaced
+}
+
+func (s strategy) PrepareForCreate(ctx context.Context, obj runtime.Object) {
+	accessor, err := metaAccessor(obj)
+	if err != nil {
+		return
+	}
+	accessor.SetGeneration(1)
+}
+
+func (s strategy) PrepareForUpdate(ctx context.Context, obj, old runtime.Object) {
+	newAccessor, err := metaAccessor(obj)
+	if err != nil {
+		return
+	}
+	oldAccessor, err := metaAccessor(old)
+	if err != nil {
+		return
+	}
+	generation := oldAccessor.GetGeneration()
+	if !apiequality.Semantic.DeepEqual(s.resource.getSpec(obj), s.resource.getSpec(old)) {
+		generation++
+	}
+	newAccessor.SetGeneration(generation)
+}
+
+func (s strategy) Validate(ctx context.Context, obj runtime.Object) field.ErrorList {
+	accessor, err := metaAccessor(obj)
+	if err != nil {
+		return field.ErrorList{field.InternalError(field.NewPath("metadata"), err)}
+	}
+	errs := apimachineryvalidation.ValidateObjectMetaAccessor(accessor, s.resource.namespaced, apimachineryvalidation.NameIsDNSSubdomain, field.NewPath("metadata"))
+	if s.resource.validateSpec != nil {
+		errs = append(errs, s.resource.validateSpec(obj)...)
+	}
+	return errs
+}
+
+func (s strategy) ValidateUpdate(ctx context.Context, obj, old runtime.Object) field.ErrorList {
+	newAccessor, err := metaAccessor(obj)
+	if err != nil {
+		return field.ErrorList{field.InternalError(field.NewPath("metadata"), err)}
+	}
+	oldAccessor, err := metaAccessor(old)
+	if err != nil {
+		return field.ErrorList{field.InternalError(field.NewPath("metadata"), err)}
+	}
+	errs := apimachineryvalidation.ValidateObjectMetaAccessorUpdate(newAccessor, oldAccessor, field.NewPath("metadata"))
+	if s.resource.validateSpec != nil {
+		errs = append(errs, s.resource.validateSpec(obj)...)
+	}
+	return errs
+}
+
+func (s strategy) WarningsOnCreate(ctx context.Context, obj runtime.Object) []string {
+	return nil
+}
+
+func (s strategy) WarningsOnUpdate(ctx context.Context, obj, old runtime.Object) []string {
+	return nil
+}
+
+func (s strategy) Canonicalize(obj runtime.Object) {}
+
+func (s strategy) AllowCreateOnUpdate() bool {
+	return false
+}
+
+func (s strategy) AllowUnconditionalUpdate() bool {
+	return true
+}
+
+func metaAccessor(obj runtime.Object) (metav1.Object, error) {
+	accessor, ok := obj.(metav1.Object)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+	return accessor, nil
+}
+
+// REST is the REST storage of a resource.
+type REST struct {
+	*genericregistry.Store
+	shortNames []string
+}
+
+// Assert that REST implements rest.ShortNamesProvider
+var _ rest.ShortNamesProvider = &REST{}
+
+// ShortNames implements rest.ShortNamesProvider.
+func (r *REST) ShortNames() []string {
+	return r.shortNames
+}
+
+// newREST creates the REST storage of a resource, using the given storage
+// for the objects.
+func newREST(res resource, store storage.Interface) (*REST, error) {
+	groupResource := nfdv1alpha1.SchemeGroupVersion.WithResource(res.plural).GroupResource()
+	s := strategy{ObjectTyper: Scheme, NameGenerator: names.SimpleNameGenerator, resource: res}
+
+	registry := &genericregistry.Store{
+		NewFunc:                   res.newFunc,
+		NewListFunc:               res.newList,
+		DefaultQualifiedResource:  groupResource,
+		SingularQualifiedResource: nfdv1alpha1.SchemeGroupVersion.WithResource(res.singular).GroupResource(),
+		CreateStrategy:            s,
+		UpdateStrategy:            s,
+		DeleteStrategy:            s,
+		TableConvertor:            rest.NewDefaultTableConvertor(groupResource),
+	}
+	options := &generic.StoreOptions{RESTOptions: restOptionsGetter{store: store}}
+	if err := registry.CompleteWithOptions(options); err != nil {
+		return nil, err
+	}
+	return &REST{Store: registry, shortNames: res.shortNames}, nil
+}
+
+// restOptionsGetter makes the generic registry use the given storage
+// instead of etcd.
+type restOptionsGetter struct {
+	store storage.Interface
+}
+
+// GetRESTOptions implements generic.RESTOptionsGetter.
+func (g restOptionsGetter) GetRESTOptions(resource schema.GroupResource) (generic.RESTOptions, error) {
+	return generic.RESTOptions{
+		StorageConfig: &storagebackend.ConfigForResource{
+			GroupResource: resource,
+			Config: storagebackend.Config{
+				Codec: Codecs.LegacyCodec(nfdv1alpha1.SchemeGroupVersion),
+			},
+		},
+		Decorator: func(*storagebackend.ConfigForResource, string, func(obj runtime.Object) (string, error), func() runtime.Object, func() runtime.Object, storage.AttrFunc, storage.IndexerFuncs, *cache.Indexers) (storage.Interface, factory.DestroyFunc, error) {
+			return g.store, func() {}, nil
+		},
+		ResourcePrefix: "/" + resource.Group + "/" + resource.Resource,
+	}, nil
+}
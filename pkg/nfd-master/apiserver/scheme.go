@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+var (
+	// Scheme contains the types served by the API server.
+	Scheme = runtime.NewScheme()
+	// Codecs provides the serializers of the types served by the API server.
+	Codecs = serializer.NewCodecFactory(Scheme)
+)
+
+// internalGroupVersion is the internal version of the API group. The API is
+// only served in one version so the external types are used as the internal
+// types as well, making conversions plain copies.
+var internalGroupVersion = schema.GroupVersion{Group: nfdv1alpha1.SchemeGroupVersion.Group, Version: runtime.APIVersionInternal}
+
+func init() {
+	utilruntime.Must(nfdv1alpha1.AddToScheme(Scheme))
+	Scheme.AddKnownTypes(internalGroupVersion,
+		&nfdv1alpha1.NodeFeature{},
+		&nfdv1alpha1.NodeFeatureList{},
+		&nfdv1alpha1.NodeFeatureRule{},
+		&nfdv1alpha1.NodeFeatureRuleList{},
+		&nfdv1alpha1.NamespacedNodeFeatureRule{},
+		&nfdv1alpha1.NamespacedNodeFeatureRuleList{},
+	)
+	utilruntime.Must(Scheme.SetVersionPriority(nfdv1alpha1.SchemeGroupVersion))
+
+	// The generic API server needs the options and status types in the
+	// empty group
+	metav1.AddToGroupVersion(Scheme, schema.GroupVersion{Version: "v1"})
+	Scheme.AddUnversionedTypes(schema.GroupVersion{Group: "", Version: "v1"},
+		&metav1.Status{},
+		&metav1.APIVersions{},
+		&metav1.APIGroupList{},
+		&metav1.APIGroup{},
+		&metav1.APIResourceList{},
+	)
+}
@@ -0,0 +1,666 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/storage"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// eventHistorySize is the number of events retained for starting
+	// watches from a past resource version
+	eventHistorySize = 1000
+	// watchChanSize is the size of the event buffer of a watcher. Watchers
+	// that fall behind more than this are terminated.
+	watchChanSize = 100
+	// resourceVersionFile is the file in the storage directory holding the
+	// latest resource version
+	resourceVersionFile = "resourceversion"
+)
+
+// storedObject is an object in the store. The object is never modified after
+// it has been stored.
+type storedObject struct {
+	obj runtime.Object
+	rv  uint64
+}
+
+// storeEvent is a change of an object in the store.
+type storeEvent struct {
+	key     string
+	typ     watch.EventType
+	obj     runtime.Object
+	prevObj runtime.Object
+	rv      uint64
+}
+
+// memStore is an implementation of storage.Interface keeping the objects in
+// memory, and optionally persisting them on local disk. One store serves all
+// resources, the keys of the objects are prefixed with the resource name.
+type memStore struct {
+	sync.RWMutex
+	objects map[string]*storedObject
+	// rv is the latest resource version
+	rv uint64
+	// history is a ring buffer of the latest events, for starting watches
+	// from a past resource version
+	history  []*storeEvent
+	watchers map[*memWatcher]bool
+
+	// dir is the directory where the objects are persisted, if any
+	dir       string
+	codec     runtime.Codec
+	versioner storage.Versioner
+}
+
+// Assert that memStore implements storage.Interface
+var _ storage.Interface = &memStore{}
+
+// newMemStore creates a new store. If dir is not empty, objects are persisted
+// in the directory and the objects stored in it are loaded.
+func newMemStore(dir string, codec runtime.Codec) (*memStore, error) {
+	s := &memStore{
+		objects:  make(map[string]*storedObject),
+		watchers: make(map[*memWatcher]bool),
+		// Zero is not a valid resource version
+		rv:        1,
+		dir:       dir,
+		codec:     codec,
+		versioner: storage.APIObjectVersioner{},
+	}
+	if dir != "" {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// load reads the objects persisted in the storage directory.
+func (s *memStore) load() error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read storage directory: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		key, err := url.PathUnescape(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			klog.InfoS("ignoring unknown file in storage directory", "fileName", e.Name())
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		obj, _, err := s.codec.Decode(data, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to decode stored object %q: %w", key, err)
+		}
+		rv, err := s.versioner.ObjectResourceVersion(obj)
+		if err != nil {
+			return fmt.Errorf("invalid resource version of stored object %q: %w", key, err)
+		}
+		s.objects[key] = &storedObject{obj: obj, rv: rv}
+		if rv > s.rv {
+			s.rv = rv
+		}
+	}
+
+	// Resource versions must not be re-used after deletions
+	if data, err := os.ReadFile(filepath.Join(s.dir, resourceVersionFile)); err == nil {
+		if rv, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err == nil && rv > s.rv {
+			s.rv = rv
+		}
+	}
+	klog.InfoS("loaded stored objects", "objectCount", len(s.objects), "resourceVersion", s.rv)
+	return nil
+}
+
+// persist writes an object with resource version rv into, or deletes it (if
+// obj is nil) from the storage directory. Must be called with the lock held.
+func (s *memStore) persist(key string, obj runtime.Object, rv uint64) error {
+	if s.dir == "" {
+		return nil
+	}
+	// The resource version is written first. If writing the object fails
+	// afterwards, the resource version is merely skipped.
+	if err := writeFileAtomic(filepath.Join(s.dir, resourceVersionFile), []byte(strconv.FormatUint(rv, 10))); err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.dir, url.PathEscape(key)+".json")
+	if obj == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return syncDir(s.dir)
+	}
+	data, err := runtime.Encode(s.codec, obj)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic writes a file via a temporary file so that a partially
+// written file is never left behind. The data is synced to disk before the
+// file is renamed, and the rename is synced before returning.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir syncs a directory so that the creation, renaming and removal of
+// files in it are persisted.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// commit stores (or deletes if obj is nil) an object with a new resource
+// version and notifies the watchers. The store is not modified if persisting
+// the object fails. Must be called with the lock held.
+func (s *memStore) commit(key string, typ watch.EventType, obj, prevObj runtime.Object) (runtime.Object, error) {
+	rv := s.rv + 1
+	if obj != nil {
+		obj = obj.DeepCopyObject()
+		if err := s.versioner.UpdateObject(obj, rv); err != nil {
+			return nil, err
+		}
+	} else {
+		// Deleted objects are delivered to watchers with the resource
+		// version of the deletion
+		obj = prevObj.DeepCopyObject()
+		if err := s.versioner.UpdateObject(obj, rv); err != nil {
+			return nil, err
+		}
+	}
+
+	var persisted runtime.Object
+	if typ != watch.Deleted {
+		persisted = obj
+	}
+	if err := s.persist(key, persisted, rv); err != nil {
+		klog.ErrorS(err, "failed to persist object", "key", key)
+		return nil, storage.NewInternalErrorf("failed to persist object %q: %v", key, err)
+	}
+
+	s.rv = rv
+	if typ == watch.Deleted {
+		delete(s.objects, key)
+	} else {
+		s.objects[key] = &storedObject{obj: obj, rv: rv}
+	}
+
+	e := &storeEvent{key: key, typ: typ, obj: obj, prevObj: prevObj, rv: rv}
+	if len(s.history) >= eventHistorySize {
+		s.history = s.history[1:]
+	}
+	s.history = append(s.history, e)
+	for w := range s.watchers {
+		if !w.send(e) {
+			delete(s.watchers, w)
+		}
+	}
+	return obj, nil
+}
+
+// Versioner implements storage.Interface.
+func (s *memStore) Versioner() storage.Versioner {
+	return s.versioner
+}
+
+// Create implements storage.Interface.
+func (s *memStore) Create(ctx context.Context, key string, obj, out runtime.Object, ttl uint64) error {
+	if version, err := s.versioner.ObjectResourceVersion(obj); err == nil && version != 0 {
+		return storage.NewInvalidObjError(key, "resourceVersion should not be set on objects to be created")
+	}
+	if err := s.versioner.PrepareObjectForStorage(obj); err != nil {
+		return fmt.Errorf("PrepareObjectForStorage failed: %w", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if cur, ok := s.objects[key]; ok {
+		return storage.NewKeyExistsError(key, int64(cur.rv))
+	}
+	stored, err := s.commit(key, watch.Added, obj, nil)
+	if err != nil {
+		return err
+	}
+	return copyInto(stored, out)
+}
+
+// Delete implements storage.Interface.
+func (s *memStore) Delete(ctx context.Context, key string, out runtime.Object, preconditions *storage.Preconditions, validateDeletion storage.ValidateObjectFunc, cachedExistingObject runtime.Object) error {
+	for {
+		s.RLock()
+		cur, ok := s.objects[key]
+		s.RUnlock()
+		if !ok {
+			return storage.NewKeyNotFoundError(key, 0)
+		}
+		if err := preconditions.Check(key, cur.obj); err != nil {
+			return err
+		}
+		if err := validateDeletion(ctx, cur.obj.DeepCopyObject()); err != nil {
+			return err
+		}
+
+		s.Lock()
+		if latest, ok := s.objects[key]; !ok || latest.rv != cur.rv {
+			// Modified concurrently, retry
+			s.Unlock()
+			continue
+		}
+		_, err := s.commit(key, watch.Deleted, nil, cur.obj)
+		s.Unlock()
+		if err != nil {
+			return err
+		}
+		return copyInto(cur.obj, out)
+	}
+}
+
+// Get implements storage.Interface.
+func (s *memStore) Get(ctx context.Context, key string, opts storage.GetOptions, objPtr runtime.Object) error {
+	s.RLock()
+	cur, ok := s.objects[key]
+	s.RUnlock()
+	if !ok {
+		if opts.IgnoreNotFound {
+			return runtime.SetZeroValue(objPtr)
+		}
+		return storage.NewKeyNotFoundError(key, 0)
+	}
+	return copyInto(cur.obj, objPtr)
+}
+
+// GetList implements storage.Interface.
+func (s *memStore) GetList(ctx context.Context, key string, opts storage.ListOptions, listObj runtime.Object) error {
+	listPtr, err := meta.GetItemsPtr(listObj)
+	if err != nil {
+		return err
+	}
+	listAccessor, err := meta.ListAccessor(listObj)
+	if err != nil {
+		return err
+	}
+
+	prefix := key
+	if opts.Recursive && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	fromKey := ""
+	if opts.Predicate.Continue != "" {
+		if !opts.Recursive {
+			return apierrors.NewBadRequest("continue is only supported for lists")
+		}
+		fromKey, _, err = storage.DecodeContinue(opts.Predicate.Continue, prefix)
+		if err != nil {
+			return apierrors.NewBadRequest(fmt.Sprintf("invalid continue token: %v", err))
+		}
+	}
+
+	s.RLock()
+	rv := s.rv
+	var keys []string
+	for k := range s.objects {
+		if (opts.Recursive && strings.HasPrefix(k, prefix) && k >= fromKey) || (!opts.Recursive && k == key) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	objs := make([]runtime.Object, 0, len(keys))
+	for _, k := range keys {
+		objs = append(objs, s.objects[k].obj)
+	}
+	s.RUnlock()
+
+	items := make([]runtime.Object, 0, len(objs))
+	continueKey := ""
+	remaining := int64(0)
+	for i, obj := range objs {
+		if opts.Predicate.Limit > 0 && int64(len(items)) >= opts.Predicate.Limit {
+			continueKey = keys[i]
+			for _, o := range objs[i:] {
+				if ok, _ := opts.Predicate.Matches(o); ok {
+					remaining++
+				}
+			}
+			break
+		}
+		ok, err := opts.Predicate.Matches(obj)
+		if err != nil {
+			return err
+		}
+		if ok {
+			items = append(items, obj.DeepCopyObject())
+		}
+	}
+	if err := meta.SetList(listObj, items); err != nil {
+		return err
+	}
+	// Make sure that the list is not nil so that it is serialized as an
+	// empty array
+	if reflect.ValueOf(listPtr).Elem().IsNil() {
+		reflect.ValueOf(listPtr).Elem().Set(reflect.MakeSlice(reflect.ValueOf(listPtr).Elem().Type(), 0, 0))
+	}
+
+	if continueKey != "" && remaining > 0 {
+		// The continue token holds the key of the first object not returned
+		token, err := storage.EncodeContinue(continueKey, prefix, int64(rv))
+		if err != nil {
+			return err
+		}
+		listAccessor.SetContinue(token)
+		listAccessor.SetRemainingItemCount(&remaining)
+	}
+	return s.versioner.UpdateList(listObj, rv, "", nil)
+}
+
+// GuaranteedUpdate implements storage.Interface.
+func (s *memStore) GuaranteedUpdate(ctx context.Context, key string, destination runtime.Object, ignoreNotFound bool, preconditions *storage.Preconditions, tryUpdate storage.UpdateFunc, cachedExistingObject runtime.Object) error {
+	for {
+		s.RLock()
+		cur, exists := s.objects[key]
+		s.RUnlock()
+
+		var curObj runtime.Object
+		var curRV uint64
+		if exists {
+			curObj, curRV = cur.obj.DeepCopyObject(), cur.rv
+		} else {
+			if !ignoreNotFound {
+				return storage.NewKeyNotFoundError(key, 0)
+			}
+			curObj = reflect.New(reflect.TypeOf(destination).Elem()).Interface().(runtime.Object)
+		}
+		if err := preconditions.Check(key, curObj); err != nil {
+			return err
+		}
+
+		ret, _, err := tryUpdate(curObj, storage.ResponseMeta{ResourceVersion: curRV})
+		if err != nil {
+			return err
+		}
+		if err := s.versioner.PrepareObjectForStorage(ret); err != nil {
+			return fmt.Errorf("PrepareObjectForStorage failed: %w", err)
+		}
+
+		s.Lock()
+		latest, stillExists := s.objects[key]
+		if stillExists != exists || (exists && latest.rv != curRV) {
+			// Modified concurrently, retry
+			s.Unlock()
+			continue
+		}
+		if exists && isUnchanged(cur.obj, ret) {
+			s.Unlock()
+			return copyInto(cur.obj, destination)
+		}
+		typ := watch.Modified
+		var prevObj runtime.Object
+		if exists {
+			prevObj = cur.obj
+		} else {
+			typ = watch.Added
+		}
+		stored, err := s.commit(key, typ, ret, prevObj)
+		s.Unlock()
+		if err != nil {
+			return err
+		}
+		return copyInto(stored, destination)
+	}
+}
+
+// isUnchanged returns true if the updated object equals the stored object,
+// apart from the resource version.
+func isUnchanged(stored, updated runtime.Object) bool {
+	a := stored.DeepCopyObject()
+	if err := (storage.APIObjectVersioner{}).PrepareObjectForStorage(a); err != nil {
+		return false
+	}
+	return apiequality.Semantic.DeepEqual(a, updated)
+}
+
+// Count implements storage.Interface.
+func (s *memStore) Count(key string) (int64, error) {
+	prefix := key
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	s.RLock()
+	defer s.RUnlock()
+	n := int64(0)
+	for k := range s.objects {
+		if strings.HasPrefix(k, prefix) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// RequestWatchProgress implements storage.Interface. Progress notifications
+// are not supported.
+func (s *memStore) RequestWatchProgress(ctx context.Context) error {
+	return nil
+}
+
+// Watch implements storage.Interface.
+func (s *memStore) Watch(ctx context.Context, key string, opts storage.ListOptions) (watch.Interface, error) {
+	startRV := uint64(0)
+	if opts.ResourceVersion != "" {
+		rv, err := s.versioner.ParseResourceVersion(opts.ResourceVersion)
+		if err != nil {
+			return nil, err
+		}
+		startRV = rv
+	}
+
+	prefix := key
+	if opts.Recursive && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	w := &memWatcher{
+		key:       key,
+		prefix:    prefix,
+		recursive: opts.Recursive,
+		predicate: opts.Predicate,
+		ch:        make(chan watch.Event, watchChanSize),
+		done:      make(chan struct{}),
+		store:     s,
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	var initial []*storeEvent
+	if startRV == 0 {
+		// Start with the current state of the objects
+		keys := make([]string, 0, len(s.objects))
+		for k := range s.objects {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			initial = append(initial, &storeEvent{key: k, typ: watch.Added, obj: s.objects[k].obj, rv: s.objects[k].rv})
+		}
+	} else if startRV < s.rv {
+		if len(s.history) == 0 || s.history[0].rv > startRV+1 {
+			return nil, apierrors.NewResourceExpired(fmt.Sprintf("too old resource version: %d (%d)", startRV, s.rv))
+		}
+		for _, e := range s.history {
+			if e.rv > startRV {
+				initial = append(initial, e)
+			}
+		}
+	}
+	if len(initial) > watchChanSize {
+		// Make room for the backlog so that the watcher is not terminated
+		// right away
+		w.ch = make(chan watch.Event, len(initial)+watchChanSize)
+	}
+	for _, e := range initial {
+		w.send(e)
+	}
+	s.watchers[w] = true
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.Stop()
+		case <-w.done:
+		}
+	}()
+	return w, nil
+}
+
+// memWatcher is a watch on the objects of a memStore.
+type memWatcher struct {
+	key       string
+	prefix    string
+	recursive bool
+	predicate storage.SelectionPredicate
+	ch        chan watch.Event
+	done      chan struct{}
+	stopOnce  sync.Once
+	store     *memStore
+}
+
+// ResultChan implements watch.Interface.
+func (w *memWatcher) ResultChan() <-chan watch.Event {
+	return w.ch
+}
+
+// Stop implements watch.Interface.
+func (w *memWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		w.store.Lock()
+		delete(w.store.watchers, w)
+		w.store.Unlock()
+		close(w.done)
+		close(w.ch)
+	})
+}
+
+// send delivers an event to the watcher, if the watcher is interested in
+// it. Returns false if the watcher fell behind and was terminated. Must be
+// called with the lock of the store held.
+func (w *memWatcher) send(e *storeEvent) bool {
+	if (w.recursive && !strings.HasPrefix(e.key, w.prefix)) || (!w.recursive && e.key != w.key) {
+		return true
+	}
+
+	curMatches, _ := w.predicate.Matches(e.obj)
+	prevMatches := false
+	if e.prevObj != nil {
+		prevMatches, _ = w.predicate.Matches(e.prevObj)
+	}
+
+	var event watch.Event
+	switch {
+	case e.typ == watch.Deleted:
+		if !curMatches {
+			return true
+		}
+		event = watch.Event{Type: watch.Deleted, Object: e.obj.DeepCopyObject()}
+	case curMatches && prevMatches:
+		event = watch.Event{Type: watch.Modified, Object: e.obj.DeepCopyObject()}
+	case curMatches:
+		event = watch.Event{Type: watch.Added, Object: e.obj.DeepCopyObject()}
+	case prevMatches:
+		// The object no longer matches the selector
+		obj := e.prevObj.DeepCopyObject()
+		if err := w.store.versioner.UpdateObject(obj, e.rv); err != nil {
+			return true
+		}
+		event = watch.Event{Type: watch.Deleted, Object: obj}
+	default:
+		return true
+	}
+
+	select {
+	case <-w.done:
+		return false
+	case w.ch <- event:
+		return true
+	default:
+		klog.InfoS("terminating watch that fell behind", "key", w.key)
+		w.stopOnce.Do(func() {
+			close(w.done)
+			close(w.ch)
+		})
+		return false
+	}
+}
+
+// copyInto copies an object into another object of the same type.
+func copyInto(in, out runtime.Object) error {
+	if reflect.TypeOf(in) != reflect.TypeOf(out) {
+		return fmt.Errorf("type mismatch: %T vs %T", in, out)
+	}
+	reflect.ValueOf(out).Elem().Set(reflect.ValueOf(in.DeepCopyObject()).Elem())
+	return nil
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+const (
+	// baselineDataKey is the key of the ConfigMap data holding the baseline
+	baselineDataKey = "baseline"
+	// baselineDeviationTaintKey is the key of the taint added to nodes whose
+	// features deviate from their baseline
+	baselineDeviationTaintKey = nfdv1alpha1.TaintNs + "/baseline-deviation"
+	// maxBaselineDeviationsInEvent is the maximum number of deviations listed
+	// in the message of an event
+	maxBaselineDeviationsInEvent = 10
+)
+
+// featureBaseline is the recorded baseline of a node.
+type featureBaseline struct {
+	// FeatureSets are the feature sets that the baseline was recorded for
+	FeatureSets []string `json:"featureSets"`
+	// Elements are the elements of the feature sets, indexed by feature set
+	// name. Instances are identified by their attributes.
+	Elements map[string]map[string]string `json:"elements"`
+}
+
+// baselineDeviation is a difference between the baseline and the current
+// features of a node.
+type baselineDeviation struct {
+	FeatureSet string
+	Element    string
+	// Old is nil for added elements
+	Old *string
+	// New is nil for missing elements
+	New *string
+}
+
+func (d baselineDeviation) String() string {
+	switch {
+	case d.Old == nil:
+		return fmt.Sprintf("added %s %s", d.FeatureSet, d.Element)
+	case d.New == nil:
+		return fmt.Sprintf("missing %s %s", d.FeatureSet, d.Element)
+	}
+	return fmt.Sprintf("changed %s %s: %s -> %s", d.FeatureSet, d.Element, *d.Old, *d.New)
+}
+
+// baselineTracker keeps track of the nodes that deviate from their baseline
+// so that events are only emitted when the deviations of a node change.
+type baselineTracker struct {
+	sync.Mutex
+	deviations map[string]string
+	recorder   record.EventRecorder
+	// client is used for storing the baselines
+	client kubernetes.Interface
+}
+
+func newBaselineTracker(cli kubernetes.Interface, recorder record.EventRecorder) *baselineTracker {
+	return &baselineTracker{
+		deviations: make(map[string]string),
+		recorder:   recorder,
+		client:     cli,
+	}
+}
+
+// update records the deviations of a node and emits an event if they changed.
+func (t *baselineTracker) update(nodeName string, deviations []baselineDeviation) {
+	strs := make([]string, len(deviations))
+	for i, d := range deviations {
+		strs[i] = d.String()
+	}
+	summary := strings.Join(strs, "; ")
+
+	t.Lock()
+	prev, wasDeviating := t.deviations[nodeName]
+	if len(deviations) > 0 {
+		t.deviations[nodeName] = summary
+	} else {
+		delete(t.deviations, nodeName)
+	}
+	t.Unlock()
+
+	baselineDeviations.WithLabelValues(nodeName).Set(float64(len(deviations)))
+
+	// Nodes are cluster-scoped, use the node name as the UID like kubelet does
+	ref := &corev1.ObjectReference{Kind: "Node", Name: nodeName, UID: types.UID(nodeName)}
+	switch {
+	case len(deviations) > 0 && summary != prev:
+		baselineDeviationsDetected.Inc()
+		if len(strs) > maxBaselineDeviationsInEvent {
+			strs = append(strs[:maxBaselineDeviationsInEvent], fmt.Sprintf("and %d more", len(strs)-maxBaselineDeviationsInEvent))
+		}
+		klog.InfoS("node features deviate from baseline", "nodeName", nodeName, "deviations", summary)
+		t.recorder.Eventf(ref, corev1.EventTypeWarning, "FeatureBaselineDeviation", "Node features deviate from the baseline: %s", strings.Join(strs, "; "))
+	case len(deviations) == 0 && wasDeviating:
+		klog.InfoS("node features match the baseline again", "nodeName", nodeName)
+		t.recorder.Event(ref, corev1.EventTypeNormal, "FeatureBaselineRestored", "Node features match the baseline")
+	}
+}
+
+// accepted emits an event about a (re-)recorded baseline.
+func (t *baselineTracker) accepted(nodeName string) {
+	ref := &corev1.ObjectReference{Kind: "Node", Name: nodeName, UID: types.UID(nodeName)}
+	t.recorder.Event(ref, corev1.EventTypeNormal, "FeatureBaselineAccepted", "Recorded the current node features as the baseline")
+}
+
+// checkFeatureBaseline compares the features of a node against its recorded
+// baseline. The baseline is recorded if the node has none and
+// acceptOnFirstSight is enabled, or if the operator has requested the current
+// features to be accepted. Returns the taint to add to the node if it deviates
+// from the baseline, or nil. With dryRun the baseline is not recorded and no
+// events or metrics are emitted.
+func (m *nfdMaster) checkFeatureBaseline(nodeName string, features *nfdv1alpha1.Features, dryRun bool) (*corev1.Taint, error) {
+	config := m.config.FeatureBaseline
+	if len(config.FeatureSets) == 0 || m.nfdController == nil || m.nfdController.baselineLister == nil || m.baselineTracker == nil {
+		return nil, nil
+	}
+
+	// Nothing to compare if none of the feature sets is present, e.g. when
+	// the node has no NodeFeature objects because nfd-worker was uninstalled
+	// or the objects are being recreated
+	current := newFeatureBaseline(config.FeatureSets, features)
+	if len(current.FeatureSets) == 0 {
+		klog.V(2).InfoS("no baselined features present, skipping feature baseline check", "nodeName", nodeName)
+		return nil, nil
+	}
+
+	cm, err := m.nfdController.baselineLister.ConfigMaps(m.namespace).Get(nfdv1alpha1.FeatureBaselineName(nodeName))
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get feature baseline of node %q: %w", nodeName, err)
+	}
+
+	acceptRequested := false
+	if cm != nil {
+		_, acceptRequested = cm.Annotations[nfdv1alpha1.BaselineAcceptAnnotation]
+	}
+	if (cm == nil && config.AcceptOnFirstSight) || acceptRequested {
+		if dryRun {
+			return nil, nil
+		}
+		if err := m.storeFeatureBaseline(nodeName, cm, current); err != nil {
+			return nil, err
+		}
+		m.baselineTracker.accepted(nodeName)
+		m.baselineTracker.update(nodeName, nil)
+		return nil, nil
+	}
+	if cm == nil {
+		// No baseline, waiting for the operator to accept one
+		return nil, nil
+	}
+
+	baseline := &featureBaseline{}
+	if err := json.Unmarshal([]byte(cm.Data[baselineDataKey]), baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse feature baseline of node %q: %w", nodeName, err)
+	}
+
+	deviations := compareFeatureBaseline(baseline, current)
+	if !dryRun {
+		m.baselineTracker.update(nodeName, deviations)
+	}
+	if len(deviations) == 0 || config.TaintEffect == "" {
+		return nil, nil
+	}
+	return &corev1.Taint{Key: baselineDeviationTaintKey, Value: "true", Effect: config.TaintEffect}, nil
+}
+
+// storeFeatureBaseline creates or updates the baseline ConfigMap of a node.
+func (m *nfdMaster) storeFeatureBaseline(nodeName string, cm *corev1.ConfigMap, baseline *featureBaseline) error {
+	data, err := json.Marshal(baseline)
+	if err != nil {
+		return fmt.Errorf("failed to serialize feature baseline of node %q: %w", nodeName, err)
+	}
+
+	create := cm == nil
+	if create {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nfdv1alpha1.FeatureBaselineName(nodeName),
+				Namespace: m.namespace,
+			},
+		}
+	} else {
+		cm = cm.DeepCopy()
+	}
+	if cm.Labels == nil {
+		cm.Labels = make(map[string]string)
+	}
+	cm.Labels[nfdv1alpha1.FeatureBaselineLabel] = "true"
+	cm.Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel] = nodeName
+	if cm.Annotations == nil {
+		cm.Annotations = make(map[string]string)
+	}
+	delete(cm.Annotations, nfdv1alpha1.BaselineAcceptAnnotation)
+	cm.Annotations[nfdv1alpha1.BaselineAcceptedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	cm.Data = map[string]string{baselineDataKey: string(data)}
+
+	cli := m.baselineTracker.client
+	if create {
+		_, err = cli.CoreV1().ConfigMaps(m.namespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+	} else {
+		_, err = cli.CoreV1().ConfigMaps(m.namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store feature baseline of node %q: %w", nodeName, err)
+	}
+	klog.InfoS("recorded feature baseline", "nodeName", nodeName, "featureSets", baseline.FeatureSets)
+	return nil
+}
+
+// newFeatureBaseline creates a baseline of the given feature sets. Feature
+// sets not present in the features are left out.
+func newFeatureBaseline(featureSets []string, features *nfdv1alpha1.Features) *featureBaseline {
+	b := &featureBaseline{
+		FeatureSets: []string{},
+		Elements:    make(map[string]map[string]string, len(featureSets)),
+	}
+	if features == nil {
+		return b
+	}
+	for _, name := range featureSets {
+		present := false
+		elems := make(map[string]string)
+		if s, ok := features.Flags[name]; ok {
+			present = true
+			for e := range s.Elements {
+				elems[e] = ""
+			}
+		}
+		if s, ok := features.Attributes[name]; ok {
+			present = true
+			for e, v := range s.Elements {
+				elems[e] = v
+			}
+		}
+		if s, ok := features.Instances[name]; ok {
+			present = true
+			for _, i := range s.Elements {
+				elems[instanceKey(i.Attributes)] = ""
+			}
+		}
+		if present {
+			b.FeatureSets = append(b.FeatureSets, name)
+			b.Elements[name] = elems
+		}
+	}
+	sort.Strings(b.FeatureSets)
+	return b
+}
+
+// instanceKey returns a string identifying an instance by its attributes.
+func instanceKey(attrs map[string]string) string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		keys[i] = k + "=" + attrs[k]
+	}
+	return strings.Join(keys, ",")
+}
+
+// compareFeatureBaseline returns the deviations of the current features from
+// the baseline. Only feature sets present in both are compared.
+func compareFeatureBaseline(baseline, current *featureBaseline) []baselineDeviation {
+	deviations := []baselineDeviation{}
+	for _, name := range current.FeatureSets {
+		old, ok := baseline.Elements[name]
+		if !ok {
+			continue
+		}
+		cur := current.Elements[name]
+		for e, v := range old {
+			v := v
+			if n, ok := cur[e]; !ok {
+				deviations = append(deviations, baselineDeviation{FeatureSet: name, Element: e, Old: &v})
+			} else if n != v {
+				n := n
+				deviations = append(deviations, baselineDeviation{FeatureSet: name, Element: e, Old: &v, New: &n})
+			}
+		}
+		for e, v := range cur {
+			v := v
+			if _, ok := old[e]; !ok {
+				deviations = append(deviations, baselineDeviation{FeatureSet: name, Element: e, New: &v})
+			}
+		}
+	}
+	sort.Slice(deviations, func(i, j int) bool {
+		if deviations[i].FeatureSet != deviations[j].FeatureSet {
+			return deviations[i].FeatureSet < deviations[j].FeatureSet
+		}
+		return deviations[i].Element < deviations[j].Element
+	})
+	return deviations
+}
@@ -0,0 +1,349 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned"
+	nfdinformers "sigs.k8s.io/node-feature-discovery/pkg/generated/informers/externalversions"
+	nfdlisters "sigs.k8s.io/node-feature-discovery/pkg/generated/listers/nfd/v1alpha1"
+)
+
+// stripManagedFields is an informer transform function that drops the
+// managed fields of objects before they are stored in the cache.
+func stripManagedFields(obj interface{}) (interface{}, error) {
+	if accessor, ok := obj.(metav1.Object); ok {
+		accessor.SetManagedFields(nil)
+	}
+	return obj, nil
+}
+
+// featureFilter selects the features stored in the NodeFeature cache.
+type featureFilter struct {
+	// used are the features referenced by the rules
+	used sets.Set[string]
+	// retain are (glob patterns of) features that are always stored
+	retain []string
+}
+
+// keep returns true if a feature should be stored in the cache.
+func (f *featureFilter) keep(name string) bool {
+	if f.used.Has(name) {
+		return true
+	}
+	for _, p := range f.retain {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// covers returns true if all the given features are stored by the filter.
+func (f *featureFilter) covers(features sets.Set[string]) bool {
+	for name := range features {
+		if !f.keep(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// transform is an informer transform function that drops the managed fields
+// and the features not selected by the filter from NodeFeature objects.
+func (f *featureFilter) transform(obj interface{}) (interface{}, error) {
+	nf, ok := obj.(*nfdv1alpha1.NodeFeature)
+	if !ok {
+		return stripManagedFields(obj)
+	}
+	nf.ManagedFields = nil
+
+	features := &nf.Spec.Features
+	for name := range features.Flags {
+		if !f.keep(name) {
+			delete(features.Flags, name)
+		}
+	}
+	for name := range features.Attributes {
+		if !f.keep(name) {
+			delete(features.Attributes, name)
+		}
+	}
+	for name := range features.Instances {
+		if !f.keep(name) {
+			delete(features.Instances, name)
+		}
+	}
+	return nf, nil
+}
+
+// ruleFeatureRefs returns the names of the features referenced by the given
+// rules, either in feature matchers or in dynamic label and extended resource
+// values.
+func ruleFeatureRefs(rules []nfdv1alpha1.Rule) sets.Set[string] {
+	refs := sets.New[string]()
+	addMatcher := func(m nfdv1alpha1.FeatureMatcher) {
+		for _, term := range m {
+			refs.Insert(term.Feature)
+		}
+	}
+	addDynamic := func(values map[string]string) {
+		for _, v := range values {
+			if !strings.HasPrefix(v, "@") {
+				continue
+			}
+			// Dynamic values are in the form of @domain.feature.element
+			if split := strings.SplitN(v[1:], ".", 3); len(split) == 3 {
+				refs.Insert(split[0] + "." + split[1])
+			}
+		}
+	}
+
+	for _, rule := range rules {
+		addMatcher(rule.MatchFeatures)
+		for _, m := range rule.MatchAny {
+			addMatcher(m.MatchFeatures)
+		}
+		addDynamic(rule.Labels)
+		addDynamic(rule.ExtendedResources)
+	}
+	return refs
+}
+
+// nodeFeatureCache is a NodeFeature lister backed by an informer that only
+// stores the features referenced by NodeFeatureRule (and
+// NamespacedNodeFeatureRule) objects, plus the features that are always
+// retained. When the rules start referencing new features the cache is
+// rebuilt in the background and swapped in after it has synced. The old cache
+// remains in use until then.
+type nodeFeatureCache struct {
+	sync.RWMutex
+	lister   nfdlisters.NodeFeatureLister
+	synced   cache.InformerSynced
+	filter   *featureFilter
+	stopChan chan struct{}
+
+	client       nfdclientset.Interface
+	resyncPeriod time.Duration
+	handler      cache.ResourceEventHandler
+	// rebuildLock serializes cache rebuilds
+	rebuildLock sync.Mutex
+	// rebuild is the cache being rebuilt, kept over failed ensure() calls so
+	// that the initial listing of the objects is not started over
+	rebuild *featureCacheRebuild
+	stopped bool
+	done    chan struct{}
+}
+
+// featureCacheRebuild is an informer of a NodeFeature cache being rebuilt.
+type featureCacheRebuild struct {
+	lister   nfdlisters.NodeFeatureLister
+	synced   cache.InformerSynced
+	filter   *featureFilter
+	stopChan chan struct{}
+}
+
+// List implements nfdlisters.NodeFeatureLister.
+func (c *nodeFeatureCache) List(selector k8sLabels.Selector) ([]*nfdv1alpha1.NodeFeature, error) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.lister.List(selector)
+}
+
+// NodeFeatures implements nfdlisters.NodeFeatureLister.
+func (c *nodeFeatureCache) NodeFeatures(namespace string) nfdlisters.NodeFeatureNamespaceLister {
+	c.RLock()
+	defer c.RUnlock()
+	return c.lister.NodeFeatures(namespace)
+}
+
+// newNodeFeatureCache creates and starts a NodeFeature cache storing the
+// features referenced by the current rules, listed from the API server.
+func newNodeFeatureCache(client nfdclientset.Interface, resyncPeriod time.Duration, retain []string, namespacedRules bool, handler cache.ResourceEventHandler) (*nodeFeatureCache, error) {
+	used, err := listRuleFeatureRefs(client, namespacedRules)
+	if err != nil {
+		return nil, err
+	}
+	c := &nodeFeatureCache{
+		client:       client,
+		resyncPeriod: resyncPeriod,
+		handler:      handler,
+		done:         make(chan struct{}),
+	}
+	filter := &featureFilter{used: used, retain: retain}
+	lister, synced, stopChan, err := c.startInformer(filter, false)
+	if err != nil {
+		return nil, err
+	}
+	c.lister, c.synced, c.filter, c.stopChan = lister, synced, filter, stopChan
+	klog.InfoS("caching NodeFeature objects with features referenced by rules", "features", sets.List(used), "retainFeatures", retain)
+	return c, nil
+}
+
+// listRuleFeatureRefs returns the features referenced by the rules in the
+// cluster.
+func listRuleFeatureRefs(client nfdclientset.Interface, namespacedRules bool) (sets.Set[string], error) {
+	refs := sets.New[string]()
+	nfrs, err := client.NfdV1alpha1().NodeFeatureRules().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NodeFeatureRule resources: %w", err)
+	}
+	for _, nfr := range nfrs.Items {
+		refs = refs.Union(ruleFeatureRefs(nfr.Spec.Rules))
+	}
+	if namespacedRules {
+		nnfrs, err := client.NfdV1alpha1().NamespacedNodeFeatureRules("").List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list NamespacedNodeFeatureRule resources: %w", err)
+		}
+		for _, nnfr := range nnfrs.Items {
+			refs = refs.Union(ruleFeatureRefs(nnfr.Spec.Rules))
+		}
+	}
+	return refs, nil
+}
+
+// startInformer starts a new NodeFeature informer using the given feature
+// filter. If ignoreInitialList is true, events of the initial listing of
+// objects are not passed to the event handler.
+func (c *nodeFeatureCache) startInformer(filter *featureFilter, ignoreInitialList bool) (nfdlisters.NodeFeatureLister, cache.InformerSynced, chan struct{}, error) {
+	factory := nfdinformers.NewSharedInformerFactory(c.client, c.resyncPeriod)
+	informer := factory.Nfd().V1alpha1().NodeFeatures()
+	if err := informer.Informer().SetTransform(filter.transform); err != nil {
+		return nil, nil, nil, err
+	}
+	handler := c.handler
+	if ignoreInitialList {
+		handler = cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj interface{}, isInInitialList bool) {
+				if !isInInitialList {
+					c.handler.OnAdd(obj, false)
+				}
+			},
+			UpdateFunc: c.handler.OnUpdate,
+			DeleteFunc: c.handler.OnDelete,
+		}
+	}
+	if _, err := informer.Informer().AddEventHandler(handler); err != nil {
+		return nil, nil, nil, err
+	}
+	stopChan := make(chan struct{})
+	factory.Start(stopChan)
+	return informer.Lister(), informer.Informer().HasSynced, stopChan, nil
+}
+
+// hasSynced returns true if the current informer of the cache has synced.
+func (c *nodeFeatureCache) hasSynced() bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.synced()
+}
+
+// ensure rebuilds the cache if it does not store all of the given features.
+// Returns true if the cache was rebuilt. If the new cache does not sync within
+// the timeout an error is returned and the rebuild continues in the
+// background, to be waited for by the next call.
+func (c *nodeFeatureCache) ensure(used sets.Set[string], timeout time.Duration) (bool, error) {
+	c.rebuildLock.Lock()
+	defer c.rebuildLock.Unlock()
+
+	c.Lock()
+	if c.stopped {
+		c.Unlock()
+		return false, nil
+	}
+	if c.filter.covers(used) {
+		// Rules changed back before the rebuild completed
+		c.stopRebuild()
+		c.Unlock()
+		return false, nil
+	}
+	if c.rebuild != nil && !c.rebuild.filter.covers(used) {
+		klog.InfoS("rules reference new features, restarting the rebuild of the NodeFeature cache")
+		c.stopRebuild()
+	}
+	if c.rebuild == nil {
+		klog.InfoS("rules reference new features, rebuilding the NodeFeature cache", "features", sets.List(used))
+		newFilter := &featureFilter{used: used, retain: c.filter.retain}
+		lister, synced, stopChan, err := c.startInformer(newFilter, true)
+		if err != nil {
+			c.Unlock()
+			return false, err
+		}
+		c.rebuild = &featureCacheRebuild{lister: lister, synced: synced, filter: newFilter, stopChan: stopChan}
+	}
+	r := c.rebuild
+	c.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if !cache.WaitForCacheSync(ctx.Done(), r.synced) {
+		return false, fmt.Errorf("NodeFeature cache did not sync within %v", timeout)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if c.stopped {
+		return false, nil
+	}
+	close(c.stopChan)
+	c.lister, c.synced, c.filter, c.stopChan = r.lister, r.synced, r.filter, r.stopChan
+	c.rebuild = nil
+	featureCacheRebuilds.Inc()
+	return true, nil
+}
+
+// stopRebuild stops the informer of the cache being rebuilt, if any. Must be
+// called with the lock held.
+func (c *nodeFeatureCache) stopRebuild() {
+	if c.rebuild != nil {
+		close(c.rebuild.stopChan)
+		c.rebuild = nil
+	}
+}
+
+// stop stops the informers of the cache.
+func (c *nodeFeatureCache) stop() {
+	c.Lock()
+	defer c.Unlock()
+	if !c.stopped {
+		close(c.stopChan)
+		close(c.done)
+		c.stopRebuild()
+		c.stopped = true
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// Reasons of rejecting gRPC requests, used as the "reason" label of the
+// nfd_grpc_requests_rejected_total metric
+const (
+	grpcRejectRateLimit        = "rate_limit"
+	grpcRejectConcurrencyLimit = "concurrency_limit"
+	grpcRejectDeadlineExceeded = "deadline_exceeded"
+)
+
+// rateLimiterSweepInterval is the minimum interval of dropping the rate
+// limiters of idle clients
+const rateLimiterSweepInterval = time.Minute
+
+// maxRateLimiters is the maximum number of clients with a rate limiter
+const maxRateLimiters = 10000
+
+// grpcLimiter holds the state of the rate and concurrency limits of the gRPC
+// server.
+type grpcLimiter struct {
+	sync.Mutex
+	// limiters are the token buckets of the clients, indexed by client
+	// identity
+	limiters  map[string]*rate.Limiter
+	lastSweep time.Time
+	// inFlight is the number of node updates in progress
+	inFlight atomic.Int32
+}
+
+// allow returns true if a client may make a request now, consuming one token
+// from its bucket. A zero limit disables rate limiting.
+func (l *grpcLimiter) allow(identity string, limit float64, burst int) bool {
+	if limit <= 0 {
+		return true
+	}
+	now := time.Now()
+
+	l.Lock()
+	defer l.Unlock()
+	if l.limiters == nil {
+		l.limiters = make(map[string]*rate.Limiter)
+	}
+	if now.Sub(l.lastSweep) >= rateLimiterSweepInterval {
+		l.sweep(now)
+	}
+
+	lim, ok := l.limiters[identity]
+	if !ok {
+		if len(l.limiters) >= maxRateLimiters {
+			l.sweep(now)
+		}
+		if len(l.limiters) >= maxRateLimiters {
+			// Drop an arbitrary limiter to bound the memory used by clients
+			// making up new identities
+			for id := range l.limiters {
+				delete(l.limiters, id)
+				break
+			}
+		}
+		lim = rate.NewLimiter(rate.Limit(limit), burst)
+		l.limiters[identity] = lim
+	} else if lim.Limit() != rate.Limit(limit) || lim.Burst() != burst {
+		// Configuration was changed
+		lim.SetLimitAt(now, rate.Limit(limit))
+		lim.SetBurstAt(now, burst)
+	}
+	return lim.AllowN(now, 1)
+}
+
+// sweep drops the rate limiters whose bucket is full. They are equivalent to
+// a newly created one.
+func (l *grpcLimiter) sweep(now time.Time) {
+	for identity, lim := range l.limiters {
+		if lim.TokensAt(now) >= float64(lim.Burst()) {
+			delete(l.limiters, identity)
+		}
+	}
+	l.lastSweep = now
+}
+
+// acquire reserves a slot for a node update. Returns false if max updates are
+// already in progress. A zero max disables the limit. Each successful acquire
+// must be paired with a release.
+func (l *grpcLimiter) acquire(max int) bool {
+	n := l.inFlight.Add(1)
+	if max > 0 && int(n) > max {
+		l.inFlight.Add(-1)
+		return false
+	}
+	return true
+}
+
+// release frees a slot reserved with acquire.
+func (l *grpcLimiter) release() {
+	l.inFlight.Add(-1)
+}
+
+// grpcServerOptions returns the options of the gRPC server derived from the
+// configuration.
+func (m *nfdMaster) grpcServerOptions() []grpc.ServerOption {
+	c := m.config.GrpcServer
+	return []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(c.MaxRecvMsgSize),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             c.KeepaliveMinTime.Duration,
+			PermitWithoutStream: c.KeepalivePermitWithoutStream,
+		}),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle: c.MaxConnectionIdle.Duration,
+		}),
+		grpc.UnaryInterceptor(m.grpcTimeoutInterceptor),
+	}
+}
+
+// grpcTimeoutInterceptor sets the configured request timeout as the deadline
+// of the request context. The node update of the request is cancelled when
+// the deadline is exceeded.
+func (m *nfdMaster) grpcTimeoutInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	timeout := m.config.GrpcServer.RequestTimeout.Duration
+	if timeout <= 0 {
+		return handler(ctx, req)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := handler(ctx, req)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		grpcRequestsRejected.WithLabelValues(grpcRejectDeadlineExceeded).Inc()
+		klog.InfoS("gRPC request timed out", "method", info.FullMethod, "timeout", timeout)
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	return resp, err
+}
+
+// checkGrpcRateLimit returns an error if the client has exceeded its request
+// rate.
+func (m *nfdMaster) checkGrpcRateLimit(ctx context.Context, nodeName string) error {
+	c := m.config.GrpcServer
+	identity := grpcClientIdentity(ctx, m.args.VerifyNodeName, nodeName)
+	if !m.grpcLimiter.allow(identity, c.RateLimit, c.RateLimitBurst) {
+		grpcRequestsRejected.WithLabelValues(grpcRejectRateLimit).Inc()
+		return status.Errorf(codes.ResourceExhausted, "rate limit of client %q exceeded", identity)
+	}
+	return nil
+}
+
+// grpcClientIdentity returns the identity of a client for rate limiting. The
+// node name of the request is only used if it has been verified against the
+// client certificate. Otherwise the client is identified by the subject of its
+// verified certificate, or by its address.
+func grpcClientIdentity(ctx context.Context, nodeNameVerified bool, nodeName string) string {
+	if nodeNameVerified {
+		return nodeName
+	}
+	client, ok := peer.FromContext(ctx)
+	if !ok {
+		return nodeName
+	}
+	if tlsAuth, ok := client.AuthInfo.(credentials.TLSInfo); ok {
+		if chains := tlsAuth.State.VerifiedChains; len(chains) > 0 && len(chains[0]) > 0 {
+			return "cert:" + chains[0][0].Subject.String()
+		}
+	}
+	if client.Addr == nil {
+		return nodeName
+	}
+	// The port differs between connections of a client
+	addr := client.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return "addr:" + addr
+}
@@ -0,0 +1,482 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/node-feature-discovery/pkg/apihelper"
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned"
+)
+
+const (
+	// hubModeEdge makes nfd-master mirror its NodeFeatures into a hub cluster
+	// and apply the labels created by the hub.
+	hubModeEdge = "edge"
+	// hubModeHub makes nfd-master process NodeFeatures mirrored from edge
+	// clusters and write the resulting labels back into the hub.
+	hubModeHub = "hub"
+
+	// hubOutputNameSuffix is appended to the name of a mirrored NodeFeature
+	// object to get the name of the object carrying the hub output.
+	hubOutputNameSuffix = "-hub-output"
+
+	// hubRequestTimeout limits the duration of individual requests to the hub
+	// cluster so that an unreachable hub does not stall the mirroring.
+	hubRequestTimeout = 10 * time.Second
+
+	// hubMirrorWorkers is the number of workers mirroring the features of
+	// updated nodes into the hub cluster
+	hubMirrorWorkers = 2
+	// hubMirrorMaxRetries is the number of retries of mirroring the features
+	// of a node. The periodic sync takes care of nodes whose retries ran out.
+	hubMirrorMaxRetries = 5
+)
+
+// hubSyncer mirrors the NodeFeature objects of an edge cluster into a
+// per-cluster namespace in the hub cluster and copies the rule output
+// produced by the hub back into the edge cluster.
+type hubSyncer struct {
+	localClient    nfdclientset.Interface
+	hubClient      nfdclientset.Interface
+	localNamespace string
+	hubNamespace   string
+	clusterName    string
+
+	// listNodeFeatures returns the current NodeFeature objects of a node
+	listNodeFeatures func(nodeName string) ([]*nfdv1alpha1.NodeFeature, error)
+	// queue holds the nodes whose features are to be mirrored, separate from
+	// the node updates so that a slow hub does not delay them
+	queue    workqueue.RateLimitingInterface
+	stopChan chan struct{}
+}
+
+func newHubSyncer(localClient, hubClient nfdclientset.Interface, localNamespace string, config HubConfig, listNodeFeatures func(string) ([]*nfdv1alpha1.NodeFeature, error)) *hubSyncer {
+	hubNamespace := config.Namespace
+	if hubNamespace == "" {
+		hubNamespace = config.ClusterName
+	}
+	return &hubSyncer{
+		localClient:    localClient,
+		hubClient:      hubClient,
+		localNamespace: localNamespace,
+		hubNamespace:   hubNamespace,
+		clusterName:    config.ClusterName,
+
+		listNodeFeatures: listNodeFeatures,
+		queue:            workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		stopChan:         make(chan struct{}),
+	}
+}
+
+// run syncs with the hub periodically until stopped. Failures are tolerated:
+// the mirrored features and the hub output last received stay in place until
+// the hub becomes reachable again.
+func (h *hubSyncer) run(period time.Duration) {
+	klog.InfoS("starting hub syncer", "clusterName", h.clusterName, "hubNamespace", h.hubNamespace, "syncPeriod", period)
+	for i := 0; i < hubMirrorWorkers; i++ {
+		go func() {
+			for h.processMirrorRequest() {
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		if err := h.sync(); err != nil {
+			klog.ErrorS(err, "failed to sync with hub cluster, retaining previous state")
+			hubSyncFailures.Inc()
+		}
+		select {
+		case <-ticker.C:
+		case <-h.stopChan:
+			klog.InfoS("hub syncer stopped")
+			return
+		}
+	}
+}
+
+func (h *hubSyncer) stop() {
+	h.queue.ShutDown()
+	close(h.stopChan)
+}
+
+// queueNode queues the features of a node for mirroring into the hub cluster.
+func (h *hubSyncer) queueNode(nodeName string) {
+	h.queue.Add(nodeName)
+}
+
+// processMirrorRequest mirrors the features of one queued node. Failed nodes
+// are retried with backoff. Returns false when the queue has been shut down.
+func (h *hubSyncer) processMirrorRequest() bool {
+	item, quit := h.queue.Get()
+	if quit {
+		return false
+	}
+	defer h.queue.Done(item)
+
+	nodeName := item.(string)
+	objs, err := h.listNodeFeatures(nodeName)
+	if err == nil {
+		err = h.mirrorNode(nodeName, objs)
+	}
+	if err != nil {
+		if h.queue.NumRequeues(item) < hubMirrorMaxRetries {
+			klog.V(1).InfoS("retrying mirroring of node features", "nodeName", nodeName, "error", err)
+			h.queue.AddRateLimited(item)
+			return true
+		}
+		klog.ErrorS(err, "failed to mirror node features to hub cluster", "nodeName", nodeName)
+		hubSyncFailures.Inc()
+	}
+	h.queue.Forget(item)
+	return true
+}
+
+// sync mirrors the features of all nodes into the hub cluster, removes stale
+// mirrors and fetches the hub output.
+func (h *hubSyncer) sync() error {
+	list, err := h.localClient.NfdV1alpha1().NodeFeatures("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list local NodeFeature objects: %w", err)
+	}
+
+	nodeObjs := make(map[string][]*nfdv1alpha1.NodeFeature)
+	for i := range list.Items {
+		o := &list.Items[i]
+		if nodeName, err := getNodeNameForObj(o); err == nil {
+			nodeObjs[nodeName] = append(nodeObjs[nodeName], o)
+		}
+	}
+
+	var errs []error
+	for nodeName, objs := range nodeObjs {
+		if err := h.mirrorNode(nodeName, objs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Remove mirrors of nodes that do not have any features anymore
+	sel := fmt.Sprintf("%s=%s,!%s", nfdv1alpha1.NodeFeatureObjClusterNameLabel, h.clusterName, nfdv1alpha1.NodeFeatureObjHubOutputLabel)
+	mirrors, err := h.hubClient.NfdV1alpha1().NodeFeatures(h.hubNamespace).List(context.TODO(), metav1.ListOptions{LabelSelector: sel})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list mirrored NodeFeature objects: %w", err))
+	} else {
+		for _, o := range mirrors.Items {
+			if _, ok := nodeObjs[o.Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel]]; !ok {
+				if err := deleteNodeFeature(h.hubClient, o.Namespace, o.Name); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	if err := h.pullOutputs(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// mirrorNode updates the mirrored features of one node in the hub cluster.
+// The mirror is deleted if there are no (non-hub-output) NodeFeature objects
+// for the node.
+func (h *hubSyncer) mirrorNode(nodeName string, objs []*nfdv1alpha1.NodeFeature) error {
+	var features []*nfdv1alpha1.NodeFeature
+	for _, o := range objs {
+		if !isHubOutput(o) {
+			features = append(features, o)
+		}
+	}
+	if len(features) == 0 {
+		return deleteNodeFeature(h.hubClient, h.hubNamespace, nodeName)
+	}
+
+	sortNodeFeatures(features, h.localNamespace)
+	spec := features[0].Spec.DeepCopy()
+	for _, o := range features[1:] {
+		o.Spec.DeepCopy().MergeInto(spec)
+	}
+
+	mirror := &nfdv1alpha1.NodeFeature{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nodeName,
+			Namespace: h.hubNamespace,
+			Labels: map[string]string{
+				nfdv1alpha1.NodeFeatureObjNodeNameLabel:    nodeName,
+				nfdv1alpha1.NodeFeatureObjClusterNameLabel: h.clusterName,
+			},
+		},
+		Spec: *spec,
+	}
+	if err := applyNodeFeature(h.hubClient, mirror); err != nil {
+		return fmt.Errorf("failed to mirror features of node %q: %w", nodeName, err)
+	}
+	return nil
+}
+
+// pullOutputs copies the hub output objects of this cluster into the local
+// nfd namespace where they are processed like any other NodeFeature object.
+// Local copies are kept intact if the hub cannot be reached.
+func (h *hubSyncer) pullOutputs() error {
+	sel := k8sLabels.SelectorFromSet(k8sLabels.Set{nfdv1alpha1.NodeFeatureObjHubOutputLabel: "true"})
+	outputs, err := h.hubClient.NfdV1alpha1().NodeFeatures(h.hubNamespace).List(context.TODO(), metav1.ListOptions{LabelSelector: sel.String()})
+	if err != nil {
+		return fmt.Errorf("failed to list hub output: %w", err)
+	}
+
+	var errs []error
+	names := make(map[string]struct{}, len(outputs.Items))
+	for _, o := range outputs.Items {
+		nodeName, err := getNodeNameForObj(&o)
+		if err != nil {
+			klog.ErrorS(err, "ignoring hub output", "nodefeature", klog.KObj(&o))
+			continue
+		}
+		names[o.Name] = struct{}{}
+		local := newHubOutput(o.Name, h.localNamespace, nodeName, "", o.Spec.Labels)
+		if err := applyNodeFeature(h.localClient, local); err != nil {
+			errs = append(errs, fmt.Errorf("failed to store hub output of node %q: %w", nodeName, err))
+		}
+	}
+
+	locals, err := h.localClient.NfdV1alpha1().NodeFeatures(h.localNamespace).List(context.TODO(), metav1.ListOptions{LabelSelector: sel.String()})
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("failed to list local copies of hub output: %w", err))...)
+	}
+	for _, o := range locals.Items {
+		if _, ok := names[o.Name]; !ok {
+			if err := deleteNodeFeature(h.localClient, o.Namespace, o.Name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hubQueueMirrors queues all NodeFeature objects mirrored from edge clusters
+// for processing. The objects are identified by their namespace/name key.
+func (m *nfdMaster) hubQueueMirrors() error {
+	klog.InfoS("will process all mirrored node features in the hub cluster")
+
+	sel, err := k8sLabels.Parse(fmt.Sprintf("%s,!%s", nfdv1alpha1.NodeFeatureObjClusterNameLabel, nfdv1alpha1.NodeFeatureObjHubOutputLabel))
+	if err != nil {
+		return err
+	}
+	objs, err := m.nfdController.featureLister.List(sel)
+	if err != nil {
+		return err
+	}
+	for _, o := range objs {
+		key, err := cache.MetaNamespaceKeyFunc(o)
+		if err != nil {
+			return err
+		}
+		m.nodeUpdaterPool.queue.Add(key)
+	}
+	return nil
+}
+
+// hubUpdateOne processes one mirrored NodeFeature object, identified by its
+// namespace/name key, and writes the resulting labels into a hub output
+// object next to it.
+func (m *nfdMaster) hubUpdateOne(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	cli, err := m.getNfdClient()
+	if err != nil {
+		return err
+	}
+	outputName := name + hubOutputNameSuffix
+
+	obj, err := m.nfdController.featureLister.NodeFeatures(namespace).Get(name)
+	if k8serrors.IsNotFound(err) {
+		klog.V(1).InfoS("mirrored node features removed, deleting hub output", "nodefeature", key)
+		return deleteNodeFeature(cli, namespace, outputName)
+	} else if err != nil {
+		return err
+	}
+
+	nodeName, err := getNodeNameForObj(obj)
+	if err != nil {
+		return err
+	}
+
+	klog.V(1).InfoS("processing mirrored node features", "nodefeature", key)
+
+	features := obj.Spec.DeepCopy()
+	features.Labels = m.setWorkerLabels(&features.Features, features.Labels)
+	crLabels, _, _, _ := m.processNodeFeatureRule(key, &features.Features, false)
+	labels, _ := m.filterFeatureLabels(crLabels, &features.Features)
+
+	if m.config.NoPublish {
+		return nil
+	}
+	clusterName := obj.Labels[nfdv1alpha1.NodeFeatureObjClusterNameLabel]
+	return applyNodeFeature(cli, newHubOutput(outputName, namespace, nodeName, clusterName, labels))
+}
+
+func (m *nfdMaster) getNfdClient() (nfdclientset.Interface, error) {
+	m.nfdClientLock.Lock()
+	defer m.nfdClientLock.Unlock()
+
+	if m.nfdClient == nil {
+		kubeconfig, err := m.getKubeconfig()
+		if err != nil {
+			return nil, err
+		}
+		m.nfdClient, err = nfdclientset.NewForConfig(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m.nfdClient, nil
+}
+
+func (m *nfdMaster) startHubSyncer() error {
+	hubKubeconfig, err := apihelper.GetKubeconfig(m.config.Hub.Kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to read hub kubeconfig: %w", err)
+	}
+	hubKubeconfig.Timeout = hubRequestTimeout
+	hubClient, err := nfdclientset.NewForConfig(hubKubeconfig)
+	if err != nil {
+		return err
+	}
+	localClient, err := m.getNfdClient()
+	if err != nil {
+		return err
+	}
+
+	m.hubSyncer = newHubSyncer(localClient, hubClient, m.namespace, m.config.Hub, m.listNodeFeatures)
+	go m.hubSyncer.run(m.config.Hub.SyncPeriod.Duration)
+	return nil
+}
+
+func (m *nfdMaster) stopHubSyncer() {
+	if m.hubSyncer != nil {
+		m.hubSyncer.stop()
+		m.hubSyncer = nil
+	}
+}
+
+// newHubOutput returns a NodeFeature object carrying hub output for a node.
+// The cluster name label is only set on the objects in the hub cluster.
+func newHubOutput(name, namespace, nodeName, clusterName string, labels map[string]string) *nfdv1alpha1.NodeFeature {
+	obj := &nfdv1alpha1.NodeFeature{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				nfdv1alpha1.NodeFeatureObjNodeNameLabel:  nodeName,
+				nfdv1alpha1.NodeFeatureObjHubOutputLabel: "true",
+			},
+		},
+		Spec: nfdv1alpha1.NodeFeatureSpec{Labels: labels},
+	}
+	if clusterName != "" {
+		obj.Labels[nfdv1alpha1.NodeFeatureObjClusterNameLabel] = clusterName
+	}
+	return obj
+}
+
+// isHubOutput returns true if the object carries hub output.
+func isHubOutput(obj metav1.Object) bool {
+	_, ok := obj.GetLabels()[nfdv1alpha1.NodeFeatureObjHubOutputLabel]
+	return ok
+}
+
+// isFromEdgeCluster returns true if the object is a mirror of the features
+// of a node in an edge cluster, or the hub output written for it. The node
+// name label of these objects refers to a node in the edge cluster.
+func isFromEdgeCluster(obj metav1.Object) bool {
+	_, ok := obj.GetLabels()[nfdv1alpha1.NodeFeatureObjClusterNameLabel]
+	return ok
+}
+
+// sortNodeFeatures sorts NodeFeature objects in the order they are merged:
+// objects in the nfd namespace first, the rest sorted by name and namespace.
+func sortNodeFeatures(objs []*nfdv1alpha1.NodeFeature, namespace string) {
+	sort.Slice(objs, func(i, j int) bool {
+		// Objects in our nfd namespace gets into the beginning of the list
+		if objs[i].Namespace == namespace && objs[j].Namespace != namespace {
+			return true
+		}
+		if objs[i].Namespace != namespace && objs[j].Namespace == namespace {
+			return false
+		}
+		// After the nfd namespace, sort objects by their name
+		if objs[i].Name != objs[j].Name {
+			return objs[i].Name < objs[j].Name
+		}
+		// Objects with the same name are sorted by their namespace
+		return objs[i].Namespace < objs[j].Namespace
+	})
+}
+
+// applyNodeFeature creates a NodeFeature object or updates the labels and
+// spec of an existing one.
+func applyNodeFeature(cli nfdclientset.Interface, obj *nfdv1alpha1.NodeFeature) error {
+	nfClient := cli.NfdV1alpha1().NodeFeatures(obj.Namespace)
+	old, err := nfClient.Get(context.TODO(), obj.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		klog.V(2).InfoS("creating NodeFeature object", "nodefeature", klog.KObj(obj))
+		_, err = nfClient.Create(context.TODO(), obj, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if apiequality.Semantic.DeepEqual(old.Labels, obj.Labels) && apiequality.Semantic.DeepEqual(old.Spec, obj.Spec) {
+		klog.V(4).InfoS("no changes in NodeFeature object", "nodefeature", klog.KObj(obj))
+		return nil
+	}
+	updated := old.DeepCopy()
+	updated.Labels = obj.Labels
+	updated.Spec = obj.Spec
+	klog.V(2).InfoS("updating NodeFeature object", "nodefeature", klog.KObj(obj))
+	_, err = nfClient.Update(context.TODO(), updated, metav1.UpdateOptions{})
+	return err
+}
+
+// deleteNodeFeature deletes a NodeFeature object, ignoring missing objects.
+func deleteNodeFeature(cli nfdclientset.Interface, namespace, name string) error {
+	err := cli.NfdV1alpha1().NodeFeatures(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete NodeFeature %s/%s: %w", namespace, name, err)
+	}
+	if err == nil {
+		klog.V(2).InfoS("deleted NodeFeature object", "nodefeature", klog.KRef(namespace, name))
+	}
+	return nil
+}
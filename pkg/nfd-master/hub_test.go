@@ -0,0 +1,201 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/workqueue"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func newTestNodeFeature(namespace, name, nodeName string, labels map[string]string, flags ...string) *nfdv1alpha1.NodeFeature {
+	nf := &nfdv1alpha1.NodeFeature{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName},
+		},
+		Spec: *nfdv1alpha1.NewNodeFeatureSpec(),
+	}
+	nf.Spec.Labels = labels
+	if len(flags) > 0 {
+		nf.Spec.Features.Flags["kernel.loadedmodule"] = nfdv1alpha1.NewFlagFeatures(flags...)
+	}
+	return nf
+}
+
+func TestHubMode(t *testing.T) {
+	Convey("When running an edge cluster connected to a hub cluster", t, func() {
+		ctx := context.TODO()
+		edgeClient := fake.NewSimpleClientset(
+			newTestNodeFeature("nfd", "node-1", "node-1", map[string]string{"worker-label": "true"}, "kvm"),
+			newTestNodeFeature("nfd", "node-2", "node-2", nil, "vfio"),
+		)
+		rule := &nfdv1alpha1.NodeFeatureRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "hub-rule"},
+			Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+				Rules: []nfdv1alpha1.Rule{
+					{
+						Name:   "kvm",
+						Labels: map[string]string{"kvm": "true"},
+						MatchFeatures: nfdv1alpha1.FeatureMatcher{
+							{
+								Feature: "kernel.loadedmodule",
+								MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+									"kvm": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchExists},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		hubClient := fake.NewSimpleClientset(rule)
+
+		listNodeFeatures := func(nodeName string) ([]*nfdv1alpha1.NodeFeature, error) {
+			obj, err := edgeClient.NfdV1alpha1().NodeFeatures("nfd").Get(ctx, nodeName, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return []*nfdv1alpha1.NodeFeature{obj}, nil
+		}
+		syncer := newHubSyncer(edgeClient, hubClient, "nfd", HubConfig{ClusterName: "edge-1"}, listNodeFeatures)
+		defer syncer.queue.ShutDown()
+
+		Convey("Features of updated nodes should be mirrored from the queue", func() {
+			syncer.queueNode("node-1")
+			So(syncer.processMirrorRequest(), ShouldBeTrue)
+
+			mirror, err := hubClient.NfdV1alpha1().NodeFeatures("edge-1").Get(ctx, "node-1", metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			So(mirror.Spec.Labels, ShouldResemble, map[string]string{"worker-label": "true"})
+
+			Convey("And failures should be retried with backoff", func() {
+				hubClient.PrependReactor("*", "nodefeatures", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					return true, nil, fmt.Errorf("connection refused")
+				})
+				syncer.queueNode("node-2")
+				So(syncer.processMirrorRequest(), ShouldBeTrue)
+				So(syncer.queue.NumRequeues("node-2"), ShouldEqual, 1)
+			})
+		})
+
+		Convey("Node features should be mirrored into the cluster namespace of the hub", func() {
+			So(syncer.sync(), ShouldBeNil)
+
+			mirrors, err := hubClient.NfdV1alpha1().NodeFeatures("edge-1").List(ctx, metav1.ListOptions{})
+			So(err, ShouldBeNil)
+			So(mirrors.Items, ShouldHaveLength, 2)
+
+			mirror, err := hubClient.NfdV1alpha1().NodeFeatures("edge-1").Get(ctx, "node-1", metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			So(mirror.Labels[nfdv1alpha1.NodeFeatureObjClusterNameLabel], ShouldEqual, "edge-1")
+			So(mirror.Spec.Labels, ShouldResemble, map[string]string{"worker-label": "true"})
+			So(mirror.Spec.Features.Flags["kernel.loadedmodule"].Elements, ShouldContainKey, "kvm")
+
+			Convey("And labels produced by the hub should be applied in the edge cluster", func() {
+				hubMaster := newMockMaster(nil)
+				hubMaster.config.AutoDefaultNs = true
+				hubMaster.config.Hub.Mode = hubModeHub
+				hubMaster.nfdClient = hubClient
+				// Features of a node of the hub cluster itself, with the
+				// same name as an edge node
+				_, err := hubClient.NfdV1alpha1().NodeFeatures("nfd").Create(ctx, newTestNodeFeature("nfd", "node-1", "node-1", nil, "hub-module"), metav1.CreateOptions{})
+				So(err, ShouldBeNil)
+				hubMaster.nfdController = newMockNfdAPIController(hubClient)
+				defer hubMaster.nfdController.stop()
+				So(func() error {
+					// Wait for the informer cache to catch up
+					for i := 0; i < 50; i++ {
+						if objs, _ := hubMaster.nfdController.featureLister.List(k8sLabels.Everything()); len(objs) == 3 {
+							return nil
+						}
+						time.Sleep(100 * time.Millisecond)
+					}
+					return fmt.Errorf("timeout waiting for informer cache")
+				}(), ShouldBeNil)
+
+				// Mirrors are not merged into the features of local nodes
+				objs, err := hubMaster.listNodeFeatures("node-1")
+				So(err, ShouldBeNil)
+				So(objs, ShouldHaveLength, 1)
+				So(objs[0].Namespace, ShouldEqual, "nfd")
+
+				hubMaster.nodeUpdaterPool = newNodeUpdaterPool(hubMaster)
+				hubMaster.nodeUpdaterPool.queue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+				So(hubMaster.hubQueueMirrors(), ShouldBeNil)
+				So(hubMaster.nodeUpdaterPool.queue.Len(), ShouldEqual, 2)
+
+				So(hubMaster.hubUpdateOne("edge-1/node-1"), ShouldBeNil)
+				So(hubMaster.hubUpdateOne("edge-1/node-2"), ShouldBeNil)
+
+				output, err := hubClient.NfdV1alpha1().NodeFeatures("edge-1").Get(ctx, "node-1"+hubOutputNameSuffix, metav1.GetOptions{})
+				So(err, ShouldBeNil)
+				So(output.Spec.Labels, ShouldResemble, map[string]string{nfdv1alpha1.FeatureLabelNs + "/kvm": "true"})
+
+				So(syncer.sync(), ShouldBeNil)
+				local, err := edgeClient.NfdV1alpha1().NodeFeatures("nfd").Get(ctx, "node-1"+hubOutputNameSuffix, metav1.GetOptions{})
+				So(err, ShouldBeNil)
+				So(local.Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel], ShouldEqual, "node-1")
+				So(local.Labels, ShouldContainKey, nfdv1alpha1.NodeFeatureObjHubOutputLabel)
+				So(local.Spec.Labels, ShouldResemble, map[string]string{nfdv1alpha1.FeatureLabelNs + "/kvm": "true"})
+
+				// Local copies of hub output must not be mirrored back to the hub
+				mirror, err := hubClient.NfdV1alpha1().NodeFeatures("edge-1").Get(ctx, "node-1", metav1.GetOptions{})
+				So(err, ShouldBeNil)
+				So(mirror.Spec.Labels, ShouldResemble, map[string]string{"worker-label": "true"})
+
+				Convey("And the hub output should be retained while the hub is unreachable", func() {
+					hubClient.PrependReactor("*", "nodefeatures", func(action clienttesting.Action) (bool, runtime.Object, error) {
+						return true, nil, fmt.Errorf("connection refused")
+					})
+					So(syncer.sync(), ShouldNotBeNil)
+
+					_, err := edgeClient.NfdV1alpha1().NodeFeatures("nfd").Get(ctx, "node-1"+hubOutputNameSuffix, metav1.GetOptions{})
+					So(err, ShouldBeNil)
+				})
+
+				Convey("And the hub output should be removed when the hub removes it", func() {
+					So(hubClient.NfdV1alpha1().NodeFeatures("edge-1").Delete(ctx, "node-1"+hubOutputNameSuffix, metav1.DeleteOptions{}), ShouldBeNil)
+					So(syncer.sync(), ShouldBeNil)
+
+					_, err := edgeClient.NfdV1alpha1().NodeFeatures("nfd").Get(ctx, "node-1"+hubOutputNameSuffix, metav1.GetOptions{})
+					So(err, ShouldNotBeNil)
+				})
+			})
+
+			Convey("And mirrors of removed nodes should be deleted", func() {
+				So(edgeClient.NfdV1alpha1().NodeFeatures("nfd").Delete(ctx, "node-2", metav1.DeleteOptions{}), ShouldBeNil)
+				So(syncer.sync(), ShouldBeNil)
+
+				_, err := hubClient.NfdV1alpha1().NodeFeatures("edge-1").Get(ctx, "node-2", metav1.GetOptions{})
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/health/grpc_health_v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	taintutils "k8s.io/kubernetes/pkg/util/taints"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+const (
+	// readinessHealthService is the gRPC health service used for the
+	// readiness probe of nfd-master. It is not serving while node updates
+	// are paused.
+	readinessHealthService = "readiness"
+
+	// cacheSyncTimeout is the maximum time to wait for the informer caches
+	// to sync before updating all nodes.
+	cacheSyncTimeout = 30 * time.Second
+
+	// maxReportedNodes limits the number of node names in log messages and
+	// events.
+	maxReportedNodes = 5
+)
+
+var errMassUpdatePaused = errors.New("node updates paused by mass update protection")
+
+// massUpdatePlan describes the NFD-managed node properties that an update of
+// all nodes would remove.
+type massUpdatePlan struct {
+	totalNodes int
+	// removals contains the labels, extended resources and taints to be
+	// removed, per node name
+	removals map[string][]string
+}
+
+// affectedFraction returns the fraction of nodes that would lose some
+// NFD-managed properties.
+func (p *massUpdatePlan) affectedFraction() float64 {
+	if p.totalNodes == 0 {
+		return 0
+	}
+	return float64(len(p.removals)) / float64(p.totalNodes)
+}
+
+// affectedNodes returns the sorted names of the affected nodes.
+func (p *massUpdatePlan) affectedNodes() []string {
+	names := make([]string, 0, len(p.removals))
+	for name := range p.removals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fingerprint identifies the set of removals, used by the operator to confirm
+// a paused update.
+func (p *massUpdatePlan) fingerprint() string {
+	h := sha256.New()
+	for _, name := range p.affectedNodes() {
+		items := append([]string{}, p.removals[name]...)
+		sort.Strings(items)
+		fmt.Fprintf(h, "%s:%s\n", name, strings.Join(items, ","))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// massUpdateGuard holds the state of the mass update protection.
+type massUpdateGuard struct {
+	sync.Mutex
+	paused      bool
+	fingerprint string
+	// heldBack contains the nodes affected by the paused update
+	heldBack map[string]struct{}
+}
+
+func (g *massUpdateGuard) isPaused() bool {
+	g.Lock()
+	defer g.Unlock()
+	return g.paused
+}
+
+// isHeldBack returns true if updates of the node are paused, i.e. the node
+// would lose NFD-managed properties in the paused update. Other nodes, e.g.
+// nodes that joined the cluster after the update was paused, are updated
+// normally.
+func (g *massUpdateGuard) isHeldBack(nodeName string) bool {
+	g.Lock()
+	defer g.Unlock()
+	_, ok := g.heldBack[nodeName]
+	return g.paused && ok
+}
+
+// checkMassUpdate verifies that an update of all nodes would not remove
+// NFD-managed labels, extended resources or taints from too many nodes at
+// once. If it would, node updates are paused until the condition clears or
+// the operator confirms the update.
+func (m *nfdMaster) checkMassUpdate(nodes []corev1.Node) error {
+	c := m.config.MassUpdateProtection
+	if c.Threshold <= 0 || len(nodes) < c.MinNodes || m.config.NoPublish {
+		m.resumeNodeUpdates("mass update protection not in effect")
+		return nil
+	}
+
+	plan, err := m.planNodeUpdates(nodes)
+	if err != nil {
+		return err
+	}
+	massUpdateAffectedNodes.Set(float64(len(plan.removals)))
+
+	if plan.affectedFraction() <= c.Threshold {
+		m.resumeNodeUpdates("the number of affected nodes is below the threshold")
+		return nil
+	}
+
+	fingerprint := plan.fingerprint()
+	if c.Confirm == fingerprint {
+		klog.InfoS("mass node update confirmed", "fingerprint", fingerprint, "affectedNodes", len(plan.removals), "totalNodes", plan.totalNodes)
+		m.resumeNodeUpdates("the update was confirmed by the operator")
+		return nil
+	}
+
+	m.pauseNodeUpdates(plan, fingerprint)
+	return errMassUpdatePaused
+}
+
+// planNodeUpdates determines the NFD-managed labels, extended resources and
+// taints that an update of all nodes would remove.
+func (m *nfdMaster) planNodeUpdates(nodes []corev1.Node) (*massUpdatePlan, error) {
+	plan := &massUpdatePlan{totalNodes: len(nodes), removals: make(map[string][]string)}
+
+	for i := range nodes {
+		node := &nodes[i]
+		objs, err := m.listNodeFeatures(node.Name)
+		if err != nil {
+			return nil, err
+		}
+		features := m.mergeNodeFeatures(objs)
+
+		labels, _, extendedResources, taints := m.computeNodeFeatures(node.Name, features.Labels, &features.Features, true)
+		if removals := m.nodeRemovals(node, labels, extendedResources, taints); len(removals) > 0 {
+			plan.removals[node.Name] = removals
+		}
+	}
+	return plan, nil
+}
+
+// nodeRemovals returns the NFD-managed labels, extended resources and taints
+// of a node that are missing from the new set.
+func (m *nfdMaster) nodeRemovals(node *corev1.Node, labels Labels, extendedResources ExtendedResources, taints []corev1.Taint) []string {
+	var removals []string
+
+	for _, name := range nfdv1alpha1.ParseNsNames(node.Annotations[m.instanceAnnotation(nfdv1alpha1.FeatureLabelsAnnotation)], nfdv1alpha1.FeatureLabelNs) {
+		if _, ok := labels[name]; !ok {
+			removals = append(removals, "label/"+name)
+		}
+	}
+	for _, name := range nfdv1alpha1.ParseNsNames(node.Annotations[m.instanceAnnotation(nfdv1alpha1.ExtendedResourceAnnotation)], nfdv1alpha1.FeatureLabelNs) {
+		if _, ok := extendedResources[name]; !ok {
+			removals = append(removals, "extendedresource/"+name)
+		}
+	}
+	if val := node.Annotations[nfdv1alpha1.NodeTaintsAnnotation]; val != "" {
+		oldTaints, _, err := taintutils.ParseTaints(strings.Split(val, ","))
+		if err != nil {
+			klog.ErrorS(err, "failed to parse taints annotation", "nodeName", node.Name)
+		}
+		for _, taint := range oldTaints {
+			if !taintutils.TaintExists(taints, &taint) {
+				removals = append(removals, "taint/"+taint.ToString())
+			}
+		}
+	}
+	return removals
+}
+
+// pauseNodeUpdates stops node updates and reports the paused state.
+func (m *nfdMaster) pauseNodeUpdates(plan *massUpdatePlan, fingerprint string) {
+	m.massUpdateGuard.Lock()
+	defer m.massUpdateGuard.Unlock()
+
+	massUpdatePaused.Set(1)
+	if m.healthServer != nil {
+		m.healthServer.SetServingStatus(readinessHealthService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	}
+	m.massUpdateGuard.heldBack = make(map[string]struct{}, len(plan.removals))
+	for name := range plan.removals {
+		m.massUpdateGuard.heldBack[name] = struct{}{}
+	}
+
+	// Only report changes in the state
+	if m.massUpdateGuard.paused && m.massUpdateGuard.fingerprint == fingerprint {
+		klog.V(1).InfoS("node updates still paused", "fingerprint", fingerprint)
+		return
+	}
+	m.massUpdateGuard.paused = true
+	m.massUpdateGuard.fingerprint = fingerprint
+
+	nodeNames := plan.affectedNodes()
+	if len(nodeNames) > maxReportedNodes {
+		nodeNames = append(nodeNames[:maxReportedNodes], "...")
+	}
+	msg := fmt.Sprintf("node updates paused: NFD-managed labels, extended resources or taints would be removed from %d of %d nodes (%s), exceeding the threshold of %.0f%%; "+
+		"set massUpdateProtection.confirm to %q in the nfd-master configuration to proceed",
+		len(plan.removals), plan.totalNodes, strings.Join(nodeNames, ", "), m.config.MassUpdateProtection.Threshold*100, fingerprint)
+	klog.InfoS("node updates paused by mass update protection", "affectedNodes", len(plan.removals), "totalNodes", plan.totalNodes, "nodeNames", nodeNames, "fingerprint", fingerprint)
+	m.recordEvent(corev1.EventTypeWarning, "MassUpdatePaused", msg)
+}
+
+// resumeNodeUpdates resumes paused node updates.
+func (m *nfdMaster) resumeNodeUpdates(reason string) {
+	m.massUpdateGuard.Lock()
+	defer m.massUpdateGuard.Unlock()
+
+	massUpdatePaused.Set(0)
+	if m.healthServer != nil {
+		m.healthServer.SetServingStatus(readinessHealthService, grpc_health_v1.HealthCheckResponse_SERVING)
+	}
+
+	if !m.massUpdateGuard.paused {
+		return
+	}
+	m.massUpdateGuard.paused = false
+	m.massUpdateGuard.fingerprint = ""
+	m.massUpdateGuard.heldBack = nil
+
+	msg := "node updates resumed: " + reason
+	klog.InfoS("node updates resumed", "reason", reason)
+	m.recordEvent(corev1.EventTypeNormal, "MassUpdateResumed", msg)
+}
+
+// recordEvent records an Event for the nfd-master pod. Nothing is recorded if
+// the pod name is not known or publishing is disabled.
+func (m *nfdMaster) recordEvent(eventType, reason, message string) {
+	if m.eventRecorder == nil {
+		return
+	}
+	if m.podName == "" {
+		klog.V(2).InfoS("not recording event, POD_NAME not specified", "reason", reason)
+		return
+	}
+	ref := &corev1.ObjectReference{APIVersion: "v1", Kind: "Pod", Namespace: m.namespace, Name: m.podName}
+	m.eventRecorder.Event(ref, eventType, reason, message)
+}
@@ -23,15 +23,25 @@ import (
 
 // When adding metric names, see https://prometheus.io/docs/practices/naming/#metric-names
 const (
-	buildInfoQuery           = "nfd_master_build_info"
-	nodeUpdateRequestsQuery  = "nfd_node_update_requests_total"
-	nodeUpdatesQuery         = "nfd_node_updates_total"
-	nodeUpdateFailuresQuery  = "nfd_node_update_failures_total"
-	nodeLabelsRejectedQuery  = "nfd_node_labels_rejected_total"
-	nodeERsRejectedQuery     = "nfd_node_extendedresources_rejected_total"
-	nodeTaintsRejectedQuery  = "nfd_node_taints_rejected_total"
-	nfrProcessingTimeQuery   = "nfd_nodefeaturerule_processing_duration_seconds"
-	nfrProcessingErrorsQuery = "nfd_nodefeaturerule_processing_errors_total"
+	buildInfoQuery            = "nfd_master_build_info"
+	nodeUpdateRequestsQuery   = "nfd_node_update_requests_total"
+	nodeUpdatesQuery          = "nfd_node_updates_total"
+	nodeUpdateFailuresQuery   = "nfd_node_update_failures_total"
+	nodeLabelsRejectedQuery   = "nfd_node_labels_rejected_total"
+	nodeERsRejectedQuery      = "nfd_node_extendedresources_rejected_total"
+	nodeTaintsRejectedQuery   = "nfd_node_taints_rejected_total"
+	nfrProcessingTimeQuery    = "nfd_nodefeaturerule_processing_duration_seconds"
+	nfrProcessingErrorsQuery  = "nfd_nodefeaturerule_processing_errors_total"
+	nfrScheduleActiveQuery    = "nfd_nodefeaturerule_schedule_active"
+	nfrOutputsRetainedQuery   = "nfd_nodefeaturerule_outputs_retained_total"
+	hubSyncFailuresQuery      = "nfd_hub_sync_failures_total"
+	massUpdatePausedQuery     = "nfd_mass_update_paused"
+	massUpdateAffectedQuery   = "nfd_mass_update_affected_nodes"
+	nnfrOutputsRejectedQuery  = "nfd_namespacednodefeaturerule_outputs_rejected_total"
+	featureCacheRebuildsQuery = "nfd_nodefeature_cache_rebuilds_total"
+	baselineDeviationsQuery   = "nfd_node_feature_baseline_deviations"
+	baselineDetectedQuery     = "nfd_node_feature_baseline_deviations_detected_total"
+	grpcRequestsRejectedQuery = "nfd_grpc_requests_rejected_total"
 )
 
 var (
@@ -81,8 +91,71 @@ var (
 		Name: nfrProcessingErrorsQuery,
 		Help: "Number of errors encountered while processing NodeFeatureRule objects.",
 	})
+	nfrScheduleActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: nfrScheduleActiveQuery,
+		Help: "Whether the schedule of a NodeFeatureRule rule is currently active (1) or not (0).",
+	},
+		[]string{
+			"name",
+			"rule",
+		},
+	)
+	nfrOutputsRetained = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: nfrOutputsRetainedQuery,
+		Help: "Number of times the last successful output of a failed NodeFeatureRule rule was retained.",
+	})
+	hubSyncFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: hubSyncFailuresQuery,
+		Help: "Number of failed attempts to sync NodeFeatures with the hub cluster.",
+	})
+	massUpdatePaused = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: massUpdatePausedQuery,
+		Help: "Whether node updates are paused by the mass update protection (1) or not (0).",
+	})
+	massUpdateAffectedNodes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: massUpdateAffectedQuery,
+		Help: "Number of nodes that would lose NFD-managed labels, extended resources or taints in the last evaluated update of all nodes.",
+	})
+	nnfrOutputsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: nnfrOutputsRejectedQuery,
+		Help: "Number of NamespacedNodeFeatureRule outputs rejected by the namespaced rules policy.",
+	},
+		[]string{
+			"namespace",
+		},
+	)
+	featureCacheRebuilds = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: featureCacheRebuildsQuery,
+		Help: "Number of times the NodeFeature cache was rebuilt because rules started referencing new features.",
+	})
+	baselineDeviations = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: baselineDeviationsQuery,
+		Help: "Number of feature elements of a node that deviate from the feature baseline of the node.",
+	},
+		[]string{
+			"node",
+		},
+	)
+	baselineDeviationsDetected = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: baselineDetectedQuery,
+		Help: "Number of times new deviations from the feature baseline of a node were detected.",
+	})
+	grpcRequestsRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: grpcRequestsRejectedQuery,
+			Help: "Number of gRPC requests rejected by the rate, concurrency or deadline limits.",
+		},
+		[]string{"reason"},
+	)
 )
 
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 // registerVersion exposes the Operator build version.
 func registerVersion(version string) {
 	buildInfo.SetToCurrentTime()
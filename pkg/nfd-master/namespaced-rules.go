@@ -0,0 +1,224 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+	taintutils "k8s.io/kubernetes/pkg/util/taints"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+const (
+	// namespacePlaceholder is replaced by the Kubernetes namespace of a
+	// NamespacedNodeFeatureRule in the allowed label namespaces.
+	namespacePlaceholder = "{namespace}"
+
+	// defaultNamespacedRulesKey is the key of the allowed label namespaces
+	// applied to Kubernetes namespaces not listed explicitly.
+	defaultNamespacedRulesKey = "*"
+)
+
+// validate checks the namespaced rules policy.
+func (c *NamespacedRulesConfig) validate() error {
+	for namespace, labelNamespaces := range c.LabelNamespaces {
+		if namespace != defaultNamespacedRulesKey {
+			if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+				return fmt.Errorf("invalid namespace %q in namespacedRules.labelNamespaces: %s", namespace, strings.Join(errs, "; "))
+			}
+		}
+		for _, ns := range labelNamespaces {
+			expanded := strings.ReplaceAll(ns, namespacePlaceholder, "x")
+			if errs := validation.IsDNS1123Subdomain(expanded); len(errs) > 0 {
+				return fmt.Errorf("invalid label namespace %q in namespacedRules.labelNamespaces: %s", ns, strings.Join(errs, "; "))
+			}
+			if isReservedNs(expanded) {
+				return fmt.Errorf("label namespace %q in namespacedRules.labelNamespaces is reserved", ns)
+			}
+		}
+	}
+	return nil
+}
+
+// allowedLabelNamespaces returns the label and annotation namespaces that
+// NamespacedNodeFeatureRule objects in a Kubernetes namespace may create.
+func (c *NamespacedRulesConfig) allowedLabelNamespaces(namespace string) []string {
+	patterns, ok := c.LabelNamespaces[namespace]
+	if !ok {
+		patterns = c.LabelNamespaces[defaultNamespacedRulesKey]
+	}
+	out := make([]string, len(patterns))
+	for i, p := range patterns {
+		out[i] = strings.ReplaceAll(p, namespacePlaceholder, namespace)
+	}
+	return out
+}
+
+// isReservedNs returns true if a namespace is reserved for Kubernetes and
+// thus never available to NamespacedNodeFeatureRule objects.
+func isReservedNs(ns string) bool {
+	for _, reserved := range []string{"kubernetes.io", "k8s.io"} {
+		if ns == reserved || strings.HasSuffix(ns, "."+reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNsAllowed returns true if a namespace matches one of the allowed
+// namespaces, or is a subdomain of one.
+func isNsAllowed(ns string, allowed []string) bool {
+	if ns == "" || isReservedNs(ns) {
+		return false
+	}
+	for _, a := range allowed {
+		if ns == a || strings.HasSuffix(ns, "."+a) {
+			return true
+		}
+	}
+	return false
+}
+
+// processNamespacedNodeFeatureRules processes the NamespacedNodeFeatureRule
+// objects for a node. The objects are processed after the cluster-wide
+// NodeFeatureRule objects, in the order of their namespace and name. Rules in
+// one Kubernetes namespace only see the feedback of earlier rules in the same
+// namespace and of the cluster-wide rules. Outputs not allowed by the
+// namespaced rules policy are dropped. If two namespaces create the same
+// output, the one processed first takes precedence. See executeRule for
+// dryRun.
+func (m *nfdMaster) processNamespacedNodeFeatureRules(nodeName string, features *nfdv1alpha1.Features, dryRun bool) (Labels, Annotations, ExtendedResources, []corev1.Taint) {
+	labels := Labels{}
+	annotations := Annotations{}
+	extendedResources := ExtendedResources{}
+	var taints []corev1.Taint
+
+	if m.nfdController == nil || m.nfdController.nsRuleLister == nil {
+		return labels, annotations, extendedResources, taints
+	}
+
+	objs, err := m.nfdController.nsRuleLister.List(k8sLabels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "failed to list NamespacedNodeFeatureRule resources")
+		return labels, annotations, extendedResources, taints
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		if objs[i].Namespace != objs[j].Namespace {
+			return objs[i].Namespace < objs[j].Namespace
+		}
+		return objs[i].Name < objs[j].Name
+	})
+
+	var nsFeatures *nfdv1alpha1.Features
+	for i, obj := range objs {
+		// Each namespace starts from the features after cluster-wide rules
+		if i == 0 || obj.Namespace != objs[i-1].Namespace {
+			nsFeatures = features.DeepCopy()
+		}
+
+		t := time.Now()
+		klog.V(1).InfoS("executing NamespacedNodeFeatureRule", "namespacednodefeaturerule", klog.KObj(obj), "nodeName", nodeName)
+		for _, rule := range obj.Spec.Rules {
+			ruleOut, ok := m.executeRule(nodeName, obj, &rule, nsFeatures, dryRun)
+			if !ok {
+				continue
+			}
+			m.filterNamespacedRuleOutput(obj.Namespace, &ruleOut)
+
+			addMissingKeys(labels, ruleOut.Labels)
+			addMissingKeys(annotations, ruleOut.Annotations)
+			addMissingKeys(extendedResources, ruleOut.ExtendedResources)
+			for _, taint := range ruleOut.Taints {
+				if !taintutils.TaintExists(taints, &taint) {
+					taints = append(taints, taint)
+				}
+			}
+
+			// Feed back rule output to features map for subsequent rules to match
+			nsFeatures.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, ruleOut.Labels)
+			nsFeatures.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, ruleOut.Vars)
+		}
+		if !dryRun {
+			nfrProcessingTime.WithLabelValues(ruleObjName(obj), nodeName).Observe(time.Since(t).Seconds())
+		}
+	}
+
+	return labels, annotations, extendedResources, taints
+}
+
+// filterNamespacedRuleOutput drops the outputs of a NamespacedNodeFeatureRule
+// rule that are not allowed by the namespaced rules policy.
+func (m *nfdMaster) filterNamespacedRuleOutput(namespace string, out *nodefeaturerule.RuleOutput) {
+	c := &m.config.NamespacedRules
+	allowed := c.allowedLabelNamespaces(namespace)
+
+	reject := func(kind, name, reason string) {
+		klog.InfoS("ignoring output of NamespacedNodeFeatureRule", "namespace", namespace, "kind", kind, "name", name, "reason", reason)
+		nnfrOutputsRejected.WithLabelValues(namespace).Inc()
+	}
+
+	filterMap := func(kind string, in map[string]string, enabled bool) map[string]string {
+		outMap := make(map[string]string, len(in))
+		for name, value := range in {
+			ns, _ := splitNs(name)
+			switch {
+			case !enabled:
+				reject(kind, name, "not allowed by policy")
+			case !isNsAllowed(ns, allowed):
+				reject(kind, name, fmt.Sprintf("namespace %q not allowed", ns))
+			default:
+				outMap[name] = value
+			}
+		}
+		return outMap
+	}
+	out.Labels = filterMap("label", out.Labels, true)
+	out.Annotations = filterMap("annotation", out.Annotations, true)
+	out.ExtendedResources = filterMap("extendedResource", out.ExtendedResources, c.AllowExtendedResources)
+
+	taints := make([]corev1.Taint, 0, len(out.Taints))
+	for _, taint := range out.Taints {
+		ns, _ := splitNs(taint.Key)
+		switch {
+		case !c.AllowTaints:
+			reject("taint", taint.Key, "not allowed by policy")
+		case !isNsAllowed(ns, allowed):
+			reject("taint", taint.Key, fmt.Sprintf("namespace %q not allowed", ns))
+		default:
+			taints = append(taints, taint)
+		}
+	}
+	out.Taints = taints
+}
+
+// addMissingKeys copies the keys of src that are not present in dst.
+func addMissingKeys(dst, src map[string]string) {
+	for k, v := range src {
+		if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
+}
@@ -18,10 +18,16 @@ package nfdmaster
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	k8sinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
@@ -35,18 +41,53 @@ import (
 )
 
 type nfdController struct {
-	featureLister nfdlisters.NodeFeatureLister
-	ruleLister    nfdlisters.NodeFeatureRuleLister
+	featureLister   nfdlisters.NodeFeatureLister
+	ruleLister      nfdlisters.NodeFeatureRuleLister
+	nsRuleLister    nfdlisters.NamespacedNodeFeatureRuleLister
+	informerFactory nfdinformers.SharedInformerFactory
+	// featureCache is the NodeFeature cache if unused features are stripped
+	featureCache *nodeFeatureCache
+	// featureCacheRebuildTimeout is the time to wait for a rebuild of the
+	// NodeFeature cache to sync before retrying
+	featureCacheRebuildTimeout time.Duration
+	// ruleChangeChan coalesces rule changes to be processed by the feature
+	// cache refresher
+	ruleChangeChan    chan struct{}
+	refresherStopChan chan struct{}
+	stopRefresherOnce sync.Once
+	// baselineLister lists the ConfigMaps holding node feature baselines
+	baselineLister          corev1listers.ConfigMapLister
+	baselineInformerFactory k8sinformers.SharedInformerFactory
 
 	stopChan chan struct{}
 
 	updateAllNodesChan chan struct{}
 	updateOneNodeChan  chan string
+
+	// hubMode makes the controller request updates of individual NodeFeature
+	// objects (by namespace/name key) instead of nodes.
+	hubMode bool
 }
 
 type nfdApiControllerOptions struct {
 	DisableNodeFeature bool
 	ResyncPeriod       time.Duration
+	HubMode            bool
+	NamespacedRules    bool
+	// StripUnusedFeatures drops the features not referenced by any rule,
+	// except RetainFeatures, from the NodeFeature cache
+	StripUnusedFeatures bool
+	RetainFeatures      []string
+	// FeatureCacheRebuildTimeout is the time to wait for a rebuild of the
+	// NodeFeature cache to sync before retrying
+	FeatureCacheRebuildTimeout time.Duration
+	// FeatureBaseline enables watching the feature baselines of nodes,
+	// stored as ConfigMaps in BaselineNamespace
+	FeatureBaseline   bool
+	BaselineNamespace string
+	// RuleDeleted is called with the object of a deleted NodeFeatureRule or
+	// NamespacedNodeFeatureRule
+	RuleDeleted func(metav1.Object)
 }
 
 func newNfdController(config *restclient.Config, nfdApiControllerOptions nfdApiControllerOptions) (*nfdController, error) {
@@ -54,6 +95,11 @@ func newNfdController(config *restclient.Config, nfdApiControllerOptions nfdApiC
 		stopChan:           make(chan struct{}, 1),
 		updateAllNodesChan: make(chan struct{}, 1),
 		updateOneNodeChan:  make(chan string),
+		ruleChangeChan:     make(chan struct{}, 1),
+		refresherStopChan:  make(chan struct{}),
+		hubMode:            nfdApiControllerOptions.HubMode,
+
+		featureCacheRebuildTimeout: nfdApiControllerOptions.FeatureCacheRebuildTimeout,
 	}
 
 	nfdClient := nfdclientset.NewForConfigOrDie(config)
@@ -63,8 +109,7 @@ func newNfdController(config *restclient.Config, nfdApiControllerOptions nfdApiC
 
 	// Add informer for NodeFeature objects
 	if !nfdApiControllerOptions.DisableNodeFeature {
-		featureInformer := informerFactory.Nfd().V1alpha1().NodeFeatures()
-		if _, err := featureInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		featureHandler := cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				nfr := obj.(*nfdv1alpha1.NodeFeature)
 				klog.V(2).InfoS("NodeFeature added", "nodefeature", klog.KObj(nfr))
@@ -80,33 +125,58 @@ func newNfdController(config *restclient.Config, nfdApiControllerOptions nfdApiC
 				klog.V(2).InfoS("NodeFeature deleted", "nodefeature", klog.KObj(nfr))
 				c.updateOneNode("NodeFeature", nfr)
 			},
-		}); err != nil {
-			return nil, err
 		}
-		c.featureLister = featureInformer.Lister()
+		if nfdApiControllerOptions.StripUnusedFeatures {
+			var err error
+			c.featureCache, err = newNodeFeatureCache(nfdClient, nfdApiControllerOptions.ResyncPeriod, nfdApiControllerOptions.RetainFeatures, nfdApiControllerOptions.NamespacedRules, featureHandler)
+			if err != nil {
+				klog.ErrorS(err, "failed to create NodeFeature cache, caching all features")
+			} else {
+				c.featureLister = c.featureCache
+			}
+		}
+		if c.featureCache == nil {
+			featureInformer := informerFactory.Nfd().V1alpha1().NodeFeatures()
+			if err := featureInformer.Informer().SetTransform(stripManagedFields); err != nil {
+				return nil, err
+			}
+			if _, err := featureInformer.Informer().AddEventHandler(featureHandler); err != nil {
+				return nil, err
+			}
+			c.featureLister = featureInformer.Lister()
+		}
 	}
 
 	// Add informer for NodeFeatureRule objects
 	ruleInformer := informerFactory.Nfd().V1alpha1().NodeFeatureRules()
+	if err := ruleInformer.Informer().SetTransform(stripManagedFields); err != nil {
+		return nil, err
+	}
 	if _, err := ruleInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(object interface{}) {
 			klog.V(2).InfoS("NodeFeatureRule added", "nodefeaturerule", klog.KObj(object.(metav1.Object)))
 			if !nfdApiControllerOptions.DisableNodeFeature {
-				c.updateAllNodes()
+				c.rulesChanged()
 			}
 			// else: rules will be processed only when gRPC requests are received
 		},
 		UpdateFunc: func(oldObject, newObject interface{}) {
 			klog.V(2).InfoS("NodeFeatureRule updated", "nodefeaturerule", klog.KObj(newObject.(metav1.Object)))
 			if !nfdApiControllerOptions.DisableNodeFeature {
-				c.updateAllNodes()
+				c.rulesChanged()
 			}
 			// else: rules will be processed only when gRPC requests are received
 		},
 		DeleteFunc: func(object interface{}) {
+			if tombstone, ok := object.(cache.DeletedFinalStateUnknown); ok {
+				object = tombstone.Obj
+			}
 			klog.V(2).InfoS("NodeFeatureRule deleted", "nodefeaturerule", klog.KObj(object.(metav1.Object)))
+			if nfdApiControllerOptions.RuleDeleted != nil {
+				nfdApiControllerOptions.RuleDeleted(object.(metav1.Object))
+			}
 			if !nfdApiControllerOptions.DisableNodeFeature {
-				c.updateAllNodes()
+				c.rulesChanged()
 			}
 			// else: rules will be processed only when gRPC requests are received
 		},
@@ -115,8 +185,87 @@ func newNfdController(config *restclient.Config, nfdApiControllerOptions nfdApiC
 	}
 	c.ruleLister = ruleInformer.Lister()
 
+	// Add informer for NamespacedNodeFeatureRule objects
+	if nfdApiControllerOptions.NamespacedRules {
+		nsRuleInformer := informerFactory.Nfd().V1alpha1().NamespacedNodeFeatureRules()
+		if err := nsRuleInformer.Informer().SetTransform(stripManagedFields); err != nil {
+			return nil, err
+		}
+		if _, err := nsRuleInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(object interface{}) {
+				klog.V(2).InfoS("NamespacedNodeFeatureRule added", "namespacednodefeaturerule", klog.KObj(object.(metav1.Object)))
+				if !nfdApiControllerOptions.DisableNodeFeature {
+					c.rulesChanged()
+				}
+			},
+			UpdateFunc: func(oldObject, newObject interface{}) {
+				klog.V(2).InfoS("NamespacedNodeFeatureRule updated", "namespacednodefeaturerule", klog.KObj(newObject.(metav1.Object)))
+				if !nfdApiControllerOptions.DisableNodeFeature {
+					c.rulesChanged()
+				}
+			},
+			DeleteFunc: func(object interface{}) {
+				if tombstone, ok := object.(cache.DeletedFinalStateUnknown); ok {
+					object = tombstone.Obj
+				}
+				klog.V(2).InfoS("NamespacedNodeFeatureRule deleted", "namespacednodefeaturerule", klog.KObj(object.(metav1.Object)))
+				if nfdApiControllerOptions.RuleDeleted != nil {
+					nfdApiControllerOptions.RuleDeleted(object.(metav1.Object))
+				}
+				if !nfdApiControllerOptions.DisableNodeFeature {
+					c.rulesChanged()
+				}
+			},
+		}); err != nil {
+			return nil, err
+		}
+		c.nsRuleLister = nsRuleInformer.Lister()
+	}
+
+	// Add informer for the ConfigMaps holding feature baselines
+	if nfdApiControllerOptions.FeatureBaseline && !nfdApiControllerOptions.DisableNodeFeature {
+		cli, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		c.baselineInformerFactory = k8sinformers.NewSharedInformerFactoryWithOptions(cli, nfdApiControllerOptions.ResyncPeriod,
+			k8sinformers.WithNamespace(nfdApiControllerOptions.BaselineNamespace),
+			k8sinformers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+				opts.LabelSelector = nfdv1alpha1.FeatureBaselineLabel
+			}))
+		baselineInformer := c.baselineInformerFactory.Core().V1().ConfigMaps()
+		if err := baselineInformer.Informer().SetTransform(stripManagedFields); err != nil {
+			return nil, err
+		}
+		if _, err := baselineInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				klog.V(2).InfoS("feature baseline added", "configmap", klog.KObj(obj.(metav1.Object)))
+				c.updateOneNode("ConfigMap", obj.(metav1.Object))
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				klog.V(2).InfoS("feature baseline updated", "configmap", klog.KObj(newObj.(metav1.Object)))
+				c.updateOneNode("ConfigMap", newObj.(metav1.Object))
+			},
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				klog.V(2).InfoS("feature baseline deleted", "configmap", klog.KObj(obj.(metav1.Object)))
+				c.updateOneNode("ConfigMap", obj.(metav1.Object))
+			},
+		}); err != nil {
+			return nil, err
+		}
+		c.baselineLister = baselineInformer.Lister()
+		c.baselineInformerFactory.Start(c.stopChan)
+	}
+
 	// Start informers
 	informerFactory.Start(c.stopChan)
+	c.informerFactory = informerFactory
+	if c.featureCache != nil {
+		go c.runFeatureCacheRefresher()
+	}
 
 	utilruntime.Must(nfdv1alpha1.AddToScheme(nfdscheme.Scheme))
 	return c, nil
@@ -127,9 +276,120 @@ func (c *nfdController) stop() {
 	case c.stopChan <- struct{}{}:
 	default:
 	}
+	if c.featureCache != nil {
+		c.stopRefresherOnce.Do(func() { close(c.refresherStopChan) })
+		c.featureCache.stop()
+	}
+}
+
+// rulesChanged requests an update of all nodes after a change in the rules.
+// If unused features are stripped from the NodeFeature cache the update is
+// requested by the feature cache refresher only after the cache has the
+// features referenced by the rules.
+func (c *nfdController) rulesChanged() {
+	if c.featureCache == nil {
+		c.updateAllNodes()
+		return
+	}
+	select {
+	case c.ruleChangeChan <- struct{}{}:
+	default:
+	}
+}
+
+// runFeatureCacheRefresher processes rule changes, one at a time, until the
+// controller is stopped. Bursts of rule changes are coalesced into one
+// refresh of the NodeFeature cache. All nodes are updated after the cache has
+// the features referenced by the rules. Failed refreshes are retried.
+func (c *nfdController) runFeatureCacheRefresher() {
+	for {
+		select {
+		case <-c.ruleChangeChan:
+			if err := c.refreshFeatureCache(); err != nil {
+				klog.ErrorS(err, "failed to rebuild the NodeFeature cache, retrying", "retryInterval", cacheSyncTimeout)
+				time.AfterFunc(cacheSyncTimeout, c.rulesChanged)
+				continue
+			}
+			c.updateAllNodes()
+		case <-c.refresherStopChan:
+			return
+		}
+	}
+}
+
+// refreshFeatureCache rebuilds the NodeFeature cache if the rules reference
+// features that are not cached.
+func (c *nfdController) refreshFeatureCache() error {
+	used := sets.New[string]()
+	rules, err := c.ruleLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list NodeFeatureRule resources: %w", err)
+	}
+	for _, rule := range rules {
+		used = used.Union(ruleFeatureRefs(rule.Spec.Rules))
+	}
+	if c.nsRuleLister != nil {
+		nsRules, err := c.nsRuleLister.List(labels.Everything())
+		if err != nil {
+			return fmt.Errorf("failed to list NamespacedNodeFeatureRule resources: %w", err)
+		}
+		for _, rule := range nsRules {
+			used = used.Union(ruleFeatureRefs(rule.Spec.Rules))
+		}
+	}
+
+	_, err = c.featureCache.ensure(used, c.featureCacheRebuildTimeout)
+	return err
+}
+
+// waitForCacheSync waits until the informer caches have been synced. Returns
+// false if the caches did not sync within the timeout.
+func (c *nfdController) waitForCacheSync(timeout time.Duration) bool {
+	if c.informerFactory == nil {
+		return true
+	}
+	stopChan := make(chan struct{})
+	timer := time.AfterFunc(timeout, func() { close(stopChan) })
+	defer timer.Stop()
+
+	for typ, synced := range c.informerFactory.WaitForCacheSync(stopChan) {
+		if !synced {
+			klog.InfoS("informer cache not synced", "type", typ)
+			return false
+		}
+	}
+	if c.baselineInformerFactory != nil {
+		for typ, synced := range c.baselineInformerFactory.WaitForCacheSync(stopChan) {
+			if !synced {
+				klog.InfoS("informer cache not synced", "type", typ)
+				return false
+			}
+		}
+	}
+	if c.featureCache != nil && !cache.WaitForCacheSync(stopChan, c.featureCache.hasSynced) {
+		klog.InfoS("NodeFeature cache not synced")
+		return false
+	}
+	return true
 }
 
 func (c *nfdController) updateOneNode(typ string, obj metav1.Object) {
+	// Objects mirrored from edge clusters are processed only in hub mode,
+	// identified by their namespace/name key. Hub output objects are written
+	// by us, nothing to do.
+	if isFromEdgeCluster(obj) {
+		if !c.hubMode || isHubOutput(obj) {
+			return
+		}
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			klog.ErrorS(err, "failed to determine key for object", "type", typ, "object", klog.KObj(obj))
+			return
+		}
+		c.updateOneNodeChan <- key
+		return
+	}
+
 	nodeName, err := getNodeNameForObj(obj)
 	if err != nil {
 		klog.ErrorS(err, "failed to determine node name for object", "type", typ, "object", klog.KObj(obj))
@@ -17,12 +17,22 @@ limitations under the License.
 package nfdmaster
 
 import (
+	"context"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+	nfdinformers "sigs.k8s.io/node-feature-discovery/pkg/generated/informers/externalversions"
 )
 
 func TestGetNodeNameForObj(t *testing.T) {
@@ -42,3 +52,143 @@ func TestGetNodeNameForObj(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, n, "node-1")
 }
+
+func newFeatureCacheTestRule(name string, features ...string) *nfdv1alpha1.NodeFeatureRule {
+	rule := nfdv1alpha1.Rule{Name: name, Labels: map[string]string{"foo": "@cpu.model.family"}}
+	for _, f := range features {
+		rule.MatchFeatures = append(rule.MatchFeatures, nfdv1alpha1.FeatureMatcherTerm{Feature: f})
+	}
+	return &nfdv1alpha1.NodeFeatureRule{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       nfdv1alpha1.NodeFeatureRuleSpec{Rules: []nfdv1alpha1.Rule{rule}},
+	}
+}
+
+func newFeatureCacheTestNodeFeature() *nfdv1alpha1.NodeFeature {
+	nf := &nfdv1alpha1.NodeFeature{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:          "node-1",
+			Namespace:     "nfd",
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "nfd-worker"}},
+		},
+		Spec: *nfdv1alpha1.NewNodeFeatureSpec(),
+	}
+	nf.Spec.Features.Attributes["cpu.model"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"family": "6"})
+	nf.Spec.Features.Flags["kernel.loadedmodule"] = nfdv1alpha1.NewFlagFeatures("kvm")
+	nf.Spec.Features.Instances["pci.device"] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{{Attributes: map[string]string{"vendor": "8086"}}})
+	nf.Spec.Features.Instances["network.device"] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{{Attributes: map[string]string{"name": "eth0"}}})
+	return nf
+}
+
+func TestFeatureFilter(t *testing.T) {
+	rule := newFeatureCacheTestRule("rule", "pci.device")
+	rule.Spec.Rules[0].MatchAny = []nfdv1alpha1.MatchAnyElem{{MatchFeatures: nfdv1alpha1.FeatureMatcher{{Feature: "kernel.config"}}}}
+	refs := ruleFeatureRefs(rule.Spec.Rules)
+	assert.Equal(t, []string{"cpu.model", "kernel.config", "pci.device"}, sets.List(refs))
+
+	f := &featureFilter{used: refs, retain: []string{"network.*"}}
+	obj, err := f.transform(newFeatureCacheTestNodeFeature())
+	assert.NoError(t, err)
+	nf := obj.(*nfdv1alpha1.NodeFeature)
+	assert.Nil(t, nf.ManagedFields)
+	assert.Contains(t, nf.Spec.Features.Attributes, "cpu.model")
+	assert.Contains(t, nf.Spec.Features.Instances, "pci.device")
+	assert.Contains(t, nf.Spec.Features.Instances, "network.device")
+	assert.NotContains(t, nf.Spec.Features.Flags, "kernel.loadedmodule")
+
+	assert.True(t, f.covers(sets.New("pci.device", "network.ip")))
+	assert.False(t, f.covers(sets.New("kernel.loadedmodule")))
+}
+
+func TestNodeFeatureCache(t *testing.T) {
+	client := fake.NewSimpleClientset(newFeatureCacheTestRule("rule", "pci.device"), newFeatureCacheTestNodeFeature())
+	c, err := newNodeFeatureCache(client, time.Hour, nil, false, cache.ResourceEventHandlerFuncs{})
+	assert.NoError(t, err)
+	defer c.stop()
+
+	getFeatures := func() *nfdv1alpha1.Features {
+		nf, err := c.NodeFeatures("nfd").Get("node-1")
+		if err != nil {
+			return nil
+		}
+		return &nf.Spec.Features
+	}
+	assert.Eventually(t, func() bool { return c.hasSynced() && getFeatures() != nil }, 2*time.Second, 10*time.Millisecond)
+	assert.Contains(t, getFeatures().Instances, "pci.device")
+	assert.NotContains(t, getFeatures().Flags, "kernel.loadedmodule")
+
+	// No rebuild if the features are already cached
+	rebuilt, err := c.ensure(sets.New("pci.device"), time.Second)
+	assert.NoError(t, err)
+	assert.False(t, rebuilt)
+
+	// Rebuild when new features are referenced
+	rebuilt, err = c.ensure(sets.New("pci.device", "kernel.loadedmodule"), 2*time.Second)
+	assert.NoError(t, err)
+	assert.True(t, rebuilt)
+	assert.Contains(t, getFeatures().Flags, "kernel.loadedmodule")
+	assert.NotContains(t, getFeatures().Attributes, "cpu.model")
+
+	// A rebuild that does not sync within the timeout is continued, not
+	// started over, by the next call
+	var lists atomic.Int32
+	release := make(chan struct{})
+	client.PrependReactor("list", "nodefeatures", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		lists.Add(1)
+		<-release
+		return false, nil, nil
+	})
+	rebuilt, err = c.ensure(sets.New("pci.device", "cpu.model"), 100*time.Millisecond)
+	assert.Error(t, err)
+	assert.False(t, rebuilt)
+	assert.NotContains(t, getFeatures().Attributes, "cpu.model")
+	close(release)
+	rebuilt, err = c.ensure(sets.New("pci.device", "cpu.model"), 2*time.Second)
+	assert.NoError(t, err)
+	assert.True(t, rebuilt)
+	assert.Contains(t, getFeatures().Attributes, "cpu.model")
+	assert.Equal(t, int32(1), lists.Load())
+}
+
+func TestFeatureCacheRefresher(t *testing.T) {
+	client := fake.NewSimpleClientset(newFeatureCacheTestRule("rule", "pci.device"), newFeatureCacheTestNodeFeature())
+	fc, err := newNodeFeatureCache(client, time.Hour, nil, false, cache.ResourceEventHandlerFuncs{})
+	assert.NoError(t, err)
+
+	informerFactory := nfdinformers.NewSharedInformerFactory(client, time.Hour)
+	ruleInformer := informerFactory.Nfd().V1alpha1().NodeFeatureRules()
+	c := &nfdController{
+		ruleLister:                 ruleInformer.Lister(),
+		featureCache:               fc,
+		featureLister:              fc,
+		featureCacheRebuildTimeout: 2 * time.Second,
+		stopChan:                   make(chan struct{}, 1),
+		updateAllNodesChan:         make(chan struct{}, 1),
+		ruleChangeChan:             make(chan struct{}, 1),
+		refresherStopChan:          make(chan struct{}),
+	}
+	informerFactory.Start(c.stopChan)
+	informerFactory.WaitForCacheSync(c.stopChan)
+	go c.runFeatureCacheRefresher()
+	defer c.stop()
+	assert.Eventually(t, fc.hasSynced, 2*time.Second, 10*time.Millisecond)
+
+	// Nodes are updated only after the cache has the newly referenced features
+	_, err = client.NfdV1alpha1().NodeFeatureRules().Create(context.TODO(), newFeatureCacheTestRule("rule-2", "kernel.loadedmodule"), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		rules, _ := c.ruleLister.List(labels.Everything())
+		return len(rules) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		c.rulesChanged()
+	}
+	select {
+	case <-c.updateAllNodesChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("all nodes were not updated after a rule change")
+	}
+	nf, err := c.featureLister.NodeFeatures("nfd").Get("node-1")
+	assert.NoError(t, err)
+	assert.Contains(t, nf.Spec.Features.Flags, "kernel.loadedmodule")
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package nfdmaster
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -26,17 +28,32 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/smartystreets/assertions"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/stretchr/testify/mock"
 	"github.com/vektra/errors"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	k8sclient "k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/node-feature-discovery/pkg/apihelper"
 	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned/fake"
@@ -106,6 +123,13 @@ func newMockNfdAPIController(client *fake.Clientset) *nfdController {
 	}
 	c.ruleLister = ruleInformer.Lister()
 
+	// Add informer for NamespacedNodeFeatureRule objects
+	nsRuleInformer := informerFactory.Nfd().V1alpha1().NamespacedNodeFeatureRules()
+	if _, err := nsRuleInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{}); err != nil {
+		return nil
+	}
+	c.nsRuleLister = nsRuleInformer.Lister()
+
 	// Start informers
 	informerFactory.Start(c.stopChan)
 
@@ -180,10 +204,10 @@ func TestUpdateNodeObject(t *testing.T) {
 			}
 
 			mockAPIHelper.On("GetClient").Return(mockClient, nil)
-			mockAPIHelper.On("GetNode", mockClient, mockNodeName).Return(mockNode, nil).Twice()
-			mockAPIHelper.On("PatchNodeStatus", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(statusPatches))).Return(nil)
-			mockAPIHelper.On("PatchNode", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(metadataPatches))).Return(nil)
-			err := mockMaster.updateNodeObject(mockClient, mockNodeName, fakeFeatureLabels, fakeAnnotations, fakeExtResources, nil)
+			mockAPIHelper.On("GetNode", mock.Anything, mockClient, mockNodeName).Return(mockNode, nil).Twice()
+			mockAPIHelper.On("PatchNodeStatus", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(statusPatches))).Return(nil)
+			mockAPIHelper.On("PatchNode", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(metadataPatches))).Return(nil)
+			err := mockMaster.updateNodeObject(context.TODO(), mockClient, mockNodeName, fakeFeatureLabels, fakeAnnotations, fakeExtResources, nil)
 
 			Convey("Error is nil", func() {
 				So(err, ShouldBeNil)
@@ -193,7 +217,7 @@ func TestUpdateNodeObject(t *testing.T) {
 		Convey("When I fail to update the node with feature labels", func() {
 			expectedError := fmt.Errorf("no client is passed, client:  <nil>")
 			mockAPIHelper.On("GetClient").Return(nil, expectedError)
-			err := mockMaster.updateNodeObject(nil, mockNodeName, fakeFeatureLabels, fakeAnnotations, fakeExtResources, nil)
+			err := mockMaster.updateNodeObject(context.TODO(), nil, mockNodeName, fakeFeatureLabels, fakeAnnotations, fakeExtResources, nil)
 
 			Convey("Error is produced", func() {
 				So(err, ShouldResemble, expectedError)
@@ -203,7 +227,7 @@ func TestUpdateNodeObject(t *testing.T) {
 		Convey("When I fail to get a mock client while updating feature labels", func() {
 			expectedError := fmt.Errorf("no client is passed, client:  <nil>")
 			mockAPIHelper.On("GetClient").Return(nil, expectedError)
-			err := mockMaster.updateNodeObject(nil, mockNodeName, fakeFeatureLabels, fakeAnnotations, fakeExtResources, nil)
+			err := mockMaster.updateNodeObject(context.TODO(), nil, mockNodeName, fakeFeatureLabels, fakeAnnotations, fakeExtResources, nil)
 
 			Convey("Error is produced", func() {
 				So(err, ShouldResemble, expectedError)
@@ -213,8 +237,8 @@ func TestUpdateNodeObject(t *testing.T) {
 		Convey("When I fail to get a mock node while updating feature labels", func() {
 			expectedError := errors.New("fake error")
 			mockAPIHelper.On("GetClient").Return(mockClient, nil)
-			mockAPIHelper.On("GetNode", mockClient, mockNodeName).Return(nil, expectedError).Twice()
-			err := mockMaster.updateNodeObject(mockClient, mockNodeName, fakeFeatureLabels, fakeAnnotations, fakeExtResources, nil)
+			mockAPIHelper.On("GetNode", mock.Anything, mockClient, mockNodeName).Return(nil, expectedError).Twice()
+			err := mockMaster.updateNodeObject(context.TODO(), mockClient, mockNodeName, fakeFeatureLabels, fakeAnnotations, fakeExtResources, nil)
 
 			Convey("Error is produced", func() {
 				So(err, ShouldEqual, expectedError)
@@ -224,10 +248,10 @@ func TestUpdateNodeObject(t *testing.T) {
 		Convey("When I fail to update a mock node while updating feature labels", func() {
 			expectedError := errors.New("fake error")
 			mockAPIHelper.On("GetClient").Return(mockClient, nil)
-			mockAPIHelper.On("GetNode", mockClient, mockNodeName).Return(mockNode, nil).Twice()
-			mockAPIHelper.On("PatchNodeStatus", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(statusPatches))).Return(nil)
-			mockAPIHelper.On("PatchNode", mockClient, mockNodeName, mock.Anything).Return(expectedError).Twice()
-			err := mockMaster.updateNodeObject(mockClient, mockNodeName, fakeFeatureLabels, fakeAnnotations, fakeExtResources, nil)
+			mockAPIHelper.On("GetNode", mock.Anything, mockClient, mockNodeName).Return(mockNode, nil).Twice()
+			mockAPIHelper.On("PatchNodeStatus", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(statusPatches))).Return(nil)
+			mockAPIHelper.On("PatchNode", mock.Anything, mockClient, mockNodeName, mock.Anything).Return(expectedError).Twice()
+			err := mockMaster.updateNodeObject(context.TODO(), mockClient, mockNodeName, fakeFeatureLabels, fakeAnnotations, fakeExtResources, nil)
 
 			Convey("Error is produced", func() {
 				So(err.Error(), ShouldEndWith, expectedError.Error())
@@ -246,8 +270,8 @@ func TestUpdateMasterNode(t *testing.T) {
 		Convey("When update operation succeeds", func() {
 			expectedPatches := []apihelper.JsonPatch{}
 			mockHelper.On("GetClient").Return(mockClient, nil)
-			mockHelper.On("GetNode", mockClient, mockNodeName).Return(mockNode, nil)
-			mockHelper.On("PatchNode", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedPatches))).Return(nil)
+			mockHelper.On("GetNode", mock.Anything, mockClient, mockNodeName).Return(mockNode, nil)
+			mockHelper.On("PatchNode", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedPatches))).Return(nil)
 			err := mockMaster.updateMasterNode()
 			Convey("No error should be returned", func() {
 				So(err, ShouldBeNil)
@@ -265,7 +289,7 @@ func TestUpdateMasterNode(t *testing.T) {
 
 		Convey("When getting API node object fails", func() {
 			mockHelper.On("GetClient").Return(mockClient, nil)
-			mockHelper.On("GetNode", mockClient, mockNodeName).Return(mockNode, mockErr)
+			mockHelper.On("GetNode", mock.Anything, mockClient, mockNodeName).Return(mockNode, mockErr)
 			err := mockMaster.updateMasterNode()
 			Convey("An error should be returned", func() {
 				So(err, ShouldEqual, mockErr)
@@ -274,8 +298,8 @@ func TestUpdateMasterNode(t *testing.T) {
 
 		Convey("When updating node object fails", func() {
 			mockHelper.On("GetClient").Return(mockClient, nil)
-			mockHelper.On("GetNode", mockClient, mockNodeName).Return(mockNode, nil)
-			mockHelper.On("PatchNode", mockClient, mockNodeName, mock.Anything).Return(mockErr)
+			mockHelper.On("GetNode", mock.Anything, mockClient, mockNodeName).Return(mockNode, nil)
+			mockHelper.On("PatchNode", mock.Anything, mockClient, mockNodeName, mock.Anything).Return(mockErr)
 			err := mockMaster.updateMasterNode()
 			Convey("An error should be returned", func() {
 				So(err.Error(), ShouldEndWith, mockErr.Error())
@@ -391,9 +415,9 @@ func TestSetLabels(t *testing.T) {
 			}
 
 			mockHelper.On("GetClient").Return(mockClient, nil)
-			mockHelper.On("GetNode", mockClient, workerName).Return(mockNode, nil).Twice()
-			mockHelper.On("PatchNodeStatus", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedStatusPatches))).Return(nil)
-			mockHelper.On("PatchNode", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedPatches))).Return(nil)
+			mockHelper.On("GetNode", mock.Anything, mockClient, workerName).Return(mockNode, nil).Twice()
+			mockHelper.On("PatchNodeStatus", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedStatusPatches))).Return(nil)
+			mockHelper.On("PatchNode", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedPatches))).Return(nil)
 			_, err := mockMaster.SetLabels(mockCtx, mockReq)
 			Convey("No error should be returned", func() {
 				So(err, ShouldBeNil)
@@ -409,9 +433,9 @@ func TestSetLabels(t *testing.T) {
 
 			mockMaster.config.LabelWhiteList.Regexp = *regexp.MustCompile("^f.*2$")
 			mockHelper.On("GetClient").Return(mockClient, nil)
-			mockHelper.On("GetNode", mockClient, workerName).Return(mockNode, nil)
-			mockHelper.On("PatchNodeStatus", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedStatusPatches))).Return(nil)
-			mockHelper.On("PatchNode", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedPatches))).Return(nil)
+			mockHelper.On("GetNode", mock.Anything, mockClient, workerName).Return(mockNode, nil)
+			mockHelper.On("PatchNodeStatus", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedStatusPatches))).Return(nil)
+			mockHelper.On("PatchNode", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedPatches))).Return(nil)
 			_, err := mockMaster.SetLabels(mockCtx, mockReq)
 			Convey("Error is nil", func() {
 				So(err, ShouldBeNil)
@@ -448,9 +472,9 @@ func TestSetLabels(t *testing.T) {
 			mockMaster.config.ExtraLabelNs = map[string]struct{}{"valid.ns": {}}
 			mockMaster.args.Instance = instance
 			mockHelper.On("GetClient").Return(mockClient, nil)
-			mockHelper.On("GetNode", mockClient, workerName).Return(mockNode, nil)
-			mockHelper.On("PatchNodeStatus", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedStatusPatches))).Return(nil)
-			mockHelper.On("PatchNode", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedPatches))).Return(nil)
+			mockHelper.On("GetNode", mock.Anything, mockClient, workerName).Return(mockNode, nil)
+			mockHelper.On("PatchNodeStatus", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedStatusPatches))).Return(nil)
+			mockHelper.On("PatchNode", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedPatches))).Return(nil)
 			mockReq := &labeler.SetLabelsRequest{NodeName: workerName, NfdVersion: workerVer, Labels: mockLabels}
 			_, err := mockMaster.SetLabels(mockCtx, mockReq)
 			Convey("Error is nil", func() {
@@ -473,9 +497,9 @@ func TestSetLabels(t *testing.T) {
 
 			mockMaster.config.ResourceLabels = map[string]struct{}{"feature.node.kubernetes.io/feature-3": {}, "feature-1": {}}
 			mockHelper.On("GetClient").Return(mockClient, nil)
-			mockHelper.On("GetNode", mockClient, workerName).Return(mockNode, nil)
-			mockHelper.On("PatchNodeStatus", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedStatusPatches))).Return(nil)
-			mockHelper.On("PatchNode", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedPatches))).Return(nil)
+			mockHelper.On("GetNode", mock.Anything, mockClient, workerName).Return(mockNode, nil)
+			mockHelper.On("PatchNodeStatus", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedStatusPatches))).Return(nil)
+			mockHelper.On("PatchNode", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(expectedPatches))).Return(nil)
 			_, err := mockMaster.SetLabels(mockCtx, mockReq)
 			Convey("Error is nil", func() {
 				So(err, ShouldBeNil)
@@ -839,6 +863,349 @@ nfdApiParallelism: 100
 	})
 }
 
+func TestRuleErrorGracePeriod(t *testing.T) {
+	Convey("When processing a rule that fails to evaluate", t, func() {
+		rule := &nfdv1alpha1.NodeFeatureRule{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "test-rule"},
+			Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+				Rules: []nfdv1alpha1.Rule{
+					{
+						Name:   "count",
+						Labels: map[string]string{"many": "true"},
+						MatchFeatures: nfdv1alpha1.FeatureMatcher{
+							{
+								Feature: "test.attr",
+								MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+									"count": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchGt, Value: nfdv1alpha1.MatchValue{"1"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		mockMaster := newMockMaster(nil)
+		mockMaster.ruleOutputCache = newRuleOutputCache()
+		mockMaster.nfdController = newMockNfdAPIController(fake.NewSimpleClientset(rule))
+		defer mockMaster.nfdController.stop()
+		So(func() interface{} {
+			rules, _ := mockMaster.nfdController.ruleLister.List(k8slabels.Everything())
+			return len(rules)
+		}, withTimeout, 2*time.Second, ShouldEqual, 1)
+
+		processDryRun := func(count string, dryRun bool) Labels {
+			features := nfdv1alpha1.NewFeatures()
+			features.Attributes["test.attr"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"count": count})
+			labels, _, _, _ := mockMaster.processNodeFeatureRule(mockNodeName, features, dryRun)
+			return labels
+		}
+		process := func(count string) Labels { return processDryRun(count, false) }
+
+		So(process("5"), ShouldResemble, Labels{"many": "true"})
+
+		Convey("Output should be dropped if no grace period is configured", func() {
+			So(process("not-a-number"), ShouldBeEmpty)
+		})
+
+		Convey("Output should be retained within the grace period", func() {
+			mockMaster.config.RuleErrorGracePeriod = utils.DurationVal{Duration: time.Hour}
+			So(process("5"), ShouldResemble, Labels{"many": "true"})
+			So(process("not-a-number"), ShouldResemble, Labels{"many": "true"})
+
+			Convey("And dropped when the rule evaluates cleanly to no-match", func() {
+				So(process("0"), ShouldBeEmpty)
+				So(process("not-a-number"), ShouldBeEmpty)
+			})
+
+			Convey("And not affected by dry runs", func() {
+				So(processDryRun("0", true), ShouldBeEmpty)
+				So(processDryRun("not-a-number", true), ShouldResemble, Labels{"many": "true"})
+				So(process("not-a-number"), ShouldResemble, Labels{"many": "true"})
+			})
+
+			Convey("And purged when the rule is deleted", func() {
+				mockMaster.nfdRuleDeleted(rule)
+				So(mockMaster.ruleOutputCache.entries, ShouldBeEmpty)
+			})
+		})
+
+		Convey("Output should be dropped after the grace period", func() {
+			mockMaster.config.RuleErrorGracePeriod = utils.DurationVal{Duration: time.Nanosecond}
+			So(process("5"), ShouldResemble, Labels{"many": "true"})
+			time.Sleep(time.Millisecond)
+			So(process("not-a-number"), ShouldBeEmpty)
+		})
+
+		Convey("Node should be requeued when the grace period expires", func() {
+			queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			defer queue.ShutDown()
+			mockMaster.nodeUpdaterPool = &nodeUpdaterPool{queue: queue}
+			mockMaster.config.RuleErrorGracePeriod = utils.DurationVal{Duration: 200 * time.Millisecond}
+			So(process("5"), ShouldResemble, Labels{"many": "true"})
+			So(process("not-a-number"), ShouldResemble, Labels{"many": "true"})
+			So(queue.Len(), ShouldEqual, 0)
+			So(func() interface{} { return queue.Len() }, withTimeout, 2*time.Second, ShouldEqual, 1)
+			So(process("not-a-number"), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestRuleSchedules(t *testing.T) {
+	Convey("When processing rules with schedules", t, func() {
+		newRule := func(start string) *nfdv1alpha1.NodeFeatureRule {
+			return &nfdv1alpha1.NodeFeatureRule{
+				ObjectMeta: meta_v1.ObjectMeta{Name: "scheduled-rule"},
+				Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+					Rules: []nfdv1alpha1.Rule{
+						{
+							Name:   "scheduled",
+							Labels: map[string]string{"scheduled": "true"},
+							Schedule: &nfdv1alpha1.RuleSchedule{
+								Windows: []nfdv1alpha1.ScheduleWindow{{Start: start, Duration: meta_v1.Duration{Duration: time.Hour}}},
+							},
+						},
+					},
+				},
+			}
+		}
+		nfdClient := fake.NewSimpleClientset(newRule("0 0 * * *"))
+		mockMaster := newMockMaster(nil)
+		mockMaster.ruleScheduleCache = newRuleScheduleCache()
+		mockMaster.nfdController = newMockNfdAPIController(nfdClient)
+		defer mockMaster.nfdController.stop()
+		So(func() interface{} {
+			rules, _ := mockMaster.nfdController.ruleLister.List(k8slabels.Everything())
+			return len(rules)
+		}, withTimeout, 2*time.Second, ShouldEqual, 1)
+
+		nfrScheduleActive.Reset()
+		process := func() {
+			mockMaster.processNodeFeatureRule(mockNodeName, nfdv1alpha1.NewFeatures(), false)
+		}
+		updateRule := func(rule *nfdv1alpha1.NodeFeatureRule) {
+			_, err := nfdClient.NfdV1alpha1().NodeFeatureRules().Update(context.TODO(), rule, meta_v1.UpdateOptions{})
+			So(err, ShouldBeNil)
+			So(func() interface{} {
+				nfr, _ := mockMaster.nfdController.ruleLister.Get("scheduled-rule")
+				return nfr.Spec.Rules[0].Schedule
+			}, withTimeout, 2*time.Second, ShouldResemble, rule.Spec.Rules[0].Schedule)
+		}
+		key := ruleScheduleKey{nfrName: "scheduled-rule", ruleName: "scheduled"}
+
+		process()
+		So(testutil.CollectAndCount(nfrScheduleActive), ShouldEqual, 1)
+		So(mockMaster.ruleScheduleCache.entries, ShouldContainKey, key)
+		sched := mockMaster.ruleScheduleCache.entries[key].sched
+
+		Convey("Schedules should be parsed once per rule version", func() {
+			process()
+			So(mockMaster.ruleScheduleCache.entries[key].sched, ShouldEqual, sched)
+
+			updateRule(newRule("0 12 * * *"))
+			process()
+			So(mockMaster.ruleScheduleCache.entries[key].sched, ShouldNotEqual, sched)
+		})
+
+		Convey("Metrics should be dropped when the schedule is removed", func() {
+			rule := newRule("")
+			rule.Spec.Rules[0].Schedule = nil
+			updateRule(rule)
+			mockMaster.pruneRuleSchedules()
+			So(testutil.CollectAndCount(nfrScheduleActive), ShouldEqual, 0)
+			So(mockMaster.ruleScheduleCache.entries, ShouldBeEmpty)
+		})
+
+		Convey("Metrics should be dropped when the rule is deleted", func() {
+			mockMaster.nfdRuleDeleted(newRule(""))
+			So(testutil.CollectAndCount(nfrScheduleActive), ShouldEqual, 0)
+			So(mockMaster.ruleScheduleCache.entries, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestWorkerLabelsFeature(t *testing.T) {
+	Convey("When processing rules matching labels published by nfd-worker", t, func() {
+		rule := &nfdv1alpha1.NodeFeatureRule{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "test-rule"},
+			Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+				Rules: []nfdv1alpha1.Rule{
+					{
+						Name:   "worker-labels",
+						Labels: map[string]string{"derived": "true"},
+						MatchFeatures: nfdv1alpha1.FeatureMatcher{
+							{
+								Feature: "worker.labels",
+								MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+									"feature.node.kubernetes.io/cpu-cpuid.AVX512F": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchIsTrue},
+									"vendor.example.com/accelerator":               &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchExists},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		nodeFeature := &nfdv1alpha1.NodeFeature{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Name:      mockNodeName,
+				Namespace: "default",
+				Labels:    map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: mockNodeName},
+			},
+			Spec: nfdv1alpha1.NodeFeatureSpec{
+				Labels: map[string]string{"cpu-cpuid.AVX512F": "true", "vendor.example.com/accelerator": "x"},
+			},
+		}
+		mockMaster := newMockMaster(nil)
+		mockMaster.config.AutoDefaultNs = true
+		mockMaster.nfdController = newMockNfdAPIController(fake.NewSimpleClientset(rule, nodeFeature))
+		defer mockMaster.nfdController.stop()
+		So(func() interface{} {
+			rules, _ := mockMaster.nfdController.ruleLister.List(k8slabels.Everything())
+			features, _ := mockMaster.nfdController.featureLister.List(k8slabels.Everything())
+			return len(rules) + len(features)
+		}, withTimeout, 2*time.Second, ShouldEqual, 2)
+
+		Convey("Rules should match the labels with the default namespace added", func() {
+			features := nfdv1alpha1.NewFeatures()
+			workerLabels := map[string]string{"cpu-cpuid.AVX512F": "true", "vendor.example.com/accelerator": "x"}
+			labels, _, _, _ := mockMaster.computeNodeFeatures(mockNodeName, workerLabels, features, false)
+			So(labels, ShouldContainKey, "feature.node.kubernetes.io/derived")
+			So(features.Attributes["worker.labels"].Elements, ShouldResemble, map[string]string{
+				"feature.node.kubernetes.io/cpu-cpuid.AVX512F": "true",
+				"vendor.example.com/accelerator":               "x",
+			})
+		})
+
+		Convey("Features used for rule history should have the same worker.labels", func() {
+			allFeatures, err := mockMaster.getAllNodeFeatures()
+			So(err, ShouldBeNil)
+			So(allFeatures, ShouldContainKey, mockNodeName)
+			So(allFeatures[mockNodeName].Attributes["worker.labels"].Elements, ShouldResemble, map[string]string{
+				"feature.node.kubernetes.io/cpu-cpuid.AVX512F": "true",
+				"vendor.example.com/accelerator":               "x",
+			})
+		})
+
+		Convey("Features of the worker.labels domain in NodeFeature objects should be ignored", func() {
+			features := nfdv1alpha1.NewFeatures()
+			features.Attributes["worker.labels"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{
+				"feature.node.kubernetes.io/cpu-cpuid.AVX512F": "true",
+				"vendor.example.com/accelerator":               "x",
+			})
+			labels, _, _, _ := mockMaster.computeNodeFeatures(mockNodeName, nil, features, false)
+			So(labels, ShouldNotContainKey, "feature.node.kubernetes.io/derived")
+		})
+	})
+}
+
+func TestNamespacedNodeFeatureRules(t *testing.T) {
+	Convey("When processing NamespacedNodeFeatureRule objects", t, func() {
+		newRule := func(name string, labels map[string]string) nfdv1alpha1.Rule {
+			return nfdv1alpha1.Rule{
+				Name:   name,
+				Labels: labels,
+				MatchFeatures: nfdv1alpha1.FeatureMatcher{
+					{
+						Feature: "test.attr",
+						MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+							"foo": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchExists},
+						},
+					},
+				},
+			}
+		}
+		clusterRule := &nfdv1alpha1.NodeFeatureRule{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "cluster-rule"},
+			Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+				Rules: []nfdv1alpha1.Rule{newRule("cluster", map[string]string{"team-a.example.com/shared": "cluster"})},
+			},
+		}
+		taintRule := newRule("taint", nil)
+		taintRule.Taints = []corev1.Taint{{Key: "team-a.example.com/dedicated", Value: "true", Effect: corev1.TaintEffectNoSchedule}}
+		taintRule.ExtendedResources = map[string]string{"team-a.example.com/widgets": "2"}
+		tenantRuleA := &nfdv1alpha1.NamespacedNodeFeatureRule{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "rule", Namespace: "team-a"},
+			Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+				Rules: []nfdv1alpha1.Rule{
+					newRule("labels", map[string]string{
+						"team-a.example.com/foo":          "true",
+						"sub.team-a.example.com/bar":      "true",
+						"team-a.example.com/shared":       "tenant",
+						"team-b.example.com/foo":          "true",
+						"feature.node.kubernetes.io/fake": "true",
+						"unprefixed":                      "true",
+					}),
+					taintRule,
+				},
+			},
+		}
+		tenantRuleB := &nfdv1alpha1.NamespacedNodeFeatureRule{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "rule", Namespace: "team-b"},
+			Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+				Rules: []nfdv1alpha1.Rule{newRule("labels", map[string]string{"team-b.example.com/foo": "true"})},
+			},
+		}
+
+		mockMaster := newMockMaster(nil)
+		mockMaster.config.NamespacedRules = NamespacedRulesConfig{
+			Enable:          true,
+			LabelNamespaces: map[string][]string{"*": {"{namespace}.example.com"}},
+		}
+		mockMaster.nfdController = newMockNfdAPIController(fake.NewSimpleClientset(clusterRule, tenantRuleA, tenantRuleB))
+		defer mockMaster.nfdController.stop()
+		So(func() interface{} {
+			rules, _ := mockMaster.nfdController.nsRuleLister.List(k8slabels.Everything())
+			return len(rules)
+		}, withTimeout, 2*time.Second, ShouldEqual, 2)
+		So(func() interface{} {
+			rules, _ := mockMaster.nfdController.ruleLister.List(k8slabels.Everything())
+			return len(rules)
+		}, withTimeout, 2*time.Second, ShouldEqual, 1)
+
+		process := func() (Labels, ExtendedResources, []corev1.Taint) {
+			features := nfdv1alpha1.NewFeatures()
+			features.Attributes["test.attr"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"foo": "1"})
+			labels, _, extendedResources, taints := mockMaster.processNodeFeatureRule(mockNodeName, features, false)
+			return labels, extendedResources, taints
+		}
+
+		Convey("Only outputs in the allowed namespaces should be created", func() {
+			labels, extendedResources, taints := process()
+			So(labels, ShouldResemble, Labels{
+				"team-a.example.com/shared":  "cluster",
+				"team-a.example.com/foo":     "true",
+				"sub.team-a.example.com/bar": "true",
+				"team-b.example.com/foo":     "true",
+			})
+			So(extendedResources, ShouldBeEmpty)
+			So(taints, ShouldBeEmpty)
+		})
+
+		Convey("Taints and extended resources should be created if allowed", func() {
+			mockMaster.config.NamespacedRules.AllowTaints = true
+			mockMaster.config.NamespacedRules.AllowExtendedResources = true
+			_, extendedResources, taints := process()
+			So(extendedResources, ShouldResemble, ExtendedResources{"team-a.example.com/widgets": "2"})
+			So(taints, ShouldResemble, taintRule.Taints)
+		})
+
+		Convey("Per-namespace policy should override the default", func() {
+			mockMaster.config.NamespacedRules.LabelNamespaces["team-b"] = []string{"other.example.com"}
+			labels, _, _ := process()
+			So(labels, ShouldNotContainKey, "team-b.example.com/foo")
+		})
+	})
+
+	Convey("When validating the namespaced rules policy", t, func() {
+		c := NamespacedRulesConfig{LabelNamespaces: map[string][]string{"*": {"{namespace}.example.com"}}}
+		So(c.validate(), ShouldBeNil)
+		c.LabelNamespaces["team-a"] = []string{"feature.node.kubernetes.io"}
+		So(c.validate(), ShouldNotBeNil)
+		c.LabelNamespaces = map[string][]string{"Invalid_NS": {"example.com"}}
+		So(c.validate(), ShouldNotBeNil)
+	})
+}
+
 func BenchmarkNfdAPIUpdateAllNodes(b *testing.B) {
 	mockAPIHelper := new(apihelper.MockAPIHelpers)
 
@@ -857,7 +1224,7 @@ func BenchmarkNfdAPIUpdateAllNodes(b *testing.B) {
 	}
 
 	mockAPIHelper.On("GetClient").Return(mockClient, nil)
-	mockAPIHelper.On("GetNodes", mockClient).Return(mockNodeList(), nil)
+	mockAPIHelper.On("GetNodes", mock.Anything, mockClient).Return(mockNodeList(), nil)
 
 	mockNodeUpdaterPool.start(10)
 
@@ -865,9 +1232,9 @@ func BenchmarkNfdAPIUpdateAllNodes(b *testing.B) {
 		nodeName := fmt.Sprintf("node %v", i)
 		node := corev1.Node{}
 		node.Name = nodeName
-		mockAPIHelper.On("GetNode", mockClient, nodeName).Return(&node, nil)
-		mockAPIHelper.On("PatchNodeStatus", mockClient, nodeName, mock.MatchedBy(jsonPatchMatcher(statusPatches))).Return(nil)
-		mockAPIHelper.On("PatchNode", mockClient, nodeName, mock.MatchedBy(jsonPatchMatcher(metadataPatches))).Return(nil)
+		mockAPIHelper.On("GetNode", mock.Anything, mockClient, nodeName).Return(&node, nil)
+		mockAPIHelper.On("PatchNodeStatus", mock.Anything, mockClient, nodeName, mock.MatchedBy(jsonPatchMatcher(statusPatches))).Return(nil)
+		mockAPIHelper.On("PatchNode", mock.Anything, mockClient, nodeName, mock.MatchedBy(jsonPatchMatcher(metadataPatches))).Return(nil)
 	}
 	b.ResetTimer()
 
@@ -1002,3 +1369,437 @@ func TestGetDynamicValue(t *testing.T) {
 		})
 	}
 }
+
+func TestMassUpdateProtection(t *testing.T) {
+	Convey("When updating all nodes with mass update protection enabled", t, func() {
+		nodes := make([]corev1.Node, 4)
+		objs := make([]runtime.Object, 0, len(nodes))
+		for i := range nodes {
+			nodes[i].Name = fmt.Sprintf("node-%d", i)
+			nodes[i].Annotations = map[string]string{nfdv1alpha1.FeatureLabelsAnnotation: "feature-a"}
+			objs = append(objs, newTestNodeFeature("", nodes[i].Name, nodes[i].Name, map[string]string{"feature-a": "true"}))
+		}
+		client := fake.NewSimpleClientset(objs...)
+
+		mockMaster := newMockMaster(nil)
+		mockMaster.config.AutoDefaultNs = true
+		mockMaster.config.MassUpdateProtection = MassUpdateProtectionConfig{Threshold: 0.5}
+		mockMaster.healthServer = health.NewServer()
+		mockMaster.podName = "nfd-master-pod"
+		recorder := record.NewFakeRecorder(10)
+		mockMaster.eventRecorder = recorder
+		mockMaster.nfdController = newMockNfdAPIController(client)
+		defer mockMaster.nfdController.stop()
+		So(func() interface{} {
+			objs, _ := mockMaster.nfdController.featureLister.List(k8slabels.Everything())
+			return len(objs)
+		}, withTimeout, 2*time.Second, ShouldEqual, len(nodes))
+
+		readiness := func() grpc_health_v1.HealthCheckResponse_ServingStatus {
+			resp, err := mockMaster.healthServer.Check(context.TODO(), &grpc_health_v1.HealthCheckRequest{Service: readinessHealthService})
+			So(err, ShouldBeNil)
+			return resp.Status
+		}
+
+		Convey("Updates should proceed if no labels would be removed", func() {
+			So(mockMaster.checkMassUpdate(nodes), ShouldBeNil)
+			So(mockMaster.massUpdateGuard.isPaused(), ShouldBeFalse)
+		})
+
+		Convey("Updates should proceed if labels would be removed from few nodes", func() {
+			So(client.NfdV1alpha1().NodeFeatures("").Delete(context.TODO(), "node-0", meta_v1.DeleteOptions{}), ShouldBeNil)
+			So(func() interface{} {
+				objs, _ := mockMaster.nfdController.featureLister.List(k8slabels.Everything())
+				return len(objs)
+			}, withTimeout, 2*time.Second, ShouldEqual, len(nodes)-1)
+
+			So(mockMaster.checkMassUpdate(nodes), ShouldBeNil)
+			So(mockMaster.massUpdateGuard.isPaused(), ShouldBeFalse)
+		})
+
+		Convey("Updates should be paused if labels would be removed from too many nodes", func() {
+			for _, n := range nodes[:3] {
+				So(client.NfdV1alpha1().NodeFeatures("").Delete(context.TODO(), n.Name, meta_v1.DeleteOptions{}), ShouldBeNil)
+			}
+			So(func() interface{} {
+				objs, _ := mockMaster.nfdController.featureLister.List(k8slabels.Everything())
+				return len(objs)
+			}, withTimeout, 2*time.Second, ShouldEqual, 1)
+
+			So(mockMaster.checkMassUpdate(nodes), ShouldEqual, errMassUpdatePaused)
+			So(mockMaster.massUpdateGuard.isPaused(), ShouldBeTrue)
+			So(readiness(), ShouldEqual, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+			So(<-recorder.Events, ShouldStartWith, "Warning MassUpdatePaused node updates paused")
+
+			// The paused state is reported only once
+			So(mockMaster.checkMassUpdate(nodes), ShouldEqual, errMassUpdatePaused)
+			So(recorder.Events, ShouldBeEmpty)
+
+			// Individual updates of the affected nodes are skipped, too
+			So(mockMaster.nfdAPIUpdateOneNode("node-0"), ShouldBeNil)
+			So(mockMaster.massUpdateGuard.isHeldBack("node-0"), ShouldBeTrue)
+			So(mockMaster.massUpdateGuard.isHeldBack("node-3"), ShouldBeFalse)
+			So(mockMaster.massUpdateGuard.isHeldBack("new-node"), ShouldBeFalse)
+
+			Convey("And resumed when the operator confirms the update", func() {
+				mockMaster.config.MassUpdateProtection.Confirm = mockMaster.massUpdateGuard.fingerprint
+				So(mockMaster.checkMassUpdate(nodes), ShouldBeNil)
+				So(mockMaster.massUpdateGuard.isPaused(), ShouldBeFalse)
+				So(mockMaster.massUpdateGuard.isHeldBack("node-0"), ShouldBeFalse)
+				So(readiness(), ShouldEqual, grpc_health_v1.HealthCheckResponse_SERVING)
+				So(<-recorder.Events, ShouldStartWith, "Normal MassUpdateResumed")
+			})
+
+			Convey("And resumed when the condition clears", func() {
+				for _, n := range nodes[:3] {
+					_, err := client.NfdV1alpha1().NodeFeatures("").Create(context.TODO(), newTestNodeFeature("", n.Name, n.Name, map[string]string{"feature-a": "true"}), meta_v1.CreateOptions{})
+					So(err, ShouldBeNil)
+				}
+				So(func() interface{} {
+					objs, _ := mockMaster.nfdController.featureLister.List(k8slabels.Everything())
+					return len(objs)
+				}, withTimeout, 2*time.Second, ShouldEqual, len(nodes))
+
+				So(mockMaster.checkMassUpdate(nodes), ShouldBeNil)
+				So(mockMaster.massUpdateGuard.isPaused(), ShouldBeFalse)
+				So(readiness(), ShouldEqual, grpc_health_v1.HealthCheckResponse_SERVING)
+			})
+		})
+	})
+}
+
+func TestRecordRuleRevisions(t *testing.T) {
+	Convey("When recording NodeFeatureRule revisions", t, func() {
+		newRule := func(label string) *nfdv1alpha1.NodeFeatureRule {
+			return &nfdv1alpha1.NodeFeatureRule{
+				ObjectMeta: meta_v1.ObjectMeta{Name: "test-rule"},
+				Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+					Rules: []nfdv1alpha1.Rule{
+						{
+							Name:   "kvm",
+							Labels: map[string]string{label: "true"},
+							MatchFeatures: nfdv1alpha1.FeatureMatcher{
+								{
+									Feature: "kernel.loadedmodule",
+									MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+										"kvm": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchExists},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+		nfdClient := fake.NewSimpleClientset(
+			newRule("v1"),
+			newTestNodeFeature("", "node-1", "node-1", nil, "kvm"),
+			newTestNodeFeature("", "node-2", "node-2", nil, "kvm"),
+			newTestNodeFeature("", "node-3", "node-3", nil),
+		)
+		cli := k8sfake.NewSimpleClientset()
+
+		mockMaster := newMockMaster(nil)
+		mockMaster.namespace = "nfd"
+		mockMaster.config.RuleRevisionHistoryLimit = 2
+		mockMaster.nfdController = newMockNfdAPIController(nfdClient)
+		defer mockMaster.nfdController.stop()
+		So(func() interface{} {
+			objs, _ := mockMaster.nfdController.featureLister.List(k8slabels.Everything())
+			rules, _ := mockMaster.nfdController.ruleLister.List(k8slabels.Everything())
+			return len(objs) + len(rules)
+		}, withTimeout, 2*time.Second, ShouldEqual, 4)
+
+		revisions := func() []appsv1.ControllerRevision {
+			list, err := cli.AppsV1().ControllerRevisions("nfd").List(context.TODO(), meta_v1.ListOptions{})
+			So(err, ShouldBeNil)
+			sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Revision < list.Items[j].Revision })
+			return list.Items
+		}
+		updateRule := func(label string) {
+			_, err := nfdClient.NfdV1alpha1().NodeFeatureRules().Update(context.TODO(), newRule(label), meta_v1.UpdateOptions{})
+			So(err, ShouldBeNil)
+			So(func() interface{} {
+				rule, _ := mockMaster.nfdController.ruleLister.Get("test-rule")
+				return rule.Spec.Rules[0].Labels
+			}, withTimeout, 2*time.Second, ShouldResemble, map[string]string{label: "true"})
+			So(mockMaster.recordRuleRevisions(cli), ShouldBeNil)
+		}
+
+		So(mockMaster.recordRuleRevisions(cli), ShouldBeNil)
+		revs := revisions()
+		So(revs, ShouldHaveLength, 1)
+		So(revs[0].Revision, ShouldEqual, 1)
+		So(revs[0].Labels[nfdv1alpha1.NodeFeatureRuleNameLabel], ShouldEqual, "test-rule")
+		So(revs[0].Annotations[nfdv1alpha1.NodeFeatureRuleNameAnnotation], ShouldEqual, "test-rule")
+		So(revs[0].Annotations[nfdv1alpha1.RuleRevisionAffectedNodesAnnotation], ShouldEqual, "2")
+		So(revs[0].Annotations, ShouldContainKey, nfdv1alpha1.RuleRevisionAppliedAtAnnotation)
+
+		Convey("Unchanged rules should not create new revisions", func() {
+			So(mockMaster.recordRuleRevisions(cli), ShouldBeNil)
+			So(revisions(), ShouldHaveLength, 1)
+		})
+
+		Convey("Long rule names should be truncated in the label", func() {
+			longRule := newRule("long")
+			longRule.Name = strings.Repeat("long-rule-", 10)
+			_, err := nfdClient.NfdV1alpha1().NodeFeatureRules().Create(context.TODO(), longRule, meta_v1.CreateOptions{})
+			So(err, ShouldBeNil)
+			So(func() interface{} {
+				_, err := mockMaster.nfdController.ruleLister.Get(longRule.Name)
+				return err
+			}, withTimeout, 2*time.Second, ShouldBeNil)
+			So(mockMaster.recordRuleRevisions(cli), ShouldBeNil)
+			So(mockMaster.recordRuleRevisions(cli), ShouldBeNil)
+
+			revs := revisions()
+			So(revs, ShouldHaveLength, 2)
+			for _, rev := range revs {
+				So(validation.IsValidLabelValue(rev.Labels[nfdv1alpha1.NodeFeatureRuleNameLabel]), ShouldBeEmpty)
+			}
+			So([]string{revs[0].Annotations[nfdv1alpha1.NodeFeatureRuleNameAnnotation], revs[1].Annotations[nfdv1alpha1.NodeFeatureRuleNameAnnotation]},
+				ShouldContain, longRule.Name)
+
+			// Truncated names must not end with a separator
+			name := ruleRevisionName(strings.Repeat("a", 241)+".-rule", []byte("{}"))
+			So(validation.IsDNS1123Subdomain(name), ShouldBeEmpty)
+		})
+
+		Convey("Revisions of deleted rules should be deleted", func() {
+			err := nfdClient.NfdV1alpha1().NodeFeatureRules().Delete(context.TODO(), "test-rule", meta_v1.DeleteOptions{})
+			So(err, ShouldBeNil)
+			So(func() interface{} {
+				rules, _ := mockMaster.nfdController.ruleLister.List(k8slabels.Everything())
+				return len(rules)
+			}, withTimeout, 2*time.Second, ShouldEqual, 0)
+			So(mockMaster.recordRuleRevisions(cli), ShouldBeNil)
+			So(revisions(), ShouldBeEmpty)
+		})
+
+		Convey("Changed rules should create new revisions up to the limit", func() {
+			updateRule("v2")
+			updateRule("v3")
+			revs := revisions()
+			So(revs, ShouldHaveLength, 2)
+			So(revs[0].Revision, ShouldEqual, 2)
+			So(revs[1].Revision, ShouldEqual, 3)
+
+			Convey("And restoring an older spec should bump the old revision", func() {
+				updateRule("v2")
+				revs := revisions()
+				So(revs, ShouldHaveLength, 2)
+				So(revs[0].Revision, ShouldEqual, 3)
+				So(revs[1].Revision, ShouldEqual, 4)
+				spec := nfdv1alpha1.NodeFeatureRuleSpec{}
+				So(json.Unmarshal(revs[1].Data.Raw, &spec), ShouldBeNil)
+				So(spec.Rules[0].Labels, ShouldResemble, map[string]string{"v2": "true"})
+			})
+		})
+	})
+}
+
+func TestFeatureBaseline(t *testing.T) {
+	Convey("When checking node features against the feature baseline", t, func() {
+		cli := k8sfake.NewSimpleClientset()
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		recorder := record.NewFakeRecorder(10)
+
+		mockMaster := newMockMaster(nil)
+		mockMaster.namespace = "nfd"
+		mockMaster.config.FeatureBaseline = FeatureBaselineConfig{
+			FeatureSets:        []string{"memory.dimm", "cpu.model"},
+			AcceptOnFirstSight: true,
+			TaintEffect:        corev1.TaintEffectNoSchedule,
+		}
+		mockMaster.nfdController = &nfdController{baselineLister: corev1listers.NewConfigMapLister(indexer)}
+		mockMaster.baselineTracker = &baselineTracker{deviations: map[string]string{}, recorder: recorder, client: cli}
+
+		newFeatures := func(dimms ...string) *nfdv1alpha1.Features {
+			f := nfdv1alpha1.NewFeatures()
+			f.Attributes["cpu.model"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"family": "6"})
+			instances := []nfdv1alpha1.InstanceFeature{}
+			for _, d := range dimms {
+				instances = append(instances, *nfdv1alpha1.NewInstanceFeature(map[string]string{"locator": d, "size": "32"}))
+			}
+			f.Instances["memory.dimm"] = nfdv1alpha1.NewInstanceFeatures(instances)
+			// Not pinned in the baseline
+			f.Flags["kernel.loadedmodule"] = nfdv1alpha1.NewFlagFeatures("kvm")
+			return f
+		}
+		// Sync the fake lister with the fake client
+		syncBaseline := func() *corev1.ConfigMap {
+			cm, err := cli.CoreV1().ConfigMaps("nfd").Get(context.TODO(), nfdv1alpha1.FeatureBaselineName(mockNodeName), meta_v1.GetOptions{})
+			So(err, ShouldBeNil)
+			So(indexer.Update(cm), ShouldBeNil)
+			return cm
+		}
+
+		taint, err := mockMaster.checkFeatureBaseline(mockNodeName, newFeatures("A1", "A2"), false)
+		So(err, ShouldBeNil)
+		So(taint, ShouldBeNil)
+		cm := syncBaseline()
+		So(cm.Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel], ShouldEqual, mockNodeName)
+		So(cm.Annotations, ShouldContainKey, nfdv1alpha1.BaselineAcceptedAtAnnotation)
+		So(<-recorder.Events, ShouldContainSubstring, "FeatureBaselineAccepted")
+
+		Convey("Matching features should not be reported", func() {
+			f := newFeatures("A2", "A1")
+			f.Flags["kernel.loadedmodule"] = nfdv1alpha1.NewFlagFeatures("vfio")
+			taint, err := mockMaster.checkFeatureBaseline(mockNodeName, f, false)
+			So(err, ShouldBeNil)
+			So(taint, ShouldBeNil)
+			So(recorder.Events, ShouldBeEmpty)
+		})
+
+		Convey("Absent feature sets should not be reported", func() {
+			// No NodeFeature objects
+			taint, err := mockMaster.checkFeatureBaseline(mockNodeName, nfdv1alpha1.NewFeatures(), false)
+			So(err, ShouldBeNil)
+			So(taint, ShouldBeNil)
+
+			f := newFeatures()
+			delete(f.Instances, "memory.dimm")
+			taint, err = mockMaster.checkFeatureBaseline(mockNodeName, f, false)
+			So(err, ShouldBeNil)
+			So(taint, ShouldBeNil)
+			So(recorder.Events, ShouldBeEmpty)
+		})
+
+		Convey("Dry runs should return the taint without side effects", func() {
+			f := newFeatures("A1")
+			taint, err := mockMaster.checkFeatureBaseline(mockNodeName, f, true)
+			So(err, ShouldBeNil)
+			So(taint, ShouldNotBeNil)
+			So(recorder.Events, ShouldBeEmpty)
+			So(mockMaster.baselineTracker.deviations, ShouldBeEmpty)
+
+			// Accepting the features is left to the actual update
+			cm.Annotations[nfdv1alpha1.BaselineAcceptAnnotation] = "2024-01-01T00:00:00Z"
+			So(indexer.Update(cm), ShouldBeNil)
+			numActions := len(cli.Actions())
+			taint, err = mockMaster.checkFeatureBaseline(mockNodeName, f, true)
+			So(err, ShouldBeNil)
+			So(taint, ShouldBeNil)
+			So(cli.Actions(), ShouldHaveLength, numActions)
+		})
+
+		Convey("Deviating features should be reported", func() {
+			f := newFeatures("A1")
+			f.Attributes["cpu.model"].Elements["family"] = "25"
+			taint, err := mockMaster.checkFeatureBaseline(mockNodeName, f, false)
+			So(err, ShouldBeNil)
+			So(taint, ShouldResemble, &corev1.Taint{Key: baselineDeviationTaintKey, Value: "true", Effect: corev1.TaintEffectNoSchedule})
+			So(<-recorder.Events, ShouldEqual, "Warning FeatureBaselineDeviation Node features deviate from the baseline: changed cpu.model family: 6 -> 25; missing memory.dimm locator=A2,size=32")
+
+			// Events are only emitted when the deviations change
+			_, err = mockMaster.checkFeatureBaseline(mockNodeName, f, false)
+			So(err, ShouldBeNil)
+			So(recorder.Events, ShouldBeEmpty)
+
+			Convey("And accepting the current features should record a new baseline", func() {
+				cm.Annotations[nfdv1alpha1.BaselineAcceptAnnotation] = "2024-01-01T00:00:00Z"
+				So(indexer.Update(cm), ShouldBeNil)
+				taint, err := mockMaster.checkFeatureBaseline(mockNodeName, f, false)
+				So(err, ShouldBeNil)
+				So(taint, ShouldBeNil)
+				So(<-recorder.Events, ShouldContainSubstring, "FeatureBaselineAccepted")
+				So(<-recorder.Events, ShouldContainSubstring, "FeatureBaselineRestored")
+				So(syncBaseline().Annotations, ShouldNotContainKey, nfdv1alpha1.BaselineAcceptAnnotation)
+
+				taint, err = mockMaster.checkFeatureBaseline(mockNodeName, f, false)
+				So(err, ShouldBeNil)
+				So(taint, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestGrpcLimits(t *testing.T) {
+	Convey("When limiting gRPC requests", t, func() {
+		mockMaster := newMockMaster(nil)
+		mockMaster.config.NoPublish = true
+		mockCtx := context.Background()
+
+		Convey("Requests exceeding the rate limit of a node should be rejected", func() {
+			mockMaster.config.GrpcServer = GrpcServerConfig{RateLimit: 0.001, RateLimitBurst: 2}
+			req := &labeler.SetLabelsRequest{NodeName: mockNodeName}
+			for i := 0; i < 2; i++ {
+				_, err := mockMaster.SetLabels(mockCtx, req)
+				So(err, ShouldBeNil)
+			}
+			_, err := mockMaster.SetLabels(mockCtx, req)
+			So(status.Code(err), ShouldEqual, codes.ResourceExhausted)
+
+			// Other nodes have their own token bucket
+			_, err = mockMaster.SetLabels(mockCtx, &labeler.SetLabelsRequest{NodeName: "other-node"})
+			So(err, ShouldBeNil)
+
+			// Disabling the rate limit should take effect immediately
+			mockMaster.config.GrpcServer.RateLimit = 0
+			_, err = mockMaster.SetLabels(mockCtx, req)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Unverified node names should not be used for rate limiting", func() {
+			mockMaster.config.GrpcServer = GrpcServerConfig{RateLimit: 0.001, RateLimitBurst: 1}
+			peerCtx := peer.NewContext(mockCtx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 40000}})
+			_, err := mockMaster.SetLabels(peerCtx, &labeler.SetLabelsRequest{NodeName: "node-1"})
+			So(err, ShouldBeNil)
+			_, err = mockMaster.SetLabels(peerCtx, &labeler.SetLabelsRequest{NodeName: "node-2"})
+			So(status.Code(err), ShouldEqual, codes.ResourceExhausted)
+
+			// Other clients have their own token bucket
+			peerCtx = peer.NewContext(mockCtx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 40000}})
+			_, err = mockMaster.SetLabels(peerCtx, &labeler.SetLabelsRequest{NodeName: "node-2"})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("The number of rate limiters should be capped", func() {
+			l := &grpcLimiter{}
+			for i := 0; i < maxRateLimiters; i++ {
+				l.allow(fmt.Sprintf("node-%d", i), 0.001, 1)
+			}
+			So(l.allow("new-node", 0.001, 1), ShouldBeTrue)
+			So(len(l.limiters), ShouldEqual, maxRateLimiters)
+			So(l.limiters, ShouldContainKey, "new-node")
+		})
+
+		Convey("Rate limiters with a full bucket should be dropped", func() {
+			l := &grpcLimiter{}
+			So(l.allow("node-1", 1000, 1), ShouldBeTrue)
+			So(l.allow("node-2", 0.001, 1), ShouldBeTrue)
+			l.sweep(time.Now().Add(time.Second))
+			So(l.limiters, ShouldNotContainKey, "node-1")
+			So(l.limiters, ShouldContainKey, "node-2")
+		})
+
+		Convey("Updates exceeding the concurrency limit should be rejected", func() {
+			l := &grpcLimiter{}
+			So(l.acquire(2), ShouldBeTrue)
+			So(l.acquire(2), ShouldBeTrue)
+			So(l.acquire(2), ShouldBeFalse)
+			l.release()
+			So(l.acquire(2), ShouldBeTrue)
+			So(l.acquire(0), ShouldBeTrue)
+		})
+
+		Convey("Requests exceeding the request timeout should fail", func() {
+			mockMaster.config.GrpcServer.RequestTimeout = utils.DurationVal{Duration: 10 * time.Millisecond}
+			info := &grpc.UnaryServerInfo{FullMethod: "/v1alpha1.Labeler/SetLabels"}
+			// The handler is cancelled by the deadline
+			slowHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			_, err := mockMaster.grpcTimeoutInterceptor(mockCtx, nil, info, slowHandler)
+			So(status.Code(err), ShouldEqual, codes.DeadlineExceeded)
+
+			fastHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return "ok", nil
+			}
+			resp, err := mockMaster.grpcTimeoutInterceptor(mockCtx, nil, info, fastHandler)
+			So(err, ShouldBeNil)
+			So(resp, ShouldEqual, "ok")
+		})
+	})
+}
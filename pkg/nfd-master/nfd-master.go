@@ -19,6 +19,7 @@ package nfdmaster
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"maps"
 	"net"
@@ -29,22 +30,29 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sLabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	controller "k8s.io/kubernetes/pkg/controller"
 	klogutils "sigs.k8s.io/node-feature-discovery/pkg/utils/klog"
@@ -56,7 +64,9 @@ import (
 	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
 	"sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/validate"
+	nfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned"
 	pb "sigs.k8s.io/node-feature-discovery/pkg/labeler"
+	"sigs.k8s.io/node-feature-discovery/pkg/nfd-master/apiserver"
 	"sigs.k8s.io/node-feature-discovery/pkg/utils"
 	"sigs.k8s.io/node-feature-discovery/pkg/version"
 )
@@ -72,17 +82,25 @@ type Annotations map[string]string
 
 // NFDConfig contains the configuration settings of NfdMaster.
 type NFDConfig struct {
-	AutoDefaultNs     bool
-	DenyLabelNs       utils.StringSetVal
-	ExtraLabelNs      utils.StringSetVal
-	LabelWhiteList    utils.RegexpVal
-	NoPublish         bool
-	ResourceLabels    utils.StringSetVal
-	EnableTaints      bool
-	ResyncPeriod      utils.DurationVal
-	LeaderElection    LeaderElectionConfig
-	NfdApiParallelism int
-	Klog              klogutils.KlogConfigOpts
+	AutoDefaultNs            bool
+	DenyLabelNs              utils.StringSetVal
+	ExtraLabelNs             utils.StringSetVal
+	LabelWhiteList           utils.RegexpVal
+	NoPublish                bool
+	ResourceLabels           utils.StringSetVal
+	EnableTaints             bool
+	ResyncPeriod             utils.DurationVal
+	LeaderElection           LeaderElectionConfig
+	NfdApiParallelism        int
+	RuleErrorGracePeriod     utils.DurationVal
+	RuleRevisionHistoryLimit int
+	Klog                     klogutils.KlogConfigOpts
+	Hub                      HubConfig
+	MassUpdateProtection     MassUpdateProtectionConfig
+	NamespacedRules          NamespacedRulesConfig
+	FeatureCache             FeatureCacheConfig
+	FeatureBaseline          FeatureBaselineConfig
+	GrpcServer               GrpcServerConfig
 }
 
 // LeaderElectionConfig contains the configuration for leader election
@@ -92,6 +110,114 @@ type LeaderElectionConfig struct {
 	RetryPeriod   utils.DurationVal
 }
 
+// HubConfig contains the configuration for connecting edge clusters to a
+// central hub cluster.
+type HubConfig struct {
+	// Mode is the role of this nfd-master, "edge" or "hub". Empty disables
+	// hub mode.
+	Mode string
+	// Kubeconfig is the kubeconfig file for accessing the hub cluster (edge
+	// mode only).
+	Kubeconfig string
+	// ClusterName is the name identifying the edge cluster in the hub (edge
+	// mode only).
+	ClusterName string
+	// Namespace is the namespace in the hub cluster where the NodeFeatures of
+	// this edge cluster are mirrored. Defaults to ClusterName.
+	Namespace string
+	// SyncPeriod is the interval of syncing with the hub cluster.
+	SyncPeriod utils.DurationVal
+}
+
+// MassUpdateProtectionConfig contains the configuration of the protection
+// against removing NFD-managed labels, extended resources and taints from a
+// large part of the cluster at once.
+type MassUpdateProtectionConfig struct {
+	// Threshold is the maximum fraction of nodes that may lose NFD-managed
+	// labels, extended resources or taints in one update of all nodes. Zero
+	// disables the protection.
+	Threshold float64
+	// MinNodes is the minimum number of nodes in the cluster for the
+	// protection to be effective.
+	MinNodes int
+	// Confirm is the fingerprint of a paused update that is allowed to proceed.
+	Confirm string
+	// RecheckPeriod is the interval of re-evaluating a paused update.
+	RecheckPeriod utils.DurationVal
+}
+
+// NamespacedRulesConfig contains the policy for processing
+// NamespacedNodeFeatureRule objects created by tenants.
+type NamespacedRulesConfig struct {
+	// Enable enables processing of NamespacedNodeFeatureRule objects.
+	Enable bool
+	// LabelNamespaces are the label and annotation namespaces that the rules
+	// in a Kubernetes namespace may create, indexed by the Kubernetes
+	// namespace. The "*" key applies to namespaces not listed explicitly. The
+	// "{namespace}" placeholder is replaced by the Kubernetes namespace.
+	LabelNamespaces map[string][]string
+	// AllowExtendedResources allows the rules to create extended resources.
+	AllowExtendedResources bool
+	// AllowTaints allows the rules to create taints.
+	AllowTaints bool
+}
+
+// FeatureCacheConfig contains the configuration of the NodeFeature cache of
+// nfd-master.
+type FeatureCacheConfig struct {
+	// StripUnusedFeatures drops the features that are not referenced by any
+	// rule from the cache.
+	StripUnusedFeatures bool
+	// RetainFeatures are glob patterns of features that are always cached.
+	RetainFeatures []string
+	// RebuildTimeout is the time to wait for a rebuilt cache to sync before
+	// retrying. The rebuild continues in the background.
+	RebuildTimeout utils.DurationVal
+}
+
+// FeatureBaselineConfig contains the configuration of the per-node feature
+// baselines used for detecting hardware changes.
+type FeatureBaselineConfig struct {
+	// FeatureSets are the feature sets recorded in the baseline, e.g.
+	// "memory.dimm". Empty disables feature baselines.
+	FeatureSets []string
+	// AcceptOnFirstSight records the baseline of a node when it is seen for
+	// the first time. Otherwise a baseline is only recorded when accepted by
+	// the operator.
+	AcceptOnFirstSight bool
+	// TaintEffect is the effect of the taint added to nodes deviating from
+	// their baseline. Empty disables the taint.
+	TaintEffect corev1.TaintEffect
+}
+
+// GrpcServerConfig contains the configuration of the gRPC server of
+// nfd-master. Changes to the message size and keepalive settings take effect
+// only after restarting nfd-master.
+type GrpcServerConfig struct {
+	// MaxRecvMsgSize is the maximum size of a request message in bytes.
+	MaxRecvMsgSize int
+	// KeepaliveMinTime is the minimum interval between keepalive pings
+	// permitted from clients. Clients pinging more often are disconnected.
+	KeepaliveMinTime utils.DurationVal
+	// KeepalivePermitWithoutStream permits keepalive pings from clients
+	// without active requests.
+	KeepalivePermitWithoutStream bool
+	// MaxConnectionIdle is the time after which idle connections are closed.
+	// Zero means infinity.
+	MaxConnectionIdle utils.DurationVal
+	// RequestTimeout is the maximum time of handling a request. Zero disables
+	// the timeout.
+	RequestTimeout utils.DurationVal
+	// RateLimit is the maximum sustained rate of requests per second from a
+	// node. Zero disables rate limiting.
+	RateLimit float64
+	// RateLimitBurst is the maximum number of requests from a node in a burst.
+	RateLimitBurst int
+	// MaxConcurrentUpdates is the maximum number of node updates processed
+	// concurrently. Zero means no limit.
+	MaxConcurrentUpdates int
+}
+
 // ConfigOverrideArgs are args that override config file options
 type ConfigOverrideArgs struct {
 	DenyLabelNs       *utils.StringSetVal
@@ -121,6 +247,11 @@ type Args struct {
 	Options              string
 	EnableLeaderElection bool
 	MetricsPort          int
+	EnableApiServer      bool
+	ApiServerPort        int
+	ApiServerStorageDir  string
+	ApiServerCertFile    string
+	ApiServerKeyFile     string
 
 	Overrides ConfigOverrideArgs
 }
@@ -139,16 +270,27 @@ type NfdMaster interface {
 type nfdMaster struct {
 	*nfdController
 
-	args            Args
-	namespace       string
-	nodeName        string
-	configFilePath  string
-	server          *grpc.Server
-	stop            chan struct{}
-	ready           chan bool
-	apihelper       apihelper.APIHelpers
-	kubeconfig      *restclient.Config
-	nodeUpdaterPool *nodeUpdaterPool
+	args              Args
+	namespace         string
+	nodeName          string
+	configFilePath    string
+	server            *grpc.Server
+	stop              chan struct{}
+	ready             chan bool
+	apihelper         apihelper.APIHelpers
+	kubeconfig        *restclient.Config
+	nodeUpdaterPool   *nodeUpdaterPool
+	nfdClient         nfdclientset.Interface
+	hubSyncer         *hubSyncer
+	ruleOutputCache   *ruleOutputCache
+	ruleScheduleCache *ruleScheduleCache
+	healthServer      *health.Server
+	podName           string
+	massUpdateGuard   massUpdateGuard
+	baselineTracker   *baselineTracker
+	eventRecorder     record.EventRecorder
+	grpcLimiter       grpcLimiter
+	nfdClientLock     sync.Mutex
 	deniedNs
 	config *NFDConfig
 }
@@ -158,6 +300,7 @@ func NewNfdMaster(args *Args) (NfdMaster, error) {
 	nfd := &nfdMaster{args: *args,
 		nodeName:  utils.NodeName(),
 		namespace: utils.GetKubernetesNamespace(),
+		podName:   os.Getenv("POD_NAME"),
 		ready:     make(chan bool, 1),
 		stop:      make(chan struct{}, 1),
 	}
@@ -183,32 +326,63 @@ func NewNfdMaster(args *Args) (NfdMaster, error) {
 		}
 	}
 
+	// The store of the API server is not shared between replicas
+	if args.EnableApiServer && args.EnableLeaderElection {
+		return nfd, fmt.Errorf("-enable-apiserver cannot be used together with -enable-leader-election")
+	}
+	if (args.ApiServerCertFile == "") != (args.ApiServerKeyFile == "") {
+		return nfd, fmt.Errorf("-apiserver-cert-file and -apiserver-key-file must be specified together")
+	}
+
 	if args.ConfigFile != "" {
 		nfd.configFilePath = filepath.Clean(args.ConfigFile)
 	}
 
 	nfd.nodeUpdaterPool = newNodeUpdaterPool(nfd)
+	nfd.ruleOutputCache = newRuleOutputCache()
+	nfd.ruleScheduleCache = newRuleScheduleCache()
+	nfd.healthServer = health.NewServer()
+	nfd.healthServer.SetServingStatus(readinessHealthService, grpc_health_v1.HealthCheckResponse_SERVING)
 
 	return nfd, nil
 }
 
 func newDefaultConfig() *NFDConfig {
 	return &NFDConfig{
-		LabelWhiteList:    utils.RegexpVal{Regexp: *regexp.MustCompile("")},
-		DenyLabelNs:       utils.StringSetVal{},
-		ExtraLabelNs:      utils.StringSetVal{},
-		NoPublish:         false,
-		AutoDefaultNs:     true,
-		NfdApiParallelism: 10,
-		ResourceLabels:    utils.StringSetVal{},
-		EnableTaints:      false,
-		ResyncPeriod:      utils.DurationVal{Duration: time.Duration(1) * time.Hour},
+		LabelWhiteList:           utils.RegexpVal{Regexp: *regexp.MustCompile("")},
+		DenyLabelNs:              utils.StringSetVal{},
+		ExtraLabelNs:             utils.StringSetVal{},
+		NoPublish:                false,
+		AutoDefaultNs:            true,
+		NfdApiParallelism:        10,
+		RuleRevisionHistoryLimit: 10,
+		ResourceLabels:           utils.StringSetVal{},
+		EnableTaints:             false,
+		ResyncPeriod:             utils.DurationVal{Duration: time.Duration(1) * time.Hour},
 		LeaderElection: LeaderElectionConfig{
 			LeaseDuration: utils.DurationVal{Duration: time.Duration(15) * time.Second},
 			RetryPeriod:   utils.DurationVal{Duration: time.Duration(2) * time.Second},
 			RenewDeadline: utils.DurationVal{Duration: time.Duration(10) * time.Second},
 		},
 		Klog: make(map[string]string),
+		Hub: HubConfig{
+			SyncPeriod: utils.DurationVal{Duration: time.Duration(1) * time.Minute},
+		},
+		MassUpdateProtection: MassUpdateProtectionConfig{
+			MinNodes:      10,
+			RecheckPeriod: utils.DurationVal{Duration: time.Duration(1) * time.Minute},
+		},
+		FeatureCache: FeatureCacheConfig{
+			RebuildTimeout: utils.DurationVal{Duration: time.Duration(5) * time.Minute},
+		},
+		FeatureBaseline: FeatureBaselineConfig{
+			AcceptOnFirstSight: true,
+		},
+		GrpcServer: GrpcServerConfig{
+			MaxRecvMsgSize:   4 * 1024 * 1024,
+			KeepaliveMinTime: utils.DurationVal{Duration: time.Duration(5) * time.Minute},
+			RateLimitBurst:   5,
+		},
 	}
 }
 
@@ -229,6 +403,25 @@ func (m *nfdMaster) Run() error {
 		return m.prune()
 	}
 
+	// Run the aggregated API server before the controller starts reading
+	// objects through it
+	apiServerErr := make(chan error, 1)
+	if m.args.EnableApiServer {
+		s, err := apiserver.New(apiserver.Config{
+			Port:       m.args.ApiServerPort,
+			CertFile:   m.args.ApiServerCertFile,
+			KeyFile:    m.args.ApiServerKeyFile,
+			StorageDir: m.args.ApiServerStorageDir,
+			Kubeconfig: m.args.Kubeconfig,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create API server: %w", err)
+		}
+		apiServerStop := make(chan struct{})
+		defer close(apiServerStop)
+		go func() { apiServerErr <- s.Run(apiServerStop) }()
+	}
+
 	if m.args.CrdController {
 		err := m.startNfdApiController()
 		if err != nil {
@@ -236,6 +429,13 @@ func (m *nfdMaster) Run() error {
 		}
 	}
 
+	// The hub syncer must be in place before the node updaters start using it
+	if m.config.Hub.Mode == hubModeEdge {
+		if err := m.startHubSyncer(); err != nil {
+			return err
+		}
+	}
+
 	m.nodeUpdaterPool.start(m.config.NfdApiParallelism)
 
 	// Create watcher for config file
@@ -262,7 +462,17 @@ func (m *nfdMaster) Run() error {
 			nodeERsRejected,
 			nodeTaintsRejected,
 			nfrProcessingTime,
-			nfrProcessingErrors)
+			nfrProcessingErrors,
+			nfrScheduleActive,
+			nfrOutputsRetained,
+			hubSyncFailures,
+			massUpdatePaused,
+			massUpdateAffectedNodes,
+			nnfrOutputsRejected,
+			featureCacheRebuilds,
+			baselineDeviations,
+			baselineDeviationsDetected,
+			grpcRequestsRejected)
 		go m.Run()
 		registerVersion(version.Get())
 		defer m.Stop()
@@ -291,6 +501,9 @@ func (m *nfdMaster) Run() error {
 		case err := <-grpcErr:
 			return fmt.Errorf("error in serving gRPC: %w", err)
 
+		case err := <-apiServerErr:
+			return fmt.Errorf("error in serving the NFD API: %w", err)
+
 		case <-configWatch.Events:
 			klog.InfoS("reloading configuration")
 			if err := m.configure(m.configFilePath, m.args.Options); err != nil {
@@ -312,8 +525,16 @@ func (m *nfdMaster) Run() error {
 			if m.nfdController != nil && m.args.EnableNodeFeatureApi {
 				m.nfdController.updateAllNodesChan <- struct{}{}
 			}
-			// Restart the node updater pool
+			// Restart the node updater pool and the hub syncer. The hub
+			// syncer is replaced while the node updaters are stopped as
+			// they use it.
 			m.nodeUpdaterPool.stop()
+			m.stopHubSyncer()
+			if m.config.Hub.Mode == hubModeEdge {
+				if err := m.startHubSyncer(); err != nil {
+					return err
+				}
+			}
 			m.nodeUpdaterPool.start(m.config.NfdApiParallelism)
 
 		case <-m.stop:
@@ -331,7 +552,7 @@ func (m *nfdMaster) runGrpcServer(errChan chan<- error) {
 		return
 	}
 
-	serverOpts := []grpc.ServerOption{}
+	serverOpts := m.grpcServerOptions()
 	tlsConfig := utils.TlsConfig{}
 	// Create watcher for TLS cert files
 	certWatch, err := utils.CreateFsWatcher(time.Second, m.args.CertFile, m.args.KeyFile, m.args.CaFile)
@@ -358,7 +579,7 @@ func (m *nfdMaster) runGrpcServer(errChan chan<- error) {
 		pb.RegisterLabelerServer(m.server, m)
 	}
 
-	grpc_health_v1.RegisterHealthServer(m.server, health.NewServer())
+	grpc_health_v1.RegisterHealthServer(m.server, m.healthServer)
 	klog.InfoS("gRPC server serving", "port", m.args.Port)
 
 	// Run gRPC server
@@ -396,12 +617,21 @@ func (m *nfdMaster) nfdAPIUpdateHandler() {
 		select {
 		case <-m.nfdController.updateAllNodesChan:
 			updateAll = true
+			// Re-evaluate a paused update without delay, the change may
+			// have resolved the situation
+			if m.massUpdateGuard.isPaused() {
+				rateLimit = time.After(time.Second)
+			}
 		case nodeName := <-m.nfdController.updateOneNodeChan:
 			updateNodes[nodeName] = struct{}{}
 		case <-rateLimit:
 			errUpdateAll := false
+			nextRun := time.Second
 			if updateAll {
-				if err := m.nfdAPIUpdateAllNodes(); err != nil {
+				if err := m.nfdAPIUpdateAllNodes(); errors.Is(err, errMassUpdatePaused) {
+					errUpdateAll = true
+					nextRun = m.config.MassUpdateProtection.RecheckPeriod.Duration
+				} else if err != nil {
 					klog.ErrorS(err, "failed to update nodes")
 					errUpdateAll = true
 				}
@@ -414,7 +644,7 @@ func (m *nfdMaster) nfdAPIUpdateHandler() {
 			// Reset "work queue" and timer
 			updateAll = errUpdateAll
 			updateNodes = map[string]struct{}{}
-			rateLimit = time.After(time.Second)
+			rateLimit = time.After(nextRun)
 		}
 	}
 }
@@ -429,6 +659,8 @@ func (m *nfdMaster) Stop() {
 
 	m.nodeUpdaterPool.stop()
 
+	m.stopHubSyncer()
+
 	close(m.stop)
 }
 
@@ -459,7 +691,7 @@ func (m *nfdMaster) prune() error {
 		return err
 	}
 
-	nodes, err := m.apihelper.GetNodes(cli)
+	nodes, err := m.apihelper.GetNodes(context.TODO(), cli)
 	if err != nil {
 		return err
 	}
@@ -468,21 +700,21 @@ func (m *nfdMaster) prune() error {
 		klog.InfoS("pruning node...", "nodeName", node.Name)
 
 		// Prune labels and extended resources
-		err := m.updateNodeObject(cli, node.Name, Labels{}, Annotations{}, ExtendedResources{}, []corev1.Taint{})
+		err := m.updateNodeObject(context.TODO(), cli, node.Name, Labels{}, Annotations{}, ExtendedResources{}, []corev1.Taint{})
 		if err != nil {
 			nodeUpdateFailures.Inc()
 			return fmt.Errorf("failed to prune node %q: %v", node.Name, err)
 		}
 
 		// Prune annotations
-		node, err := m.apihelper.GetNode(cli, node.Name)
+		node, err := m.apihelper.GetNode(context.TODO(), cli, node.Name)
 		if err != nil {
 			return err
 		}
 		maps.DeleteFunc(node.Annotations, func(k, v string) bool {
 			return strings.HasPrefix(k, m.instanceAnnotation(nfdv1alpha1.AnnotationNs))
 		})
-		err = m.apihelper.UpdateNode(cli, node)
+		err = m.apihelper.UpdateNode(context.TODO(), cli, node)
 		if err != nil {
 			return fmt.Errorf("failed to prune annotations from node %q: %v", node.Name, err)
 		}
@@ -499,7 +731,7 @@ func (m *nfdMaster) updateMasterNode() error {
 	if err != nil {
 		return err
 	}
-	node, err := m.apihelper.GetNode(cli, m.nodeName)
+	node, err := m.apihelper.GetNode(context.TODO(), cli, m.nodeName)
 	if err != nil {
 		return err
 	}
@@ -509,7 +741,7 @@ func (m *nfdMaster) updateMasterNode() error {
 		node.Annotations,
 		nil,
 		"/metadata/annotations")
-	err = m.apihelper.PatchNode(cli, node.Name, p)
+	err = m.apihelper.PatchNode(context.TODO(), cli, node.Name, p)
 	if err != nil {
 		return fmt.Errorf("failed to patch node annotations: %v", err)
 	}
@@ -650,6 +882,10 @@ func (m *nfdMaster) SetLabels(c context.Context, r *pb.SetLabelsRequest) (*pb.Se
 		klog.ErrorS(err, "gRPC client authorization failed", "nodeName", r.NodeName)
 		return &pb.SetLabelsReply{}, err
 	}
+	if err := m.checkGrpcRateLimit(c, r.NodeName); err != nil {
+		klog.ErrorS(err, "gRPC request rejected", "nodeName", r.NodeName)
+		return &pb.SetLabelsReply{}, err
+	}
 
 	switch {
 	case klog.V(4).Enabled():
@@ -665,8 +901,17 @@ func (m *nfdMaster) SetLabels(c context.Context, r *pb.SetLabelsRequest) (*pb.Se
 			return &pb.SetLabelsReply{}, err
 		}
 
-		// Create labels et al
-		if err := m.refreshNodeFeatures(cli, r.NodeName, r.GetLabels(), r.GetFeatures()); err != nil {
+		if !m.grpcLimiter.acquire(m.config.GrpcServer.MaxConcurrentUpdates) {
+			grpcRequestsRejected.WithLabelValues(grpcRejectConcurrencyLimit).Inc()
+			err := status.Errorf(codes.ResourceExhausted, "too many concurrent node updates")
+			klog.ErrorS(err, "gRPC request rejected", "nodeName", r.NodeName)
+			return &pb.SetLabelsReply{}, err
+		}
+		defer m.grpcLimiter.release()
+
+		// Create labels et al. The node update is cancelled if the request
+		// times out.
+		if err := m.refreshNodeFeatures(c, cli, r.NodeName, r.GetLabels(), r.GetFeatures()); err != nil {
 			nodeUpdateFailures.Inc()
 			return &pb.SetLabelsReply{}, err
 		}
@@ -675,18 +920,43 @@ func (m *nfdMaster) SetLabels(c context.Context, r *pb.SetLabelsRequest) (*pb.Se
 }
 
 func (m *nfdMaster) nfdAPIUpdateAllNodes() error {
+	// Incomplete caches would make the nodes look like they have no features
+	if !m.nfdController.waitForCacheSync(cacheSyncTimeout) {
+		return fmt.Errorf("timed out waiting for informer caches to sync")
+	}
+
+	// In the hub cluster the features mirrored from edge clusters are
+	// processed in addition to the nodes of the hub cluster itself
+	if m.config.Hub.Mode == hubModeHub {
+		if err := m.hubQueueMirrors(); err != nil {
+			return err
+		}
+	}
+
 	klog.InfoS("will process all nodes in the cluster")
 
+	m.pruneRuleSchedules()
+
 	cli, err := m.apihelper.GetClient()
 	if err != nil {
 		return err
 	}
 
-	nodes, err := m.apihelper.GetNodes(cli)
+	nodes, err := m.apihelper.GetNodes(context.TODO(), cli)
 	if err != nil {
 		return err
 	}
 
+	// Record the changed NodeFeatureRules before the mass update check so
+	// that the revision history is complete even if updates are held back
+	if err := m.recordRuleRevisions(cli); err != nil {
+		klog.ErrorS(err, "failed to record NodeFeatureRule revisions")
+	}
+
+	if err := m.checkMassUpdate(nodes.Items); err != nil {
+		return err
+	}
+
 	for _, node := range nodes.Items {
 		m.nodeUpdaterPool.queue.Add(node.Name)
 	}
@@ -699,55 +969,38 @@ func (m *nfdMaster) nfdAPIUpdateOneNode(nodeName string) error {
 		return nil
 	}
 
-	sel := k8sLabels.SelectorFromSet(k8sLabels.Set{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName})
-	objs, err := m.nfdController.featureLister.List(sel)
+	// In the hub cluster mirrored NodeFeature objects are queued by their
+	// namespace/name key, which is never a valid node name
+	if m.config.Hub.Mode == hubModeHub && strings.Contains(nodeName, "/") {
+		return m.hubUpdateOne(nodeName)
+	}
+
+	objs, err := m.listNodeFeatures(nodeName)
 	if err != nil {
-		return fmt.Errorf("failed to get NodeFeature resources for node %q: %w", nodeName, err)
+		return err
 	}
 
-	// Sort our objects
-	sort.Slice(objs, func(i, j int) bool {
-		// Objects in our nfd namespace gets into the beginning of the list
-		if objs[i].Namespace == m.namespace && objs[j].Namespace != m.namespace {
-			return true
-		}
-		if objs[i].Namespace != m.namespace && objs[j].Namespace == m.namespace {
-			return false
-		}
-		// After the nfd namespace, sort objects by their name
-		if objs[i].Name != objs[j].Name {
-			return objs[i].Name < objs[j].Name
-		}
-		// Objects with the same name are sorted by their namespace
-		return objs[i].Namespace < objs[j].Namespace
-	})
+	// Mirror features to the hub cluster in the background
+	if m.hubSyncer != nil {
+		m.hubSyncer.queueNode(nodeName)
+	}
 
 	if m.config.NoPublish {
 		return nil
 	}
 
-	klog.V(1).InfoS("processing of node initiated by NodeFeature API", "nodeName", nodeName)
-
-	features := nfdv1alpha1.NewNodeFeatureSpec()
+	// The node will be updated when the update of all nodes is resumed
+	if m.massUpdateGuard.isHeldBack(nodeName) {
+		klog.V(2).InfoS("node updates paused, skipping node", "nodeName", nodeName)
+		return nil
+	}
 
-	if len(objs) > 0 {
-		// Merge in features
-		//
-		// NOTE: changing the rule api to support handle multiple objects instead
-		// of merging would probably perform better with lot less data to copy.
-		features = objs[0].Spec.DeepCopy()
-		if m.config.AutoDefaultNs {
-			features.Labels = addNsToMapKeys(features.Labels, nfdv1alpha1.FeatureLabelNs)
-		}
-		for _, o := range objs[1:] {
-			s := o.Spec.DeepCopy()
-			if m.config.AutoDefaultNs {
-				s.Labels = addNsToMapKeys(s.Labels, nfdv1alpha1.FeatureLabelNs)
-			}
-			s.MergeInto(features)
-		}
+	klog.V(1).InfoS("processing of node initiated by NodeFeature API", "nodeName", nodeName)
 
-		klog.V(4).InfoS("merged nodeFeatureSpecs", "newNodeFeatureSpec", utils.DelayedDumper(features))
+	features := m.mergeNodeFeatures(objs)
+	if len(objs) == 0 {
+		// Nothing to retain if all features of the node are gone
+		m.ruleOutputCache.removeNode(nodeName)
 	}
 
 	// Update node labels et al. This may also mean removing all NFD-owned
@@ -757,13 +1010,58 @@ func (m *nfdMaster) nfdAPIUpdateOneNode(nodeName string) error {
 	if err != nil {
 		return err
 	}
-	if err := m.refreshNodeFeatures(cli, nodeName, features.Labels, &features.Features); err != nil {
+	if err := m.refreshNodeFeatures(context.TODO(), cli, nodeName, features.Labels, &features.Features); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// listNodeFeatures returns the NodeFeature objects of a node, sorted in the
+// order they are merged. Objects mirrored from edge clusters are skipped.
+func (m *nfdMaster) listNodeFeatures(nodeName string) ([]*nfdv1alpha1.NodeFeature, error) {
+	sel := k8sLabels.SelectorFromSet(k8sLabels.Set{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName})
+	all, err := m.nfdController.featureLister.List(sel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get NodeFeature resources for node %q: %w", nodeName, err)
+	}
+
+	objs := make([]*nfdv1alpha1.NodeFeature, 0, len(all))
+	for _, o := range all {
+		if !isFromEdgeCluster(o) {
+			objs = append(objs, o)
+		}
+	}
+	sortNodeFeatures(objs, m.namespace)
+	return objs, nil
+}
+
+// mergeNodeFeatures merges the (sorted) NodeFeature objects of a node into
+// one NodeFeatureSpec.
+func (m *nfdMaster) mergeNodeFeatures(objs []*nfdv1alpha1.NodeFeature) *nfdv1alpha1.NodeFeatureSpec {
+	if len(objs) == 0 {
+		return nfdv1alpha1.NewNodeFeatureSpec()
+	}
+
+	// NOTE: changing the rule api to support handle multiple objects instead
+	// of merging would probably perform better with lot less data to copy.
+	features := objs[0].Spec.DeepCopy()
+	if m.config.AutoDefaultNs {
+		features.Labels = addNsToMapKeys(features.Labels, nfdv1alpha1.FeatureLabelNs)
+	}
+	for _, o := range objs[1:] {
+		s := o.Spec.DeepCopy()
+		if m.config.AutoDefaultNs {
+			s.Labels = addNsToMapKeys(s.Labels, nfdv1alpha1.FeatureLabelNs)
+		}
+		s.MergeInto(features)
+	}
+
+	klog.V(4).InfoS("merged nodeFeatureSpecs", "newNodeFeatureSpec", utils.DelayedDumper(features))
+
+	return features
+}
+
 // filterExtendedResources filters extended resources and returns a map
 // of valid extended resources.
 func (m *nfdMaster) filterExtendedResources(features *nfdv1alpha1.Features, extendedResources ExtendedResources) ExtendedResources {
@@ -802,14 +1100,27 @@ func filterExtendedResource(name, value string, features *nfdv1alpha1.Features)
 	return filteredValue, nil
 }
 
-func (m *nfdMaster) refreshNodeFeatures(cli *kubernetes.Clientset, nodeName string, labels map[string]string, features *nfdv1alpha1.Features) error {
-	if m.config.AutoDefaultNs {
-		labels = addNsToMapKeys(labels, nfdv1alpha1.FeatureLabelNs)
-	} else if labels == nil {
-		labels = make(map[string]string)
+func (m *nfdMaster) refreshNodeFeatures(ctx context.Context, cli *kubernetes.Clientset, nodeName string, labels map[string]string, features *nfdv1alpha1.Features) error {
+	labels, annotations, extendedResources, taints := m.computeNodeFeatures(nodeName, labels, features, false)
+
+	err := m.updateNodeObject(ctx, cli, nodeName, labels, annotations, extendedResources, taints)
+	if err != nil {
+		klog.ErrorS(err, "failed to update node", "nodeName", nodeName)
+		return err
 	}
 
-	crLabels, crAnnotations, crExtendedResources, crTaints := m.processNodeFeatureRule(nodeName, features)
+	return nil
+}
+
+// computeNodeFeatures determines the labels, annotations, extended resources
+// and taints that should be published for a node. With dryRun the rules are
+// evaluated without side effects, i.e. without scheduling node updates,
+// touching the rule output cache and the rule metrics, or recording feature
+// baselines.
+func (m *nfdMaster) computeNodeFeatures(nodeName string, labels map[string]string, features *nfdv1alpha1.Features, dryRun bool) (Labels, Annotations, ExtendedResources, []corev1.Taint) {
+	labels = m.setWorkerLabels(features, labels)
+
+	crLabels, crAnnotations, crExtendedResources, crTaints := m.processNodeFeatureRule(nodeName, features, dryRun)
 
 	// Mix in CR-originated labels
 	maps.Copy(labels, crLabels)
@@ -831,21 +1142,22 @@ func (m *nfdMaster) refreshNodeFeatures(cli *kubernetes.Clientset, nodeName stri
 		taints = filterTaints(crTaints)
 	}
 
-	err := m.updateNodeObject(cli, nodeName, labels, annotations, extendedResources, taints)
-	if err != nil {
-		klog.ErrorS(err, "failed to update node", "nodeName", nodeName)
-		return err
+	// Taint the node if its features deviate from the baseline
+	if taint, err := m.checkFeatureBaseline(nodeName, features, dryRun); err != nil {
+		klog.ErrorS(err, "failed to check feature baseline", "nodeName", nodeName)
+	} else if taint != nil {
+		taints = append(taints, *taint)
 	}
 
-	return nil
+	return labels, annotations, extendedResources, taints
 }
 
 // setTaints sets node taints and annotations based on the taints passed via
 // nodeFeatureRule custom resorce. If empty list of taints is passed, currently
 // NFD owned taints and annotations are removed from the node.
-func (m *nfdMaster) setTaints(cli *kubernetes.Clientset, taints []corev1.Taint, nodeName string) error {
+func (m *nfdMaster) setTaints(ctx context.Context, cli *kubernetes.Clientset, taints []corev1.Taint, nodeName string) error {
 	// Fetch the node object.
-	node, err := m.apihelper.GetNode(cli, nodeName)
+	node, err := m.apihelper.GetNode(ctx, cli, nodeName)
 	if err != nil {
 		return err
 	}
@@ -887,7 +1199,7 @@ func (m *nfdMaster) setTaints(cli *kubernetes.Clientset, taints []corev1.Taint,
 	}
 
 	if taintsUpdated {
-		err = controller.PatchNodeTaints(context.TODO(), cli, nodeName, node, newNode)
+		err = controller.PatchNodeTaints(ctx, cli, nodeName, node, newNode)
 		if err != nil {
 			return fmt.Errorf("failed to patch the node %v", node.Name)
 		}
@@ -908,7 +1220,7 @@ func (m *nfdMaster) setTaints(cli *kubernetes.Clientset, taints []corev1.Taint,
 
 	patches := createPatches([]string{nfdv1alpha1.NodeTaintsAnnotation}, node.Annotations, newAnnotations, "/metadata/annotations")
 	if len(patches) > 0 {
-		err = m.apihelper.PatchNode(cli, node.Name, patches)
+		err = m.apihelper.PatchNode(ctx, cli, node.Name, patches)
 		if err != nil {
 			return fmt.Errorf("error while patching node object: %v", err)
 		}
@@ -941,7 +1253,7 @@ func authorizeClient(c context.Context, checkNodeName bool, nodeName string) err
 	return nil
 }
 
-func (m *nfdMaster) processNodeFeatureRule(nodeName string, features *nfdv1alpha1.Features) (Labels, Annotations, ExtendedResources, []corev1.Taint) {
+func (m *nfdMaster) processNodeFeatureRule(nodeName string, features *nfdv1alpha1.Features, dryRun bool) (Labels, Annotations, ExtendedResources, []corev1.Taint) {
 	if m.nfdController == nil {
 		return nil, nil, nil, nil
 	}
@@ -971,10 +1283,8 @@ func (m *nfdMaster) processNodeFeatureRule(nodeName string, features *nfdv1alpha
 			klog.InfoS("executing NodeFeatureRule", "nodefeaturerule", klog.KObj(spec), "nodeName", nodeName)
 		}
 		for _, rule := range spec.Spec.Rules {
-			ruleOut, err := nodefeaturerule.Execute(&rule, features)
-			if err != nil {
-				klog.ErrorS(err, "failed to process rule", "ruleName", rule.Name, "nodefeaturerule", klog.KObj(spec), "nodeName", nodeName)
-				nfrProcessingErrors.Inc()
+			ruleOut, ok := m.executeRule(nodeName, spec, &rule, features, dryRun)
+			if !ok {
 				continue
 			}
 			taints = append(taints, ruleOut.Taints...)
@@ -995,24 +1305,116 @@ func (m *nfdMaster) processNodeFeatureRule(nodeName string, features *nfdv1alpha
 			features.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, ruleOut.Labels)
 			features.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, ruleOut.Vars)
 		}
-		nfrProcessingTime.WithLabelValues(spec.Name, nodeName).Observe(time.Since(t).Seconds())
+		if !dryRun {
+			nfrProcessingTime.WithLabelValues(spec.Name, nodeName).Observe(time.Since(t).Seconds())
+		}
 	}
 	processingTime := time.Since(processStart)
 	klog.V(2).InfoS("processed NodeFeatureRule objects", "nodeName", nodeName, "objectCount", len(ruleSpecs), "duration", processingTime)
 
+	// Mix in the outputs of namespaced rules, cluster-wide rules take
+	// precedence
+	nsLabels, nsAnnotations, nsExtendedResources, nsTaints := m.processNamespacedNodeFeatureRules(nodeName, features, dryRun)
+	addMissingKeys(labels, nsLabels)
+	addMissingKeys(annotations, nsAnnotations)
+	addMissingKeys(extendedResources, nsExtendedResources)
+	for _, taint := range nsTaints {
+		if !taintutils.TaintExists(taints, &taint) {
+			taints = append(taints, taint)
+		}
+	}
+
 	return labels, annotations, extendedResources, taints
 }
 
+// executeRule evaluates one rule of a NodeFeatureRule (or
+// NamespacedNodeFeatureRule) object against the features of a node. Returns
+// false if the rule produced no output, i.e. its schedule is not active or
+// the evaluation failed and no previous output was retained. With dryRun no
+// node updates are scheduled and the metrics and the rule output cache are
+// left untouched.
+func (m *nfdMaster) executeRule(nodeName string, obj metav1.Object, rule *nfdv1alpha1.Rule, features *nfdv1alpha1.Features, dryRun bool) (nodefeaturerule.RuleOutput, bool) {
+	objName := ruleObjName(obj)
+	if rule.Schedule != nil {
+		sched, err := m.ruleScheduleCache.get(ruleScheduleKey{nfrName: objName, ruleName: rule.Name}, rule.Schedule)
+		if err != nil {
+			if !dryRun {
+				klog.ErrorS(err, "invalid rule schedule", "ruleName", rule.Name, "nodefeaturerule", klog.KObj(obj), "nodeName", nodeName)
+				nfrProcessingErrors.Inc()
+			}
+			return nodefeaturerule.RuleOutput{}, false
+		}
+		status := sched.Status(time.Now())
+		if !dryRun {
+			nfrScheduleActive.WithLabelValues(objName, rule.Name).Set(boolToFloat(status.Active))
+			// Re-process the node when the schedule state may change
+			m.requeueNodeAt(nodeName, status.NextBoundary)
+		}
+		if !status.Active {
+			klog.V(2).InfoS("rule schedule not active, skipping", "ruleName", rule.Name, "nodefeaturerule", klog.KObj(obj), "nodeName", nodeName, "nextBoundary", status.NextBoundary)
+			return nodefeaturerule.RuleOutput{}, false
+		}
+	}
+
+	ruleOut, err := nodefeaturerule.Execute(rule, features)
+	outputKey := ruleOutputKey{nodeName: nodeName, nfrName: objName, ruleName: rule.Name}
+	if err != nil {
+		// Retain the last successful output to avoid churn caused by
+		// transient errors
+		cached, expires, ok := m.ruleOutputCache.get(outputKey, m.config.RuleErrorGracePeriod.Duration, time.Now())
+		if dryRun {
+			return cached, ok
+		}
+		klog.ErrorS(err, "failed to process rule", "ruleName", rule.Name, "nodefeaturerule", klog.KObj(obj), "nodeName", nodeName)
+		nfrProcessingErrors.Inc()
+		if !ok {
+			return nodefeaturerule.RuleOutput{}, false
+		}
+		klog.InfoS("retaining last successful output of failed rule", "ruleName", rule.Name, "nodefeaturerule", klog.KObj(obj), "nodeName", nodeName, "expires", expires)
+		nfrOutputsRetained.Inc()
+		// Re-process the node when the grace period expires so that the
+		// retained output is dropped if the rule still fails
+		m.requeueNodeAt(nodeName, expires)
+		return cached, true
+	} else if m.config.RuleErrorGracePeriod.Duration > 0 && !dryRun {
+		if ruleOut.Matched {
+			m.ruleOutputCache.store(outputKey, ruleOut, time.Now())
+		} else {
+			m.ruleOutputCache.remove(outputKey)
+		}
+	}
+	return ruleOut, true
+}
+
+// ruleObjName returns the name of a rule object, prefixed with the namespace
+// for namespaced objects.
+func ruleObjName(obj metav1.Object) string {
+	if ns := obj.GetNamespace(); ns != "" {
+		return ns + "/" + obj.GetName()
+	}
+	return obj.GetName()
+}
+
+// requeueNodeAt schedules a node to be re-processed at the given time. Has no
+// effect if the time is zero or the node updater pool is not running.
+func (m *nfdMaster) requeueNodeAt(nodeName string, t time.Time) {
+	if t.IsZero() || m.nodeUpdaterPool == nil || m.nodeUpdaterPool.queue == nil {
+		return
+	}
+	klog.V(3).InfoS("scheduling node update", "nodeName", nodeName, "time", t)
+	m.nodeUpdaterPool.queue.AddAfter(nodeName, time.Until(t))
+}
+
 // updateNodeObject ensures the Kubernetes node object is up to date,
 // creating new labels and extended resources where necessary and removing
 // outdated ones. Also updates the corresponding annotations.
-func (m *nfdMaster) updateNodeObject(cli *kubernetes.Clientset, nodeName string, labels Labels, featureAnnotations Annotations, extendedResources ExtendedResources, taints []corev1.Taint) error {
+func (m *nfdMaster) updateNodeObject(ctx context.Context, cli *kubernetes.Clientset, nodeName string, labels Labels, featureAnnotations Annotations, extendedResources ExtendedResources, taints []corev1.Taint) error {
 	if cli == nil {
 		return fmt.Errorf("no client is passed, client:  %v", cli)
 	}
 
 	// Get the worker node object
-	node, err := m.apihelper.GetNode(cli, nodeName)
+	node, err := m.apihelper.GetNode(ctx, cli, nodeName)
 	if err != nil {
 		return err
 	}
@@ -1055,8 +1457,8 @@ func (m *nfdMaster) updateNodeObject(cli *kubernetes.Clientset, nodeName string,
 	}
 
 	// Create JSON patches for changes in labels and annotations
-	oldLabels := stringToNsNames(node.Annotations[m.instanceAnnotation(nfdv1alpha1.FeatureLabelsAnnotation)], nfdv1alpha1.FeatureLabelNs)
-	oldAnnotations := stringToNsNames(node.Annotations[m.instanceAnnotation(nfdv1alpha1.FeatureAnnotationsTrackingAnnotation)], nfdv1alpha1.FeatureAnnotationNs)
+	oldLabels := nfdv1alpha1.ParseNsNames(node.Annotations[m.instanceAnnotation(nfdv1alpha1.FeatureLabelsAnnotation)], nfdv1alpha1.FeatureLabelNs)
+	oldAnnotations := nfdv1alpha1.ParseNsNames(node.Annotations[m.instanceAnnotation(nfdv1alpha1.FeatureAnnotationsTrackingAnnotation)], nfdv1alpha1.FeatureAnnotationNs)
 	patches := createPatches(oldLabels, node.Labels, labels, "/metadata/labels")
 	oldAnnotations = append(oldAnnotations, []string{
 		m.instanceAnnotation(nfdv1alpha1.FeatureLabelsAnnotation),
@@ -1069,13 +1471,13 @@ func (m *nfdMaster) updateNodeObject(cli *kubernetes.Clientset, nodeName string,
 
 	// patch node status with extended resource changes
 	statusPatches := m.createExtendedResourcePatches(node, extendedResources)
-	err = m.apihelper.PatchNodeStatus(cli, node.Name, statusPatches)
+	err = m.apihelper.PatchNodeStatus(ctx, cli, node.Name, statusPatches)
 	if err != nil {
 		return fmt.Errorf("error while patching extended resources: %v", err)
 	}
 
 	// Patch the node object in the apiserver
-	err = m.apihelper.PatchNode(cli, node.Name, patches)
+	err = m.apihelper.PatchNode(ctx, cli, node.Name, patches)
 	if err != nil {
 		return fmt.Errorf("error while patching node object: %v", err)
 	}
@@ -1088,7 +1490,7 @@ func (m *nfdMaster) updateNodeObject(cli *kubernetes.Clientset, nodeName string,
 	}
 
 	// Set taints
-	err = m.setTaints(cli, taints, node.Name)
+	err = m.setTaints(ctx, cli, taints, node.Name)
 	if err != nil {
 		return err
 	}
@@ -1137,7 +1539,7 @@ func (m *nfdMaster) createExtendedResourcePatches(n *corev1.Node, extendedResour
 	patches := []apihelper.JsonPatch{}
 
 	// Form a list of namespaced resource names managed by us
-	oldResources := stringToNsNames(n.Annotations[m.instanceAnnotation(nfdv1alpha1.ExtendedResourceAnnotation)], nfdv1alpha1.FeatureLabelNs)
+	oldResources := nfdv1alpha1.ParseNsNames(n.Annotations[m.instanceAnnotation(nfdv1alpha1.ExtendedResourceAnnotation)], nfdv1alpha1.FeatureLabelNs)
 
 	// figure out which resources to remove
 	for _, resource := range oldResources {
@@ -1170,6 +1572,56 @@ func (m *nfdMaster) createExtendedResourcePatches(n *corev1.Node, extendedResour
 
 // Parse configuration options
 func (m *nfdMaster) configure(filepath string, overrides string) error {
+	c, err := m.loadConfig(filepath, overrides)
+	if err != nil {
+		return err
+	}
+
+	m.config = c
+
+	if err := klogutils.MergeKlogConfiguration(m.args.Klog, c.Klog); err != nil {
+		return err
+	}
+
+	if !c.NoPublish {
+		kubeconfig, err := m.getKubeconfig()
+		if err != nil {
+			return err
+		}
+		m.apihelper = apihelper.K8sHelpers{Kubeconfig: kubeconfig}
+
+		cli, err := m.apihelper.GetClient()
+		if err != nil {
+			return err
+		}
+		if m.eventRecorder == nil {
+			m.eventRecorder = m.newEventRecorder(cli)
+		}
+		if len(c.FeatureBaseline.FeatureSets) > 0 && m.baselineTracker == nil {
+			m.baselineTracker = newBaselineTracker(cli, m.eventRecorder)
+		}
+	}
+
+	// Pre-process DenyLabelNS into 2 lists: one for normal ns, and the other for wildcard ns
+	normalDeniedNs, wildcardDeniedNs := preProcessDeniedNamespaces(c.DenyLabelNs)
+	m.deniedNs.normal = normalDeniedNs
+	m.deniedNs.wildcard = wildcardDeniedNs
+
+	klog.InfoS("configuration successfully updated", "configuration", utils.DelayedDumper(m.config))
+
+	return nil
+}
+
+// newEventRecorder returns a recorder for the events emitted by nfd-master.
+func (m *nfdMaster) newEventRecorder(cli kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: cli.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "nfd-master", Host: m.nodeName})
+}
+
+// loadConfig reads the configuration from a file, applies the overrides from
+// the command line and validates it.
+func (m *nfdMaster) loadConfig(filepath string, overrides string) (*NFDConfig, error) {
 	// Create a new default config
 	c := newDefaultConfig()
 
@@ -1180,12 +1632,12 @@ func (m *nfdMaster) configure(filepath string, overrides string) error {
 			if os.IsNotExist(err) {
 				klog.InfoS("config file not found, using defaults", "path", filepath)
 			} else {
-				return fmt.Errorf("error reading config file: %w", err)
+				return nil, fmt.Errorf("error reading config file: %w", err)
 			}
 		} else {
 			err = yaml.Unmarshal(data, c)
 			if err != nil {
-				return fmt.Errorf("failed to parse config file: %w", err)
+				return nil, fmt.Errorf("failed to parse config file: %w", err)
 			}
 
 			klog.InfoS("configuration file parsed", "path", filepath)
@@ -1194,7 +1646,7 @@ func (m *nfdMaster) configure(filepath string, overrides string) error {
 
 	// Parse config overrides
 	if err := yaml.Unmarshal([]byte(overrides), c); err != nil {
-		return fmt.Errorf("failed to parse -options: %s", err)
+		return nil, fmt.Errorf("failed to parse -options: %s", err)
 	}
 	if m.args.Overrides.NoPublish != nil {
 		c.NoPublish = *m.args.Overrides.NoPublish
@@ -1222,31 +1674,106 @@ func (m *nfdMaster) configure(filepath string, overrides string) error {
 	}
 
 	if c.NfdApiParallelism <= 0 {
-		return fmt.Errorf("the maximum number of concurrent labelers should be a non-zero positive number")
+		return nil, fmt.Errorf("the maximum number of concurrent labelers should be a non-zero positive number")
+	}
+	if c.RuleErrorGracePeriod.Duration < 0 {
+		return nil, fmt.Errorf("ruleErrorGracePeriod must not be negative")
+	}
+	if c.RuleRevisionHistoryLimit < 0 {
+		return nil, fmt.Errorf("ruleRevisionHistoryLimit must not be negative")
+	}
+	if c.MassUpdateProtection.Threshold < 0 || c.MassUpdateProtection.Threshold > 1 {
+		return nil, fmt.Errorf("massUpdateProtection.threshold must be between 0 and 1")
+	}
+	if c.MassUpdateProtection.Threshold > 0 && c.MassUpdateProtection.RecheckPeriod.Duration <= 0 {
+		return nil, fmt.Errorf("massUpdateProtection.recheckPeriod must be a positive duration")
 	}
 
-	m.config = c
+	if err := c.NamespacedRules.validate(); err != nil {
+		return nil, err
+	}
+	for _, p := range c.FeatureCache.RetainFeatures {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q in featureCache.retainFeatures: %w", p, err)
+		}
+	}
+	if c.FeatureCache.RebuildTimeout.Duration <= 0 {
+		return nil, fmt.Errorf("featureCache.rebuildTimeout must be a positive duration")
+	}
+	if c.FeatureCache.StripUnusedFeatures && c.Hub.Mode != "" {
+		return nil, fmt.Errorf("featureCache.stripUnusedFeatures cannot be used in %q hub mode", c.Hub.Mode)
+	}
+	for _, name := range c.FeatureBaseline.FeatureSets {
+		if split := strings.Split(name, "."); len(split) != 2 || split[0] == "" || split[1] == "" {
+			return nil, fmt.Errorf("invalid feature set %q in featureBaseline.featureSets, must be in the form <domain>.<feature>", name)
+		}
+	}
+	if len(c.FeatureBaseline.FeatureSets) > 0 && c.Hub.Mode == hubModeHub {
+		return nil, fmt.Errorf("featureBaseline cannot be used in %q hub mode", hubModeHub)
+	}
+	switch c.FeatureBaseline.TaintEffect {
+	case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		return nil, fmt.Errorf("invalid featureBaseline.taintEffect %q", c.FeatureBaseline.TaintEffect)
+	}
 
-	if err := klogutils.MergeKlogConfiguration(m.args.Klog, c.Klog); err != nil {
-		return err
+	if c.GrpcServer.MaxRecvMsgSize <= 0 {
+		return nil, fmt.Errorf("grpcServer.maxRecvMsgSize must be a positive number")
+	}
+	if c.GrpcServer.KeepaliveMinTime.Duration < 0 {
+		return nil, fmt.Errorf("grpcServer.keepaliveMinTime must not be negative")
+	}
+	if c.GrpcServer.MaxConnectionIdle.Duration < 0 {
+		return nil, fmt.Errorf("grpcServer.maxConnectionIdle must not be negative")
+	}
+	if c.GrpcServer.RequestTimeout.Duration < 0 {
+		return nil, fmt.Errorf("grpcServer.requestTimeout must not be negative")
+	}
+	if c.GrpcServer.RateLimit < 0 {
+		return nil, fmt.Errorf("grpcServer.rateLimit must not be negative")
+	}
+	if c.GrpcServer.RateLimit > 0 && c.GrpcServer.RateLimitBurst <= 0 {
+		return nil, fmt.Errorf("grpcServer.rateLimitBurst must be a positive number when rate limiting is enabled")
+	}
+	if c.GrpcServer.MaxConcurrentUpdates < 0 {
+		return nil, fmt.Errorf("grpcServer.maxConcurrentUpdates must not be negative")
 	}
 
-	if !c.NoPublish {
-		kubeconfig, err := m.getKubeconfig()
-		if err != nil {
-			return err
+	switch c.Hub.Mode {
+	case "":
+	case hubModeEdge:
+		if c.Hub.ClusterName == "" {
+			return nil, fmt.Errorf("hub.clusterName must be specified in %q hub mode", hubModeEdge)
 		}
-		m.apihelper = apihelper.K8sHelpers{Kubeconfig: kubeconfig}
+		if c.Hub.SyncPeriod.Duration <= 0 {
+			return nil, fmt.Errorf("hub.syncPeriod must be a positive duration")
+		}
+	case hubModeHub:
+		if !m.args.EnableNodeFeatureApi || !m.args.CrdController {
+			return nil, fmt.Errorf("%q hub mode requires the NodeFeature API and the CRD controller to be enabled", hubModeHub)
+		}
+	default:
+		return nil, fmt.Errorf("invalid hub.mode %q, must be one of %q or %q", c.Hub.Mode, hubModeEdge, hubModeHub)
 	}
 
-	// Pre-process DenyLabelNS into 2 lists: one for normal ns, and the other for wildcard ns
-	normalDeniedNs, wildcardDeniedNs := preProcessDeniedNamespaces(c.DenyLabelNs)
-	m.deniedNs.normal = normalDeniedNs
-	m.deniedNs.wildcard = wildcardDeniedNs
-
-	klog.InfoS("configuration successfully updated", "configuration", utils.DelayedDumper(m.config))
+	return c, nil
+}
 
-	return nil
+// setWorkerLabels makes the labels published by nfd-worker available to rules
+// as the worker.labels feature. The default namespace is added to unprefixed
+// label names if autoDefaultNs is enabled. Returns the (prefixed) labels,
+// never nil. Features are not always present in requests of the deprecated
+// gRPC API.
+func (m *nfdMaster) setWorkerLabels(features *nfdv1alpha1.Features, labels map[string]string) map[string]string {
+	if m.config.AutoDefaultNs {
+		labels = addNsToMapKeys(labels, nfdv1alpha1.FeatureLabelNs)
+	} else if labels == nil {
+		labels = make(map[string]string)
+	}
+	if features != nil {
+		features.SetWorkerLabels(labels)
+	}
+	return labels
 }
 
 // addNsToMapKeys creates a copy of a map with the namespace (prefix) added to
@@ -1286,20 +1813,6 @@ func splitNs(fullname string) (string, string) {
 	return "", fullname
 }
 
-// stringToNsNames is a helper for converting a string of comma-separated names
-// into a slice of fully namespaced names
-func stringToNsNames(cslist, ns string) []string {
-	var names []string
-	if cslist != "" {
-		names = strings.Split(cslist, ",")
-		for i, name := range names {
-			// Expect that names may omit the ns part
-			names[i] = addNs(name, ns)
-		}
-	}
-	return names
-}
-
 // Seperate denied namespaces into two lists:
 // one contains wildcard namespaces the other contains normal namespaces
 func preProcessDeniedNamespaces(deniedNs map[string]struct{}) (normalDeniedNs map[string]struct{}, wildcardDeniedNs map[string]struct{}) {
@@ -1317,10 +1830,7 @@ func preProcessDeniedNamespaces(deniedNs map[string]struct{}) (normalDeniedNs ma
 }
 
 func (m *nfdMaster) instanceAnnotation(name string) string {
-	if m.args.Instance == "" {
-		return name
-	}
-	return m.args.Instance + "." + name
+	return nfdv1alpha1.InstanceAnnotation(m.args.Instance, name)
 }
 
 func (m *nfdMaster) startNfdApiController() error {
@@ -1330,8 +1840,17 @@ func (m *nfdMaster) startNfdApiController() error {
 	}
 	klog.InfoS("starting the nfd api controller")
 	m.nfdController, err = newNfdController(kubeconfig, nfdApiControllerOptions{
-		DisableNodeFeature: !m.args.EnableNodeFeatureApi,
-		ResyncPeriod:       m.config.ResyncPeriod.Duration,
+		DisableNodeFeature:         !m.args.EnableNodeFeatureApi,
+		ResyncPeriod:               m.config.ResyncPeriod.Duration,
+		HubMode:                    m.config.Hub.Mode == hubModeHub,
+		NamespacedRules:            m.config.NamespacedRules.Enable,
+		StripUnusedFeatures:        m.config.FeatureCache.StripUnusedFeatures,
+		FeatureCacheRebuildTimeout: m.config.FeatureCache.RebuildTimeout.Duration,
+		// Features pinned in baselines must not be stripped from the cache
+		RetainFeatures:    append(append([]string{}, m.config.FeatureCache.RetainFeatures...), m.config.FeatureBaseline.FeatureSets...),
+		FeatureBaseline:   len(m.config.FeatureBaseline.FeatureSets) > 0,
+		BaselineNamespace: m.namespace,
+		RuleDeleted:       m.nfdRuleDeleted,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to initialize CRD controller: %w", err)
@@ -1339,6 +1858,50 @@ func (m *nfdMaster) startNfdApiController() error {
 	return nil
 }
 
+// nfdRuleDeleted drops the state kept for a deleted NodeFeatureRule or
+// NamespacedNodeFeatureRule.
+func (m *nfdMaster) nfdRuleDeleted(obj metav1.Object) {
+	objName := ruleObjName(obj)
+	m.ruleOutputCache.removeRule(objName)
+	m.ruleScheduleCache.removeRule(objName)
+	nfrScheduleActive.DeletePartialMatch(prometheus.Labels{"name": objName})
+}
+
+// pruneRuleSchedules drops the parsed schedules and the schedule metrics of
+// rules that have been removed or no longer have a schedule.
+func (m *nfdMaster) pruneRuleSchedules() {
+	keep := make(map[ruleScheduleKey]struct{})
+	addRules := func(obj metav1.Object, rules []nfdv1alpha1.Rule) {
+		for _, rule := range rules {
+			if rule.Schedule != nil {
+				keep[ruleScheduleKey{nfrName: ruleObjName(obj), ruleName: rule.Name}] = struct{}{}
+			}
+		}
+	}
+	nfrs, err := m.nfdController.ruleLister.List(k8sLabels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "failed to list NodeFeatureRule resources")
+		return
+	}
+	for _, nfr := range nfrs {
+		addRules(nfr, nfr.Spec.Rules)
+	}
+	if m.nfdController.nsRuleLister != nil {
+		nnfrs, err := m.nfdController.nsRuleLister.List(k8sLabels.Everything())
+		if err != nil {
+			klog.ErrorS(err, "failed to list NamespacedNodeFeatureRule resources")
+			return
+		}
+		for _, nnfr := range nnfrs {
+			addRules(nnfr, nnfr.Spec.Rules)
+		}
+	}
+
+	for _, k := range m.ruleScheduleCache.prune(keep) {
+		nfrScheduleActive.DeleteLabelValues(k.nfrName, k.ruleName)
+	}
+}
+
 func (m *nfdMaster) nfdAPIUpdateHandlerWithLeaderElection() {
 	ctx := context.Background()
 	client, err := m.apihelper.GetClient()
@@ -84,9 +84,9 @@ func TestRunNodeUpdater(t *testing.T) {
 	metadataPatches := []apihelper.JsonPatch{}
 
 	mockAPIHelper.On("GetClient").Return(mockClient, nil)
-	mockAPIHelper.On("GetNode", mockClient, mockNodeName).Return(mockNode, nil)
-	mockAPIHelper.On("PatchNodeStatus", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(statusPatches))).Return(nil)
-	mockAPIHelper.On("PatchNode", mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(metadataPatches))).Return(nil)
+	mockAPIHelper.On("GetNode", mock.Anything, mockClient, mockNodeName).Return(mockNode, nil)
+	mockAPIHelper.On("PatchNodeStatus", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(statusPatches))).Return(nil)
+	mockAPIHelper.On("PatchNode", mock.Anything, mockClient, mockNodeName, mock.MatchedBy(jsonPatchMatcher(metadataPatches))).Return(nil)
 
 	mockNodeUpdaterPool.start(10)
 	Convey("Queue has no element", t, func() {
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdlisters "sigs.k8s.io/node-feature-discovery/pkg/generated/listers/nfd/v1alpha1"
+)
+
+// RuleEvaluator computes the labels and taints nfd-master would publish for
+// nodes from a given set of NodeFeature, NodeFeatureRule and
+// NamespacedNodeFeatureRule objects. The objects are processed exactly like
+// in nfd-master, with its configuration, but without access to the cluster
+// and without any side effects. Used for simulations in kubectl-nfd.
+type RuleEvaluator struct {
+	m *nfdMaster
+}
+
+// RuleEvaluatorObjects are the API objects evaluated by a RuleEvaluator.
+type RuleEvaluatorObjects struct {
+	NodeFeatures               []*nfdv1alpha1.NodeFeature
+	NodeFeatureRules           []*nfdv1alpha1.NodeFeatureRule
+	NamespacedNodeFeatureRules []*nfdv1alpha1.NamespacedNodeFeatureRule
+}
+
+// NewRuleEvaluator creates a new RuleEvaluator. The nfd-master configuration
+// is read from configFile, the defaults are used if it is empty. namespace is
+// the namespace of nfd-master, the NodeFeature objects in it take precedence
+// when the features of a node are merged. NamespacedNodeFeatureRule objects
+// are ignored unless enabled in the configuration.
+func NewRuleEvaluator(configFile, namespace string, objs RuleEvaluatorObjects) (*RuleEvaluator, error) {
+	m := &nfdMaster{
+		args:              Args{EnableNodeFeatureApi: true, CrdController: true},
+		namespace:         namespace,
+		ruleOutputCache:   newRuleOutputCache(),
+		ruleScheduleCache: newRuleScheduleCache(),
+	}
+	config, err := m.loadConfig(configFile, "")
+	if err != nil {
+		return nil, err
+	}
+	m.config = config
+	m.deniedNs.normal, m.deniedNs.wildcard = preProcessDeniedNamespaces(config.DenyLabelNs)
+
+	featureIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, o := range objs.NodeFeatures {
+		if err := featureIndexer.Add(o); err != nil {
+			return nil, err
+		}
+	}
+	ruleIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, o := range objs.NodeFeatureRules {
+		if err := ruleIndexer.Add(o); err != nil {
+			return nil, err
+		}
+	}
+	m.nfdController = &nfdController{
+		featureLister: nfdlisters.NewNodeFeatureLister(featureIndexer),
+		ruleLister:    nfdlisters.NewNodeFeatureRuleLister(ruleIndexer),
+	}
+	if config.NamespacedRules.Enable {
+		nsRuleIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		for _, o := range objs.NamespacedNodeFeatureRules {
+			if err := nsRuleIndexer.Add(o); err != nil {
+				return nil, err
+			}
+		}
+		m.nfdController.nsRuleLister = nfdlisters.NewNamespacedNodeFeatureRuleLister(nsRuleIndexer)
+	}
+
+	return &RuleEvaluator{m: m}, nil
+}
+
+// Evaluate returns the labels and taints that nfd-master would publish for a
+// node.
+func (e *RuleEvaluator) Evaluate(nodeName string) (map[string]string, []corev1.Taint, error) {
+	objs, err := e.m.listNodeFeatures(nodeName)
+	if err != nil {
+		return nil, nil, err
+	}
+	features := e.m.mergeNodeFeatures(objs)
+	labels, _, _, taints := e.m.computeNodeFeatures(nodeName, features.Labels, &features.Features, true)
+	return labels, taints, nil
+}
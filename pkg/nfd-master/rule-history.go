@@ -0,0 +1,250 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+// recordRuleRevisions records a new revision of each NodeFeatureRule whose
+// spec differs from the latest recorded revision. Revisions are stored as
+// ControllerRevision objects in the namespace of nfd-master, annotated with
+// the full name of the NodeFeatureRule, the time the revision was observed
+// and the number of nodes the rule matched. At most RuleRevisionHistoryLimit
+// revisions are kept per NodeFeatureRule. The revisions of deleted
+// NodeFeatureRules are deleted.
+func (m *nfdMaster) recordRuleRevisions(cli kubernetes.Interface) error {
+	limit := m.config.RuleRevisionHistoryLimit
+	if limit <= 0 || m.nfdController == nil || m.nfdController.ruleLister == nil {
+		return nil
+	}
+
+	rules, err := m.nfdController.ruleLister.List(k8sLabels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list NodeFeatureRule resources: %w", err)
+	}
+
+	revList, err := cli.AppsV1().ControllerRevisions(m.namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: nfdv1alpha1.NodeFeatureRuleNameLabel})
+	if err != nil {
+		return fmt.Errorf("failed to list NodeFeatureRule revisions: %w", err)
+	}
+	history := make(map[string][]*appsv1.ControllerRevision)
+	for i := range revList.Items {
+		rev := &revList.Items[i]
+		history[revisionRuleName(rev)] = append(history[revisionRuleName(rev)], rev)
+	}
+
+	var nodeFeatures map[string]*nfdv1alpha1.Features
+	var errs []error
+	for _, nfr := range rules {
+		revs := history[nfr.Name]
+		delete(history, nfr.Name)
+
+		data, err := json.Marshal(nfr.Spec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to serialize NodeFeatureRule %q: %w", nfr.Name, err))
+			continue
+		}
+		revName := ruleRevisionName(nfr.Name, data)
+
+		sort.Slice(revs, func(i, j int) bool { return revs[i].Revision < revs[j].Revision })
+
+		var latest int64
+		var existing *appsv1.ControllerRevision
+		if len(revs) > 0 {
+			latest = revs[len(revs)-1].Revision
+			if revs[len(revs)-1].Name == revName {
+				// No changes since the latest revision
+				continue
+			}
+		}
+		for _, rev := range revs {
+			if rev.Name == revName {
+				existing = rev
+			}
+		}
+
+		// Only evaluate node features if some rule has changed
+		if nodeFeatures == nil {
+			nodeFeatures, err = m.getAllNodeFeatures()
+			if err != nil {
+				return err
+			}
+		}
+		annotations := map[string]string{
+			nfdv1alpha1.NodeFeatureRuleNameAnnotation:       nfr.Name,
+			nfdv1alpha1.RuleRevisionAppliedAtAnnotation:     time.Now().UTC().Format(time.RFC3339),
+			nfdv1alpha1.RuleRevisionAffectedNodesAnnotation: strconv.Itoa(countMatchingNodes(nfr, nodeFeatures)),
+		}
+
+		var current *appsv1.ControllerRevision
+		if existing != nil {
+			// The spec of an older revision was restored, bump it to be
+			// the latest
+			rev := existing.DeepCopy()
+			rev.Revision = latest + 1
+			rev.Annotations = annotations
+			current, err = cli.AppsV1().ControllerRevisions(m.namespace).Update(context.TODO(), rev, metav1.UpdateOptions{})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to update revision %q of NodeFeatureRule %q: %w", rev.Name, nfr.Name, err))
+				continue
+			}
+		} else {
+			rev := &appsv1.ControllerRevision{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        revName,
+					Namespace:   m.namespace,
+					Labels:      map[string]string{nfdv1alpha1.NodeFeatureRuleNameLabel: nfdv1alpha1.NodeFeatureRuleNameLabelValue(nfr.Name)},
+					Annotations: annotations,
+				},
+				Data:     runtime.RawExtension{Raw: data},
+				Revision: latest + 1,
+			}
+			current, err = cli.AppsV1().ControllerRevisions(m.namespace).Create(context.TODO(), rev, metav1.CreateOptions{})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to create revision of NodeFeatureRule %q: %w", nfr.Name, err))
+				continue
+			}
+		}
+		klog.InfoS("recorded NodeFeatureRule revision", "nodefeaturerule", klog.KObj(nfr), "revision", current.Revision, "affectedNodes", annotations[nfdv1alpha1.RuleRevisionAffectedNodesAnnotation])
+
+		// Drop the oldest revisions exceeding the limit
+		revs = append(removeRevision(revs, current.Name), current)
+		for _, rev := range revs[:max(0, len(revs)-limit)] {
+			if err := cli.AppsV1().ControllerRevisions(m.namespace).Delete(context.TODO(), rev.Name, metav1.DeleteOptions{}); err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete revision %q of NodeFeatureRule %q: %w", rev.Name, nfr.Name, err))
+			}
+		}
+	}
+
+	// The remaining revisions belong to deleted NodeFeatureRules
+	for nfrName, revs := range history {
+		for _, rev := range revs {
+			if err := cli.AppsV1().ControllerRevisions(m.namespace).Delete(context.TODO(), rev.Name, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("failed to delete revision %q of deleted NodeFeatureRule %q: %w", rev.Name, nfrName, err))
+			}
+		}
+		klog.InfoS("deleted revisions of deleted NodeFeatureRule", "nodefeaturerule", nfrName, "revisionCount", len(revs))
+	}
+	return errors.Join(errs...)
+}
+
+// getAllNodeFeatures returns the merged features of all nodes, indexed by
+// node name.
+func (m *nfdMaster) getAllNodeFeatures() (map[string]*nfdv1alpha1.Features, error) {
+	ret := make(map[string]*nfdv1alpha1.Features)
+	if m.nfdController.featureLister == nil {
+		return ret, nil
+	}
+
+	objs, err := m.nfdController.featureLister.List(k8sLabels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NodeFeature resources: %w", err)
+	}
+	byNode := make(map[string][]*nfdv1alpha1.NodeFeature)
+	for _, obj := range objs {
+		if isFromEdgeCluster(obj) {
+			continue
+		}
+		if nodeName, err := getNodeNameForObj(obj); err == nil {
+			byNode[nodeName] = append(byNode[nodeName], obj)
+		}
+	}
+	for nodeName, nodeObjs := range byNode {
+		sortNodeFeatures(nodeObjs, m.namespace)
+		spec := m.mergeNodeFeatures(nodeObjs)
+		m.setWorkerLabels(&spec.Features, spec.Labels)
+		ret[nodeName] = &spec.Features
+	}
+	return ret, nil
+}
+
+// countMatchingNodes returns the number of nodes that at least one rule of a
+// NodeFeatureRule matches. Schedules of the rules are not taken into account.
+func countMatchingNodes(nfr *nfdv1alpha1.NodeFeatureRule, nodeFeatures map[string]*nfdv1alpha1.Features) int {
+	n := 0
+	for _, nodeFeatures := range nodeFeatures {
+		features := nodeFeatures.DeepCopy()
+		matched := false
+		for _, rule := range nfr.Spec.Rules {
+			ruleOut, err := nodefeaturerule.Execute(&rule, features)
+			if err != nil {
+				continue
+			}
+			matched = matched || ruleOut.Matched
+			features.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, ruleOut.Labels)
+			features.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, ruleOut.Vars)
+		}
+		if matched {
+			n++
+		}
+	}
+	return n
+}
+
+// ruleRevisionName returns the name of the ControllerRevision object storing
+// a NodeFeatureRule spec.
+func ruleRevisionName(nfrName string, data []byte) string {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])[:10]
+	if maxLen := validation.DNS1123SubdomainMaxLength - len(hash) - 1; len(nfrName) > maxLen {
+		// The name must not end with a separator before the hash
+		nfrName = strings.TrimRight(nfrName[:maxLen], "-.")
+	}
+	return nfrName + "-" + hash
+}
+
+// revisionRuleName returns the name of the NodeFeatureRule that a revision
+// belongs to. The label value is truncated for long names so the annotation
+// takes precedence.
+func revisionRuleName(rev *appsv1.ControllerRevision) string {
+	if name, ok := rev.Annotations[nfdv1alpha1.NodeFeatureRuleNameAnnotation]; ok {
+		return name
+	}
+	return rev.Labels[nfdv1alpha1.NodeFeatureRuleNameLabel]
+}
+
+func removeRevision(revs []*appsv1.ControllerRevision, name string) []*appsv1.ControllerRevision {
+	out := make([]*appsv1.ControllerRevision, 0, len(revs))
+	for _, rev := range revs {
+		if rev.Name != name {
+			out = append(out, rev)
+		}
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"sync"
+	"time"
+
+	"sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+// ruleOutputKey identifies the output of one rule for one node.
+type ruleOutputKey struct {
+	nodeName string
+	nfrName  string
+	ruleName string
+}
+
+type cachedRuleOutput struct {
+	output    nodefeaturerule.RuleOutput
+	timestamp time.Time
+}
+
+// ruleOutputCache stores the last successful output of rules, used for
+// retaining the output of rules that fail to evaluate. A nil cache is valid
+// and stores nothing.
+type ruleOutputCache struct {
+	sync.Mutex
+	entries map[ruleOutputKey]cachedRuleOutput
+}
+
+func newRuleOutputCache() *ruleOutputCache {
+	return &ruleOutputCache{entries: make(map[ruleOutputKey]cachedRuleOutput)}
+}
+
+// store saves the output of a rule that evaluated successfully and matched.
+func (c *ruleOutputCache) store(key ruleOutputKey, output nodefeaturerule.RuleOutput, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.entries[key] = cachedRuleOutput{output: output, timestamp: now}
+}
+
+// remove drops the saved output of a rule, e.g. when the rule evaluated
+// successfully but did not match.
+func (c *ruleOutputCache) remove(key ruleOutputKey) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	delete(c.entries, key)
+}
+
+// get returns the saved output of a rule and the time it expires, if it is
+// younger than maxAge. Expired entries are dropped.
+func (c *ruleOutputCache) get(key ruleOutputKey, maxAge time.Duration, now time.Time) (nodefeaturerule.RuleOutput, time.Time, bool) {
+	if c == nil {
+		return nodefeaturerule.RuleOutput{}, time.Time{}, false
+	}
+	c.Lock()
+	defer c.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nodefeaturerule.RuleOutput{}, time.Time{}, false
+	}
+	expires := e.timestamp.Add(maxAge)
+	if !now.Before(expires) {
+		delete(c.entries, key)
+		return nodefeaturerule.RuleOutput{}, time.Time{}, false
+	}
+	return e.output, expires, true
+}
+
+// removeNode drops all saved rule outputs of a node.
+func (c *ruleOutputCache) removeNode(nodeName string) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	for k := range c.entries {
+		if k.nodeName == nodeName {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// removeRule drops all saved outputs of a NodeFeatureRule (or
+// NamespacedNodeFeatureRule).
+func (c *ruleOutputCache) removeRule(nfrName string) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	for k := range c.entries {
+		if k.nfrName == nfrName {
+			delete(c.entries, k)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"sync"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+// ruleScheduleKey identifies the schedule of one rule.
+type ruleScheduleKey struct {
+	nfrName  string
+	ruleName string
+}
+
+type cachedRuleSchedule struct {
+	// spec is the schedule the entry was parsed from. Objects from the
+	// informer caches are never modified in place, so a different pointer
+	// means a new version of the rule.
+	spec  *nfdv1alpha1.RuleSchedule
+	sched *nodefeaturerule.Schedule
+	err   error
+}
+
+// ruleScheduleCache stores the parsed schedules of rules so that they are
+// parsed only once per version of the rule. A nil cache is valid and parses
+// the schedule on every lookup.
+type ruleScheduleCache struct {
+	sync.Mutex
+	entries map[ruleScheduleKey]cachedRuleSchedule
+}
+
+func newRuleScheduleCache() *ruleScheduleCache {
+	return &ruleScheduleCache{entries: make(map[ruleScheduleKey]cachedRuleSchedule)}
+}
+
+// get returns the parsed schedule of a rule, parsing it if the rule has
+// changed since the previous lookup.
+func (c *ruleScheduleCache) get(key ruleScheduleKey, spec *nfdv1alpha1.RuleSchedule) (*nodefeaturerule.Schedule, error) {
+	if c == nil {
+		return nodefeaturerule.ParseSchedule(spec)
+	}
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.entries[key]; ok && e.spec == spec {
+		return e.sched, e.err
+	}
+	sched, err := nodefeaturerule.ParseSchedule(spec)
+	c.entries[key] = cachedRuleSchedule{spec: spec, sched: sched, err: err}
+	return sched, err
+}
+
+// removeRule drops all schedules of a NodeFeatureRule (or
+// NamespacedNodeFeatureRule).
+func (c *ruleScheduleCache) removeRule(nfrName string) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	for k := range c.entries {
+		if k.nfrName == nfrName {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// prune drops the schedules of rules not in keep, i.e. rules that have been
+// removed or no longer have a schedule. Returns the keys of the dropped
+// schedules.
+func (c *ruleScheduleCache) prune(keep map[ruleScheduleKey]struct{}) []ruleScheduleKey {
+	if c == nil {
+		return nil
+	}
+	c.Lock()
+	defer c.Unlock()
+	var removed []ruleScheduleKey
+	for k := range c.entries {
+		if _, ok := keep[k]; !ok {
+			delete(c.entries, k)
+			removed = append(removed, k)
+		}
+	}
+	return removed
+}
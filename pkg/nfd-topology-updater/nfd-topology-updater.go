@@ -32,6 +32,7 @@ import (
 	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
 	"sigs.k8s.io/node-feature-discovery/pkg/apihelper"
 	"sigs.k8s.io/node-feature-discovery/pkg/nfd-topology-updater/kubeletnotifier"
+	"sigs.k8s.io/node-feature-discovery/pkg/nrtclient"
 	"sigs.k8s.io/node-feature-discovery/pkg/podres"
 	"sigs.k8s.io/node-feature-discovery/pkg/resourcemonitor"
 	"sigs.k8s.io/node-feature-discovery/pkg/topologypolicy"
@@ -80,6 +81,7 @@ type nfdTopologyUpdater struct {
 	configFilePath      string
 	config              *NFDConfig
 	kubeletConfigFunc   func() (*kubeletconfigv1beta1.KubeletConfiguration, error)
+	nrtClient           nrtclient.Interface
 }
 
 // NewTopologyUpdater creates a new NfdTopologyUpdater instance.
@@ -215,13 +217,36 @@ func (w *nfdTopologyUpdater) Stop() {
 	}
 }
 
-func (w *nfdTopologyUpdater) updateNodeResourceTopology(zoneInfo v1alpha2.ZoneList, scanResponse resourcemonitor.ScanResponse, readKubeletConfig bool) error {
+// getNRTClient returns a client for NodeResourceTopology objects. The API
+// version is re-negotiated if renegotiate is true so that NodeResourceTopology
+// CRD upgrades are picked up.
+func (w *nfdTopologyUpdater) getNRTClient(renegotiate bool) (nrtclient.Interface, error) {
+	if w.nrtClient != nil && !renegotiate {
+		return w.nrtClient, nil
+	}
+
 	cli, err := w.apihelper.GetTopologyClient()
+	if err != nil {
+		return nil, err
+	}
+	nrtClient, err := nrtclient.New(cli)
+	if err != nil {
+		return nil, fmt.Errorf("failed to negotiate NodeResourceTopology API version: %w", err)
+	}
+	if w.nrtClient == nil || w.nrtClient.Version() != nrtClient.Version() {
+		klog.InfoS("using NodeResourceTopology API version", "version", nrtClient.Version())
+	}
+	w.nrtClient = nrtClient
+	return nrtClient, nil
+}
+
+func (w *nfdTopologyUpdater) updateNodeResourceTopology(zoneInfo v1alpha2.ZoneList, scanResponse resourcemonitor.ScanResponse, readKubeletConfig bool) error {
+	cli, err := w.getNRTClient(readKubeletConfig)
 	if err != nil {
 		return err
 	}
 
-	nrt, err := cli.TopologyV1alpha2().NodeResourceTopologies().Get(context.TODO(), w.nodeName, metav1.GetOptions{})
+	nrt, err := cli.Get(context.TODO(), w.nodeName, metav1.GetOptions{})
 	if errors.IsNotFound(err) {
 		nrtNew := v1alpha2.NodeResourceTopology{
 			ObjectMeta: metav1.ObjectMeta{
@@ -237,7 +262,7 @@ func (w *nfdTopologyUpdater) updateNodeResourceTopology(zoneInfo v1alpha2.ZoneLi
 
 		updateAttributes(&nrtNew.Attributes, scanResponse.Attributes)
 
-		if _, err := cli.TopologyV1alpha2().NodeResourceTopologies().Create(context.TODO(), &nrtNew, metav1.CreateOptions{}); err != nil {
+		if _, err := cli.Create(context.TODO(), &nrtNew, metav1.CreateOptions{}); err != nil {
 			return fmt.Errorf("failed to create NodeResourceTopology: %w", err)
 		}
 		return nil
@@ -258,7 +283,7 @@ func (w *nfdTopologyUpdater) updateNodeResourceTopology(zoneInfo v1alpha2.ZoneLi
 
 	updateAttributes(&nrtMutated.Attributes, attributes)
 
-	nrtUpdated, err := cli.TopologyV1alpha2().NodeResourceTopologies().Update(context.TODO(), nrtMutated, metav1.UpdateOptions{})
+	nrtUpdated, err := cli.Update(context.TODO(), nrtMutated, metav1.UpdateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to update NodeResourceTopology: %w", err)
 	}
@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/node-feature-discovery/source"
+)
+
+var (
+	// bootIDPath is the file containing the boot ID of the host
+	bootIDPath = "/proc/sys/kernel/random/boot_id"
+	// kernelReleasePath is the file containing the kernel version of the host
+	kernelReleasePath = "/proc/sys/kernel/osrelease"
+)
+
+// staticFeatureCache tracks the validity of the features of static feature
+// sources between discovery rounds. The features themselves are held by the
+// sources. The cached features of a source are valid as long as the boot ID,
+// the kernel version and the entries of the hotplug directories of the source
+// are unchanged.
+type staticFeatureCache struct {
+	// keys contains the cache key of the last successful discovery, per
+	// source name
+	keys map[string]string
+}
+
+func newStaticFeatureCache() *staticFeatureCache {
+	return &staticFeatureCache{keys: make(map[string]string)}
+}
+
+// lookup returns the current cache key of a source and whether the features
+// discovered previously are still valid. Sources that do not implement the
+// StaticFeatureSource interface are never cached.
+func (c *staticFeatureCache) lookup(s source.FeatureSource) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	static, ok := s.(source.StaticFeatureSource)
+	if !ok {
+		return "", false
+	}
+	key, err := staticCacheKey(static.HotplugDirs())
+	if err != nil {
+		return "", false
+	}
+	prev, ok := c.keys[s.Name()]
+	return key, ok && prev == key
+}
+
+// store records the cache key of a successful discovery of a source.
+func (c *staticFeatureCache) store(s source.FeatureSource, key string) {
+	if c != nil && key != "" {
+		c.keys[s.Name()] = key
+	}
+}
+
+// invalidate drops the cache entry of a source.
+func (c *staticFeatureCache) invalidate(s source.FeatureSource) {
+	if c != nil {
+		delete(c.keys, s.Name())
+	}
+}
+
+// staticCacheKey returns a key identifying the boot, the kernel version and the
+// devices present in the given hotplug directories.
+func staticCacheKey(hotplugDirs []string) (string, error) {
+	h := sha256.New()
+	for _, path := range []string{bootIDPath, kernelReleasePath} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\n", strings.TrimSpace(string(data)))
+	}
+	for _, dir := range hotplugDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s:", dir)
+		for _, e := range entries {
+			fmt.Fprintf(h, "%s,", e.Name())
+		}
+		fmt.Fprintln(h)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
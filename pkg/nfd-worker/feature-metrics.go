@@ -0,0 +1,245 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// featureMetricsConfig selects the features exported as info metrics.
+type featureMetricsConfig struct {
+	// Features are the exported feature sets
+	Features []featureMetric
+	// MaxSeriesPerFeature limits the number of series exported per feature
+	// set, the rest are dropped
+	MaxSeriesPerFeature int
+}
+
+// featureMetric selects one feature set to export.
+type featureMetric struct {
+	// Feature is the name of the feature set, e.g. "cpu.model"
+	Feature string
+	// Attributes are the attributes (or the elements of flag features) to
+	// export. All of them are exported if empty.
+	Attributes []string
+}
+
+var invalidMetricNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// featureMetricName returns the name of the info metric of a feature set.
+func featureMetricName(feature string) string {
+	return "nfd_feature_" + strings.ToLower(invalidMetricNameChars.ReplaceAllString(feature, "_")) + "_info"
+}
+
+// featureMetricLabelName converts an attribute name into a metric label name.
+func featureMetricLabelName(attr string) string {
+	name := invalidMetricNameChars.ReplaceAllString(attr, "_")
+	if name == "node" || strings.HasPrefix(name, "__") || (name[0] >= '0' && name[0] <= '9') {
+		name = "attr_" + name
+	}
+	return name
+}
+
+func (c *featureMetricsConfig) validate() error {
+	if c.MaxSeriesPerFeature <= 0 {
+		return fmt.Errorf("maxSeriesPerFeature must be positive")
+	}
+	names := make(map[string]string, len(c.Features))
+	for i, f := range c.Features {
+		if s := strings.Split(f.Feature, "."); len(s) != 2 || s[0] == "" || s[1] == "" {
+			return fmt.Errorf("features[%d]: invalid feature name %q, must be in the form <domain>.<feature>", i, f.Feature)
+		}
+		name := featureMetricName(f.Feature)
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("features[%d]: features %q and %q map to the same metric %q", i, other, f.Feature, name)
+		}
+		names[name] = f.Feature
+		for _, a := range f.Attributes {
+			if a == "" {
+				return fmt.Errorf("features[%d]: attribute name must not be empty", i)
+			}
+		}
+	}
+	return nil
+}
+
+// featureInfoCollector is a Prometheus collector exporting the selected
+// features as info metrics. The set of labels of each metric depends on the
+// discovered features so the metrics are re-created after each discovery
+// round, and the collector is unchecked, i.e. it does not describe its
+// metrics upfront.
+type featureInfoCollector struct {
+	sync.RWMutex
+	metrics []prometheus.Metric
+}
+
+// Describe implements prometheus.Collector.
+func (c *featureInfoCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *featureInfoCollector) Collect(ch chan<- prometheus.Metric) {
+	c.RLock()
+	defer c.RUnlock()
+	for _, m := range c.metrics {
+		ch <- m
+	}
+}
+
+// update re-creates the info metrics from the given features.
+func (c *featureInfoCollector) update(config featureMetricsConfig, nodeName string, features *nfdv1alpha1.Features) {
+	var metrics []prometheus.Metric
+	for _, f := range config.Features {
+		rows := featureInfoRows(f, features)
+		if len(rows) == 0 {
+			featureMetricsDroppedSeries.DeleteLabelValues(f.Feature)
+			continue
+		}
+		dropped := 0
+		if len(rows) > config.MaxSeriesPerFeature {
+			dropped = len(rows) - config.MaxSeriesPerFeature
+			klog.InfoS("too many series in feature info metric, dropping the rest", "feature", f.Feature, "series", len(rows), "maxSeriesPerFeature", config.MaxSeriesPerFeature)
+			rows = rows[:config.MaxSeriesPerFeature]
+		}
+		featureMetricsDroppedSeries.WithLabelValues(f.Feature).Set(float64(dropped))
+
+		// All series of a metric must have the same labels
+		attrSet := make(map[string]bool)
+		for _, r := range rows {
+			for a := range r {
+				attrSet[a] = true
+			}
+		}
+		attrs := make([]string, 0, len(attrSet))
+		for a := range attrSet {
+			attrs = append(attrs, a)
+		}
+		sort.Strings(attrs)
+		labelNames := make([]string, len(attrs))
+		seenLabels := make(map[string]bool, len(attrs))
+		for i, a := range attrs {
+			labelNames[i] = featureMetricLabelName(a)
+			for seenLabels[labelNames[i]] {
+				labelNames[i] += "_"
+			}
+			seenLabels[labelNames[i]] = true
+		}
+
+		desc := prometheus.NewDesc(featureMetricName(f.Feature),
+			fmt.Sprintf("Node feature %s discovered by nfd-worker.", f.Feature),
+			labelNames, prometheus.Labels{"node": nodeName})
+		for _, r := range rows {
+			values := make([]string, len(attrs))
+			for i, a := range attrs {
+				values[i] = r[a]
+			}
+			m, err := prometheus.NewConstMetric(desc, prometheus.GaugeValue, 1, values...)
+			if err != nil {
+				klog.ErrorS(err, "failed to create feature info metric", "feature", f.Feature)
+				continue
+			}
+			metrics = append(metrics, m)
+		}
+	}
+
+	c.Lock()
+	c.metrics = metrics
+	c.Unlock()
+}
+
+// featureInfoRows returns the series of the info metric of one feature set as
+// maps of attribute names to values. Attribute features are exported as one
+// series, and each element of flag and instance features as a separate
+// series. Duplicate series are dropped.
+func featureInfoRows(f featureMetric, features *nfdv1alpha1.Features) []map[string]string {
+	selected := func(attr string) bool {
+		if len(f.Attributes) == 0 {
+			return true
+		}
+		for _, a := range f.Attributes {
+			if a == attr {
+				return true
+			}
+		}
+		return false
+	}
+
+	var rows []map[string]string
+	if s, ok := features.Flags[f.Feature]; ok {
+		for name := range s.Elements {
+			if selected(name) {
+				rows = append(rows, map[string]string{"name": name})
+			}
+		}
+	}
+	if s, ok := features.Attributes[f.Feature]; ok {
+		row := make(map[string]string)
+		for k, v := range s.Elements {
+			if selected(k) {
+				row[k] = v
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	if s, ok := features.Instances[f.Feature]; ok {
+		for _, i := range s.Elements {
+			row := make(map[string]string)
+			for k, v := range i.Attributes {
+				if selected(k) {
+					row[k] = v
+				}
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	// Sort for deterministic selection of series if the limit is exceeded
+	keys := make(map[string]map[string]string, len(rows))
+	for _, r := range rows {
+		keys[rowKey(r)] = r
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	rows = rows[:0]
+	for _, k := range sorted {
+		rows = append(rows, keys[k])
+	}
+	return rows
+}
+
+// rowKey returns a string identifying a series by its attributes.
+func rowKey(row map[string]string) string {
+	// Map keys are sorted by the encoder
+	data, _ := json.Marshal(row)
+	return string(data)
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/node-feature-discovery/pkg/utils"
+)
+
+// labelTransform is one step of the label transformation pipeline. Exactly one
+// of the actions (Rename, Drop, MapValues or Prefix) must be specified.
+type labelTransform struct {
+	// Source restricts the step to labels created by one label source.
+	Source    string
+	Rename    *renameTransform
+	Drop      *dropTransform
+	MapValues *mapValuesTransform
+	// Prefix is prepended to the name part (i.e. after the namespace) of
+	// label keys.
+	Prefix string
+}
+
+// renameTransform renames labels whose key matches a regexp. Replacement may
+// refer to capture groups of the regexp, e.g. "${1}".
+type renameTransform struct {
+	Match       utils.RegexpVal
+	Replacement string
+}
+
+// dropTransform drops labels by key and/or value. If both are specified, both
+// must match for the label to be dropped.
+type dropTransform struct {
+	Key   *utils.RegexpVal
+	Value *utils.RegexpVal
+}
+
+// mapValuesTransform replaces label values according to a lookup table.
+// Values not found in the table are left intact. If Key is specified, only
+// labels with a matching key are affected.
+type mapValuesTransform struct {
+	Key   *utils.RegexpVal
+	Table map[string]string
+}
+
+func (t *labelTransform) validate() error {
+	n := 0
+	if t.Rename != nil {
+		n++
+	}
+	if t.Drop != nil {
+		n++
+		if t.Drop.Key == nil && t.Drop.Value == nil {
+			return fmt.Errorf("drop: key or value must be specified")
+		}
+	}
+	if t.MapValues != nil {
+		n++
+	}
+	if t.Prefix != "" {
+		n++
+	}
+	if n != 1 {
+		return fmt.Errorf("exactly one of rename, drop, mapValues or prefix must be specified")
+	}
+	return nil
+}
+
+// transformLabels runs the labels of one label source through the
+// transformation pipeline. Labels that end up with an invalid key or value
+// are dropped.
+func transformLabels(transforms []labelTransform, sourceName string, labels Labels) Labels {
+	if len(transforms) == 0 {
+		return labels
+	}
+
+	for i, t := range transforms {
+		if t.Source != "" && t.Source != sourceName {
+			continue
+		}
+		labels = t.apply(i, labels)
+	}
+
+	out := make(Labels, len(labels))
+	for k, v := range labels {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			klog.InfoS("ignoring transformed label with invalid name", "labelKey", k, "errors", errs)
+			continue
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			klog.InfoS("ignoring transformed label with invalid value", "labelKey", k, "labelValue", v, "errors", errs)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// apply runs one transformation step. Labels are processed in sorted key
+// order so that if several labels end up with the same key, the label whose
+// original key sorts first wins regardless of map iteration order.
+func (t *labelTransform) apply(step int, labels Labels) Labels {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make(Labels, len(labels))
+	for _, origKey := range keys {
+		k, v := origKey, labels[origKey]
+		switch {
+		case t.Rename != nil:
+			if t.Rename.Match.MatchString(k) {
+				newKey := t.Rename.Match.ReplaceAllString(k, t.Rename.Replacement)
+				klog.V(4).InfoS("renaming label", "step", step, "labelKey", k, "newLabelKey", newKey)
+				k = newKey
+			}
+		case t.Drop != nil:
+			if (t.Drop.Key == nil || t.Drop.Key.MatchString(k)) && (t.Drop.Value == nil || t.Drop.Value.MatchString(v)) {
+				klog.V(4).InfoS("dropping label", "step", step, "labelKey", k, "labelValue", v)
+				continue
+			}
+		case t.MapValues != nil:
+			if t.MapValues.Key == nil || t.MapValues.Key.MatchString(k) {
+				if newValue, ok := t.MapValues.Table[v]; ok {
+					klog.V(4).InfoS("mapping label value", "step", step, "labelKey", k, "labelValue", v, "newLabelValue", newValue)
+					v = newValue
+				}
+			}
+		case t.Prefix != "":
+			newKey := t.Prefix + k
+			if ns, name, ok := strings.Cut(k, "/"); ok {
+				newKey = ns + "/" + t.Prefix + name
+			}
+			klog.V(4).InfoS("prefixing label", "step", step, "labelKey", k, "newLabelKey", newKey)
+			k = newKey
+		}
+		if _, ok := out[k]; ok {
+			klog.InfoS("ignoring transformed label with a conflicting key", "step", step, "labelKey", origKey, "newLabelKey", k)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -25,6 +25,8 @@ import (
 const (
 	buildInfoQuery                = "nfd_worker_build_info"
 	featureDiscoveryDurationQuery = "nfd_feature_discovery_duration_seconds"
+	staticFeatureCacheHitsQuery   = "nfd_worker_static_feature_cache_hits_total"
+	featureMetricsDroppedQuery    = "nfd_worker_feature_info_dropped_series"
 )
 
 var (
@@ -36,7 +38,21 @@ var (
 		},
 		[]string{"node"},
 	)
-	buildInfo = prometheus.NewGauge(prometheus.GaugeOpts{
+	staticFeatureCacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: staticFeatureCacheHitsQuery,
+		Help: "Number of discovery rounds where the cached features of a static feature source were re-used.",
+	},
+		[]string{"source"},
+	)
+	featureMetricsDroppedSeries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: featureMetricsDroppedQuery,
+		Help: "Number of series of a feature info metric dropped in the last discovery round because of the series limit.",
+	},
+		[]string{"feature"},
+	)
+	// featureInfo exports the selected features as info metrics
+	featureInfo = &featureInfoCollector{}
+	buildInfo   = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: buildInfoQuery,
 		Help: "Version from which Node Feature Discovery was built.",
 		ConstLabels: map[string]string{
@@ -24,9 +24,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/stretchr/testify/mock"
 	"github.com/vektra/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"sigs.k8s.io/node-feature-discovery/pkg/labeler"
@@ -35,6 +37,7 @@ import (
 	"sigs.k8s.io/node-feature-discovery/source/cpu"
 	"sigs.k8s.io/node-feature-discovery/source/kernel"
 	"sigs.k8s.io/node-feature-discovery/source/pci"
+	"sigs.k8s.io/yaml"
 )
 
 const fakeLabelSourceName string = "testSource"
@@ -314,7 +317,8 @@ func TestNewNfdWorker(t *testing.T) {
 			worker := w.(*nfdWorker)
 			So(worker.configure("", ""), ShouldBeNil)
 			Convey("all sources should be enabled and the whitelist regexp should be empty", func() {
-				So(len(worker.featureSources), ShouldEqual, len(source.GetAllFeatureSources())-1)
+				// The fake and benchmark sources are disabled by default
+				So(len(worker.featureSources), ShouldEqual, len(source.GetAllFeatureSources())-2)
 				So(len(worker.labelSources), ShouldEqual, len(source.GetAllLabelSources())-1)
 				So(worker.config.Core.LabelWhiteList, ShouldResemble, emptyRegexp)
 			})
@@ -338,6 +342,44 @@ func TestNewNfdWorker(t *testing.T) {
 				So(worker.config.Core.LabelWhiteList, ShouldResemble, emptyRegexp)
 			})
 		})
+
+		Convey("with instance name specified", func() {
+			Convey("NodeFeature object name and labels should contain the instance name", func() {
+				w, err := NewNfdWorker(&Args{Instance: "slow", EnableNodeFeatureApi: true})
+				So(err, ShouldBeNil)
+				name, labels := w.(*nfdWorker).nodeFeatureObjMeta("node-1")
+				So(name, ShouldStartWith, "node-1-slow-")
+				So(name, ShouldHaveLength, len("node-1-slow-")+instanceObjHashLen)
+				So(labels, ShouldResemble, map[string]string{
+					nfdv1alpha1.NodeFeatureObjNodeNameLabel:       "node-1",
+					nfdv1alpha1.NodeFeatureObjWorkerInstanceLabel: "slow",
+				})
+
+				// Must not collide with the default object of another node
+				So(name, ShouldNotEqual, "node-1-slow")
+
+				// Long node names are truncated
+				name, _ = w.(*nfdWorker).nodeFeatureObjMeta(strings.Repeat("a", 250))
+				So(name, ShouldHaveLength, validation.DNS1123SubdomainMaxLength)
+				So(validation.IsDNS1123Subdomain(name), ShouldBeEmpty)
+			})
+			Convey("an invalid instance name should be rejected", func() {
+				_, err := NewNfdWorker(&Args{Instance: "Slow_Worker", EnableNodeFeatureApi: true})
+				So(err, ShouldNotBeNil)
+			})
+			Convey("an error should be returned if the NodeFeature API is disabled", func() {
+				_, err := NewNfdWorker(&Args{Instance: "slow"})
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("without instance name specified", func() {
+			w, err := NewNfdWorker(&Args{})
+			So(err, ShouldBeNil)
+			name, labels := w.(*nfdWorker).nodeFeatureObjMeta("node-1")
+			So(name, ShouldEqual, "node-1")
+			So(labels, ShouldResemble, map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: "node-1"})
+		})
 	})
 }
 
@@ -349,7 +391,7 @@ func TestCreateFeatureLabels(t *testing.T) {
 
 		Convey("When fake feature source is configured", func() {
 			emptyLabelWL := regexp.MustCompile("")
-			labels := createFeatureLabels(sources, *emptyLabelWL)
+			labels := createFeatureLabels(sources, *emptyLabelWL, nil)
 
 			Convey("Proper fake labels are returned", func() {
 				So(len(labels), ShouldEqual, 3)
@@ -359,7 +401,7 @@ func TestCreateFeatureLabels(t *testing.T) {
 			})
 		})
 		Convey("When fake feature source is configured with a whitelist that doesn't match", func() {
-			labels := createFeatureLabels(sources, *regexp.MustCompile(".*rdt.*"))
+			labels := createFeatureLabels(sources, *regexp.MustCompile(".*rdt.*"), nil)
 
 			Convey("fake labels are not returned", func() {
 				So(len(labels), ShouldEqual, 0)
@@ -368,6 +410,57 @@ func TestCreateFeatureLabels(t *testing.T) {
 				So(labels, ShouldNotContainKey, "fake-fakefeature3")
 			})
 		})
+		Convey("When label transforms are configured", func() {
+			transforms := []labelTransform{}
+			So(yaml.Unmarshal([]byte(`
+- rename:
+    match: "^feature.node.kubernetes.io/fake-(.*)$"
+    replacement: "example.com/${1}"
+- drop:
+    key: "fakefeature2$"
+- mapValues:
+    table:
+      "true": "yes"
+- source: fake
+  prefix: "hw-"
+- source: other
+  prefix: "other-"
+`), &transforms), ShouldBeNil)
+			for _, t := range transforms {
+				So(t.validate(), ShouldBeNil)
+			}
+			labels := createFeatureLabels(sources, *regexp.MustCompile(""), transforms)
+
+			Convey("labels are transformed", func() {
+				So(labels, ShouldResemble, Labels{
+					"example.com/hw-fakefeature1": "yes",
+					"example.com/hw-fakefeature3": "yes",
+				})
+			})
+		})
+		Convey("When label transforms map several labels to the same key", func() {
+			transforms := []labelTransform{{Rename: &renameTransform{
+				Match:       utils.RegexpVal{Regexp: *regexp.MustCompile("^feature.node.kubernetes.io/fake-fakefeature[0-9]$")},
+				Replacement: "example.com/fake",
+			}}}
+			labels := createFeatureLabels(sources, *regexp.MustCompile(""), transforms)
+
+			Convey("the label with the first original key is kept", func() {
+				So(labels, ShouldResemble, Labels{"example.com/fake": "true"})
+				for i := 0; i < 10; i++ {
+					So(transformLabels(transforms, "fake", Labels{"feature.node.kubernetes.io/fake-fakefeature1": "a", "feature.node.kubernetes.io/fake-fakefeature2": "b"}),
+						ShouldResemble, Labels{"example.com/fake": "a"})
+				}
+			})
+		})
+		Convey("When a label transform would produce an invalid label", func() {
+			transforms := []labelTransform{{MapValues: &mapValuesTransform{Table: map[string]string{"true": "not valid"}}}}
+			labels := createFeatureLabels(sources, *regexp.MustCompile(""), transforms)
+
+			Convey("the label is dropped", func() {
+				So(len(labels), ShouldEqual, 0)
+			})
+		})
 	})
 }
 
@@ -399,3 +492,199 @@ func TestAdvertiseFeatureLabels(t *testing.T) {
 		})
 	})
 }
+
+type fakeStaticSource struct {
+	dirs        []string
+	discoveries int
+	err         error
+}
+
+func (s *fakeStaticSource) Name() string                       { return "fake-static" }
+func (s *fakeStaticSource) GetFeatures() *nfdv1alpha1.Features { return nfdv1alpha1.NewFeatures() }
+func (s *fakeStaticSource) HotplugDirs() []string              { return s.dirs }
+func (s *fakeStaticSource) Discover() error {
+	s.discoveries++
+	return s.err
+}
+
+func TestStaticFeatureCache(t *testing.T) {
+	Convey("When caching the features of static sources", t, func() {
+		tmpDir := t.TempDir()
+		origBootIDPath, origKernelReleasePath := bootIDPath, kernelReleasePath
+		defer func() { bootIDPath, kernelReleasePath = origBootIDPath, origKernelReleasePath }()
+		bootIDPath = filepath.Join(tmpDir, "boot_id")
+		kernelReleasePath = filepath.Join(tmpDir, "osrelease")
+		hotplugDir := filepath.Join(tmpDir, "devices")
+		So(os.WriteFile(bootIDPath, []byte("boot-1\n"), 0644), ShouldBeNil)
+		So(os.WriteFile(kernelReleasePath, []byte("6.1.0\n"), 0644), ShouldBeNil)
+		So(os.MkdirAll(filepath.Join(hotplugDir, "0000:00:01.0"), 0755), ShouldBeNil)
+
+		c := newStaticFeatureCache()
+		s := &fakeStaticSource{dirs: []string{hotplugDir}}
+
+		key, cached := c.lookup(s)
+		So(cached, ShouldBeFalse)
+		c.store(s, key)
+		_, cached = c.lookup(s)
+		So(cached, ShouldBeTrue)
+
+		Convey("Dynamic sources should never be cached", func() {
+			_, cached := c.lookup(source.GetFeatureSource(kernel.Name))
+			So(cached, ShouldBeFalse)
+		})
+
+		Convey("Hotplug should invalidate the cache", func() {
+			So(os.MkdirAll(filepath.Join(hotplugDir, "0000:00:02.0"), 0755), ShouldBeNil)
+			_, cached := c.lookup(s)
+			So(cached, ShouldBeFalse)
+		})
+
+		Convey("Reboot should invalidate the cache", func() {
+			So(os.WriteFile(bootIDPath, []byte("boot-2\n"), 0644), ShouldBeNil)
+			_, cached := c.lookup(s)
+			So(cached, ShouldBeFalse)
+		})
+
+		Convey("Kernel update should invalidate the cache", func() {
+			So(os.WriteFile(kernelReleasePath, []byte("6.2.0\n"), 0644), ShouldBeNil)
+			_, cached := c.lookup(s)
+			So(cached, ShouldBeFalse)
+		})
+
+		Convey("Invalidated entries should not be cached", func() {
+			c.invalidate(s)
+			_, cached := c.lookup(s)
+			So(cached, ShouldBeFalse)
+		})
+
+		Convey("Device removal should invalidate the cache", func() {
+			So(os.Remove(filepath.Join(hotplugDir, "0000:00:01.0")), ShouldBeNil)
+			_, cached := c.lookup(s)
+			So(cached, ShouldBeFalse)
+		})
+
+		Convey("Changes in device directories should not invalidate the cache", func() {
+			So(os.WriteFile(filepath.Join(hotplugDir, "0000:00:01.0", "enable"), []byte("1"), 0644), ShouldBeNil)
+			_, cached := c.lookup(s)
+			So(cached, ShouldBeTrue)
+		})
+
+		Convey("Missing hotplug directories should be cacheable", func() {
+			s := &fakeStaticSource{dirs: []string{filepath.Join(tmpDir, "non-existent")}}
+			key, cached := c.lookup(s)
+			So(key, ShouldNotBeEmpty)
+			So(cached, ShouldBeFalse)
+			c.store(s, key)
+			_, cached = c.lookup(s)
+			So(cached, ShouldBeTrue)
+		})
+
+		Convey("Nothing should be cached if the boot ID cannot be read", func() {
+			So(os.Remove(bootIDPath), ShouldBeNil)
+			key, cached := c.lookup(s)
+			So(key, ShouldBeEmpty)
+			So(cached, ShouldBeFalse)
+		})
+
+		Convey("A nil cache should never cache", func() {
+			var nilCache *staticFeatureCache
+			key, cached := nilCache.lookup(s)
+			So(cached, ShouldBeFalse)
+			nilCache.store(s, key)
+			nilCache.invalidate(s)
+		})
+
+		Convey("Discovery should be skipped while the cache is valid", func() {
+			w, err := NewNfdWorker(&Args{})
+			So(err, ShouldBeNil)
+			worker := w.(*nfdWorker)
+			So(worker.configure("", `{"core": {"noPublish": true, "cacheStaticFeatures": true}}`), ShouldBeNil)
+			worker.featureSources = []source.FeatureSource{s}
+
+			So(worker.runFeatureDiscovery(), ShouldBeNil)
+			So(worker.runFeatureDiscovery(), ShouldBeNil)
+			So(s.discoveries, ShouldEqual, 1)
+
+			Convey("and repeated after a failed discovery", func() {
+				So(os.MkdirAll(filepath.Join(hotplugDir, "0000:00:02.0"), 0755), ShouldBeNil)
+				s.err = errors.New("discovery failed")
+				So(worker.runFeatureDiscovery(), ShouldBeNil)
+				So(worker.runFeatureDiscovery(), ShouldBeNil)
+				So(s.discoveries, ShouldEqual, 3)
+
+				s.err = nil
+				So(worker.runFeatureDiscovery(), ShouldBeNil)
+				So(worker.runFeatureDiscovery(), ShouldBeNil)
+				So(s.discoveries, ShouldEqual, 4)
+			})
+
+			Convey("and the cache should be reset on config reload", func() {
+				So(worker.configure("", `{"core": {"noPublish": true, "cacheStaticFeatures": true}}`), ShouldBeNil)
+				So(worker.staticFeatureCache.keys, ShouldBeEmpty)
+				worker.featureSources = []source.FeatureSource{s}
+				So(worker.runFeatureDiscovery(), ShouldBeNil)
+				So(s.discoveries, ShouldEqual, 2)
+			})
+
+			Convey("and the cache should be dropped when disabled", func() {
+				So(worker.configure("", `{"core": {"noPublish": true}}`), ShouldBeNil)
+				So(worker.staticFeatureCache, ShouldBeNil)
+				worker.featureSources = []source.FeatureSource{s}
+				So(worker.runFeatureDiscovery(), ShouldBeNil)
+				So(worker.runFeatureDiscovery(), ShouldBeNil)
+				So(s.discoveries, ShouldEqual, 3)
+			})
+		})
+	})
+}
+
+func TestFeatureInfoMetrics(t *testing.T) {
+	Convey("When exporting features as info metrics", t, func() {
+		features := nfdv1alpha1.NewFeatures()
+		features.Attributes["cpu.model"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"vendor_id": "Intel", "family": "6", "id": "85"})
+		features.Flags["kernel.loadedmodule"] = nfdv1alpha1.NewFlagFeatures("kvm", "vfio", "e1000e")
+		features.Instances["network.device"] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{
+			{Attributes: map[string]string{"name": "eth0", "driver": "ice", "speed": "25000"}},
+			{Attributes: map[string]string{"name": "eth1", "driver": "ice"}},
+			{Attributes: map[string]string{"name": "eth2", "driver": "e1000e", "speed": "1000"}},
+		})
+		config := featureMetricsConfig{
+			MaxSeriesPerFeature: 2,
+			Features: []featureMetric{
+				{Feature: "cpu.model", Attributes: []string{"vendor_id", "family"}},
+				{Feature: "kernel.loadedmodule", Attributes: []string{"kvm", "vfio"}},
+				{Feature: "network.device", Attributes: []string{"name", "speed"}},
+				{Feature: "missing.feature"},
+			},
+		}
+		So(config.validate(), ShouldBeNil)
+
+		c := &featureInfoCollector{}
+		c.update(config, "node-1", features)
+		expected := `
+# HELP nfd_feature_cpu_model_info Node feature cpu.model discovered by nfd-worker.
+# TYPE nfd_feature_cpu_model_info gauge
+nfd_feature_cpu_model_info{family="6",node="node-1",vendor_id="Intel"} 1
+# HELP nfd_feature_kernel_loadedmodule_info Node feature kernel.loadedmodule discovered by nfd-worker.
+# TYPE nfd_feature_kernel_loadedmodule_info gauge
+nfd_feature_kernel_loadedmodule_info{name="kvm",node="node-1"} 1
+nfd_feature_kernel_loadedmodule_info{name="vfio",node="node-1"} 1
+# HELP nfd_feature_network_device_info Node feature network.device discovered by nfd-worker.
+# TYPE nfd_feature_network_device_info gauge
+nfd_feature_network_device_info{name="eth0",node="node-1",speed="25000"} 1
+nfd_feature_network_device_info{name="eth1",node="node-1",speed=""} 1
+`
+		So(testutil.CollectAndCompare(c, strings.NewReader(expected)), ShouldBeNil)
+		So(testutil.ToFloat64(featureMetricsDroppedSeries.WithLabelValues("network.device")), ShouldEqual, 1)
+
+		Convey("Invalid configuration should be rejected", func() {
+			config.Features = append(config.Features, featureMetric{Feature: "cpu_model"})
+			So(config.validate(), ShouldNotBeNil)
+			config.Features = []featureMetric{{Feature: "cpu.model_x"}, {Feature: "cpu_model.x"}}
+			So(config.validate(), ShouldNotBeNil)
+			config.Features = nil
+			config.MaxSeriesPerFeature = 0
+			So(config.validate(), ShouldNotBeNil)
+		})
+	})
+}
@@ -17,8 +17,10 @@ limitations under the License.
 package nfdworker
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -51,9 +53,12 @@ import (
 	"sigs.k8s.io/node-feature-discovery/source"
 
 	// Register all source packages
+	_ "sigs.k8s.io/node-feature-discovery/source/benchmark"
 	_ "sigs.k8s.io/node-feature-discovery/source/cpu"
 	_ "sigs.k8s.io/node-feature-discovery/source/custom"
+	_ "sigs.k8s.io/node-feature-discovery/source/devnodes"
 	_ "sigs.k8s.io/node-feature-discovery/source/fake"
+	_ "sigs.k8s.io/node-feature-discovery/source/firmware"
 	_ "sigs.k8s.io/node-feature-discovery/source/kernel"
 	_ "sigs.k8s.io/node-feature-discovery/source/local"
 	_ "sigs.k8s.io/node-feature-discovery/source/memory"
@@ -64,6 +69,10 @@ import (
 	_ "sigs.k8s.io/node-feature-discovery/source/usb"
 )
 
+// instanceObjHashLen is the length of the hash suffix in the names of the
+// NodeFeature objects of worker instances
+const instanceObjHashLen = 10
+
 // NfdWorker is the interface for nfd-worker daemon
 type NfdWorker interface {
 	Run() error
@@ -77,13 +86,19 @@ type NFDConfig struct {
 }
 
 type coreConfig struct {
-	Klog           klogutils.KlogConfigOpts
-	LabelWhiteList utils.RegexpVal
-	NoPublish      bool
-	FeatureSources []string
-	Sources        *[]string
-	LabelSources   []string
-	SleepInterval  utils.DurationVal
+	Klog            klogutils.KlogConfigOpts
+	LabelWhiteList  utils.RegexpVal
+	LabelTransforms []labelTransform
+	NoPublish       bool
+	FeatureSources  []string
+	Sources         *[]string
+	LabelSources    []string
+	SleepInterval   utils.DurationVal
+	// CacheStaticFeatures enables re-using the features of static feature
+	// sources between discovery rounds
+	CacheStaticFeatures bool
+	// FeatureMetrics selects the features exported as info metrics
+	FeatureMetrics featureMetricsConfig
 }
 
 type sourcesConfig map[string]source.Config
@@ -97,6 +112,7 @@ type Args struct {
 	CertFile             string
 	ConfigFile           string
 	EnableNodeFeatureApi bool
+	Instance             string
 	KeyFile              string
 	Klog                 map[string]*utils.KlogFlagVal
 	Kubeconfig           string
@@ -129,6 +145,7 @@ type nfdWorker struct {
 	stop                chan struct{} // channel for signaling stop
 	featureSources      []source.FeatureSource
 	labelSources        []source.LabelSource
+	staticFeatureCache  *staticFeatureCache
 }
 
 // This ticker can represent infinite and normal intervals.
@@ -158,6 +175,15 @@ func NewNfdWorker(args *Args) (NfdWorker, error) {
 		}
 	}
 
+	if args.Instance != "" {
+		if errs := validation.IsDNS1123Label(args.Instance); len(errs) > 0 {
+			return nfd, fmt.Errorf("invalid -instance %q: %s", args.Instance, strings.Join(errs, "; "))
+		}
+		if !args.EnableNodeFeatureApi {
+			return nfd, fmt.Errorf("-instance requires the NodeFeature API to be enabled")
+		}
+	}
+
 	if args.ConfigFile != "" {
 		nfd.configFilePath = filepath.Clean(args.ConfigFile)
 	}
@@ -173,6 +199,7 @@ func newDefaultConfig() *NFDConfig {
 			FeatureSources: []string{"all"},
 			LabelSources:   []string{"all"},
 			Klog:           make(map[string]string),
+			FeatureMetrics: featureMetricsConfig{MaxSeriesPerFeature: 100},
 		},
 	}
 }
@@ -193,8 +220,17 @@ func (w *nfdWorker) runFeatureDiscovery() error {
 	discoveryStart := time.Now()
 	for _, s := range w.featureSources {
 		currentSourceStart := time.Now()
+		cacheKey, cached := w.staticFeatureCache.lookup(s)
+		if cached {
+			klog.V(3).InfoS("re-using cached features", "featureSource", s.Name())
+			staticFeatureCacheHits.WithLabelValues(s.Name()).Inc()
+			continue
+		}
 		if err := s.Discover(); err != nil {
 			klog.ErrorS(err, "feature discovery failed", "source", s.Name())
+			w.staticFeatureCache.invalidate(s)
+		} else {
+			w.staticFeatureCache.store(s, cacheKey)
 		}
 		klog.V(3).InfoS("feature discovery completed", "featureSource", s.Name(), "duration", time.Since(currentSourceStart))
 	}
@@ -205,8 +241,10 @@ func (w *nfdWorker) runFeatureDiscovery() error {
 	if w.config.Core.SleepInterval.Duration > 0 && discoveryDuration > w.config.Core.SleepInterval.Duration/2 {
 		klog.InfoS("feature discovery sources took over half of sleep interval ", "duration", discoveryDuration, "sleepInterval", w.config.Core.SleepInterval.Duration)
 	}
+	featureInfo.update(w.config.Core.FeatureMetrics, utils.NodeName(), source.GetAllFeatures())
+
 	// Get the set of feature labels.
-	labels := createFeatureLabels(w.labelSources, w.config.Core.LabelWhiteList.Regexp)
+	labels := createFeatureLabels(w.labelSources, w.config.Core.LabelWhiteList.Regexp, w.config.Core.LabelTransforms)
 
 	// Update the node with the feature labels.
 	if !w.config.Core.NoPublish {
@@ -219,7 +257,7 @@ func (w *nfdWorker) runFeatureDiscovery() error {
 // Run NfdWorker client. Returns if a fatal error is encountered, or, after
 // one request if OneShot is set to 'true' in the worker args.
 func (w *nfdWorker) Run() error {
-	klog.InfoS("Node Feature Discovery Worker", "version", version.Get(), "nodeName", utils.NodeName(), "namespace", w.kubernetesNamespace)
+	klog.InfoS("Node Feature Discovery Worker", "version", version.Get(), "nodeName", utils.NodeName(), "namespace", w.kubernetesNamespace, "instance", w.args.Instance)
 
 	// Create watcher for config file and read initial configuration
 	configWatch, err := utils.CreateFsWatcher(time.Second, w.configFilePath)
@@ -247,7 +285,10 @@ func (w *nfdWorker) Run() error {
 	if w.args.MetricsPort > 0 {
 		m := utils.CreateMetricsServer(w.args.MetricsPort,
 			buildInfo,
-			featureDiscoveryDuration)
+			featureDiscoveryDuration,
+			staticFeatureCacheHits,
+			featureMetricsDroppedSeries,
+			featureInfo)
 		go m.Run()
 		registerVersion(version.Get())
 		defer m.Stop()
@@ -526,6 +567,15 @@ func (w *nfdWorker) configure(filepath string, overrides string) error {
 
 	c.Core.sanitize()
 
+	for i, t := range c.Core.LabelTransforms {
+		if err := t.validate(); err != nil {
+			return fmt.Errorf("invalid core.labelTransforms[%d]: %w", i, err)
+		}
+	}
+	if err := c.Core.FeatureMetrics.validate(); err != nil {
+		return fmt.Errorf("invalid core.featureMetrics: %w", err)
+	}
+
 	w.config = c
 
 	if err := w.configureCore(c.Core); err != nil {
@@ -537,13 +587,25 @@ func (w *nfdWorker) configure(filepath string, overrides string) error {
 		s.SetConfig(c.Sources[s.Name()])
 	}
 
+	// Features must be re-discovered with the new configuration
+	w.staticFeatureCache = nil
+	if c.Core.CacheStaticFeatures {
+		w.staticFeatureCache = newStaticFeatureCache()
+	}
+	for _, s := range source.GetAllFeatureSources() {
+		if cs, ok := s.(source.CachingSource); ok {
+			cs.SetCachingEnabled(c.Core.CacheStaticFeatures)
+		}
+	}
+
 	klog.InfoS("configuration successfully updated", "configuration", w.config)
 	return nil
 }
 
 // createFeatureLabels returns the set of feature labels from the enabled
-// sources and the whitelist argument.
-func createFeatureLabels(sources []source.LabelSource, labelWhiteList regexp.Regexp) (labels Labels) {
+// sources, filtered by the whitelist argument and run through the label
+// transformation pipeline.
+func createFeatureLabels(sources []source.LabelSource, labelWhiteList regexp.Regexp, transforms []labelTransform) (labels Labels) {
 	labels = Labels{}
 
 	// Get labels from all enabled label sources
@@ -554,6 +616,7 @@ func createFeatureLabels(sources []source.LabelSource, labelWhiteList regexp.Reg
 			klog.ErrorS(err, "discovery failed", "source", source.Name())
 			continue
 		}
+		labelsFromSource = transformLabels(transforms, source.Name(), labelsFromSource)
 
 		maps.Copy(labels, labelsFromSource)
 	}
@@ -670,6 +733,7 @@ func (m *nfdWorker) updateNodeFeatureObject(labels Labels) error {
 	}
 	nodename := utils.NodeName()
 	namespace := m.kubernetesNamespace
+	objName, objLabels := m.nodeFeatureObjMeta(nodename)
 
 	features := source.GetAllFeatures()
 
@@ -694,13 +758,13 @@ func (m *nfdWorker) updateNodeFeatureObject(labels Labels) error {
 
 	// TODO: we could implement some simple caching of the object, only get it
 	// every 10 minutes or so because nobody else should really be modifying it
-	if nfr, err := cli.NfdV1alpha1().NodeFeatures(namespace).Get(context.TODO(), nodename, metav1.GetOptions{}); errors.IsNotFound(err) {
-		klog.InfoS("creating NodeFeature object", "nodefeature", klog.KObj(nfr))
+	if nfr, err := cli.NfdV1alpha1().NodeFeatures(namespace).Get(context.TODO(), objName, metav1.GetOptions{}); errors.IsNotFound(err) {
+		klog.InfoS("creating NodeFeature object", "nodefeature", klog.KRef(namespace, objName))
 		nfr = &nfdv1alpha1.NodeFeature{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            nodename,
+				Name:            objName,
 				Annotations:     map[string]string{nfdv1alpha1.WorkerVersionAnnotation: version.Get()},
-				Labels:          map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodename},
+				Labels:          objLabels,
 				OwnerReferences: ownerRefs,
 			},
 			Spec: nfdv1alpha1.NodeFeatureSpec{
@@ -720,7 +784,7 @@ func (m *nfdWorker) updateNodeFeatureObject(labels Labels) error {
 	} else {
 		nfrUpdated := nfr.DeepCopy()
 		nfrUpdated.Annotations = map[string]string{nfdv1alpha1.WorkerVersionAnnotation: version.Get()}
-		nfrUpdated.Labels = map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodename}
+		nfrUpdated.Labels = objLabels
 		nfrUpdated.OwnerReferences = ownerRefs
 		nfrUpdated.Spec = nfdv1alpha1.NodeFeatureSpec{
 			Features: *features,
@@ -741,6 +805,28 @@ func (m *nfdWorker) updateNodeFeatureObject(labels Labels) error {
 	return nil
 }
 
+// nodeFeatureObjMeta returns the name and labels of the NodeFeature object of
+// this worker instance. The name of the instance, if specified, and a hash of
+// the node and instance names are appended to the node name so that multiple
+// worker instances on the same node do not overwrite each other's NodeFeature
+// objects, nor the objects of other nodes whose name happens to contain the
+// instance name (e.g. instance "gpu" on node "worker" vs. node "worker-gpu").
+func (m *nfdWorker) nodeFeatureObjMeta(nodeName string) (string, map[string]string) {
+	labels := map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName}
+	if m.args.Instance == "" {
+		return nodeName, labels
+	}
+	labels[nfdv1alpha1.NodeFeatureObjWorkerInstanceLabel] = m.args.Instance
+
+	sum := sha256.Sum256([]byte(nodeName + "/" + m.args.Instance))
+	suffix := "-" + hex.EncodeToString(sum[:])[:instanceObjHashLen]
+	prefix := nodeName + "-" + m.args.Instance
+	if len(prefix)+len(suffix) > validation.DNS1123SubdomainMaxLength {
+		prefix = strings.TrimRight(prefix[:validation.DNS1123SubdomainMaxLength-len(suffix)], "-.")
+	}
+	return prefix + suffix, labels
+}
+
 // getNfdClient returns the clientset for using the nfd CRD api
 func (m *nfdWorker) getNfdClient() (*nfdclient.Clientset, error) {
 	if m.nfdClient != nil {
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nrtclient
+
+import (
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+)
+
+// ToV1alpha1 converts a v1alpha2 NodeResourceTopology to v1alpha1. The
+// top-level attributes are dropped as v1alpha1 does not support them.
+func ToV1alpha1(in *v1alpha2.NodeResourceTopology) *v1alpha1.NodeResourceTopology {
+	out := &v1alpha1.NodeResourceTopology{
+		ObjectMeta:       *in.ObjectMeta.DeepCopy(),
+		TopologyPolicies: append([]string{}, in.TopologyPolicies...),
+		Zones:            make(v1alpha1.ZoneList, len(in.Zones)),
+	}
+	for i, z := range in.Zones {
+		zone := v1alpha1.Zone{Name: z.Name, Type: z.Type, Parent: z.Parent}
+		for _, c := range z.Costs {
+			zone.Costs = append(zone.Costs, v1alpha1.CostInfo{Name: c.Name, Value: c.Value})
+		}
+		for _, a := range z.Attributes {
+			zone.Attributes = append(zone.Attributes, v1alpha1.AttributeInfo{Name: a.Name, Value: a.Value})
+		}
+		for _, r := range z.Resources {
+			zone.Resources = append(zone.Resources, v1alpha1.ResourceInfo{
+				Name:        r.Name,
+				Capacity:    r.Capacity.DeepCopy(),
+				Allocatable: r.Allocatable.DeepCopy(),
+				Available:   r.Available.DeepCopy(),
+			})
+		}
+		out.Zones[i] = zone
+	}
+	return out
+}
+
+// FromV1alpha1 converts a v1alpha1 NodeResourceTopology to v1alpha2.
+func FromV1alpha1(in *v1alpha1.NodeResourceTopology) *v1alpha2.NodeResourceTopology {
+	out := &v1alpha2.NodeResourceTopology{
+		ObjectMeta:       *in.ObjectMeta.DeepCopy(),
+		TopologyPolicies: append([]string{}, in.TopologyPolicies...),
+		Zones:            make(v1alpha2.ZoneList, len(in.Zones)),
+		Attributes:       v1alpha2.AttributeList{},
+	}
+	for i, z := range in.Zones {
+		zone := v1alpha2.Zone{Name: z.Name, Type: z.Type, Parent: z.Parent}
+		for _, c := range z.Costs {
+			zone.Costs = append(zone.Costs, v1alpha2.CostInfo{Name: c.Name, Value: c.Value})
+		}
+		for _, a := range z.Attributes {
+			zone.Attributes = append(zone.Attributes, v1alpha2.AttributeInfo{Name: a.Name, Value: a.Value})
+		}
+		for _, r := range z.Resources {
+			zone.Resources = append(zone.Resources, v1alpha2.ResourceInfo{
+				Name:        r.Name,
+				Capacity:    r.Capacity.DeepCopy(),
+				Allocatable: r.Allocatable.DeepCopy(),
+				Available:   r.Available.DeepCopy(),
+			})
+		}
+		out.Zones[i] = zone
+	}
+	return out
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nrtclient implements a client for NodeResourceTopology objects that
+// negotiates the API version with the API server. Objects are handled in the
+// v1alpha2 representation and converted to the negotiated API version.
+package nrtclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	topologyapi "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology"
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+)
+
+// SupportedVersions are the NodeResourceTopology API versions supported by
+// the client, in the order of preference.
+var SupportedVersions = []string{"v1alpha2", "v1alpha1"}
+
+// ErrNotServed is returned if the API server does not serve any supported
+// version of the NodeResourceTopology API.
+var ErrNotServed = errors.New("no supported version of the NodeResourceTopology API served")
+
+// Interface is a client for NodeResourceTopology objects.
+type Interface interface {
+	// Version returns the NodeResourceTopology API version in use.
+	Version() string
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1alpha2.NodeResourceTopology, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1alpha2.NodeResourceTopologyList, error)
+	Create(ctx context.Context, nrt *v1alpha2.NodeResourceTopology, opts metav1.CreateOptions) (*v1alpha2.NodeResourceTopology, error)
+	Update(ctx context.Context, nrt *v1alpha2.NodeResourceTopology, opts metav1.UpdateOptions) (*v1alpha2.NodeResourceTopology, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+}
+
+// New returns a client using the most preferred NodeResourceTopology API
+// version served by the API server.
+func New(cli topologyclientset.Interface) (Interface, error) {
+	version, err := NegotiateVersion(cli.Discovery())
+	if err != nil {
+		return nil, err
+	}
+	return NewForVersion(cli, version)
+}
+
+// NewForVersion returns a client using the given NodeResourceTopology API
+// version.
+func NewForVersion(cli topologyclientset.Interface, version string) (Interface, error) {
+	switch version {
+	case "v1alpha2":
+		return &v1alpha2Client{cli: cli}, nil
+	case "v1alpha1":
+		return &v1alpha1Client{cli: cli}, nil
+	}
+	return nil, fmt.Errorf("unsupported NodeResourceTopology API version %q", version)
+}
+
+// NegotiateVersion returns the most preferred NodeResourceTopology API version
+// served by the API server.
+func NegotiateVersion(d discovery.DiscoveryInterface) (string, error) {
+	groups, err := d.ServerGroups()
+	if err != nil {
+		return "", fmt.Errorf("failed to discover API groups: %w", err)
+	}
+
+	served := make(map[string]struct{})
+	for _, g := range groups.Groups {
+		if g.Name != topologyapi.GroupName {
+			continue
+		}
+		for _, v := range g.Versions {
+			served[v.Version] = struct{}{}
+		}
+	}
+	for _, v := range SupportedVersions {
+		if _, ok := served[v]; ok {
+			return v, nil
+		}
+	}
+	return "", ErrNotServed
+}
+
+type v1alpha2Client struct {
+	cli topologyclientset.Interface
+}
+
+func (c *v1alpha2Client) Version() string { return "v1alpha2" }
+
+func (c *v1alpha2Client) Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1alpha2.NodeResourceTopology, error) {
+	return c.cli.TopologyV1alpha2().NodeResourceTopologies().Get(ctx, name, opts)
+}
+
+func (c *v1alpha2Client) List(ctx context.Context, opts metav1.ListOptions) (*v1alpha2.NodeResourceTopologyList, error) {
+	return c.cli.TopologyV1alpha2().NodeResourceTopologies().List(ctx, opts)
+}
+
+func (c *v1alpha2Client) Create(ctx context.Context, nrt *v1alpha2.NodeResourceTopology, opts metav1.CreateOptions) (*v1alpha2.NodeResourceTopology, error) {
+	return c.cli.TopologyV1alpha2().NodeResourceTopologies().Create(ctx, nrt, opts)
+}
+
+func (c *v1alpha2Client) Update(ctx context.Context, nrt *v1alpha2.NodeResourceTopology, opts metav1.UpdateOptions) (*v1alpha2.NodeResourceTopology, error) {
+	return c.cli.TopologyV1alpha2().NodeResourceTopologies().Update(ctx, nrt, opts)
+}
+
+func (c *v1alpha2Client) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.cli.TopologyV1alpha2().NodeResourceTopologies().Delete(ctx, name, opts)
+}
+
+type v1alpha1Client struct {
+	cli topologyclientset.Interface
+}
+
+func (c *v1alpha1Client) Version() string { return "v1alpha1" }
+
+func (c *v1alpha1Client) Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1alpha2.NodeResourceTopology, error) {
+	nrt, err := c.cli.TopologyV1alpha1().NodeResourceTopologies().Get(ctx, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	return FromV1alpha1(nrt), nil
+}
+
+func (c *v1alpha1Client) List(ctx context.Context, opts metav1.ListOptions) (*v1alpha2.NodeResourceTopologyList, error) {
+	list, err := c.cli.TopologyV1alpha1().NodeResourceTopologies().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	ret := &v1alpha2.NodeResourceTopologyList{ListMeta: list.ListMeta, Items: make([]v1alpha2.NodeResourceTopology, len(list.Items))}
+	for i := range list.Items {
+		ret.Items[i] = *FromV1alpha1(&list.Items[i])
+	}
+	return ret, nil
+}
+
+func (c *v1alpha1Client) Create(ctx context.Context, nrt *v1alpha2.NodeResourceTopology, opts metav1.CreateOptions) (*v1alpha2.NodeResourceTopology, error) {
+	created, err := c.cli.TopologyV1alpha1().NodeResourceTopologies().Create(ctx, ToV1alpha1(nrt), opts)
+	if err != nil {
+		return nil, err
+	}
+	return FromV1alpha1(created), nil
+}
+
+func (c *v1alpha1Client) Update(ctx context.Context, nrt *v1alpha2.NodeResourceTopology, opts metav1.UpdateOptions) (*v1alpha2.NodeResourceTopology, error) {
+	updated, err := c.cli.TopologyV1alpha1().NodeResourceTopologies().Update(ctx, ToV1alpha1(nrt), opts)
+	if err != nil {
+		return nil, err
+	}
+	return FromV1alpha1(updated), nil
+}
+
+func (c *v1alpha1Client) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.cli.TopologyV1alpha1().NodeResourceTopologies().Delete(ctx, name, opts)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nrtclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+	faketopologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newFakeClient(groupVersions ...string) *faketopologyclientset.Clientset {
+	cli := faketopologyclientset.NewSimpleClientset()
+	for _, gv := range groupVersions {
+		cli.Fake.Resources = append(cli.Fake.Resources, &metav1.APIResourceList{
+			GroupVersion: gv,
+			APIResources: []metav1.APIResource{{Name: "noderesourcetopologies", Kind: "NodeResourceTopology"}},
+		})
+	}
+	return cli
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	tcs := []struct {
+		name          string
+		groupVersions []string
+		expected      string
+		expectedErr   error
+	}{
+		{name: "newest version preferred", groupVersions: []string{"topology.node.k8s.io/v1alpha1", "topology.node.k8s.io/v1alpha2"}, expected: "v1alpha2"},
+		{name: "only older version served", groupVersions: []string{"topology.node.k8s.io/v1alpha1"}, expected: "v1alpha1"},
+		{name: "unsupported versions ignored", groupVersions: []string{"topology.node.k8s.io/v1beta1", "topology.node.k8s.io/v1alpha1"}, expected: "v1alpha1"},
+		{name: "other groups ignored", groupVersions: []string{"example.com/v1alpha2"}, expectedErr: ErrNotServed},
+		{name: "api not served", expectedErr: ErrNotServed},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := NegotiateVersion(newFakeClient(tc.groupVersions...).Discovery())
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestV1alpha1Client(t *testing.T) {
+	fake := newFakeClient("topology.node.k8s.io/v1alpha1")
+	cli, err := New(fake)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1alpha1", cli.Version())
+
+	nrt := &v1alpha2.NodeResourceTopology{
+		ObjectMeta:       metav1.ObjectMeta{Name: "node-1"},
+		TopologyPolicies: []string{"None"},
+		Attributes:       v1alpha2.AttributeList{{Name: "topologyManagerPolicy", Value: "none"}},
+		Zones: v1alpha2.ZoneList{
+			{
+				Name:       "node-0",
+				Type:       "Node",
+				Costs:      v1alpha2.CostList{{Name: "node-0", Value: 10}},
+				Attributes: v1alpha2.AttributeList{{Name: "a", Value: "b"}},
+				Resources: v1alpha2.ResourceInfoList{
+					{Name: "cpu", Capacity: resource.MustParse("4"), Allocatable: resource.MustParse("3"), Available: resource.MustParse("2")},
+				},
+			},
+		},
+	}
+	_, err = cli.Create(context.TODO(), nrt, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	// The object is stored as v1alpha1
+	stored, err := fake.TopologyV1alpha1().NodeResourceTopologies().Get(context.TODO(), "node-1", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"None"}, stored.TopologyPolicies)
+	assert.Len(t, stored.Zones, 1)
+	assert.Equal(t, "3", stored.Zones[0].Resources[0].Allocatable.String())
+
+	// Zones survive the round trip, top-level attributes are dropped
+	got, err := cli.Get(context.TODO(), "node-1", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, nrt.Zones, got.Zones)
+	assert.Empty(t, got.Attributes)
+
+	list, err := cli.List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, list.Items, 1)
+
+	assert.NoError(t, cli.Delete(context.TODO(), "node-1", metav1.DeleteOptions{}))
+	list, err = cli.List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, list.Items)
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+	"unsafe"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+const (
+	// wallTimeFactor limits the wall-clock time of a benchmark relative to
+	// its CPU time budget, in case the thread is starved
+	wallTimeFactor = 10
+	// diskBlockSize is the size of the reads of the disk benchmark
+	diskBlockSize = 4096
+	// cacheLineSize is the stride of the memory latency benchmark
+	cacheLineSize = 64
+)
+
+// sink prevents the compiler from optimizing away the benchmark loops
+var sink uint64
+
+// budget tracks the CPU time consumed by a benchmark running on a locked OS
+// thread.
+type budget struct {
+	limit     time.Duration
+	startCPU  time.Duration
+	startWall time.Time
+}
+
+func newBudget(limit time.Duration) *budget {
+	return &budget{limit: limit, startCPU: threadCPUTime(), startWall: time.Now()}
+}
+
+// used returns the CPU time consumed since the start of the benchmark.
+func (b *budget) used() time.Duration {
+	return threadCPUTime() - b.startCPU
+}
+
+// exhausted returns true if the CPU time (or wall-clock time) limit has been
+// reached.
+func (b *budget) exhausted() bool {
+	return b.used() >= b.limit || time.Since(b.startWall) >= wallTimeFactor*b.limit
+}
+
+// runBenchmarks runs all benchmarks and returns the results as features.
+func runBenchmarks(config *Config) *nfdv1alpha1.Features {
+	features := nfdv1alpha1.NewFeatures()
+
+	// Measure the CPU time of the calling thread only
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	limit := config.TimeLimit.Duration
+	features.Attributes[CPUFeature] = nfdv1alpha1.NewAttributeFeatures(map[string]string{
+		"int_mops":  strconv.FormatInt(benchInteger(limit), 10),
+		"fp_mflops": strconv.FormatInt(benchFloat(limit), 10),
+	})
+
+	bufSize := config.MemoryBufferSizeMiB << 20
+	features.Attributes[MemoryFeature] = nfdv1alpha1.NewAttributeFeatures(map[string]string{
+		"bandwidth_mbps": strconv.FormatInt(benchMemoryBandwidth(limit, bufSize), 10),
+		"latency_ns":     strconv.FormatInt(benchMemoryLatency(limit, bufSize), 10),
+	})
+
+	disks := make([]nfdv1alpha1.InstanceFeature, 0, len(config.DiskPaths))
+	for _, path := range config.DiskPaths {
+		iops, err := benchDiskRead(limit, path)
+		if err != nil {
+			klog.ErrorS(err, "disk benchmark failed", "path", path)
+			continue
+		}
+		disks = append(disks, *nfdv1alpha1.NewInstanceFeature(map[string]string{
+			"path":      path,
+			"read_iops": strconv.FormatInt(iops, 10),
+		}))
+	}
+	features.Instances[DiskFeature] = nfdv1alpha1.NewInstanceFeatures(disks)
+
+	return features
+}
+
+// perSecond returns the rate of n operations in duration d, scaled by scale.
+func perSecond(n float64, d time.Duration, scale float64) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Round(n / d.Seconds() / scale))
+}
+
+// benchInteger measures the single-core integer throughput in millions of
+// operations per second.
+func benchInteger(limit time.Duration) int64 {
+	const chunk = 1 << 20
+	// Four operations per iteration: shift, xor, multiply and add
+	const opsPerIter = 4
+
+	x, acc := uint64(88172645463325252), uint64(0)
+	iters := 0
+	b := newBudget(limit)
+	for !b.exhausted() {
+		for i := 0; i < chunk; i++ {
+			x ^= x << 13
+			acc += x * 0x9E3779B97F4A7C15
+		}
+		iters += chunk
+	}
+	sink += acc
+	return perSecond(float64(iters*opsPerIter), b.used(), 1e6)
+}
+
+// benchFloat measures the single-core floating point throughput in millions of
+// floating point operations per second.
+func benchFloat(limit time.Duration) int64 {
+	const chunk = 1 << 20
+	// Four independent multiply-adds per iteration
+	const flopsPerIter = 8
+
+	a, b0, c, d := 1.0, 1.1, 1.2, 1.3
+	const mul, add = 0.999999, 1e-7
+	iters := 0
+	b := newBudget(limit)
+	for !b.exhausted() {
+		for i := 0; i < chunk; i++ {
+			a = a*mul + add
+			b0 = b0*mul + add
+			c = c*mul + add
+			d = d*mul + add
+		}
+		iters += chunk
+	}
+	sink += uint64(a + b0 + c + d)
+	return perSecond(float64(iters*flopsPerIter), b.used(), 1e6)
+}
+
+// benchMemoryBandwidth measures the memory copy bandwidth in megabytes per
+// second, using a buffer of the given size.
+func benchMemoryBandwidth(limit time.Duration, size int) int64 {
+	half := size / 2
+	if half == 0 {
+		return 0
+	}
+	buf := make([]byte, 2*half)
+	src, dst := buf[:half], buf[half:]
+	// Touch the pages before measuring
+	for i := 0; i < len(buf); i += os.Getpagesize() {
+		buf[i] = byte(i)
+	}
+
+	copied := 0
+	b := newBudget(limit)
+	for !b.exhausted() {
+		copy(dst, src)
+		src, dst = dst, src
+		copied += half
+	}
+	return perSecond(float64(copied), b.used(), 1e6)
+}
+
+// benchMemoryLatency measures the memory access latency in nanoseconds by
+// chasing pointers in random order over a buffer of the given size.
+func benchMemoryLatency(limit time.Duration, size int) int64 {
+	const chunk = 1 << 16
+
+	// One slot per cache line, all linked in one cycle in random order
+	stride := cacheLineSize / 8
+	n := size / cacheLineSize
+	if n < 2 {
+		return 0
+	}
+	buf := make([]uint64, n*stride)
+	perm := rand.New(rand.NewSource(1)).Perm(n)
+	for i := 0; i < n; i++ {
+		buf[perm[i]*stride] = uint64(perm[(i+1)%n] * stride)
+	}
+
+	p, accesses := uint64(0), 0
+	b := newBudget(limit)
+	for !b.exhausted() {
+		for i := 0; i < chunk; i++ {
+			p = buf[p]
+		}
+		accesses += chunk
+	}
+	sink += p
+	if accesses == 0 {
+		return 0
+	}
+	return int64(math.Round(float64(b.used().Nanoseconds()) / float64(accesses)))
+}
+
+// benchDiskRead measures the random read IOPS of a file or block device.
+// The page cache is bypassed if supported by the file system.
+func benchDiskRead(limit time.Duration, path string) (int64, error) {
+	f, err := openDirect(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine size of %q: %w", path, err)
+	}
+	blocks := size / diskBlockSize
+	if blocks == 0 {
+		return 0, fmt.Errorf("%q is smaller than %d bytes", path, diskBlockSize)
+	}
+
+	// Direct I/O requires a buffer aligned to the block size
+	raw := make([]byte, 2*diskBlockSize)
+	off := int(uintptr(unsafe.Pointer(&raw[0])) % diskBlockSize)
+	if off != 0 {
+		off = diskBlockSize - off
+	}
+	buf := raw[off : off+diskBlockSize]
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	reads := 0
+	// Reads mostly wait for I/O so limit the wall-clock time instead of CPU
+	// time
+	start := time.Now()
+	for time.Since(start) < limit {
+		if _, err := f.ReadAt(buf, rnd.Int63n(blocks)*diskBlockSize); err != nil {
+			return 0, fmt.Errorf("failed to read %q: %w", path, err)
+		}
+		reads++
+	}
+	return perSecond(float64(reads), time.Since(start), 1), nil
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package benchmark
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/utils"
+	"sigs.k8s.io/node-feature-discovery/source"
+)
+
+// Name of this feature source
+const Name = "benchmark"
+
+const (
+	// CPUFeature is the attribute feature holding the CPU benchmark results
+	CPUFeature = "cpu"
+	// MemoryFeature is the attribute feature holding the memory benchmark results
+	MemoryFeature = "memory"
+	// DiskFeature is the instance feature holding the disk benchmark results
+	DiskFeature = "disk"
+)
+
+// bootIDPath is the file containing the boot ID of the host
+var bootIDPath = "/proc/sys/kernel/random/boot_id"
+
+// Config contains the configuration parameters of this source.
+type Config struct {
+	// TimeLimit is the CPU time budget of each benchmark
+	TimeLimit utils.DurationVal `json:"timeLimit,omitempty"`
+	// MemoryBufferSizeMiB is the size of the buffer used in the memory
+	// benchmarks
+	MemoryBufferSizeMiB int `json:"memoryBufferSizeMiB,omitempty"`
+	// DiskPaths are the files or block devices to run the disk read
+	// benchmark on
+	DiskPaths []string `json:"diskPaths,omitempty"`
+	// CacheFile is the file where the results are stored across restarts of
+	// nfd-worker. Results are only kept in memory if empty.
+	CacheFile string `json:"cacheFile,omitempty"`
+}
+
+// Limits of the configuration parameters, preventing benchmarks from
+// exhausting the resources of nfd-worker
+const (
+	maxTimeLimit           = 10 * time.Second
+	maxMemoryBufferSizeMiB = 1024
+)
+
+// newDefaultConfig returns a new config with pre-populated defaults
+func newDefaultConfig() *Config {
+	return &Config{
+		TimeLimit:           utils.DurationVal{Duration: 200 * time.Millisecond},
+		MemoryBufferSizeMiB: 64,
+	}
+}
+
+// cachedResults are the results of a benchmark run, valid for one boot and
+// configuration of the source.
+type cachedResults struct {
+	Key      string                `json:"key"`
+	Features *nfdv1alpha1.Features `json:"features"`
+}
+
+// benchmarkSource implements the FeatureSource, ConfigurableSource and
+// SupplementalSource interfaces.
+type benchmarkSource struct {
+	config   *Config
+	features *nfdv1alpha1.Features
+	cache    *cachedResults
+}
+
+// Singleton source instance
+var (
+	src                            = benchmarkSource{config: newDefaultConfig()}
+	_   source.FeatureSource       = &src
+	_   source.ConfigurableSource  = &src
+	_   source.SupplementalSource  = &src
+	_   source.StaticFeatureSource = &src
+)
+
+// Name returns an identifier string for this feature source.
+func (s *benchmarkSource) Name() string { return Name }
+
+// NewConfig method of the ConfigurableSource interface
+func (s *benchmarkSource) NewConfig() source.Config { return newDefaultConfig() }
+
+// GetConfig method of the ConfigurableSource interface
+func (s *benchmarkSource) GetConfig() source.Config { return s.config }
+
+// SetConfig method of the ConfigurableSource interface
+func (s *benchmarkSource) SetConfig(conf source.Config) {
+	switch v := conf.(type) {
+	case *Config:
+		v.sanitize()
+		s.config = v
+	default:
+		panic(fmt.Sprintf("invalid config type: %T", conf))
+	}
+}
+
+// sanitize replaces out-of-range configuration parameters with their
+// defaults.
+func (c *Config) sanitize() {
+	defaults := newDefaultConfig()
+	if d := c.TimeLimit.Duration; d <= 0 || d > maxTimeLimit {
+		klog.ErrorS(nil, "invalid timeLimit, using the default", "timeLimit", d, "max", maxTimeLimit, "default", defaults.TimeLimit.Duration)
+		c.TimeLimit = defaults.TimeLimit
+	}
+	if n := c.MemoryBufferSizeMiB; n <= 0 || n > maxMemoryBufferSizeMiB {
+		klog.ErrorS(nil, "invalid memoryBufferSizeMiB, using the default", "memoryBufferSizeMiB", n, "max", maxMemoryBufferSizeMiB, "default", defaults.MemoryBufferSizeMiB)
+		c.MemoryBufferSizeMiB = defaults.MemoryBufferSizeMiB
+	}
+}
+
+// DisableByDefault method of the SupplementalSource interface. Benchmarks
+// consume CPU time and need to be enabled explicitly.
+func (s *benchmarkSource) DisableByDefault() bool { return true }
+
+// HotplugDirs method of the StaticFeatureSource interface. The results only
+// change on reboot.
+func (s *benchmarkSource) HotplugDirs() []string { return nil }
+
+// Discover method of the FeatureSource interface. The benchmarks are run once
+// per boot, the results are served from the cache afterwards.
+func (s *benchmarkSource) Discover() error {
+	key := s.cacheKey()
+
+	if s.cache == nil && key != "" {
+		s.cache = readCache(s.config.CacheFile)
+	}
+	if s.cache != nil && s.cache.Key == key {
+		klog.V(2).InfoS("using cached benchmark results", "featureSource", s.Name())
+		s.features = s.cache.Features
+		return nil
+	}
+
+	klog.InfoS("running benchmarks", "featureSource", s.Name(), "timeLimit", s.config.TimeLimit.Duration)
+	s.features = runBenchmarks(s.config)
+	s.cache = &cachedResults{Key: key, Features: s.features}
+	if key != "" {
+		if err := writeCache(s.config.CacheFile, s.cache); err != nil {
+			klog.ErrorS(err, "failed to store benchmark results", "path", s.config.CacheFile)
+		}
+	}
+
+	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+
+	return nil
+}
+
+// GetFeatures method of the FeatureSource Interface.
+func (s *benchmarkSource) GetFeatures() *nfdv1alpha1.Features {
+	if s.features == nil {
+		s.features = nfdv1alpha1.NewFeatures()
+	}
+	return s.features
+}
+
+// cacheKey returns a key identifying the boot and the configuration of the
+// source. Returns an empty string if the boot ID cannot be read, in which case
+// the results are only cached for the lifetime of the process.
+func (s *benchmarkSource) cacheKey() string {
+	bootID, err := os.ReadFile(bootIDPath)
+	if err != nil {
+		klog.V(1).InfoS("failed to read boot ID, not persisting benchmark results", "err", err)
+		return ""
+	}
+	conf, err := json.Marshal(s.config)
+	if err != nil {
+		return ""
+	}
+	h := sha256.Sum256([]byte(strings.TrimSpace(string(bootID)) + "\n" + string(conf)))
+	return hex.EncodeToString(h[:])
+}
+
+func readCache(path string) *cachedResults {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.ErrorS(err, "failed to read cached benchmark results", "path", path)
+		}
+		return nil
+	}
+	c := &cachedResults{}
+	if err := json.Unmarshal(data, c); err != nil || c.Features == nil {
+		klog.ErrorS(err, "ignoring invalid cached benchmark results", "path", path)
+		return nil
+	}
+	return c
+}
+
+func writeCache(path string, c *cachedResults) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Write atomically so that a crash does not leave a truncated file behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func init() {
+	source.Register(&src)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/node-feature-discovery/pkg/utils"
+)
+
+func TestBenchmarkSource(t *testing.T) {
+	assert.Equal(t, src.Name(), Name)
+	assert.True(t, src.DisableByDefault())
+
+	tmpDir := t.TempDir()
+	bootIDPath = filepath.Join(tmpDir, "boot_id")
+	assert.NoError(t, os.WriteFile(bootIDPath, []byte("boot-1\n"), 0644))
+	diskPath := filepath.Join(tmpDir, "disk")
+	assert.NoError(t, os.WriteFile(diskPath, make([]byte, 16*diskBlockSize), 0644))
+
+	s := &benchmarkSource{config: &Config{
+		TimeLimit:           utils.DurationVal{Duration: 10 * time.Millisecond},
+		MemoryBufferSizeMiB: 1,
+		DiskPaths:           []string{diskPath, filepath.Join(tmpDir, "missing")},
+		CacheFile:           filepath.Join(tmpDir, "cache", "benchmark.json"),
+	}}
+	assert.NoError(t, s.Discover())
+	features := s.GetFeatures()
+
+	assertPositive := func(v string) {
+		i, err := strconv.ParseInt(v, 10, 64)
+		assert.NoError(t, err)
+		assert.Positive(t, i)
+	}
+	assertPositive(features.Attributes[CPUFeature].Elements["int_mops"])
+	assertPositive(features.Attributes[CPUFeature].Elements["fp_mflops"])
+	assertPositive(features.Attributes[MemoryFeature].Elements["bandwidth_mbps"])
+	assertPositive(features.Attributes[MemoryFeature].Elements["latency_ns"])
+	// Failing disk paths are skipped
+	assert.Len(t, features.Instances[DiskFeature].Elements, 1)
+	assert.Equal(t, diskPath, features.Instances[DiskFeature].Elements[0].Attributes["path"])
+	assertPositive(features.Instances[DiskFeature].Elements[0].Attributes["read_iops"])
+
+	// Results are re-used within the same boot, also after a restart
+	s2 := &benchmarkSource{config: s.config}
+	assert.NoError(t, s2.Discover())
+	assert.Equal(t, features, s2.GetFeatures())
+
+	// Benchmarks are re-run after a reboot
+	assert.NoError(t, os.WriteFile(bootIDPath, []byte("boot-2\n"), 0644))
+	s.cache.Features.Attributes[CPUFeature].Elements["int_mops"] = "-1"
+	assert.NoError(t, s.Discover())
+	assertPositive(s.GetFeatures().Attributes[CPUFeature].Elements["int_mops"])
+}
+
+func TestSetConfig(t *testing.T) {
+	s := &benchmarkSource{}
+	defaults := newDefaultConfig()
+
+	s.SetConfig(&Config{TimeLimit: utils.DurationVal{Duration: -time.Second}, MemoryBufferSizeMiB: -1})
+	assert.Equal(t, defaults.TimeLimit, s.config.TimeLimit)
+	assert.Equal(t, defaults.MemoryBufferSizeMiB, s.config.MemoryBufferSizeMiB)
+
+	s.SetConfig(&Config{TimeLimit: utils.DurationVal{Duration: time.Hour}, MemoryBufferSizeMiB: 1 << 20})
+	assert.Equal(t, defaults.TimeLimit, s.config.TimeLimit)
+	assert.Equal(t, defaults.MemoryBufferSizeMiB, s.config.MemoryBufferSizeMiB)
+
+	s.SetConfig(&Config{TimeLimit: utils.DurationVal{Duration: time.Second}, MemoryBufferSizeMiB: 256})
+	assert.Equal(t, time.Second, s.config.TimeLimit.Duration)
+	assert.Equal(t, 256, s.config.MemoryBufferSizeMiB)
+}
@@ -0,0 +1,38 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package benchmark
+
+import (
+	"os"
+	"time"
+)
+
+var processStart = time.Now()
+
+// threadCPUTime falls back to the wall-clock time on platforms without
+// per-thread CPU time accounting.
+func threadCPUTime() time.Duration {
+	return time.Since(processStart)
+}
+
+// openDirect opens a file for reading.
+func openDirect(path string) (*os.File, error) {
+	return os.Open(path)
+}
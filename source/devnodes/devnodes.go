@@ -0,0 +1,241 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devnodes
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/utils"
+	"sigs.k8s.io/node-feature-discovery/pkg/utils/hostpath"
+	"sigs.k8s.io/node-feature-discovery/source"
+)
+
+// Name of this feature source
+const Name = "devnodes"
+
+const (
+	// DeviceFeature is the name of the feature set that holds the device
+	// nodes found
+	DeviceFeature = "device"
+	// PresentFeature is the name of the feature set that holds the paths of
+	// the device nodes found
+	PresentFeature = "present"
+)
+
+// devPrefix is the directory containing the device nodes on the host
+const devPrefix = "/dev/"
+
+// Config contains the configuration parameters of this source.
+type Config struct {
+	// Paths are the device nodes to detect. Glob patterns are supported.
+	Paths []string `json:"paths,omitempty"`
+}
+
+// newDefaultConfig returns a new config with pre-populated defaults
+func newDefaultConfig() *Config {
+	return &Config{
+		Paths: []string{
+			// Virtualization
+			"/dev/kvm",
+			"/dev/vhost-net",
+			"/dev/vhost-vsock",
+			"/dev/net/tun",
+			"/dev/vfio/vfio",
+			"/dev/fuse",
+			// Confidential computing
+			"/dev/sgx_enclave",
+			"/dev/sgx_provision",
+			"/dev/sev",
+			"/dev/sev-guest",
+			"/dev/tdx_guest",
+			// Security devices
+			"/dev/tpm[0-9]*",
+			"/dev/tpmrm[0-9]*",
+			"/dev/hwrng",
+		},
+	}
+}
+
+// devnodesSource implements the FeatureSource, ConfigurableSource and
+// StaticFeatureSource interfaces.
+type devnodesSource struct {
+	config   *Config
+	features *nfdv1alpha1.Features
+}
+
+// Singleton source instance
+var (
+	src                            = devnodesSource{config: newDefaultConfig()}
+	_   source.FeatureSource       = &src
+	_   source.ConfigurableSource  = &src
+	_   source.StaticFeatureSource = &src
+)
+
+// Name returns an identifier string for this feature source.
+func (s *devnodesSource) Name() string { return Name }
+
+// NewConfig method of the ConfigurableSource interface
+func (s *devnodesSource) NewConfig() source.Config { return newDefaultConfig() }
+
+// GetConfig method of the ConfigurableSource interface
+func (s *devnodesSource) GetConfig() source.Config { return s.config }
+
+// SetConfig method of the ConfigurableSource interface
+func (s *devnodesSource) SetConfig(conf source.Config) {
+	switch v := conf.(type) {
+	case *Config:
+		s.config = v
+	default:
+		panic(fmt.Sprintf("invalid config type: %T", conf))
+	}
+}
+
+// HotplugDirs method of the StaticFeatureSource interface. Devices are
+// added and removed when they are hotplugged or kernel modules loaded.
+func (s *devnodesSource) HotplugDirs() []string {
+	return []string{hostpath.SysfsDir.Path("dev", "block"), hostpath.SysfsDir.Path("dev", "char")}
+}
+
+// Discover method of the FeatureSource interface
+func (s *devnodesSource) Discover() error {
+	s.features = nfdv1alpha1.NewFeatures()
+
+	devs := detectDevNodes(s.config.Paths)
+	paths := make([]string, 0, len(devs))
+	for _, d := range devs {
+		paths = append(paths, d.Attributes["path"])
+	}
+	s.features.Instances[DeviceFeature] = nfdv1alpha1.NewInstanceFeatures(devs)
+	s.features.Flags[PresentFeature] = nfdv1alpha1.NewFlagFeatures(paths...)
+
+	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+
+	return nil
+}
+
+// GetFeatures method of the FeatureSource Interface
+func (s *devnodesSource) GetFeatures() *nfdv1alpha1.Features {
+	if s.features == nil {
+		s.features = nfdv1alpha1.NewFeatures()
+	}
+	return s.features
+}
+
+// detectDevNodes returns the device nodes matching the given paths. The
+// device nodes are not looked up from /dev, instead the devices known to the
+// kernel are listed from /sys/dev and matched by the names of their device
+// nodes. This way the /dev directory of the host need not be accessible.
+func detectDevNodes(patterns []string) []nfdv1alpha1.InstanceFeature {
+	var valid []string
+	for _, pattern := range patterns {
+		pattern = path.Clean(pattern)
+		if !strings.HasPrefix(pattern, devPrefix) {
+			klog.InfoS("ignoring device path outside /dev", "path", pattern)
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			klog.ErrorS(err, "invalid device path pattern", "path", pattern)
+			continue
+		}
+		valid = append(valid, pattern)
+	}
+
+	devs := []nfdv1alpha1.InstanceFeature{}
+	if len(valid) == 0 {
+		return devs
+	}
+	for _, devType := range []string{"block", "char"} {
+		entries, err := os.ReadDir(hostpath.SysfsDir.Path("dev", devType))
+		if err != nil {
+			klog.ErrorS(err, "failed to list devices", "type", devType)
+			continue
+		}
+		for _, e := range entries {
+			attrs := devNodeAttrs(devType, e.Name())
+			if attrs == nil || !matchAny(valid, attrs["path"]) {
+				continue
+			}
+			devs = append(devs, *nfdv1alpha1.NewInstanceFeature(attrs))
+		}
+	}
+	sort.Slice(devs, func(i, j int) bool { return devs[i].Attributes["path"] < devs[j].Attributes["path"] })
+	return devs
+}
+
+// devNodeAttrs returns the attributes of the device node of a device, given
+// the type and the major:minor number of the device. Returns nil if the
+// device has no device node.
+func devNodeAttrs(devType, devNum string) map[string]string {
+	major, minor, ok := strings.Cut(devNum, ":")
+	if !ok {
+		return nil
+	}
+	sysPath := hostpath.SysfsDir.Path("dev", devType, devNum)
+
+	// The uevent file tells the name of the device node relative to /dev
+	data, err := os.ReadFile(filepath.Join(sysPath, "uevent"))
+	if err != nil {
+		klog.V(4).InfoS("failed to read uevent of device", "path", sysPath, "err", err)
+		return nil
+	}
+	var devName string
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "DEVNAME="); ok {
+			devName = v
+			break
+		}
+	}
+	if devName == "" {
+		return nil
+	}
+
+	attrs := map[string]string{
+		"path":  devPrefix + devName,
+		"type":  devType,
+		"major": major,
+		"minor": minor,
+	}
+	if v := utils.LinkBase(filepath.Join(sysPath, "device", "driver")); v != "" {
+		attrs["driver"] = v
+	}
+	if v := utils.LinkBase(filepath.Join(sysPath, "subsystem")); v != "" {
+		attrs["subsystem"] = v
+	}
+	return attrs
+}
+
+// matchAny returns true if the path matches any of the glob patterns.
+func matchAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	source.Register(&src)
+}
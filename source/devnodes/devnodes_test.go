@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devnodes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/node-feature-discovery/pkg/utils/hostpath"
+)
+
+func TestDevnodesSource(t *testing.T) {
+	assert.Equal(t, src.Name(), Name)
+
+	// Check that GetFeatures works with empty features
+	src.features = nil
+	assert.Empty(t, src.GetFeatures().Instances)
+}
+
+func TestDetectDevNodes(t *testing.T) {
+	root := t.TempDir()
+	oldSysfsDir := hostpath.SysfsDir
+	hostpath.SysfsDir = hostpath.HostDir(root)
+	defer func() { hostpath.SysfsDir = oldSysfsDir }()
+
+	mkdev := func(devType, devNum, uevent string) string {
+		p := filepath.Join(root, "dev", devType, devNum)
+		assert.NoError(t, os.MkdirAll(filepath.Join(p, "device"), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(p, "uevent"), []byte(uevent), 0644))
+		return p
+	}
+	kvm := mkdev("char", "10:232", "MAJOR=10\nMINOR=232\nDEVNAME=kvm\nDEVMODE=0666\n")
+	assert.NoError(t, os.Symlink("../../../class/misc", filepath.Join(kvm, "subsystem")))
+	assert.NoError(t, os.Symlink("../../../bus/platform/drivers/kvm", filepath.Join(kvm, "device", "driver")))
+	mkdev("char", "10:200", "MAJOR=10\nMINOR=200\nDEVNAME=net/tun\n")
+	mkdev("char", "10:224", "MAJOR=10\nMINOR=224\nDEVNAME=tpm0\n")
+	mkdev("char", "5:0", "MAJOR=5\nMINOR=0\nDEVNAME=tty\n")
+	mkdev("block", "259:0", "MAJOR=259\nMINOR=0\nDEVNAME=nvme0n1\nDEVTYPE=disk\n")
+	// Devices without a device node
+	mkdev("char", "4:1", "MAJOR=4\nMINOR=1\n")
+
+	devs := detectDevNodes([]string{"/dev/kvm", "/dev/net/tun", "/dev/tpm*", "/dev/nvme*", "/dev/missing", "/etc/passwd", "/dev/kvm", "/dev/[", "/dev/../dev/tty"})
+	assert.Len(t, devs, 5)
+	assert.Equal(t, map[string]string{
+		"path":      "/dev/kvm",
+		"type":      "char",
+		"major":     "10",
+		"minor":     "232",
+		"driver":    "kvm",
+		"subsystem": "misc",
+	}, devs[0].Attributes)
+	assert.Equal(t, "/dev/net/tun", devs[1].Attributes["path"])
+	assert.Equal(t, map[string]string{
+		"path":  "/dev/nvme0n1",
+		"type":  "block",
+		"major": "259",
+		"minor": "0",
+	}, devs[2].Attributes)
+	assert.Equal(t, "/dev/tpm0", devs[3].Attributes["path"])
+	assert.Equal(t, "/dev/tty", devs[4].Attributes["path"])
+
+	src.config = &Config{Paths: []string{"/dev/kvm", "/dev/net/tun", "/dev/tpm*"}}
+	defer func() { src.config = newDefaultConfig() }()
+	assert.Equal(t, []string{filepath.Join(root, "dev", "block"), filepath.Join(root, "dev", "char")}, src.HotplugDirs())
+	assert.NoError(t, src.Discover())
+	assert.Len(t, src.GetFeatures().Instances[DeviceFeature].Elements, 3)
+	assert.Contains(t, src.GetFeatures().Flags[PresentFeature].Elements, "/dev/net/tun")
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package firmware
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// acpiHeaderLen is the length of the common header of ACPI system description
+// tables
+const acpiHeaderLen = 36
+
+// hmatAttrs are the memory access attributes of a NUMA node, in access class
+// directories
+var hmatAttrs = []string{"read_latency", "write_latency", "read_bandwidth", "write_bandwidth"}
+
+var (
+	tableIndexSuffix = regexp.MustCompile(`\d+$`)
+	numaNodeDir      = regexp.MustCompile(`^node(\d+)$`)
+	accessClassDir   = regexp.MustCompile(`^access(\d+)$`)
+)
+
+// acpiHeaderWarning makes the missing privileges for reading the ACPI table
+// headers to be reported only once
+var acpiHeaderWarning sync.Once
+
+// detectAcpiTables returns the ACPI tables of the platform. The files under
+// /sys/firmware/acpi/tables are only readable by root. Without root
+// privileges, only the name and signature of the tables are detected and this
+// degraded mode is reported once.
+func detectAcpiTables(tablesPath string) ([]nfdv1alpha1.InstanceFeature, error) {
+	entries, err := os.ReadDir(tablesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []nfdv1alpha1.InstanceFeature{}, nil
+		}
+		return nil, err
+	}
+
+	tables := make([]nfdv1alpha1.InstanceFeature, 0, len(entries))
+	for _, e := range entries {
+		// Skip the data and dynamic subdirectories
+		if e.IsDir() {
+			continue
+		}
+		attrs, err := readAcpiTableHeader(filepath.Join(tablesPath, e.Name()))
+		if err != nil {
+			if os.IsPermission(err) {
+				acpiHeaderWarning.Do(func() {
+					klog.InfoS("ACPI table headers are not readable, nfd-worker must run as root to read them, only the name and signature of the tables are detected", "path", tablesPath)
+				})
+			} else {
+				klog.V(4).InfoS("failed to read ACPI table header", "table", e.Name(), "err", err)
+			}
+			attrs = map[string]string{"signature": tableIndexSuffix.ReplaceAllString(e.Name(), "")}
+		}
+		attrs["name"] = e.Name()
+		tables = append(tables, *nfdv1alpha1.NewInstanceFeature(attrs))
+	}
+	return tables, nil
+}
+
+// readAcpiTableHeader reads and parses the common header of an ACPI table.
+func readAcpiTableHeader(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hdr := make([]byte, acpiHeaderLen)
+	if _, err := io.ReadFull(f, hdr); err != nil {
+		return nil, err
+	}
+	return parseAcpiTableHeader(hdr)
+}
+
+// parseAcpiTableHeader parses the common header of an ACPI table.
+func parseAcpiTableHeader(hdr []byte) (map[string]string, error) {
+	if len(hdr) < acpiHeaderLen {
+		return nil, fmt.Errorf("short ACPI table header (%d bytes)", len(hdr))
+	}
+	signature := acpiString(hdr[0:4])
+	if signature == "" {
+		return nil, fmt.Errorf("invalid ACPI table signature %q", hdr[0:4])
+	}
+	attrs := map[string]string{
+		"signature":    signature,
+		"length":       strconv.FormatUint(uint64(binary.LittleEndian.Uint32(hdr[4:8])), 10),
+		"revision":     strconv.Itoa(int(hdr[8])),
+		"oem_revision": strconv.FormatUint(uint64(binary.LittleEndian.Uint32(hdr[24:28])), 10),
+	}
+	if s := acpiString(hdr[10:16]); s != "" {
+		attrs["oem_id"] = s
+	}
+	if s := acpiString(hdr[16:24]); s != "" {
+		attrs["oem_table_id"] = s
+	}
+	if s := acpiString(hdr[28:32]); s != "" {
+		attrs["creator_id"] = s
+	}
+	return attrs, nil
+}
+
+// acpiString converts a fixed-length, space or NUL padded ACPI string field
+// into a string. Returns an empty string if the field contains non-printable
+// characters.
+func acpiString(b []byte) string {
+	s := strings.TrimRight(string(b), " \x00")
+	for _, c := range s {
+		if c < 0x20 || c > 0x7e {
+			return ""
+		}
+	}
+	return s
+}
+
+// detectHmat returns the memory access performance of the NUMA nodes with
+// memory, per access class, as parsed from the ACPI HMAT by the kernel.
+// Access class 0 describes the access from the nearest initiators of any type
+// and access class 1 from the nearest CPUs.
+func detectHmat(nodesPath string) ([]nfdv1alpha1.InstanceFeature, error) {
+	nodes, err := os.ReadDir(nodesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []nfdv1alpha1.InstanceFeature{}, nil
+		}
+		return nil, err
+	}
+
+	features := []nfdv1alpha1.InstanceFeature{}
+	for _, n := range nodes {
+		m := numaNodeDir.FindStringSubmatch(n.Name())
+		if m == nil {
+			continue
+		}
+		nodePath := filepath.Join(nodesPath, n.Name())
+		classes, err := os.ReadDir(nodePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range classes {
+			cm := accessClassDir.FindStringSubmatch(c.Name())
+			if cm == nil {
+				continue
+			}
+			attrs := readHmatAttrs(filepath.Join(nodePath, c.Name(), "initiators"))
+			if len(attrs) == 0 {
+				continue
+			}
+			attrs["node"] = m[1]
+			attrs["access_class"] = cm[1]
+			features = append(features, *nfdv1alpha1.NewInstanceFeature(attrs))
+		}
+	}
+	sort.Slice(features, func(i, j int) bool {
+		a, b := features[i].Attributes, features[j].Attributes
+		for _, k := range []string{"node", "access_class"} {
+			x, _ := strconv.Atoi(a[k])
+			y, _ := strconv.Atoi(b[k])
+			if x != y {
+				return x < y
+			}
+		}
+		return false
+	})
+	return features, nil
+}
+
+// readHmatAttrs reads the memory access attributes and the initiator nodes of
+// one access class of a NUMA node.
+func readHmatAttrs(initiatorsPath string) map[string]string {
+	attrs := make(map[string]string)
+	for _, a := range hmatAttrs {
+		if data, err := os.ReadFile(filepath.Join(initiatorsPath, a)); err == nil {
+			attrs[a] = strings.TrimSpace(string(data))
+		}
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	entries, _ := os.ReadDir(initiatorsPath)
+	var initiators []int
+	for _, e := range entries {
+		if m := numaNodeDir.FindStringSubmatch(e.Name()); m != nil {
+			id, _ := strconv.Atoi(m[1])
+			initiators = append(initiators, id)
+		}
+	}
+	sort.Ints(initiators)
+	ids := make([]string, len(initiators))
+	for i, id := range initiators {
+		ids[i] = strconv.Itoa(id)
+	}
+	attrs["initiators"] = strings.Join(ids, ",")
+	return attrs
+}
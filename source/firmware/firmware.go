@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package firmware
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/utils"
+	"sigs.k8s.io/node-feature-discovery/pkg/utils/hostpath"
+	"sigs.k8s.io/node-feature-discovery/source"
+)
+
+// Name of this feature source
+const Name = "firmware"
+
+const (
+	// AcpiTableFeature is the name of the feature set that holds the ACPI
+	// tables of the platform
+	AcpiTableFeature = "acpi_table"
+	// BootFeature is the name of the feature set that holds the boot mode and
+	// the firmware services available to the OS
+	BootFeature = "boot"
+	// HmatFeature is the name of the feature set that holds the memory access
+	// performance of NUMA nodes, as described by the ACPI HMAT
+	HmatFeature = "hmat"
+)
+
+// firmwareSource implements the FeatureSource and StaticFeatureSource
+// interfaces.
+type firmwareSource struct {
+	features *nfdv1alpha1.Features
+}
+
+// Singleton source instance
+var (
+	src firmwareSource
+	_   source.FeatureSource       = &src
+	_   source.StaticFeatureSource = &src
+)
+
+// Name returns an identifier string for this feature source.
+func (s *firmwareSource) Name() string { return Name }
+
+// HotplugDirs method of the StaticFeatureSource interface. NUMA nodes may be
+// added on memory hotplug, e.g. with CXL memory devices.
+func (s *firmwareSource) HotplugDirs() []string {
+	return []string{hostpath.SysfsDir.Path("devices/system/node")}
+}
+
+// Discover method of the FeatureSource interface
+func (s *firmwareSource) Discover() error {
+	s.features = nfdv1alpha1.NewFeatures()
+
+	tables, err := detectAcpiTables(hostpath.SysfsDir.Path("firmware/acpi/tables"))
+	if err != nil {
+		klog.ErrorS(err, "failed to detect ACPI tables")
+	} else {
+		s.features.Instances[AcpiTableFeature] = nfdv1alpha1.NewInstanceFeatures(tables)
+	}
+
+	s.features.Attributes[BootFeature] = nfdv1alpha1.NewAttributeFeatures(detectBoot(hostpath.SysfsDir.Path("firmware/efi")))
+
+	hmat, err := detectHmat(hostpath.SysfsDir.Path("devices/system/node"))
+	if err != nil {
+		klog.ErrorS(err, "failed to detect HMAT memory access attributes")
+	} else {
+		s.features.Instances[HmatFeature] = nfdv1alpha1.NewInstanceFeatures(hmat)
+	}
+
+	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+
+	return nil
+}
+
+// GetFeatures method of the FeatureSource Interface
+func (s *firmwareSource) GetFeatures() *nfdv1alpha1.Features {
+	if s.features == nil {
+		s.features = nfdv1alpha1.NewFeatures()
+	}
+	return s.features
+}
+
+// efiRuntimeServicesFiles are files under the EFI sysfs directory, any of
+// which indicates that the EFI runtime services are available to the OS
+var efiRuntimeServicesFiles = []string{"runtime", "runtime-map", "efivars", "vars"}
+
+// detectBoot detects the boot mode of the system, i.e. whether the system was
+// booted by EFI firmware or legacy BIOS.
+func detectBoot(efiPath string) map[string]string {
+	if _, err := os.Stat(efiPath); err != nil {
+		return map[string]string{"mode": "legacy"}
+	}
+
+	attrs := map[string]string{
+		"mode":                 "efi",
+		"efi_runtime_services": "false",
+	}
+	if data, err := os.ReadFile(filepath.Join(efiPath, "fw_platform_size")); err == nil {
+		attrs["efi_platform_size"] = strings.TrimSpace(string(data))
+	}
+	for _, f := range efiRuntimeServicesFiles {
+		if _, err := os.Stat(filepath.Join(efiPath, f)); err == nil {
+			attrs["efi_runtime_services"] = "true"
+			break
+		}
+	}
+	return attrs
+}
+
+func init() {
+	source.Register(&src)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package firmware
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirmwareSource(t *testing.T) {
+	assert.Equal(t, src.Name(), Name)
+
+	// Check that GetFeatures works with empty features
+	src.features = nil
+	assert.Empty(t, src.GetFeatures().Instances)
+}
+
+func newAcpiTableHeader(signature, oemID, oemTableID string, length uint32) []byte {
+	hdr := make([]byte, acpiHeaderLen)
+	copy(hdr[0:4], signature)
+	binary.LittleEndian.PutUint32(hdr[4:8], length)
+	hdr[8] = 2
+	copy(hdr[10:16], oemID+"      ")
+	copy(hdr[16:24], oemTableID+"        ")
+	binary.LittleEndian.PutUint32(hdr[24:28], 1)
+	copy(hdr[28:32], "INTL")
+	return hdr
+}
+
+func TestDetectAcpiTables(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "dynamic"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "HMAT"), newAcpiTableHeader("HMAT", "INTEL", "EDK2", 1024), 0644))
+	// Truncated table, only the name is known
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "SSDT2"), []byte("SSDT"), 0644))
+
+	tables, err := detectAcpiTables(root)
+	assert.NoError(t, err)
+	assert.Len(t, tables, 2)
+	assert.Equal(t, map[string]string{
+		"name":         "HMAT",
+		"signature":    "HMAT",
+		"length":       "1024",
+		"revision":     "2",
+		"oem_id":       "INTEL",
+		"oem_table_id": "EDK2",
+		"oem_revision": "1",
+		"creator_id":   "INTL",
+	}, tables[0].Attributes)
+	assert.Equal(t, map[string]string{"name": "SSDT2", "signature": "SSDT"}, tables[1].Attributes)
+
+	// No ACPI
+	tables, err = detectAcpiTables(filepath.Join(root, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, tables)
+}
+
+func TestDetectBoot(t *testing.T) {
+	root := t.TempDir()
+	assert.Equal(t, map[string]string{"mode": "legacy"}, detectBoot(filepath.Join(root, "efi")))
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "efi"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "efi", "fw_platform_size"), []byte("64\n"), 0644))
+	assert.Equal(t, map[string]string{"mode": "efi", "efi_platform_size": "64", "efi_runtime_services": "false"}, detectBoot(filepath.Join(root, "efi")))
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "efi", "efivars"), 0755))
+	assert.Equal(t, "true", detectBoot(filepath.Join(root, "efi"))["efi_runtime_services"])
+}
+
+func TestDetectHmat(t *testing.T) {
+	root := t.TempDir()
+	mkfile := func(path, content string) {
+		p := filepath.Join(root, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.NoError(t, os.WriteFile(p, []byte(content+"\n"), 0644))
+	}
+	// DRAM node with a CPU initiator and a CPU-less CXL memory node
+	mkfile("node0/access0/initiators/read_latency", "80")
+	mkfile("node0/access0/initiators/read_bandwidth", "100000")
+	mkfile("node0/access0/initiators/node0", "")
+	mkfile("node10/access0/initiators/read_latency", "250")
+	mkfile("node10/access0/initiators/write_latency", "300")
+	mkfile("node10/access0/initiators/node0", "")
+	mkfile("node10/access0/initiators/node1", "")
+	mkfile("node10/access1/initiators/read_latency", "260")
+	mkfile("node10/access1/initiators/node0", "")
+	// Node without access attributes
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "node1"), 0755))
+	mkfile("possible", "0-1,10")
+
+	features, err := detectHmat(root)
+	assert.NoError(t, err)
+	assert.Len(t, features, 3)
+	assert.Equal(t, map[string]string{"node": "0", "access_class": "0", "initiators": "0", "read_latency": "80", "read_bandwidth": "100000"}, features[0].Attributes)
+	assert.Equal(t, map[string]string{"node": "10", "access_class": "0", "initiators": "0,1", "read_latency": "250", "write_latency": "300"}, features[1].Attributes)
+	assert.Equal(t, "1", features[2].Attributes["access_class"])
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/node-feature-discovery/pkg/utils/hostpath"
+)
+
+var (
+	// Markers around the gzipped kernel config embedded in the kernel image
+	// by CONFIG_IKCONFIG
+	ikconfigStart = []byte("IKCFG_ST")
+	ikconfigEnd   = []byte("IKCFG_ED")
+
+	// maxKernelImageSize limits the size of the kernel image file.
+	maxKernelImageSize int64 = 64 << 20
+	// maxVmlinuxScanSize limits the amount of decompressed data scanned for
+	// the kernel config. The config is stored in the read-only data of the
+	// kernel, well within the first 128 MiB of the decompressed image.
+	maxVmlinuxScanSize int64 = 128 << 20
+	// maxIkconfigSize limits the size of the (gzipped and decompressed)
+	// kernel config.
+	maxIkconfigSize = 4 << 20
+
+	// ikconfigScanChunkSize is the size of the chunks read when scanning for
+	// the kernel config.
+	ikconfigScanChunkSize = 64 << 10
+)
+
+// kernelCompression is one of the compression formats of the vmlinux payload
+// of a kernel image.
+type kernelCompression struct {
+	name      string
+	magic     []byte
+	newReader func(io.Reader) (io.Reader, error)
+}
+
+var kernelCompressions = []kernelCompression{
+	{
+		name:  "gzip",
+		magic: []byte{0x1f, 0x8b, 0x08},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	{
+		name:      "xz",
+		magic:     xzMagic,
+		newReader: newXzReader,
+	},
+	{
+		name:  "zstd",
+		magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		},
+	},
+	{
+		// Legacy lz4 frame format used by the kernel
+		name:  "lz4",
+		magic: []byte{0x02, 0x21, 0x4c, 0x18},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return lz4.NewReader(r), nil
+		},
+	},
+}
+
+// ikconfigCache caches the kernel config extracted from the kernel image,
+// indexed by kernel version. Extraction is expensive and the result does not
+// change until the node is booted into another kernel. Failures are not
+// cached as the kernel image may become readable later, e.g. after the boot
+// partition has been mounted.
+var ikconfigCache = struct {
+	sync.Mutex
+	configs map[string][]byte
+}{configs: map[string][]byte{}}
+
+// readIkconfig reads the kernel config embedded in the image of the running
+// kernel.
+func readIkconfig(kVer string) ([]byte, error) {
+	ikconfigCache.Lock()
+	defer ikconfigCache.Unlock()
+
+	if data, ok := ikconfigCache.configs[kVer]; ok {
+		return data, nil
+	}
+
+	paths := []string{
+		hostpath.BootDir.Path("vmlinuz-" + kVer),
+		hostpath.UsrDir.Path("lib/modules/" + kVer + "/vmlinuz"),
+	}
+	var errs []error
+	for _, path := range paths {
+		data, err := readIkconfigFromImage(path)
+		if err == nil {
+			klog.V(2).InfoS("read kernel config from kernel image", "path", path)
+			ikconfigCache.configs[kVer] = data
+			return data, nil
+		}
+		errs = append(errs, err)
+	}
+
+	return nil, fmt.Errorf("no embedded kernel config found for kernel %s: %v", kVer, errs)
+}
+
+// readIkconfigFromImage extracts the kernel config embedded in a kernel
+// image file.
+func readIkconfigFromImage(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxKernelImageSize {
+		return nil, fmt.Errorf("%s: kernel image too big (%d bytes)", path, info.Size())
+	}
+	image, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := extractIkconfig(image)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// extractIkconfig extracts the embedded kernel config from a kernel image.
+// The image may be an uncompressed vmlinux or a compressed kernel image
+// (e.g. bzImage) with a gzip, xz, zstd or lz4 compressed vmlinux payload.
+// Similar to scripts/extract-ikconfig of the Linux kernel, all occurrences of
+// the magic numbers of the supported formats are tried as the payload. The
+// payload is decompressed as a stream, without holding the decompressed
+// image in memory.
+func extractIkconfig(image []byte) ([]byte, error) {
+	if data, err := scanIkconfig(bytes.NewReader(image)); err == nil {
+		return data, nil
+	}
+
+	for _, c := range kernelCompressions {
+		for off := 0; ; off++ {
+			i := bytes.Index(image[off:], c.magic)
+			if i < 0 {
+				break
+			}
+			off += i
+
+			if data, err := scanCompressedIkconfig(c, image[off:]); err == nil {
+				klog.V(3).InfoS("found embedded kernel config", "compression", c.name, "offset", off)
+				return data, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("embedded kernel config not found")
+}
+
+// scanCompressedIkconfig scans the compressed vmlinux payload starting at the
+// beginning of the given buffer for the kernel config.
+func scanCompressedIkconfig(c kernelCompression, data []byte) ([]byte, error) {
+	r, err := c.newReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := r.(interface{ Close() }); ok {
+		defer closer.Close()
+	}
+	return scanIkconfig(r)
+}
+
+// scanIkconfig scans a vmlinux image for the gzipped kernel config between
+// the IKCONFIG markers and decompresses it. Read errors are treated as the
+// end of the data as a compressed payload is typically followed by trailing
+// data.
+func scanIkconfig(r io.Reader) ([]byte, error) {
+	s := &markerScanner{r: io.LimitReader(r, maxVmlinuxScanSize)}
+	if _, err := s.until(ikconfigStart, false); err != nil {
+		return nil, fmt.Errorf("IKCONFIG start marker not found")
+	}
+	config, err := s.until(ikconfigEnd, true)
+	if err != nil {
+		return nil, fmt.Errorf("IKCONFIG end marker not found: %w", err)
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(config))
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	data, err := io.ReadAll(io.LimitReader(gr, int64(maxIkconfigSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxIkconfigSize {
+		return nil, fmt.Errorf("kernel config too big")
+	}
+	return data, nil
+}
+
+// markerScanner finds markers in a stream of data, reading it in chunks.
+type markerScanner struct {
+	r   io.Reader
+	buf []byte
+	eof bool
+}
+
+// until consumes the data up to and including the next occurrence of the
+// marker. The data preceding the marker is returned if keep is set, limited
+// to maxIkconfigSize bytes.
+func (s *markerScanner) until(marker []byte, keep bool) ([]byte, error) {
+	var kept []byte
+	chunk := make([]byte, ikconfigScanChunkSize)
+	for {
+		if i := bytes.Index(s.buf, marker); i >= 0 {
+			if keep {
+				kept = append(kept, s.buf[:i]...)
+			}
+			s.buf = s.buf[i+len(marker):]
+			if len(kept) > maxIkconfigSize {
+				return nil, fmt.Errorf("data exceeds %d bytes", maxIkconfigSize)
+			}
+			return kept, nil
+		}
+		if s.eof {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		// Retain a possible partial marker at the end of the buffer
+		if n := len(s.buf) - len(marker) + 1; n > 0 {
+			if keep {
+				kept = append(kept, s.buf[:n]...)
+			}
+			s.buf = append(s.buf[:0], s.buf[n:]...)
+		}
+		if len(kept) > maxIkconfigSize {
+			return nil, fmt.Errorf("data exceeds %d bytes", maxIkconfigSize)
+		}
+
+		n, err := io.ReadFull(s.r, chunk)
+		s.buf = append(s.buf, chunk[:n]...)
+		if err != nil {
+			s.eof = true
+		}
+	}
+}
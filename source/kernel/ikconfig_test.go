@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/node-feature-discovery/pkg/utils/hostpath"
+)
+
+func TestExtractIkconfig(t *testing.T) {
+	// The fixture kernels embed the same config in an uncompressed vmlinux
+	// and in compressed kernel images with a fake setup header
+	for _, name := range []string{"vmlinux", "vmlinuz-gzip", "vmlinuz-xz", "vmlinuz-zstd", "vmlinuz-lz4"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := readIkconfigFromImage(filepath.Join("testdata", name))
+			assert.NoError(t, err)
+			assert.Contains(t, string(data), "CONFIG_IKCONFIG=y\n")
+			assert.Contains(t, string(data), `CONFIG_LOCALVERSION="-fixture"`)
+		})
+	}
+
+	_, err := extractIkconfig([]byte("not a kernel image"))
+	assert.Error(t, err)
+}
+
+func TestXzReader(t *testing.T) {
+	image, err := os.ReadFile(filepath.Join("testdata", "vmlinuz-xz"))
+	assert.NoError(t, err)
+	vmlinux, err := os.ReadFile(filepath.Join("testdata", "vmlinux"))
+	assert.NoError(t, err)
+	stream := image[bytes.LastIndex(image, xzMagic):]
+
+	// The payload of the fixture is x86 code with relative calls and jumps,
+	// compressed with the BCJ filter, followed by the vmlinux fixture. The
+	// result must not depend on how the data is read.
+	for name, wrap := range map[string]func(io.Reader) io.Reader{
+		"full":     func(r io.Reader) io.Reader { return r },
+		"one byte": iotest.OneByteReader,
+		"half":     iotest.HalfReader,
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, err := newXzReader(wrap(bytes.NewReader(stream)))
+			assert.NoError(t, err)
+			data, err := io.ReadAll(wrap(r))
+			assert.NoError(t, err)
+			assert.True(t, bytes.HasSuffix(data, vmlinux))
+			sum := sha256.Sum256(data)
+			assert.Equal(t, "e0227f5fb03d393005ca87075cbcc55362bc359db1c90e78d4ee5a6308f2ebd4", hex.EncodeToString(sum[:]))
+		})
+	}
+
+	// Corrupted stream header
+	corrupted := append([]byte{}, stream...)
+	corrupted[7] ^= 0x01
+	_, err = newXzReader(bytes.NewReader(corrupted))
+	assert.Error(t, err)
+}
+
+func TestScanIkconfig(t *testing.T) {
+	var config bytes.Buffer
+	w := gzip.NewWriter(&config)
+	_, err := w.Write([]byte("CONFIG_IKCONFIG=y\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	// Markers spanning chunk boundaries
+	for _, pad := range []int{0, ikconfigScanChunkSize - 4, ikconfigScanChunkSize - len(ikconfigStart) - config.Len() - 2} {
+		image := append(bytes.Repeat([]byte{0}, pad), ikconfigStart...)
+		image = append(image, config.Bytes()...)
+		image = append(image, ikconfigEnd...)
+		image = append(image, bytes.Repeat([]byte{0}, 100)...)
+		data, err := scanIkconfig(bytes.NewReader(image))
+		assert.NoError(t, err)
+		assert.Equal(t, "CONFIG_IKCONFIG=y\n", string(data))
+	}
+
+	// Missing end marker
+	_, err = scanIkconfig(bytes.NewReader(append(append([]byte{}, ikconfigStart...), config.Bytes()...)))
+	assert.Error(t, err)
+}
+
+func TestReadIkconfig(t *testing.T) {
+	bootDir := t.TempDir()
+	oldBootDir := hostpath.BootDir
+	hostpath.BootDir = hostpath.HostDir(bootDir)
+	defer func() { hostpath.BootDir = oldBootDir }()
+
+	image, err := os.ReadFile(filepath.Join("testdata", "vmlinuz-zstd"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(bootDir, "vmlinuz-6.1.0-test"), image, 0644))
+
+	data, err := readIkconfig("6.1.0-test")
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "CONFIG_NO_HZ=y\n")
+
+	// Subsequent reads are served from the cache
+	assert.NoError(t, os.Remove(filepath.Join(bootDir, "vmlinuz-6.1.0-test")))
+	cached, err := readIkconfig("6.1.0-test")
+	assert.NoError(t, err)
+	assert.Equal(t, data, cached)
+
+	_, err = readIkconfig("6.1.0-missing")
+	assert.Error(t, err)
+
+	// Failures are not cached
+	assert.NoError(t, os.WriteFile(filepath.Join(bootDir, "vmlinuz-6.1.0-missing"), image, 0644))
+	_, err = readIkconfig("6.1.0-missing")
+	assert.NoError(t, err)
+}
@@ -25,6 +25,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"k8s.io/klog/v2"
+
 	"sigs.k8s.io/node-feature-discovery/pkg/utils/hostpath"
 )
 
@@ -95,8 +97,15 @@ func parseKconfig(configPath string) (realKconfig, legacyKconfig map[string]stri
 		}
 	}
 
+	// Fall back to extracting the config embedded in the kernel image
+	if raw == nil && kVer != "" {
+		if raw, err = readIkconfig(kVer); err != nil {
+			klog.V(2).InfoS("failed to extract kernel config from kernel image", "err", err)
+		}
+	}
+
 	if raw == nil {
-		return nil, nil, fmt.Errorf("failed to read kernel config from %+v", append([]string{configPath}, searchPaths...))
+		return nil, nil, fmt.Errorf("failed to read kernel config from %+v or the kernel image", append([]string{configPath}, searchPaths...))
 	}
 
 	// Process data, line-by-line
@@ -18,6 +18,7 @@ package kernel
 
 import (
 	"fmt"
+	"os"
 	"strconv"
 
 	"k8s.io/klog/v2"
@@ -57,13 +58,28 @@ func newDefaultConfig() *Config {
 	}
 }
 
-// kernelSource implements the FeatureSource, LabelSource and ConfigurableSource interfaces.
+// kernelSource implements the FeatureSource, LabelSource, ConfigurableSource
+// and CachingSource interfaces.
 type kernelSource struct {
 	config   *Config
 	features *nfdv1alpha1.Features
 	// legacyKconfig contains mangled kconfig values used for
 	// kernel.config-<flag> labels and legacy kConfig custom rules.
 	legacyKconfig map[string]string
+	// cache contains the features that only change with the kernel
+	cache        staticCache
+	cacheEnabled bool
+}
+
+// staticCache holds the kconfig and builtin modules, which only change with
+// the kernel version, so that they need not be re-read and decompressed on
+// every discovery round.
+type staticCache struct {
+	// key identifies the kernel (and kconfig file) the cache is valid for
+	key            string
+	realKconfig    map[string]string
+	legacyKconfig  map[string]string
+	builtinModules []string
 }
 
 // Singleton source instance
@@ -72,6 +88,7 @@ var (
 	_   source.FeatureSource      = &src
 	_   source.LabelSource        = &src
 	_   source.ConfigurableSource = &src
+	_   source.CachingSource      = &src
 )
 
 func (s *kernelSource) Name() string { return Name }
@@ -87,11 +104,18 @@ func (s *kernelSource) SetConfig(conf source.Config) {
 	switch v := conf.(type) {
 	case *Config:
 		s.config = v
+		s.cache = staticCache{}
 	default:
 		panic(fmt.Sprintf("invalid config type: %T", conf))
 	}
 }
 
+// SetCachingEnabled method of the CachingSource interface
+func (s *kernelSource) SetCachingEnabled(enabled bool) {
+	s.cacheEnabled = enabled
+	s.cache = staticCache{}
+}
+
 // Priority method of the LabelSource interface
 func (s *kernelSource) Priority() int { return 0 }
 
@@ -128,14 +152,26 @@ func (s *kernelSource) Discover() error {
 		s.features.Attributes[VersionFeature] = nfdv1alpha1.NewAttributeFeatures(version)
 	}
 
+	// Drop cached kconfig and builtin modules if caching is disabled or the
+	// kernel has changed
+	if !s.cacheEnabled {
+		s.cache = staticCache{}
+	} else if key := s.staticCacheKey(); key == "" || key != s.cache.key {
+		s.cache = staticCache{key: key}
+	}
+
 	// Read kconfig
-	if realKconfig, legacyKconfig, err := parseKconfig(s.config.KconfigFile); err != nil {
-		s.legacyKconfig = nil
-		klog.ErrorS(err, "failed to read kconfig")
-	} else {
-		s.features.Attributes[ConfigFeature] = nfdv1alpha1.NewAttributeFeatures(realKconfig)
-		s.legacyKconfig = legacyKconfig
+	if s.cache.realKconfig == nil {
+		if realKconfig, legacyKconfig, err := parseKconfig(s.config.KconfigFile); err != nil {
+			klog.ErrorS(err, "failed to read kconfig")
+		} else {
+			s.cache.realKconfig, s.cache.legacyKconfig = realKconfig, legacyKconfig
+		}
+	}
+	if s.cache.realKconfig != nil {
+		s.features.Attributes[ConfigFeature] = nfdv1alpha1.NewAttributeFeatures(s.cache.realKconfig)
 	}
+	s.legacyKconfig = s.cache.legacyKconfig
 
 	var enabledModules []string
 	if kmods, err := getLoadedModules(); err != nil {
@@ -145,10 +181,15 @@ func (s *kernelSource) Discover() error {
 		s.features.Flags[LoadedModuleFeature] = nfdv1alpha1.NewFlagFeatures(kmods...)
 	}
 
-	if builtinMods, err := getBuiltinModules(); err != nil {
-		klog.ErrorS(err, "failed to get builtin kernel modules")
-	} else {
-		enabledModules = append(enabledModules, builtinMods...)
+	if s.cache.builtinModules == nil {
+		if builtinMods, err := getBuiltinModules(); err != nil {
+			klog.ErrorS(err, "failed to get builtin kernel modules")
+		} else {
+			s.cache.builtinModules = builtinMods
+		}
+	}
+	if s.cache.builtinModules != nil {
+		enabledModules = append(enabledModules, s.cache.builtinModules...)
 		s.features.Flags[EnabledModuleFeature] = nfdv1alpha1.NewFlagFeatures(enabledModules...)
 	}
 
@@ -164,6 +205,25 @@ func (s *kernelSource) Discover() error {
 	return nil
 }
 
+// staticCacheKey returns the key identifying the kernel and the kconfig file,
+// or an empty string if the kernel version cannot be determined.
+func (s *kernelSource) staticCacheKey() string {
+	kVersion, err := getVersion()
+	if err != nil {
+		return ""
+	}
+	key := kVersion + "\x00" + s.config.KconfigFile
+	if s.config.KconfigFile != "" {
+		// A custom kconfig file may change independently of the kernel
+		fi, err := os.Stat(s.config.KconfigFile)
+		if err != nil {
+			return ""
+		}
+		key += fmt.Sprintf("\x00%d\x00%d", fi.Size(), fi.ModTime().UnixNano())
+	}
+	return key
+}
+
 func (s *kernelSource) GetFeatures() *nfdv1alpha1.Features {
 	if s.features == nil {
 		s.features = nfdv1alpha1.NewFeatures()
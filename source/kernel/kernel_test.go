@@ -33,3 +33,24 @@ func TestKernelSource(t *testing.T) {
 	assert.Empty(t, l)
 
 }
+
+func TestKernelSourceCache(t *testing.T) {
+	defer src.SetCachingEnabled(false)
+
+	key := src.staticCacheKey()
+	if key == "" {
+		t.Skip("kernel version not available")
+	}
+	discoverCached := func() bool {
+		src.cache = staticCache{key: key, builtinModules: []string{"fake-builtin"}}
+		assert.NoError(t, src.Discover())
+		_, ok := src.GetFeatures().Flags[EnabledModuleFeature].Elements["fake-builtin"]
+		return ok
+	}
+
+	src.SetCachingEnabled(true)
+	assert.True(t, discoverCached())
+
+	src.SetCachingEnabled(false)
+	assert.False(t, discoverCached())
+}
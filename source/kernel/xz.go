@@ -0,0 +1,308 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+const (
+	xzFilterX86   = 0x04
+	xzFilterLzma2 = 0x21
+)
+
+var xzMagic = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+
+// newXzReader returns a reader decompressing the first block of an xz
+// stream. Contrary to the generic xz decoders available, the BCJ filter for
+// x86 that the kernel build (scripts/xz_wrap.sh) applies to x86 kernel images
+// is supported. Kernel images are compressed in a single block, subsequent
+// blocks and the integrity check of the data are ignored.
+func newXzReader(r io.Reader) (io.Reader, error) {
+	var streamHeader [12]byte
+	if _, err := io.ReadFull(r, streamHeader[:]); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(streamHeader[:6], xzMagic) {
+		return nil, fmt.Errorf("xz: invalid magic")
+	}
+	if crc32.ChecksumIEEE(streamHeader[6:8]) != binary.LittleEndian.Uint32(streamHeader[8:]) {
+		return nil, fmt.Errorf("xz: stream header checksum mismatch")
+	}
+	if streamHeader[6] != 0 || streamHeader[7]&0xf0 != 0 {
+		return nil, fmt.Errorf("xz: unsupported stream flags")
+	}
+
+	// Block header
+	var size [1]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	if size[0] == 0 {
+		return nil, fmt.Errorf("xz: empty stream")
+	}
+	hdr := make([]byte, (int(size[0])+1)*4)
+	hdr[0] = size[0]
+	if _, err := io.ReadFull(r, hdr[1:]); err != nil {
+		return nil, err
+	}
+	crcOff := len(hdr) - 4
+	if crc32.ChecksumIEEE(hdr[:crcOff]) != binary.LittleEndian.Uint32(hdr[crcOff:]) {
+		return nil, fmt.Errorf("xz: block header checksum mismatch")
+	}
+	filters, err := parseXzBlockHeader(hdr[1:crcOff])
+	if err != nil {
+		return nil, err
+	}
+
+	// The filters are listed in the order they are applied when compressing,
+	// the last one being LZMA2
+	last := filters[len(filters)-1]
+	if last.id != xzFilterLzma2 || len(last.props) != 1 {
+		return nil, fmt.Errorf("xz: unsupported filter chain, LZMA2 must be the last filter")
+	}
+	dictCap, err := xzLzma2DictCap(last.props[0])
+	if err != nil {
+		return nil, err
+	}
+	lr, err := lzma.Reader2Config{DictCap: dictCap}.NewReader2(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var dr io.Reader = lr
+	for i := len(filters) - 2; i >= 0; i-- {
+		f := filters[i]
+		if f.id != xzFilterX86 {
+			return nil, fmt.Errorf("xz: unsupported filter %#x", f.id)
+		}
+		var start uint32
+		switch len(f.props) {
+		case 0:
+		case 4:
+			start = binary.LittleEndian.Uint32(f.props)
+		default:
+			return nil, fmt.Errorf("xz: invalid x86 filter properties")
+		}
+		dr = &bcjX86Reader{r: dr, pos: start}
+	}
+	return dr, nil
+}
+
+type xzFilter struct {
+	id    uint64
+	props []byte
+}
+
+// parseXzBlockHeader parses the block flags, optional sizes and the filter
+// flags of an xz block header.
+func parseXzBlockHeader(hdr []byte) ([]xzFilter, error) {
+	if len(hdr) < 1 {
+		return nil, fmt.Errorf("xz: truncated block header")
+	}
+	flags := hdr[0]
+	if flags&0x3c != 0 {
+		return nil, fmt.Errorf("xz: unsupported block flags")
+	}
+	buf := bytes.NewReader(hdr[1:])
+
+	// Compressed and uncompressed size, not needed for decoding
+	for _, present := range []bool{flags&0x40 != 0, flags&0x80 != 0} {
+		if present {
+			if _, err := readXzVarint(buf); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	filters := make([]xzFilter, int(flags&0x03)+1)
+	for i := range filters {
+		id, err := readXzVarint(buf)
+		if err != nil {
+			return nil, err
+		}
+		n, err := readXzVarint(buf)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(buf.Len()) {
+			return nil, fmt.Errorf("xz: truncated filter properties")
+		}
+		props := make([]byte, n)
+		if _, err := io.ReadFull(buf, props); err != nil {
+			return nil, err
+		}
+		filters[i] = xzFilter{id: id, props: props}
+	}
+	return filters, nil
+}
+
+// readXzVarint reads a variable-length integer of the xz format.
+func readXzVarint(r io.ByteReader) (uint64, error) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("xz: truncated block header")
+		}
+		v |= uint64(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("xz: invalid integer in block header")
+}
+
+// xzLzma2DictCap returns the dictionary capacity to use for decoding LZMA2
+// data with the given dictionary size property. The dictionary does not need
+// to be bigger than the amount of data we ever decompress.
+func xzLzma2DictCap(prop byte) (int, error) {
+	if prop > 40 {
+		return 0, fmt.Errorf("xz: invalid LZMA2 dictionary size")
+	}
+	size := int64(0xffffffff)
+	if prop < 40 {
+		size = int64(2|prop&1) << (prop/2 + 11)
+	}
+	if size > maxVmlinuxScanSize {
+		size = maxVmlinuxScanSize
+	}
+	if size < lzma.MinDictCap {
+		size = lzma.MinDictCap
+	}
+	return int(size), nil
+}
+
+// bcjX86Reader reverses the BCJ (branch/call/jump) filter for x86 of the xz
+// format. The filter converts the relative addresses of call and jump
+// instructions to absolute addresses to improve the compression ratio. This
+// is a port of the decoder of xz-embedded used by the Linux kernel.
+type bcjX86Reader struct {
+	r        io.Reader
+	pos      uint32
+	prevMask uint32
+	// buf holds the data read from r, of which the first filtered bytes
+	// have been decoded
+	buf      []byte
+	filtered int
+	err      error
+}
+
+// bcjX86ChunkSize is the amount of data decoded at a time.
+const bcjX86ChunkSize = 64 << 10
+
+func (b *bcjX86Reader) Read(p []byte) (int, error) {
+	for b.filtered == 0 {
+		if b.err != nil {
+			if len(b.buf) == 0 {
+				return 0, b.err
+			}
+			// Trailing bytes shorter than an instruction are not filtered
+			b.filtered = len(b.buf)
+			break
+		}
+
+		if b.buf == nil {
+			b.buf = make([]byte, 0, bcjX86ChunkSize)
+		}
+		n, err := b.r.Read(b.buf[len(b.buf):cap(b.buf)])
+		b.buf = b.buf[:len(b.buf)+n]
+		b.err = err
+
+		b.filtered = b.filter(b.buf)
+		b.pos += uint32(b.filtered)
+	}
+
+	n := copy(p, b.buf[:b.filtered])
+	b.filtered -= n
+	b.buf = b.buf[:copy(b.buf, b.buf[n:])]
+	return n, nil
+}
+
+// filter decodes the call and jump instructions in buf, returning the number
+// of bytes decoded. The remaining bytes may be the beginning of an
+// instruction and are decoded when more data is available.
+func (b *bcjX86Reader) filter(buf []byte) int {
+	maskToAllowedStatus := [8]bool{true, true, true, false, true, false, false, false}
+	maskToBitNum := [8]uint32{0, 1, 2, 2, 3, 3, 3, 3}
+	testMSByte := func(b byte) bool { return b == 0x00 || b == 0xff }
+
+	if len(buf) <= 4 {
+		return 0
+	}
+
+	size := len(buf) - 4
+	prevPos := -1
+	prevMask := b.prevMask
+	i := 0
+	for ; i < size; i++ {
+		if buf[i]&0xfe != 0xe8 {
+			continue
+		}
+
+		dist := i - prevPos
+		if dist > 3 {
+			prevMask = 0
+		} else {
+			prevMask = (prevMask << (dist - 1)) & 7
+			if prevMask != 0 {
+				if !maskToAllowedStatus[prevMask] || testMSByte(buf[i+4-int(maskToBitNum[prevMask])]) {
+					prevPos = i
+					prevMask = (prevMask << 1) | 1
+					continue
+				}
+			}
+		}
+		prevPos = i
+
+		if !testMSByte(buf[i+4]) {
+			prevMask = (prevMask << 1) | 1
+			continue
+		}
+
+		src := binary.LittleEndian.Uint32(buf[i+1:])
+		var dest uint32
+		for {
+			dest = src - (b.pos + uint32(i) + 5)
+			if prevMask == 0 {
+				break
+			}
+			j := maskToBitNum[prevMask] * 8
+			if !testMSByte(byte(dest >> (24 - j))) {
+				break
+			}
+			src = dest ^ (1<<(32-j) - 1)
+		}
+		dest &= 0x01ffffff
+		dest |= 0 - (dest & 0x01000000)
+		binary.LittleEndian.PutUint32(buf[i+1:], dest)
+		i += 4
+	}
+
+	if dist := i - prevPos; dist > 3 {
+		b.prevMask = 0
+	} else {
+		b.prevMask = prevMask << (dist - 1)
+	}
+	return i
+}
@@ -48,8 +48,9 @@ type memorySource struct {
 // Singleton source instance
 var (
 	src memorySource
-	_   source.FeatureSource = &src
-	_   source.LabelSource   = &src
+	_   source.FeatureSource       = &src
+	_   source.StaticFeatureSource = &src
+	_   source.LabelSource         = &src
 )
 
 // Name returns an identifier string for this feature source.
@@ -82,6 +83,11 @@ func (s *memorySource) GetLabels() (source.FeatureLabels, error) {
 	return labels, nil
 }
 
+// HotplugDirs method of the StaticFeatureSource interface
+func (s *memorySource) HotplugDirs() []string {
+	return []string{hostpath.SysfsDir.Path("bus/node/devices"), hostpath.SysfsDir.Path("bus/nd/devices")}
+}
+
 // Discover method of the FeatureSource interface
 func (s *memorySource) Discover() error {
 	s.features = nfdv1alpha1.NewFeatures()
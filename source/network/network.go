@@ -106,6 +106,14 @@ func (s *networkSource) Discover() error {
 	s.features.Instances[DeviceFeature] = nfdv1alpha1.InstanceFeatureSet{Elements: devs}
 	s.features.Instances[VirtualFeature] = nfdv1alpha1.InstanceFeatureSet{Elements: virts}
 
+	devNames := make([]string, len(devs))
+	for i, dev := range devs {
+		devNames[i] = dev.Attributes["name"]
+	}
+	pfs, vfs := detectSriov(hostpath.SysfsDir.Path(sysfsBaseDir), devNames)
+	s.features.Instances[SriovPfFeature] = nfdv1alpha1.InstanceFeatureSet{Elements: pfs}
+	s.features.Instances[SriovVfFeature] = nfdv1alpha1.InstanceFeatureSet{Elements: vfs}
+
 	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
 
 	return nil
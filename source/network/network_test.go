@@ -17,6 +17,9 @@ limitations under the License.
 package network
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,3 +36,95 @@ func TestNetworkSource(t *testing.T) {
 	assert.Empty(t, l)
 
 }
+
+func TestDetectSriov(t *testing.T) {
+	root := t.TempDir()
+	mkfile := func(path, content string) {
+		p := filepath.Join(root, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.NoError(t, os.WriteFile(p, []byte(content+"\n"), 0644))
+	}
+	mklink := func(target, path string) {
+		p := filepath.Join(root, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.NoError(t, os.Symlink(filepath.Join(root, target), p))
+	}
+
+	// PF with two VFs, one bound to a netdev driver and one to vfio-pci
+	pf := "devices/pci0000:00/0000:3b:00.0"
+	mkfile(pf+"/sriov_totalvfs", "8")
+	mkfile(pf+"/sriov_numvfs", "2")
+	mkfile(pf+"/numa_node", "1")
+	mkfile(pf+"/vendor", "0x15b3")
+	mklink("bus/pci/drivers/mlx5_core", pf+"/driver")
+	mklink("devices/pci0000:00/0000:3b:00.2", pf+"/virtfn0")
+	mklink("devices/pci0000:00/0000:3b:00.3", pf+"/virtfn1")
+	mklink(pf, "class/net/ens1f0/device")
+	mkfile("class/net/ens1f0/phys_switch_id", "abcd")
+	// Representor of the first VF
+	mklink(pf, "class/net/ens1f0_0/device")
+	mkfile("class/net/ens1f0_0/phys_port_name", "pf0vf0")
+
+	vf0 := "devices/pci0000:00/0000:3b:00.2"
+	mkfile(vf0+"/numa_node", "1")
+	mkfile(vf0+"/net/ens1f0v0/operstate", "up")
+	mklink("bus/pci/drivers/mlx5_core", vf0+"/driver")
+	vf1 := "devices/pci0000:00/0000:3b:00.3"
+	mkfile(vf1+"/numa_node", "1")
+	mklink("bus/pci/drivers/vfio-pci", vf1+"/driver")
+
+	// Non-SR-IOV interface
+	mkfile("devices/pci0000:00/0000:01:00.0/sriov_totalvfs", "0")
+	mklink("devices/pci0000:00/0000:01:00.0", "class/net/eno1/device")
+
+	origGetVfSettings := getVfSettings
+	defer func() { getVfSettings = origGetVfSettings }()
+	getVfSettings = func(pfName string) (map[int]map[string]string, error) {
+		assert.Equal(t, "ens1f0", pfName)
+		return map[int]map[string]string{0: {"trust": "true", "spoofchk": "false"}}, nil
+	}
+
+	pfs, vfs := detectSriov(filepath.Join(root, "class/net"), []string{"ens1f0_0", "eno1", "ens1f0"})
+
+	assert.Len(t, pfs, 1)
+	assert.Equal(t, map[string]string{
+		"name":           "ens1f0",
+		"pci_address":    "0000:3b:00.0",
+		"driver":         "mlx5_core",
+		"eswitch_mode":   "switchdev",
+		"vendor":         "0x15b3",
+		"numa_node":      "1",
+		"sriov_totalvfs": "8",
+		"sriov_numvfs":   "2",
+	}, pfs[0].Attributes)
+
+	assert.Len(t, vfs, 2)
+	assert.Equal(t, map[string]string{
+		"pci_address":    "0000:3b:00.2",
+		"pf_name":        "ens1f0",
+		"pf_pci_address": "0000:3b:00.0",
+		"vf_index":       "0",
+		"driver":         "mlx5_core",
+		"type":           "netdev",
+		"netdev":         "ens1f0v0",
+		"numa_node":      "1",
+		"trust":          "true",
+		"spoofchk":       "false",
+	}, vfs[0].Attributes)
+	assert.Equal(t, map[string]string{
+		"pci_address":    "0000:3b:00.3",
+		"pf_name":        "ens1f0",
+		"pf_pci_address": "0000:3b:00.0",
+		"vf_index":       "1",
+		"driver":         "vfio-pci",
+		"type":           "vfio",
+		"numa_node":      "1",
+	}, vfs[1].Attributes)
+	// VF settings are not available outside the host network namespace
+	getVfSettings = func(pfName string) (map[int]map[string]string, error) {
+		return nil, fmt.Errorf("%w: %q", errLinkNotFound, pfName)
+	}
+	_, vfs = detectSriov(filepath.Join(root, "class/net"), []string{"ens1f0"})
+	assert.Len(t, vfs, 2)
+	assert.NotContains(t, vfs[0].Attributes, "trust")
+}
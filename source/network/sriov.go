@@ -0,0 +1,198 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"sigs.k8s.io/node-feature-discovery/pkg/utils"
+)
+
+const (
+	// SriovPfFeature exposes SR-IOV physical functions
+	SriovPfFeature = "sriov_pf"
+	// SriovVfFeature exposes SR-IOV virtual functions
+	SriovVfFeature = "sriov_vf"
+)
+
+// VF types, depending on the driver the VF is bound to
+const (
+	vfTypeNetdev  = "netdev"
+	vfTypeVfio    = "vfio"
+	vfTypeUnbound = "unbound"
+	vfTypeOther   = "other"
+)
+
+// representorPortName matches the phys_port_name of switchdev VF and SF
+// representors, e.g. "pf0vf1" or "c1pf0sf3"
+var representorPortName = regexp.MustCompile(`^(c\d+)?pf\d+(vf|sf)\d+$`)
+
+// pfDevAttrs is the list of files under the PCI device directory of a PF that
+// we're reading
+var pfDevAttrs = []string{"vendor", "device", "numa_node", "sriov_totalvfs", "sriov_numvfs"}
+
+// getVfSettings returns the trust and spoofchk settings of the VFs of a PF,
+// indexed by VF index
+var getVfSettings = netlinkVfSettings
+
+// errLinkNotFound is returned by getVfSettings when the PF is not visible over
+// netlink, i.e. nfd-worker is not running in the host network namespace
+var errLinkNotFound = errors.New("link not found")
+
+// hostNetnsWarning makes the missing host network namespace to be reported
+// only once
+var hostNetnsWarning sync.Once
+
+// detectSriov returns the SR-IOV physical functions among the given network
+// interfaces, and their virtual functions.
+func detectSriov(sysfsBasePath string, ifaces []string) ([]nfdv1alpha1.InstanceFeature, []nfdv1alpha1.InstanceFeature) {
+	pfs := []nfdv1alpha1.InstanceFeature{}
+	vfs := []nfdv1alpha1.InstanceFeature{}
+	seen := map[string]bool{}
+
+	sort.Strings(ifaces)
+	for _, name := range ifaces {
+		ifacePath := filepath.Join(sysfsBasePath, name)
+		devPath := filepath.Join(ifacePath, "device")
+
+		totalVfs, err := readSysfsInt(filepath.Join(devPath, "sriov_totalvfs"))
+		if err != nil || totalVfs == 0 {
+			continue
+		}
+		// Skip switchdev representors which share the PCI device of the PF
+		if representorPortName.MatchString(readSysfsString(filepath.Join(ifacePath, "phys_port_name"))) {
+			continue
+		}
+		pciAddr := utils.LinkBase(devPath)
+		if pciAddr == "" || seen[pciAddr] {
+			continue
+		}
+		seen[pciAddr] = true
+
+		pf := map[string]string{
+			"name":         name,
+			"pci_address":  pciAddr,
+			"driver":       utils.LinkBase(filepath.Join(devPath, "driver")),
+			"eswitch_mode": eswitchMode(ifacePath),
+		}
+		for _, attr := range pfDevAttrs {
+			if v := readSysfsString(filepath.Join(devPath, attr)); v != "" {
+				pf[attr] = v
+			}
+		}
+		pfs = append(pfs, *nfdv1alpha1.NewInstanceFeature(pf))
+
+		vfSettings, err := getVfSettings(name)
+		if errors.Is(err, errLinkNotFound) {
+			hostNetnsWarning.Do(func() {
+				klog.InfoS("VF trust and spoofchk settings are not available, nfd-worker must run in the host network namespace (hostNetwork) to read them", "pfName", name)
+			})
+		} else if err != nil {
+			klog.ErrorS(err, "failed to get VF settings", "pfName", name)
+		}
+		vfs = append(vfs, detectVfs(name, pciAddr, devPath, vfSettings)...)
+	}
+	return pfs, vfs
+}
+
+// detectVfs returns the virtual functions of a physical function.
+func detectVfs(pfName, pfPciAddr, pfDevPath string, vfSettings map[int]map[string]string) []nfdv1alpha1.InstanceFeature {
+	links, err := filepath.Glob(filepath.Join(pfDevPath, "virtfn*"))
+	if err != nil {
+		return nil
+	}
+
+	vfs := make([]nfdv1alpha1.InstanceFeature, 0, len(links))
+	for _, link := range links {
+		index, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(link), "virtfn"))
+		if err != nil {
+			continue
+		}
+		driver := utils.LinkBase(filepath.Join(link, "driver"))
+		vf := map[string]string{
+			"pci_address":    utils.LinkBase(link),
+			"pf_name":        pfName,
+			"pf_pci_address": pfPciAddr,
+			"vf_index":       strconv.Itoa(index),
+			"driver":         driver,
+		}
+		if v := readSysfsString(filepath.Join(link, "numa_node")); v != "" {
+			vf["numa_node"] = v
+		}
+
+		netdevs, _ := os.ReadDir(filepath.Join(link, "net"))
+		switch {
+		case driver == "":
+			vf["type"] = vfTypeUnbound
+		case strings.HasPrefix(driver, "vfio"):
+			vf["type"] = vfTypeVfio
+		case len(netdevs) > 0:
+			vf["type"] = vfTypeNetdev
+			vf["netdev"] = netdevs[0].Name()
+		default:
+			vf["type"] = vfTypeOther
+		}
+
+		maps.Copy(vf, vfSettings[index])
+		vfs = append(vfs, *nfdv1alpha1.NewInstanceFeature(vf))
+	}
+	sort.Slice(vfs, func(i, j int) bool {
+		a, _ := strconv.Atoi(vfs[i].Attributes["vf_index"])
+		b, _ := strconv.Atoi(vfs[j].Attributes["vf_index"])
+		return a < b
+	})
+	return vfs
+}
+
+// eswitchMode returns the e-switch mode of a PF. Only netdevs in switchdev
+// mode have a switch ID.
+func eswitchMode(ifacePath string) string {
+	if readSysfsString(filepath.Join(ifacePath, "phys_switch_id")) != "" {
+		return "switchdev"
+	}
+	return "legacy"
+}
+
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.V(4).InfoS("failed to read sysfs attribute", "path", path, "err", err)
+		}
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readSysfsInt(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// netlinkVfSettings returns the trust and spoofchk settings of the virtual
+// functions of a PF, indexed by VF index. The settings are read from the
+// IFLA_VF_INFO attributes of the PF link, which are available regardless of
+// the driver. Settings not reported by the driver are omitted.
+func netlinkVfSettings(pfName string) (map[int]map[string]string, error) {
+	req := nl.NewNetlinkRequest(unix.RTM_GETLINK, unix.NLM_F_ACK)
+	req.AddData(nl.NewIfInfomsg(unix.AF_UNSPEC))
+	req.AddData(nl.NewRtAttr(unix.IFLA_EXT_MASK, nl.Uint32Attr(nl.RTEXT_FILTER_VF)))
+	req.AddData(nl.NewRtAttr(unix.IFLA_IFNAME, nl.ZeroTerminated(pfName)))
+
+	msgs, err := req.Execute(unix.NETLINK_ROUTE, unix.RTM_NEWLINK)
+	if errors.Is(err, unix.ENODEV) {
+		// The interface is visible in sysfs mounted from the host but not in
+		// the network namespace of the pod
+		return nil, fmt.Errorf("%w: %q", errLinkNotFound, pfName)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get link %q: %w", pfName, err)
+	}
+	if len(msgs) != 1 || len(msgs[0]) < unix.SizeofIfInfomsg {
+		return nil, fmt.Errorf("unexpected netlink response for link %q", pfName)
+	}
+	attrs, err := nl.ParseRouteAttr(msgs[0][unix.SizeofIfInfomsg:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse attributes of link %q: %w", pfName, err)
+	}
+
+	settings := map[int]map[string]string{}
+	for _, attr := range attrs {
+		if attr.Attr.Type != unix.IFLA_VFINFO_LIST {
+			continue
+		}
+		vfInfos, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse VF info of link %q: %w", pfName, err)
+		}
+		for _, vfInfo := range vfInfos {
+			vfAttrs, err := nl.ParseRouteAttr(vfInfo.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse VF info of link %q: %w", pfName, err)
+			}
+			vf, index := map[string]string{}, -1
+			for _, vfAttr := range vfAttrs {
+				switch vfAttr.Attr.Type {
+				case nl.IFLA_VF_SPOOFCHK:
+					msg := nl.DeserializeVfSpoofchk(vfAttr.Value)
+					index = int(msg.Vf)
+					setVfSetting(vf, "spoofchk", msg.Setting)
+				case nl.IFLA_VF_TRUST:
+					msg := nl.DeserializeVfTrust(vfAttr.Value)
+					index = int(msg.Vf)
+					setVfSetting(vf, "trust", msg.Setting)
+				}
+			}
+			if index >= 0 && len(vf) > 0 {
+				settings[index] = vf
+			}
+		}
+	}
+	return settings, nil
+}
+
+// setVfSetting stores a boolean VF setting. The kernel reports settings not
+// supported by the driver as -1, which are skipped.
+func setVfSetting(vf map[string]string, attr string, setting uint32) {
+	if setting == 0 || setting == 1 {
+		vf[attr] = strconv.FormatBool(setting == 1)
+	}
+}
@@ -25,6 +25,7 @@ import (
 
 	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"sigs.k8s.io/node-feature-discovery/pkg/utils"
+	"sigs.k8s.io/node-feature-discovery/pkg/utils/hostpath"
 	"sigs.k8s.io/node-feature-discovery/source"
 )
 
@@ -56,10 +57,11 @@ type pciSource struct {
 
 // Singleton source instance
 var (
-	src                           = pciSource{config: newDefaultConfig()}
-	_   source.FeatureSource      = &src
-	_   source.LabelSource        = &src
-	_   source.ConfigurableSource = &src
+	src                            = pciSource{config: newDefaultConfig()}
+	_   source.FeatureSource       = &src
+	_   source.StaticFeatureSource = &src
+	_   source.LabelSource         = &src
+	_   source.ConfigurableSource  = &src
 )
 
 // Name returns the name of the feature source
@@ -135,6 +137,11 @@ func (s *pciSource) GetLabels() (source.FeatureLabels, error) {
 	return labels, nil
 }
 
+// HotplugDirs method of the StaticFeatureSource interface
+func (s *pciSource) HotplugDirs() []string {
+	return []string{hostpath.SysfsDir.Path("bus/pci/devices")}
+}
+
 // Discover method of the FeatureSource interface
 func (s *pciSource) Discover() error {
 	s.features = nfdv1alpha1.NewFeatures()
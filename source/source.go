@@ -77,6 +77,29 @@ type SupplementalSource interface {
 	DisableByDefault() bool
 }
 
+// StaticFeatureSource is an optional interface for feature sources whose
+// features only change on reboot, kernel update or device hotplug. The results
+// of a previous Discover() may be re-used as long as the boot ID, the kernel
+// version and the entries of the hotplug directories of the source stay the
+// same.
+type StaticFeatureSource interface {
+	FeatureSource
+
+	// HotplugDirs returns the directories whose entries change on hotplug
+	// of the devices discovered by the source.
+	HotplugDirs() []string
+}
+
+// CachingSource is an optional interface for feature sources that cache
+// parts of their features internally across discovery rounds.
+type CachingSource interface {
+	FeatureSource
+
+	// SetCachingEnabled enables or disables the internal caching of the
+	// source.
+	SetCachingEnabled(bool)
+}
+
 // FeatureLabelValue represents the value of one feature label
 type FeatureLabelValue interface{}
 
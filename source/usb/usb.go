@@ -25,6 +25,7 @@ import (
 
 	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"sigs.k8s.io/node-feature-discovery/pkg/utils"
+	"sigs.k8s.io/node-feature-discovery/pkg/utils/hostpath"
 	"sigs.k8s.io/node-feature-discovery/source"
 )
 
@@ -59,10 +60,11 @@ type usbSource struct {
 
 // Singleton source instance
 var (
-	src                           = usbSource{config: newDefaultConfig()}
-	_   source.FeatureSource      = &src
-	_   source.LabelSource        = &src
-	_   source.ConfigurableSource = &src
+	src                            = usbSource{config: newDefaultConfig()}
+	_   source.FeatureSource       = &src
+	_   source.StaticFeatureSource = &src
+	_   source.LabelSource         = &src
+	_   source.ConfigurableSource  = &src
 )
 
 // Name returns the name of the feature source
@@ -134,6 +136,11 @@ func (s *usbSource) GetLabels() (source.FeatureLabels, error) {
 	return labels, nil
 }
 
+// HotplugDirs method of the StaticFeatureSource interface
+func (s *usbSource) HotplugDirs() []string {
+	return []string{hostpath.SysfsDir.Path("bus/usb/devices")}
+}
+
 // Discover method of the FeatureSource interface
 func (s *usbSource) Discover() error {
 	s.features = nfdv1alpha1.NewFeatures()